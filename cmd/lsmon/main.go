@@ -9,10 +9,12 @@ import (
 
 	"github.com/blacknon/lssh/internal/app/lsmon"
 	"github.com/blacknon/lssh/internal/common"
+	conf "github.com/blacknon/lssh/internal/config"
 )
 
 func main() {
 	app := lsmon.Lsmon()
 	args := common.ParseArgs(app.Flags, common.NormalizeGenerateLSSHConfArgs(os.Args))
 	app.Run(args)
+	conf.WaitInventoryCacheRefresh()
 }
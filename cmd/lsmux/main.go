@@ -9,10 +9,12 @@ import (
 
 	"github.com/blacknon/lssh/internal/app/lsmux"
 	"github.com/blacknon/lssh/internal/common"
+	conf "github.com/blacknon/lssh/internal/config"
 )
 
 func main() {
 	app := lsmux.Lsmux()
 	args := common.ParseArgs(app.Flags, common.NormalizeGenerateLSSHConfArgs(os.Args))
 	app.Run(args)
+	conf.WaitInventoryCacheRefresh()
 }
@@ -10,12 +10,15 @@ import (
 
 	"github.com/blacknon/lssh/internal/app/lspipe"
 	"github.com/blacknon/lssh/internal/common"
+	conf "github.com/blacknon/lssh/internal/config"
 )
 
 func main() {
 	app := lspipe.Lspipe()
 	args := common.ParseArgs(app.Flags, common.NormalizeGenerateLSSHConfArgs(os.Args))
-	if err := app.Run(args); err != nil {
+	err := app.Run(args)
+	conf.WaitInventoryCacheRefresh()
+	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
@@ -9,10 +9,12 @@ import (
 
 	lssh "github.com/blacknon/lssh/internal/app/lssh"
 	"github.com/blacknon/lssh/internal/common"
+	conf "github.com/blacknon/lssh/internal/config"
 )
 
 func main() {
 	app := lssh.Lssh()
-	args := common.ParseArgs(app.Flags, common.NormalizeGenerateLSSHConfArgs(os.Args))
+	args := common.ParseArgs(app.Flags, common.NormalizeGenerateLSSHConfArgs(common.NormalizeConfigValidateArgs(os.Args)))
 	app.Run(args)
+	conf.WaitInventoryCacheRefresh()
 }
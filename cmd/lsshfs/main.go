@@ -10,6 +10,7 @@ import (
 
 	lsshfs "github.com/blacknon/lssh/internal/app/lsshfs"
 	"github.com/blacknon/lssh/internal/common"
+	conf "github.com/blacknon/lssh/internal/config"
 )
 
 func main() {
@@ -31,7 +32,9 @@ func main() {
 		"--generate-lssh-conf": true,
 	})
 	args = common.ParseArgs(app.Flags, args)
-	if err := app.Run(args); err != nil {
+	err := app.Run(args)
+	conf.WaitInventoryCacheRefresh()
+	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
@@ -13,6 +13,7 @@ import (
 	"github.com/blacknon/lssh/internal/check"
 	"github.com/blacknon/lssh/internal/common"
 	conf "github.com/blacknon/lssh/internal/config"
+	"github.com/blacknon/lssh/internal/hoststate"
 	"github.com/blacknon/lssh/internal/list"
 	"github.com/blacknon/lssh/internal/scp"
 	"github.com/blacknon/lssh/internal/version"
@@ -62,7 +63,7 @@ USAGE:
 	app.Version = version.AppVersion(app.Name)
 
 	app.Flags = []cli.Flag{
-		cli.StringSliceFlag{Name: "host,H", Usage: "connect servernames"},
+		cli.StringSliceFlag{Name: "host,H", Usage: "connect servernames or host selectors (ex. tag:web,env=prod,!tag:canary)"},
 		cli.BoolFlag{Name: "list,l", Usage: "print server list from config"},
 		cli.StringFlag{Name: "file,F", Value: defConf, Usage: "config file path"},
 		cli.StringFlag{Name: "generate-lssh-conf", Usage: "print generated lssh config from OpenSSH config to stdout (`~/.ssh/config` by default)."},
@@ -72,6 +73,10 @@ USAGE:
 		cli.BoolFlag{Name: "help,h", Usage: "print this help"},
 	}
 	app.Flags = append(app.Flags, common.ControlMasterOverrideFlags()...)
+	app.Flags = append(app.Flags, common.InventoryCacheFlags()...)
+	app.Flags = append(app.Flags, common.HostSelectionFlags()...)
+	app.Flags = append(app.Flags, common.ListFlags()...)
+	app.Flags = append(app.Flags, common.SelectorFlags()...)
 	app.EnableBashCompletion = true
 	app.HideHelp = true
 
@@ -79,10 +84,14 @@ USAGE:
 		// show help messages
 		if c.Bool("help") {
 			cli.ShowAppHelp(c)
-			os.Exit(0)
+			conf.Exit(0)
 		}
 
-		hosts := c.StringSlice("host")
+		hosts, err := common.GetFlagHosts(c)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+			conf.Exit(1)
+		}
 		confpath := c.String("file")
 		controlMasterOverride, controlMasterErr := common.GetControlMasterOverride(c)
 		if controlMasterErr != nil {
@@ -94,11 +103,18 @@ USAGE:
 		}
 
 		// Get config data
+		conf.SetRefreshInventory(c.Bool("refresh-inventory"))
 		data, err := conf.ReadWithFallback(confpath, os.Stderr)
 		if err != nil {
 			return err
 		}
 
+		hosts, err = list.ResolveHosts(data, hosts, c.StringSlice("select"))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+			conf.Exit(1)
+		}
+
 		// Get Server Name List (and sort List)
 		allNames := conf.GetNameList(data)
 		names := append([]string(nil), allNames...)
@@ -109,18 +125,45 @@ USAGE:
 		sort.Strings(names)
 
 		if c.Bool("list") {
-			fmt.Fprintln(os.Stdout, "lssh Server List:")
-			for _, name := range names {
-				fmt.Fprintf(os.Stdout, "  %s\n", name)
+			if err := data.WriteServerListFormat(os.Stdout, names, common.GetListOptions(c)); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+				conf.Exit(1)
+			}
+			conf.Exit(0)
+		}
+
+		if err := list.ValidateSortOrder(c.String("sort")); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+			conf.Exit(1)
+		}
+		if err := data.CheckListGroupBy(c.String("group-by")); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+			conf.Exit(1)
+		}
+
+		if c.Bool("print-selection") {
+			l := &list.ListInfo{
+				Prompt:    "lscp>>",
+				NameList:  names,
+				DataList:  data,
+				MultiFlag: true,
+				Command:   "lscp",
+				Sort:      c.String("sort"),
+				GroupBy:   c.String("group-by"),
+				Probe:     c.Bool("probe"),
+			}
+			if err := l.PrintSelection(os.Stdout, hosts); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+				conf.Exit(1)
 			}
-			os.Exit(0)
+			conf.Exit(0)
 		}
 
 		// check count args
 		if len(c.Args()) < 2 {
 			fmt.Fprintln(os.Stderr, "Too few arguments.")
 			cli.ShowAppHelp(c)
-			os.Exit(1)
+			conf.Exit(1)
 		}
 
 		// Set args path
@@ -156,12 +199,9 @@ USAGE:
 			if err != nil {
 				return err
 			}
-			if check.ExistServer(hosts, allNames) == false {
-				fmt.Fprintln(os.Stderr, "Input Server not found from list.")
-				os.Exit(1)
-			} else if len(filteredHosts) != len(hosts) {
+			if len(filteredHosts) != len(hosts) {
 				fmt.Fprintln(os.Stderr, "Input Server does not support SFTP-based transfer.")
-				os.Exit(1)
+				conf.Exit(1)
 			} else {
 				toServer = hosts
 			}
@@ -170,7 +210,7 @@ USAGE:
 		case isFromInRemote && isToRemote:
 			if len(names) == 0 {
 				fmt.Fprintln(os.Stderr, "No servers matched the current config conditions.")
-				os.Exit(1)
+				conf.Exit(1)
 			}
 			// View From list
 			from_l := new(list.ListInfo)
@@ -178,17 +218,21 @@ USAGE:
 			from_l.NameList = names
 			from_l.DataList = data
 			from_l.MultiFlag = false
+			from_l.Command = "lscp"
+			from_l.Sort = c.String("sort")
+			from_l.GroupBy = c.String("group-by")
+			from_l.Probe = c.Bool("probe")
 			from_l.View()
 			fromServer = from_l.SelectName
 
 			// Check selected
 			if len(fromServer) == 0 {
 				fmt.Fprintln(os.Stderr, "Selection cancelled.")
-				os.Exit(1)
+				conf.Exit(1)
 			}
 			if fromServer[0] == "ServerName" {
 				fmt.Fprintln(os.Stderr, "Server not selected.")
-				os.Exit(1)
+				conf.Exit(1)
 			}
 
 			// View to list
@@ -197,22 +241,26 @@ USAGE:
 			to_l.NameList = names
 			to_l.DataList = data
 			to_l.MultiFlag = true
+			to_l.Command = "lscp"
+			to_l.Sort = c.String("sort")
+			to_l.GroupBy = c.String("group-by")
+			to_l.Probe = c.Bool("probe")
 			to_l.View()
 			toServer = to_l.SelectName
 			if len(toServer) == 0 {
 				fmt.Fprintln(os.Stderr, "Selection cancelled.")
-				os.Exit(1)
+				conf.Exit(1)
 			}
 
 			if toServer[0] == "ServerName" {
 				fmt.Fprintln(os.Stderr, "Server not selected.")
-				os.Exit(1)
+				conf.Exit(1)
 			}
 
 		default:
 			if len(names) == 0 {
 				fmt.Fprintln(os.Stderr, "No servers matched the current config conditions.")
-				os.Exit(1)
+				conf.Exit(1)
 			}
 			// View List And Get Select Line
 			l := new(list.ListInfo)
@@ -220,17 +268,21 @@ USAGE:
 			l.NameList = names
 			l.DataList = data
 			l.MultiFlag = true
+			l.Command = "lscp"
+			l.Sort = c.String("sort")
+			l.GroupBy = c.String("group-by")
+			l.Probe = c.Bool("probe")
 			l.View()
 
 			selected = l.SelectName
 			// Check selected
 			if len(selected) == 0 {
 				fmt.Fprintln(os.Stderr, "Selection cancelled.")
-				os.Exit(1)
+				conf.Exit(1)
 			}
 			if selected[0] == "ServerName" {
 				fmt.Fprintln(os.Stderr, "Server not selected.")
-				os.Exit(1)
+				conf.Exit(1)
 			}
 
 			if isFromInRemote {
@@ -255,7 +307,7 @@ USAGE:
 				_, err := os.Stat(common.GetFullPath(fromPath))
 				if err != nil {
 					fmt.Fprintf(os.Stderr, "not found path %s \n", fromPath)
-					os.Exit(1)
+					conf.Exit(1)
 				}
 				fromPath = common.GetFullPath(fromPath)
 			}
@@ -300,6 +352,7 @@ USAGE:
 		}
 
 		scp.Start()
+		_ = hoststate.Record("lscp", scp.Succeeded())
 		return nil
 	}
 
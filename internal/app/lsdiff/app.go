@@ -12,6 +12,7 @@ import (
 	"github.com/blacknon/lssh/internal/check"
 	"github.com/blacknon/lssh/internal/common"
 	conf "github.com/blacknon/lssh/internal/config"
+	"github.com/blacknon/lssh/internal/list"
 	diffapp "github.com/blacknon/lssh/internal/lsdiff"
 	"github.com/blacknon/lssh/internal/version"
 	"github.com/urfave/cli"
@@ -57,18 +58,21 @@ USAGE:
 	app.EnableBashCompletion = true
 	app.HideHelp = true
 	app.Flags = []cli.Flag{
-		cli.StringSliceFlag{Name: "host,H", Usage: "connect `servername`."},
+		cli.StringSliceFlag{Name: "host,H", Usage: "connect `servername` or host selector (ex. tag:web,env=prod,!tag:canary)."},
 		cli.BoolFlag{Name: "list,l", Usage: "print server list from config."},
 		cli.StringFlag{Name: "file,F", Value: defConf, Usage: "config `filepath`."},
 		cli.StringFlag{Name: "generate-lssh-conf", Usage: "print generated lssh config from OpenSSH config to stdout (`~/.ssh/config` by default)."},
 		cli.BoolFlag{Name: "help,h", Usage: "print this help"},
 	}
 	app.Flags = append(app.Flags, common.ControlMasterOverrideFlags()...)
+	app.Flags = append(app.Flags, common.InventoryCacheFlags()...)
+	app.Flags = append(app.Flags, common.HostSelectionFlags()...)
+	app.Flags = append(app.Flags, common.ListFlags()...)
 
 	app.Action = func(c *cli.Context) error {
 		if c.Bool("help") {
 			cli.ShowAppHelp(c)
-			os.Exit(0)
+			conf.Exit(0)
 		}
 
 		if handled, err := conf.HandleGenerateConfigMode(c.String("generate-lssh-conf"), os.Stdout); handled {
@@ -80,6 +84,7 @@ USAGE:
 			return controlMasterErr
 		}
 
+		conf.SetRefreshInventory(c.Bool("refresh-inventory"))
 		config, err := conf.ReadWithFallback(c.String("file"), os.Stderr)
 		if err != nil {
 			return err
@@ -94,9 +99,34 @@ USAGE:
 		sort.Strings(names)
 
 		if c.Bool("list") {
-			fmt.Fprintln(os.Stdout, "lssh Server List:")
-			for _, name := range names {
-				fmt.Fprintf(os.Stdout, "  %s\n", name)
+			if err := config.WriteServerListFormat(os.Stdout, names, common.GetListOptions(c)); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+				conf.Exit(1)
+			}
+			return nil
+		}
+
+		flagHosts, err := common.GetFlagHosts(c)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+			conf.Exit(1)
+		}
+		flagHosts, err = list.ResolveHosts(config, flagHosts, c.StringSlice("select"))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+			conf.Exit(1)
+		}
+		if c.Bool("print-selection") {
+			l := &list.ListInfo{
+				Prompt:    "lsdiff>>",
+				NameList:  names,
+				DataList:  config,
+				MultiFlag: true,
+				Command:   "lsdiff",
+			}
+			if err := l.PrintSelection(os.Stdout, flagHosts); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+				conf.Exit(1)
 			}
 			return nil
 		}
@@ -106,7 +136,7 @@ USAGE:
 			return fmt.Errorf("lsdiff requires at least one remote path")
 		}
 
-		targets, err := resolveDiffTargets(config, allNames, names, c.StringSlice("host"), c.Args())
+		targets, err := resolveDiffTargets(config, allNames, names, flagHosts, c.Args())
 		if err != nil {
 			return err
 		}
@@ -155,9 +185,6 @@ func resolveDiffTargets(config conf.Config, allNames, supportedNames, flagHosts,
 	if len(flagHosts) < 2 {
 		return nil, fmt.Errorf("select at least two hosts")
 	}
-	if !check.ExistServer(flagHosts, allNames) {
-		return nil, fmt.Errorf("selected host not found from list")
-	}
 	if !check.ExistServer(flagHosts, supportedNames) {
 		return nil, fmt.Errorf("selected host does not support SFTP-based transfer")
 	}
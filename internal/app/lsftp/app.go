@@ -9,9 +9,9 @@ import (
 	"os"
 	"sort"
 
-	"github.com/blacknon/lssh/internal/check"
 	"github.com/blacknon/lssh/internal/common"
 	conf "github.com/blacknon/lssh/internal/config"
+	"github.com/blacknon/lssh/internal/hoststate"
 	"github.com/blacknon/lssh/internal/list"
 	"github.com/blacknon/lssh/internal/sftp"
 	"github.com/blacknon/lssh/internal/version"
@@ -55,7 +55,7 @@ USAGE:
 	app.Version = version.AppVersion(app.Name)
 
 	app.Flags = []cli.Flag{
-		cli.StringSliceFlag{Name: "host,H", Usage: "connect `servername`"},
+		cli.StringSliceFlag{Name: "host,H", Usage: "connect `servername` or host selector (ex. tag:web,env=prod,!tag:canary)"},
 		cli.BoolFlag{Name: "list,l", Usage: "print server list from config"},
 		cli.StringFlag{Name: "file,F", Value: defConf, Usage: "config file path"},
 		cli.StringFlag{Name: "generate-lssh-conf", Usage: "print generated lssh config from OpenSSH config to stdout (`~/.ssh/config` by default)."},
@@ -63,6 +63,10 @@ USAGE:
 		cli.BoolFlag{Name: "help,h", Usage: "print this help"},
 	}
 	app.Flags = append(app.Flags, common.ControlMasterOverrideFlags()...)
+	app.Flags = append(app.Flags, common.InventoryCacheFlags()...)
+	app.Flags = append(app.Flags, common.HostSelectionFlags()...)
+	app.Flags = append(app.Flags, common.ListFlags()...)
+	app.Flags = append(app.Flags, common.SelectorFlags()...)
 
 	app.EnableBashCompletion = true
 	app.HideHelp = true
@@ -71,7 +75,7 @@ USAGE:
 		// show help messages
 		if c.Bool("help") {
 			cli.ShowAppHelp(c)
-			os.Exit(0)
+			conf.Exit(0)
 		}
 
 		controlMasterOverride, controlMasterErr := common.GetControlMasterOverride(c)
@@ -79,7 +83,11 @@ USAGE:
 			return controlMasterErr
 		}
 
-		hosts := c.StringSlice("host")
+		hosts, err := common.GetFlagHosts(c)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+			conf.Exit(1)
+		}
 		confpath := c.String("file")
 
 		if handled, err := conf.HandleGenerateConfigMode(c.String("generate-lssh-conf"), os.Stdout); handled {
@@ -87,11 +95,18 @@ USAGE:
 		}
 
 		// Get config data
+		conf.SetRefreshInventory(c.Bool("refresh-inventory"))
 		data, err := conf.ReadWithFallback(confpath, os.Stderr)
 		if err != nil {
 			return err
 		}
 
+		hosts, err = list.ResolveHosts(data, hosts, c.StringSlice("select"))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+			conf.Exit(1)
+		}
+
 		// Get Server Name List (and sort List)
 		allNames := conf.GetNameList(data)
 		names := append([]string(nil), allNames...)
@@ -102,11 +117,38 @@ USAGE:
 		sort.Strings(names)
 
 		if c.Bool("list") {
-			fmt.Fprintln(os.Stdout, "lssh Server List:")
-			for _, name := range names {
-				fmt.Fprintf(os.Stdout, "  %s\n", name)
+			if err := data.WriteServerListFormat(os.Stdout, names, common.GetListOptions(c)); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+				conf.Exit(1)
 			}
-			os.Exit(0)
+			conf.Exit(0)
+		}
+
+		if err := list.ValidateSortOrder(c.String("sort")); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+			conf.Exit(1)
+		}
+		if err := data.CheckListGroupBy(c.String("group-by")); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+			conf.Exit(1)
+		}
+
+		if c.Bool("print-selection") {
+			l := &list.ListInfo{
+				Prompt:    "lsftp>>",
+				NameList:  names,
+				DataList:  data,
+				MultiFlag: true,
+				Command:   "lsftp",
+				Sort:      c.String("sort"),
+				GroupBy:   c.String("group-by"),
+				Probe:     c.Bool("probe"),
+			}
+			if err := l.PrintSelection(os.Stdout, hosts); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+				conf.Exit(1)
+			}
+			conf.Exit(0)
 		}
 
 		selected := []string{}
@@ -115,19 +157,15 @@ USAGE:
 			if err != nil {
 				return err
 			}
-			if !check.ExistServer(hosts, allNames) {
-				fmt.Fprintln(os.Stderr, "Input Server not found from list.")
-				os.Exit(1)
-			}
 			if len(filteredHosts) != len(hosts) {
 				fmt.Fprintln(os.Stderr, "Input Server does not support SFTP-based transfer.")
-				os.Exit(1)
+				conf.Exit(1)
 			}
 			selected = hosts
 		} else {
 			if len(names) == 0 {
 				fmt.Fprintln(os.Stderr, "No servers matched the current config conditions.")
-				os.Exit(1)
+				conf.Exit(1)
 			}
 
 			l := new(list.ListInfo)
@@ -135,16 +173,20 @@ USAGE:
 			l.NameList = names
 			l.DataList = data
 			l.MultiFlag = true
+			l.Command = "lsftp"
+			l.Sort = c.String("sort")
+			l.GroupBy = c.String("group-by")
+			l.Probe = c.Bool("probe")
 			l.View()
 
 			selected = l.SelectName
 			if len(selected) == 0 {
 				fmt.Fprintln(os.Stderr, "Selection cancelled.")
-				os.Exit(1)
+				conf.Exit(1)
 			}
 			if selected[0] == "ServerName" {
 				fmt.Fprintln(os.Stderr, "Server not selected.")
-				os.Exit(1)
+				conf.Exit(1)
 			}
 		}
 
@@ -157,6 +199,7 @@ USAGE:
 
 		// start lsftp shell
 		runSftp.Start()
+		_ = hoststate.Record("lsftp", selected)
 		return nil
 	}
 
@@ -19,9 +19,9 @@ import (
 	"net/http"
 	_ "net/http/pprof"
 
-	"github.com/blacknon/lssh/internal/check"
 	"github.com/blacknon/lssh/internal/common"
 	conf "github.com/blacknon/lssh/internal/config"
+	"github.com/blacknon/lssh/internal/hoststate"
 	"github.com/blacknon/lssh/internal/list"
 	mon "github.com/blacknon/lssh/internal/monitor"
 	sshcmd "github.com/blacknon/lssh/internal/ssh"
@@ -70,7 +70,7 @@ USAGE:
 	// Set options
 	app.Flags = []cli.Flag{
 		// common option
-		cli.StringSliceFlag{Name: "host,H", Usage: "connect `servername`."},
+		cli.StringSliceFlag{Name: "host,H", Usage: "connect `servername` or host selector (ex. tag:web,env=prod,!tag:canary)."},
 		cli.StringFlag{Name: "file,F", Value: defConf, Usage: "config `filepath`."},
 		cli.StringFlag{Name: "generate-lssh-conf", Usage: "print generated lssh config from OpenSSH config to stdout (`~/.ssh/config` by default)."},
 		cli.StringFlag{Name: "logfile,L", Usage: "Set log file path."},
@@ -82,6 +82,10 @@ USAGE:
 		cli.BoolFlag{Name: "help,h", Usage: "print this help"},
 	}
 	app.Flags = append(app.Flags, common.ControlMasterOverrideFlags()...)
+	app.Flags = append(app.Flags, common.InventoryCacheFlags()...)
+	app.Flags = append(app.Flags, common.HostSelectionFlags()...)
+	app.Flags = append(app.Flags, common.ListFlags()...)
+	app.Flags = append(app.Flags, common.SelectorFlags()...)
 	app.EnableBashCompletion = true
 	app.HideHelp = true
 
@@ -90,7 +94,7 @@ USAGE:
 		// show help messages
 		if c.Bool("help") {
 			cli.ShowAppHelp(c)
-			os.Exit(0)
+			conf.Exit(0)
 		}
 
 		logpath := c.String("logfile")
@@ -107,7 +111,11 @@ USAGE:
 
 		log.SetOutput(logfile)
 
-		hosts := c.StringSlice("host")
+		hosts, err := common.GetFlagHosts(c)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+			conf.Exit(1)
+		}
 		confpath := c.String("file")
 		controlMasterOverride, controlMasterErr := common.GetControlMasterOverride(c)
 		if controlMasterErr != nil {
@@ -121,11 +129,18 @@ USAGE:
 		debug := c.Bool("debug")
 
 		// Get config data
+		conf.SetRefreshInventory(c.Bool("refresh-inventory"))
 		data, err := conf.ReadWithFallback(confpath, os.Stderr)
 		if err != nil {
 			return err
 		}
 
+		hosts, err = list.ResolveHosts(data, hosts, c.StringSlice("select"))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+			conf.Exit(1)
+		}
+
 		// Set `exec command` or `shell` flag
 		isMulti := true
 
@@ -140,11 +155,38 @@ USAGE:
 
 		// Check list flag
 		if c.Bool("list") {
-			fmt.Fprintf(os.Stdout, "lssh Server List:\n")
-			for v := range names {
-				fmt.Fprintf(os.Stdout, "  %s\n", names[v])
+			if err := data.WriteServerListFormat(os.Stdout, names, common.GetListOptions(c)); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+				conf.Exit(1)
+			}
+			conf.Exit(0)
+		}
+
+		if err := list.ValidateSortOrder(c.String("sort")); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+			conf.Exit(1)
+		}
+		if err := data.CheckListGroupBy(c.String("group-by")); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+			conf.Exit(1)
+		}
+
+		if c.Bool("print-selection") {
+			l := &list.ListInfo{
+				Prompt:    "lsmon>>",
+				NameList:  names,
+				DataList:  data,
+				MultiFlag: true,
+				Command:   "lsmon",
+				Sort:      c.String("sort"),
+				GroupBy:   c.String("group-by"),
+				Probe:     c.Bool("probe"),
 			}
-			os.Exit(0)
+			if err := l.PrintSelection(os.Stdout, hosts); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+				conf.Exit(1)
+			}
+			conf.Exit(0)
 		}
 
 		selected := []string{}
@@ -153,19 +195,16 @@ USAGE:
 			if err != nil {
 				return err
 			}
-			if !check.ExistServer(hosts, allNames) {
-				fmt.Fprintln(os.Stderr, "Input Server not found from list.")
-				os.Exit(1)
-			} else if len(filteredHosts) != len(hosts) {
+			if len(filteredHosts) != len(hosts) {
 				fmt.Fprintln(os.Stderr, "Input Server does not support SFTP-based monitoring.")
-				os.Exit(1)
+				conf.Exit(1)
 			} else {
 				selected = hosts
 			}
 		} else {
 			if len(names) == 0 {
 				fmt.Fprintln(os.Stderr, "No servers matched the current config conditions.")
-				os.Exit(1)
+				conf.Exit(1)
 			}
 			// View List And Get Select Line
 			l := new(list.ListInfo)
@@ -173,16 +212,20 @@ USAGE:
 			l.NameList = names
 			l.DataList = data
 			l.MultiFlag = isMulti
+			l.Command = "lsmon"
+			l.Sort = c.String("sort")
+			l.GroupBy = c.String("group-by")
+			l.Probe = c.Bool("probe")
 
 			l.View()
 			selected = l.SelectName
 			if len(selected) == 0 {
 				fmt.Fprintln(os.Stderr, "Selection cancelled.")
-				os.Exit(1)
+				conf.Exit(1)
 			}
 			if selected[0] == "ServerName" {
 				fmt.Fprintln(os.Stderr, "Server not selected.")
-				os.Exit(1)
+				conf.Exit(1)
 			}
 		}
 
@@ -210,7 +253,11 @@ USAGE:
 		// create AuthMap
 		r.CreateAuthMethodMap()
 
-		return mon.Run(r)
+		if err := mon.Run(r); err != nil {
+			return err
+		}
+		_ = hoststate.Record("lsmon", selected)
+		return nil
 	}
 	return app
 }
@@ -15,9 +15,9 @@ import (
 	"strings"
 	"time"
 
-	"github.com/blacknon/lssh/internal/check"
 	"github.com/blacknon/lssh/internal/common"
 	conf "github.com/blacknon/lssh/internal/config"
+	"github.com/blacknon/lssh/internal/list"
 	lsmuxsession "github.com/blacknon/lssh/internal/lsmuxsession"
 	"github.com/blacknon/lssh/internal/mux"
 	"github.com/blacknon/lssh/internal/version"
@@ -56,7 +56,7 @@ USAGE:
 	app.EnableBashCompletion = true
 	app.HideHelp = true
 	app.Flags = []cli.Flag{
-		cli.StringSliceFlag{Name: "host,H", Usage: "connect `servername`."},
+		cli.StringSliceFlag{Name: "host,H", Usage: "connect `servername` or host selector (ex. tag:web,env=prod,!tag:canary)."},
 		cli.StringFlag{Name: "file,F", Value: defConf, Usage: "config `filepath`."},
 		cli.StringFlag{Name: "generate-lssh-conf", Usage: "print generated lssh config from OpenSSH config to stdout (`~/.ssh/config` by default)."},
 		cli.StringSliceFlag{Name: "R", Usage: "Remote port forward mode.Specify a `[bind_address:]port:remote_address:port`. If only one port is specified, it will operate as Reverse Dynamic Forward."},
@@ -80,11 +80,14 @@ USAGE:
 		cli.BoolFlag{Name: "mux-child", Hidden: true},
 	}
 	app.Flags = append(app.Flags, common.ControlMasterOverrideFlags()...)
+	app.Flags = append(app.Flags, common.InventoryCacheFlags()...)
+	app.Flags = append(app.Flags, common.HostSelectionFlags()...)
+	app.Flags = append(app.Flags, common.ListFlags()...)
 
 	app.Action = func(c *cli.Context) error {
 		if c.Bool("help") {
 			cli.ShowAppHelp(c)
-			os.Exit(0)
+			conf.Exit(0)
 		}
 
 		if handled, err := conf.HandleGenerateConfigMode(c.String("generate-lssh-conf"), os.Stdout); handled {
@@ -95,6 +98,7 @@ USAGE:
 			return controlMasterErr
 		}
 
+		conf.SetRefreshInventory(c.Bool("refresh-inventory"))
 		data, err := conf.ReadWithFallback(c.String("file"), os.Stderr)
 		if err != nil {
 			return err
@@ -117,16 +121,36 @@ USAGE:
 		sort.Strings(names)
 
 		if c.Bool("list") {
-			fmt.Fprintln(os.Stdout, "lssh Server List:")
-			for _, name := range names {
-				fmt.Fprintf(os.Stdout, "  %s\n", name)
+			if err := data.WriteServerListFormat(os.Stdout, names, common.GetListOptions(c)); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+				conf.Exit(1)
 			}
 			return nil
 		}
 
-		initialHosts := c.StringSlice("host")
-		if len(initialHosts) > 0 && !check.ExistServer(initialHosts, names) {
-			return fmt.Errorf("input server not found from list")
+		initialHosts, err := common.GetFlagHosts(c)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+			conf.Exit(1)
+		}
+		initialHosts, err = list.ResolveHosts(data, initialHosts, c.StringSlice("select"))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+			conf.Exit(1)
+		}
+		if c.Bool("print-selection") {
+			l := &list.ListInfo{
+				Prompt:    "lsmux>>",
+				NameList:  names,
+				DataList:  data,
+				MultiFlag: true,
+				Command:   "lsmux",
+			}
+			if err := l.PrintSelection(os.Stdout, initialHosts); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+				conf.Exit(1)
+			}
+			return nil
 		}
 		forwardConfig := mux.SessionOptions{
 			ControlMasterOverride: controlMasterOverride,
@@ -18,8 +18,10 @@ import (
 	"github.com/blacknon/lssh/internal/check"
 	"github.com/blacknon/lssh/internal/common"
 	conf "github.com/blacknon/lssh/internal/config"
+	"github.com/blacknon/lssh/internal/hoststate"
 	"github.com/blacknon/lssh/internal/list"
 	pipeapp "github.com/blacknon/lssh/internal/lspipe"
+	"github.com/blacknon/lssh/internal/output"
 	"github.com/blacknon/lssh/internal/version"
 	"github.com/urfave/cli"
 )
@@ -97,16 +99,19 @@ USAGE:
 		cli.BoolFlag{Name: "info", Usage: "show information for the named session."},
 		cli.BoolFlag{Name: "close", Usage: "close the named session."},
 		cli.BoolFlag{Name: "raw", Usage: "write pure stdout for exactly one resolved host."},
+		cli.StringFlag{Name: "output-format", Value: "text", Usage: "output format of command execution. ndjson writes a JSON object per line and per host exit, json a document at the end. [text|ndjson|json]"},
 		cli.BoolFlag{Name: "daemon", Hidden: true},
 		cli.BoolFlag{Name: "fifo-worker", Hidden: true},
 		cli.BoolFlag{Name: "help,h", Usage: "print this help"},
 	}
 	app.Flags = append(app.Flags, common.ControlMasterOverrideFlags()...)
+	app.Flags = append(app.Flags, common.InventoryCacheFlags()...)
+	app.Flags = append(app.Flags, common.HostSelectionFlags()...)
 
 	app.Action = func(c *cli.Context) error {
 		if c.Bool("help") {
 			cli.ShowAppHelp(c)
-			os.Exit(0)
+			conf.Exit(0)
 		}
 
 		if handled, err := conf.HandleGenerateConfigMode(c.String("generate-lssh-conf"), os.Stdout); handled {
@@ -118,6 +123,7 @@ USAGE:
 			return controlMasterErr
 		}
 
+		conf.SetRefreshInventory(c.Bool("refresh-inventory"))
 		config, err := conf.ReadWithFallback(c.String("file"), os.Stderr)
 		if err != nil {
 			return err
@@ -153,11 +159,24 @@ USAGE:
 		if c.Bool("close") {
 			return closeSession(name)
 		}
+		if c.Bool("print-selection") {
+			hosts, err := resolveCreateHosts(c, config)
+			if err != nil {
+				return err
+			}
+			for _, host := range hosts {
+				fmt.Fprintln(os.Stdout, host)
+			}
+			return nil
+		}
 
 		command := strings.TrimSpace(strings.Join(c.Args(), " "))
 		if command == "" {
 			return ensureSession(c, config, name)
 		}
+		if err := validateOutputFormat(c.String("output-format"), c.Bool("raw")); err != nil {
+			return err
+		}
 
 		if err := ensureSessionForCommand(c, config, name); err != nil {
 			return err
@@ -176,12 +195,25 @@ USAGE:
 			Stdin:   stdinData,
 			Stdout:  os.Stdout,
 			Stderr:  os.Stderr,
+			Format:  c.String("output-format"),
 		})
 	}
 
 	return app
 }
 
+// validateOutputFormat checks `--output-format`, which cannot be structured
+// with `--raw`.
+func validateOutputFormat(format string, raw bool) error {
+	if err := output.ValidateFormat(format); err != nil {
+		return err
+	}
+	if raw && output.IsStructured(format) {
+		return fmt.Errorf("--output-format %s cannot be used with --raw", format)
+	}
+	return nil
+}
+
 func ensureSession(c *cli.Context, config conf.Config, name string) error {
 	return ensureSessionState(c, config, name, true)
 }
@@ -218,11 +250,23 @@ func resolveCreateHosts(c *cli.Context, config conf.Config) ([]string, error) {
 		return nil, fmt.Errorf("no servers matched the current config conditions")
 	}
 
-	hosts := selectedHosts(c)
-	if len(hosts) > 0 {
-		if !check.ExistServer(hosts, names) {
-			return nil, fmt.Errorf("input server not found from list")
+	setHosts, err := hoststate.SetHosts(c.StringSlice("set"))
+	if err != nil {
+		return nil, err
+	}
+	hosts := append(selectedHosts(c), setHosts...)
+	if file := c.String("hosts-from"); file != "" {
+		fileHosts, err := common.ReadHostList(file)
+		if err != nil {
+			return nil, err
 		}
+		hosts = append(hosts, fileHosts...)
+	}
+	hosts, err = list.ResolveHosts(config, hosts, c.StringSlice("select"))
+	if err != nil {
+		return nil, err
+	}
+	if len(hosts) > 0 {
 		sort.Strings(hosts)
 		return hosts, nil
 	}
@@ -322,6 +366,10 @@ func filterNonDaemonArgs(args []string) []string {
 		"--fifo-name":          true,
 		"--host":               true,
 		"--create-host":        true,
+		"--set":                true,
+		"--select":             true,
+		"--hosts-from":         true,
+		"--output-format":      true,
 		"--generate-lssh-conf": true,
 		"-H":                   true,
 	}
@@ -335,7 +383,7 @@ func filterNonDaemonArgs(args []string) []string {
 	for i := 0; i < len(args); i++ {
 		arg := args[i]
 		switch arg {
-		case "--replace", "--daemon", "--fifo-worker", "--list", "--list-fifos", "--mkfifo", "--rmfifo", "--info", "--close", "--raw":
+		case "--replace", "--daemon", "--fifo-worker", "--list", "--list-fifos", "--mkfifo", "--rmfifo", "--info", "--close", "--raw", "--print-selection":
 			continue
 		}
 		if filteredValueFlags[arg] {
@@ -2,11 +2,12 @@ package lspipe
 
 import (
 	"reflect"
+	"strings"
 	"testing"
 )
 
 func TestFilterNonDaemonArgs(t *testing.T) {
-	args := []string{"--name", "prod", "--replace", "-F", "conf", "--raw", "hostname"}
+	args := []string{"--name", "prod", "--replace", "-F", "conf", "--set", "web", "--select", "tag:db", "--print-selection", "--raw", "--output-format", "ndjson", "hostname"}
 	got := filterNonDaemonArgs(args)
 	want := []string{"-F", "conf"}
 	if !reflect.DeepEqual(got, want) {
@@ -14,6 +15,21 @@ func TestFilterNonDaemonArgs(t *testing.T) {
 	}
 }
 
+func TestValidateOutputFormat(t *testing.T) {
+	if err := validateOutputFormat("ndjson", false); err != nil {
+		t.Fatalf("validateOutputFormat(ndjson) error = %v", err)
+	}
+	if err := validateOutputFormat("text", true); err != nil {
+		t.Fatalf("validateOutputFormat(text, raw) error = %v", err)
+	}
+	if err := validateOutputFormat("json", true); err == nil || !strings.Contains(err.Error(), "--raw") {
+		t.Fatalf("validateOutputFormat(json, raw) error = %v", err)
+	}
+	if err := validateOutputFormat("xml", false); err == nil {
+		t.Fatal("validateOutputFormat(xml) error = nil")
+	}
+}
+
 func TestTernaryStatus(t *testing.T) {
 	if got := ternaryStatus(false); got != "alive" {
 		t.Fatalf("ternaryStatus(false) = %q", got)
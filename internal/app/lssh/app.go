@@ -15,9 +15,9 @@ import (
 	"strings"
 	"time"
 
-	"github.com/blacknon/lssh/internal/check"
 	"github.com/blacknon/lssh/internal/common"
 	conf "github.com/blacknon/lssh/internal/config"
+	"github.com/blacknon/lssh/internal/hoststate"
 	"github.com/blacknon/lssh/internal/list"
 	lsmuxsession "github.com/blacknon/lssh/internal/lsmuxsession"
 	"github.com/blacknon/lssh/internal/mux"
@@ -72,14 +72,23 @@ USAGE:
     # connect ssh
     {{.Name}}
 
+    # reconnect to the last connected server.
+    {{.Name}} -
+
     # run command selected server over ssh.
     {{.Name}} command...
 
     # run command parallel in selected server over ssh.
     {{.Name}} -p command...
 
+    # run command in batches of 25% of the servers, stop on the first failure.
+    {{.Name}} --batch 25% --batch-pause 30s --fail-fast command...
+
     # run command or shell in mux UI.
     {{.Name}} -P [command...]
+
+    # replay a session recorded with log format "asciicast".
+    {{.Name}} --replay ~/log/20260101_120000_server.cast --replay-speed 2
 `
 
 	// Create app
@@ -108,9 +117,13 @@ USAGE:
 	// Set options
 	app.Flags = []cli.Flag{
 		// common option
-		cli.StringSliceFlag{Name: "host,H", Usage: "connect `servername`."},
+		cli.StringSliceFlag{Name: "host,H", Usage: "connect `servername` or host selector (ex. tag:web,env=prod,!tag:canary)."},
 		cli.StringFlag{Name: "file,F", Value: defConf, Usage: "config `filepath`."},
 		cli.StringFlag{Name: "generate-lssh-conf", Usage: "print generated lssh config from OpenSSH config to stdout (`~/.ssh/config` by default)."},
+		cli.BoolFlag{Name: "check-config", Usage: "validate the config file, print each problem with file:line and exit non-zero if any (same as `lssh config validate`)."},
+		cli.BoolFlag{Name: "generate-ssh-config", Usage: "print the effective lssh servers as OpenSSH ssh_config to stdout (limited to --host if given)."},
+		cli.StringFlag{Name: "replay", Usage: "play the asciicast recording `file` of a session (log format = \"asciicast\"). keys: space pause, left/right seek 5s, +/- speed, q quit."},
+		cli.Float64Flag{Name: "replay-speed", Value: 1, Usage: "playback `speed` of --replay."},
 
 		// port forward (with dynamic forward) option
 		cli.StringSliceFlag{Name: "L", Usage: "Local port forward mode.Specify a `[bind_address:]port:remote_address:port`. Only single connection works."},
@@ -133,6 +146,18 @@ USAGE:
 		cli.BoolFlag{Name: "Y", Usage: "Enable trusted x11 forwarding(forward to ${DISPLAY})."},
 		cli.BoolFlag{Name: "term,t", Usage: "run specified command at terminal."},
 		cli.BoolFlag{Name: "parallel,p", Usage: "run command parallel node(tail -F etc...)."},
+		cli.IntFlag{Name: "max-parallel", Usage: "connect to and run command on at most `N` hosts at the same time. 0 is unlimited."},
+		cli.StringFlag{Name: "batch", Usage: "run command in batches of `N` hosts or N% of the hosts, one batch after another (rolling)."},
+		cli.DurationFlag{Name: "batch-pause", Usage: "wait `duration` (ex. 30s) between the batches of --batch."},
+		cli.BoolFlag{Name: "fail-fast", Usage: "stop connecting to more hosts after the command failed on a host (same as --max-fail 1)."},
+		cli.IntFlag{Name: "max-fail", Usage: "stop connecting to more hosts after the command failed on `N` hosts."},
+		cli.StringFlag{Name: "order", Usage: "host order of command mode. selected order by default. [name|random]"},
+		cli.BoolFlag{Name: "summary", Usage: "print the status, exit code and duration of each host at the end of command mode."},
+		cli.StringFlag{Name: "exit-policy", Value: "any", Usage: "exit non-zero if the command failed on any, all or the majority of the hosts. [any|all|majority]"},
+		cli.StringFlag{Name: "result-file", Usage: "write the result of each host of command mode to `path` as JSON."},
+		cli.BoolFlag{Name: "group-output", Usage: "print each distinct output of command mode once, under the hosts that produced it (like dshbak -c)."},
+		cli.BoolFlag{Name: "diff-from-majority", Usage: "with --group-output, print only the outputs that differ from the most common output, as a diff."},
+		cli.StringFlag{Name: "output-format", Value: "text", Usage: "output format of command mode. ndjson writes a JSON object per line and per host exit, json a document at the end. [text|ndjson|json]"},
 		cli.BoolFlag{Name: "P", Usage: "run shell or command in mux UI (lsmux compatible)."},
 		cli.BoolFlag{Name: "hold", Usage: "keep command panes after remote command exits (with -P)."},
 		cli.BoolFlag{Name: "allow-layout-change", Usage: "allow opening new pages/panes even in command mode (with -P)."},
@@ -156,6 +181,10 @@ USAGE:
 		cli.BoolFlag{Name: "mux-child", Hidden: true},
 	}
 	app.Flags = append(app.Flags, common.ControlMasterOverrideFlags()...)
+	app.Flags = append(app.Flags, common.InventoryCacheFlags()...)
+	app.Flags = append(app.Flags, common.HostSelectionFlags()...)
+	app.Flags = append(app.Flags, common.ListFlags()...)
+	app.Flags = append(app.Flags, common.SelectorFlags()...)
 	app.EnableBashCompletion = true
 	app.HideHelp = true
 
@@ -164,10 +193,14 @@ USAGE:
 		// show help messages
 		if c.Bool("help") {
 			cli.ShowAppHelp(c)
-			os.Exit(0)
+			conf.Exit(0)
 		}
 
-		hosts := c.StringSlice("host")
+		hosts, hostsErr := common.GetFlagHosts(c)
+		if hostsErr != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", hostsErr)
+			conf.Exit(1)
+		}
 		confpath := c.String("file")
 		controlMasterOverride, controlMasterErr := common.GetControlMasterOverride(c)
 		if controlMasterErr != nil {
@@ -178,15 +211,71 @@ USAGE:
 			return err
 		}
 
+		if path := c.String("replay"); path != "" {
+			if err := replaySession(path, c.Float64("replay-speed")); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+				conf.Exit(1)
+			}
+			conf.Exit(0)
+		}
+
+		if c.Bool("check-config") {
+			conf.SetRefreshInventory(c.Bool("refresh-inventory"))
+			ok, err := conf.CheckConfig(confpath, os.Stdout)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+				conf.Exit(1)
+			}
+			if !ok {
+				conf.Exit(1)
+			}
+			conf.Exit(0)
+		}
+
 		// Get config data
+		conf.SetRefreshInventory(c.Bool("refresh-inventory"))
 		data, configErr := conf.ReadWithFallback(confpath, os.Stderr)
 		if configErr != nil {
 			return configErr
 		}
+		hosts, configErr = list.ResolveHosts(data, hosts, c.StringSlice("select"))
+		if configErr != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", configErr)
+			conf.Exit(1)
+		}
+
+		if c.Bool("generate-ssh-config") {
+			exportNames := hosts
+			if len(exportNames) == 0 {
+				exportNames = conf.GetNameList(data)
+			}
+			_, err := os.Stdout.Write(conf.GenerateOpenSSHConfig(data, exportNames))
+			return err
+		}
+
+		// `lssh -` reconnects to the hosts of the last connection, like `cd -`.
+		args := []string(c.Args())
+		if len(args) > 0 && args[0] == "-" && len(hosts) == 0 {
+			state, err := hoststate.Load()
+			if err != nil {
+				return err
+			}
+			hosts = state.LastHosts("lssh")
+			if len(hosts) == 0 {
+				fmt.Fprintln(os.Stderr, "Error: no previous connection to reconnect.")
+				conf.Exit(1)
+			}
+			hosts, err = list.ResolveHosts(data, hosts, nil)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+				conf.Exit(1)
+			}
+			args = args[1:]
+		}
 
 		// Set `exec command` or `shell` flag
 		isMulti := false
-		if len(c.Args()) > 0 && !c.Bool("not-execute") {
+		if len(args) > 0 && !c.Bool("not-execute") {
 			isMulti = true
 		}
 
@@ -196,11 +285,59 @@ USAGE:
 
 		// Check list flag
 		if c.Bool("list") {
-			fmt.Fprintf(os.Stdout, "lssh Server List:\n")
-			for v := range names {
-				fmt.Fprintf(os.Stdout, "  %s\n", names[v])
+			if err := data.WriteServerListFormat(os.Stdout, names, common.GetListOptions(c)); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+				conf.Exit(1)
+			}
+			conf.Exit(0)
+		}
+
+		if err := list.ValidateSortOrder(c.String("sort")); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+			conf.Exit(1)
+		}
+		if err := data.CheckListGroupBy(c.String("group-by")); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+			conf.Exit(1)
+		}
+		cmdOptions := cmdFlagOptions{
+			MaxParallel: c.Int("max-parallel"),
+			Batch:       c.String("batch"),
+			BatchPause:  c.Duration("batch-pause"),
+			FailFast:    c.Bool("fail-fast"),
+			MaxFail:     c.Int("max-fail"),
+			Order:       c.String("order"),
+			Summary:     c.Bool("summary"),
+			ExitPolicy:  c.String("exit-policy"),
+			ResultFile:  c.String("result-file"),
+
+			GroupOutput:      c.Bool("group-output"),
+			DiffFromMajority: c.Bool("diff-from-majority"),
+
+			OutputFormat: c.String("output-format"),
+			Term:         c.Bool("term"),
+		}
+		if err := validateCmdOptions(cmdOptions); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+			conf.Exit(1)
+		}
+
+		if c.Bool("print-selection") {
+			l := &list.ListInfo{
+				Prompt:    "lssh>>",
+				NameList:  names,
+				DataList:  data,
+				MultiFlag: true,
+				Command:   "lssh",
+				Sort:      c.String("sort"),
+				GroupBy:   c.String("group-by"),
+				Probe:     c.Bool("probe"),
+			}
+			if err := l.PrintSelection(os.Stdout, hosts); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+				conf.Exit(1)
 			}
-			os.Exit(0)
+			conf.Exit(0)
 		}
 
 		enableX11 := c.Bool("X11")
@@ -229,10 +366,6 @@ USAGE:
 			if c.Bool("enable-transfer") && c.Bool("disable-transfer") {
 				return fmt.Errorf("--enable-transfer and --disable-transfer cannot be used together")
 			}
-			if len(hosts) > 0 && !check.ExistServer(hosts, names) {
-				fmt.Fprintln(os.Stderr, "Input Server not found from list.")
-				os.Exit(1)
-			}
 
 			var (
 				err       error
@@ -246,7 +379,7 @@ USAGE:
 				f.LocalNetwork, f.Local, f.RemoteNetwork, f.Remote, err = common.ParseForwardSpec(forwardargs)
 				if err != nil {
 					fmt.Fprintf(os.Stderr, "Error: %s\n", err)
-					os.Exit(1)
+					conf.Exit(1)
 				}
 				forwards = append(forwards, f)
 			}
@@ -278,7 +411,7 @@ USAGE:
 				f.Local, f.Remote, err = common.ParseForwardPort(forwardargs)
 				if err != nil {
 					fmt.Fprintf(os.Stderr, "Error: %s\n", err)
-					os.Exit(1)
+					conf.Exit(1)
 				}
 				forwards = append(forwards, f)
 			}
@@ -288,7 +421,7 @@ USAGE:
 				port, path, parseErr := common.ParseNFSForwardPortPath(nfsReverseForwarding)
 				if parseErr != nil {
 					fmt.Fprintf(os.Stderr, "Error: %s\n", parseErr)
-					os.Exit(1)
+					conf.Exit(1)
 				}
 				forwardConfig.NFSReverseDynamicForwardPort = port
 				forwardConfig.NFSReverseDynamicForwardPath = common.GetFullPath(path)
@@ -297,7 +430,7 @@ USAGE:
 				port, path, parseErr := common.ParseNFSForwardPortPath(smbReverseForwarding)
 				if parseErr != nil {
 					fmt.Fprintf(os.Stderr, "Error: %s\n", parseErr)
-					os.Exit(1)
+					conf.Exit(1)
 				}
 				forwardConfig.SMBReverseDynamicForwardPort = port
 				forwardConfig.SMBReverseDynamicForwardPath = common.GetFullPath(path)
@@ -321,7 +454,7 @@ USAGE:
 				port, path, parseErr := common.ParseNFSForwardPortPath(nfsForwarding)
 				if parseErr != nil {
 					fmt.Fprintf(os.Stderr, "Error: %s\n", parseErr)
-					os.Exit(1)
+					conf.Exit(1)
 				}
 				run.NFSDynamicForwardPort = port
 				run.NFSDynamicForwardPath = path
@@ -330,14 +463,14 @@ USAGE:
 				port, path, parseErr := common.ParseNFSForwardPortPath(smbForwarding)
 				if parseErr != nil {
 					fmt.Fprintf(os.Stderr, "Error: %s\n", parseErr)
-					os.Exit(1)
+					conf.Exit(1)
 				}
 				run.SMBDynamicForwardPort = port
 				run.SMBDynamicForwardPath = path
 			}
 			if enabled, local, remote, tunnelErr := resolveTunnelOption(runtime.GOOS, c.String("tunnel")); tunnelErr != nil {
 				fmt.Fprintln(os.Stderr, tunnelErr)
-				os.Exit(1)
+				conf.Exit(1)
 			} else if enabled {
 				run.TunnelEnabled = true
 				run.TunnelLocal = local
@@ -345,18 +478,18 @@ USAGE:
 			}
 			forwardConfig.ParallelInfo = run.ParallelIgnoredFeatures
 
-			if len(c.Args()) > 0 && runtime.GOOS != "windows" {
+			if len(args) > 0 && runtime.GOOS != "windows" {
 				stdin := 0
 				if !terminal.IsTerminal(stdin) {
 					stdinData, err = io.ReadAll(os.Stdin)
 					if err != nil {
 						fmt.Fprintf(os.Stderr, "Error: %s\n", err)
-						os.Exit(1)
+						conf.Exit(1)
 					}
 				}
 			}
 			if c.Bool("mux-child") {
-				manager, err := mux.NewManager(data, names, c.Args(), stdinData, hosts, c.Bool("hold"), c.Bool("allow-layout-change"), forwardConfig)
+				manager, err := mux.NewManager(data, names, args, stdinData, hosts, c.Bool("hold"), c.Bool("allow-layout-change"), forwardConfig)
 				if err != nil {
 					return err
 				}
@@ -409,7 +542,7 @@ USAGE:
 				})
 			}
 
-			manager, err := mux.NewManager(data, names, c.Args(), stdinData, hosts, c.Bool("hold"), c.Bool("allow-layout-change"), forwardConfig)
+			manager, err := mux.NewManager(data, names, args, stdinData, hosts, c.Bool("hold"), c.Bool("allow-layout-change"), forwardConfig)
 			if err != nil {
 				return err
 			}
@@ -418,16 +551,11 @@ USAGE:
 
 		selected := []string{}
 		if len(hosts) > 0 {
-			if !check.ExistServer(hosts, names) {
-				fmt.Fprintln(os.Stderr, "Input Server not found from list.")
-				os.Exit(1)
-			} else {
-				selected = hosts
-			}
+			selected = hosts
 		} else {
 			if len(names) == 0 {
 				fmt.Fprintln(os.Stderr, "No servers matched the current config conditions.")
-				os.Exit(1)
+				conf.Exit(1)
 			}
 			// View List And Get Select Line
 			l := new(list.ListInfo)
@@ -435,6 +563,10 @@ USAGE:
 			l.NameList = names
 			l.DataList = data
 			l.MultiFlag = isMulti
+			l.Command = "lssh"
+			l.Sort = c.String("sort")
+			l.GroupBy = c.String("group-by")
+			l.Probe = c.Bool("probe")
 
 			l.View()
 			selected = l.SelectName
@@ -442,24 +574,24 @@ USAGE:
 			// Check selected
 			if len(selected) == 0 {
 				fmt.Fprintln(os.Stderr, "Selection cancelled.")
-				os.Exit(1)
+				conf.Exit(1)
 			}
 			if selected[0] == "ServerName" {
 				fmt.Fprintln(os.Stderr, "Server not selected.")
-				os.Exit(1)
+				conf.Exit(1)
 			}
 
 			// If -f is specified, disallow selecting multiple hosts
 			if c.Bool("f") && len(selected) > 1 {
 				fmt.Fprintln(os.Stderr, "Error: -f cannot be used with multiple hosts. Select a single host.")
-				os.Exit(1)
+				conf.Exit(1)
 			}
 		}
 
 		if err := validateConnectorShellOptions(connectorFlagOptions{
 			AttachSession:            connectorAttachSession,
 			Detach:                   connectorDetach,
-			CommandArgs:              append([]string(nil), c.Args()...),
+			CommandArgs:              append([]string(nil), args...),
 			MuxMode:                  c.Bool("P"),
 			ParallelMode:             c.Bool("parallel"),
 			TermMode:                 c.Bool("term"),
@@ -486,9 +618,10 @@ USAGE:
 		r := new(sshcmd.Run)
 		r.ServerList = selected
 		r.Conf = data
+		r.HistoryCommand = "lssh"
 		r.ControlMasterOverride = controlMasterOverride
 		switch {
-		case len(c.Args()) > 0 && !c.Bool("not-execute"):
+		case len(args) > 0 && !c.Bool("not-execute"):
 			// Becomes a shell when not-execute is given.
 			r.Mode = "cmd"
 		default:
@@ -496,10 +629,11 @@ USAGE:
 		}
 
 		// exec command
-		r.ExecCmd = c.Args()
+		r.ExecCmd = args
 		r.ConnectorAttachSession = connectorAttachSession
 		r.ConnectorDetach = connectorDetach
 		r.IsParallel = c.Bool("parallel")
+		cmdOptions.apply(r)
 
 		if enableX11 || enableTrustedX11 {
 			r.X11 = true
@@ -557,7 +691,7 @@ USAGE:
 			port, path, err := common.ParseNFSForwardPortPath(nfsForwarding)
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "Error: %s\n", err)
-				os.Exit(1)
+				conf.Exit(1)
 			}
 
 			r.NFSDynamicForwardPort = port
@@ -568,7 +702,7 @@ USAGE:
 			port, path, err := common.ParseNFSForwardPortPath(smbForwarding)
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "Error: %s\n", err)
-				os.Exit(1)
+				conf.Exit(1)
 			}
 
 			r.SMBDynamicForwardPort = port
@@ -581,7 +715,7 @@ USAGE:
 			port, path, err := common.ParseNFSForwardPortPath(nfsReverseForwarding)
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "Error: %s\n", err)
-				os.Exit(1)
+				conf.Exit(1)
 			}
 
 			path = common.GetFullPath(path)
@@ -594,7 +728,7 @@ USAGE:
 			port, path, err := common.ParseNFSForwardPortPath(smbReverseForwarding)
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "Error: %s\n", err)
-				os.Exit(1)
+				conf.Exit(1)
 			}
 
 			r.SMBReverseDynamicForwardPort = port
@@ -624,7 +758,7 @@ USAGE:
 		// Tunnel device (like ssh -w local:remote). Format: <num|any>:<num|any>
 		if enabled, local, remote, err := resolveTunnelOption(runtime.GOOS, c.String("tunnel")); err != nil {
 			fmt.Fprintln(os.Stderr, err)
-			os.Exit(1)
+			conf.Exit(1)
 		} else if enabled {
 			r.TunnelEnabled = true
 			r.TunnelLocal = local
@@ -653,7 +787,7 @@ USAGE:
 			exe, err := os.Executable()
 			if err != nil {
 				fmt.Fprintln(os.Stderr, "Error:", err)
-				os.Exit(1)
+				conf.Exit(1)
 			}
 
 			// create pipe for unix handshake
@@ -663,7 +797,7 @@ USAGE:
 				rpipe, wpipe, err = os.Pipe()
 				if err != nil {
 					fmt.Fprintln(os.Stderr, "Error creating pipe:", err)
-					os.Exit(1)
+					conf.Exit(1)
 				}
 			}
 
@@ -688,7 +822,7 @@ USAGE:
 
 			if err := cmd.Start(); err != nil {
 				fmt.Fprintln(os.Stderr, "Error starting background process:", err)
-				os.Exit(1)
+				conf.Exit(1)
 			}
 
 			pid := 0
@@ -708,18 +842,21 @@ USAGE:
 				rpipe.Close()
 				if n > 0 {
 					fmt.Fprintf(os.Stderr, "Running in background (pid %d)\n", pid)
-					os.Exit(0)
+					conf.Exit(0)
 				}
 				fmt.Fprintln(os.Stderr, "Background start failed")
-				os.Exit(1)
+				conf.Exit(1)
 			}
 
 			// on windows just exit parent after starting child
 			fmt.Fprintf(os.Stderr, "Running in background (pid %d)\n", pid)
-			os.Exit(0)
+			conf.Exit(0)
 		}
 
 		r.Start()
+		if status := r.ExitStatus(); status != 0 {
+			conf.Exit(status)
+		}
 		return nil
 	}
 	return app
@@ -0,0 +1,93 @@
+package lssh
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/blacknon/lssh/internal/output"
+	sshcmd "github.com/blacknon/lssh/internal/ssh"
+)
+
+// cmdFlagOptions are the flags that control how command mode runs over
+// the hosts (--max-parallel, --batch, --fail-fast...) and reports the
+// results (--summary, --exit-policy, --result-file, --group-output,
+// --output-format).
+type cmdFlagOptions struct {
+	MaxParallel int
+	Batch       string
+	BatchPause  time.Duration
+	FailFast    bool
+	MaxFail     int
+	Order       string
+
+	Summary    bool
+	ExitPolicy string
+	ResultFile string
+
+	// DiffFromMajority implies GroupOutput.
+	GroupOutput      bool
+	DiffFromMajority bool
+
+	// OutputFormat cannot be structured with Term, as the single host
+	// output of --term goes to the terminal as is. The same goes for
+	// GroupOutput.
+	OutputFormat string
+	Term         bool
+}
+
+func validateCmdOptions(opts cmdFlagOptions) error {
+	if opts.MaxParallel < 0 {
+		return fmt.Errorf("--max-parallel must be 0 or more")
+	}
+	if opts.MaxFail < 0 {
+		return fmt.Errorf("--max-fail must be 0 or more")
+	}
+	if opts.FailFast && opts.MaxFail > 0 {
+		return fmt.Errorf("--fail-fast and --max-fail cannot be used together")
+	}
+	if opts.BatchPause < 0 {
+		return fmt.Errorf("--batch-pause must be 0 or more")
+	}
+	if _, err := sshcmd.ParseBatchSize(opts.Batch, 1); err != nil {
+		return err
+	}
+	if err := sshcmd.ValidateOrder(opts.Order); err != nil {
+		return err
+	}
+	if err := sshcmd.ValidateExitPolicy(opts.ExitPolicy); err != nil {
+		return err
+	}
+	if err := output.ValidateFormat(opts.OutputFormat); err != nil {
+		return err
+	}
+	if opts.Term && output.IsStructured(opts.OutputFormat) {
+		return fmt.Errorf("--output-format %s cannot be used with --term", opts.OutputFormat)
+	}
+	if opts.GroupOutput || opts.DiffFromMajority {
+		if output.IsStructured(opts.OutputFormat) {
+			return fmt.Errorf("--group-output cannot be used with --output-format %s", opts.OutputFormat)
+		}
+		if opts.Term {
+			return fmt.Errorf("--group-output cannot be used with --term")
+		}
+	}
+	return nil
+}
+
+// apply sets the options to r.
+func (opts cmdFlagOptions) apply(r *sshcmd.Run) {
+	r.MaxParallel = opts.MaxParallel
+	r.Batch = opts.Batch
+	r.BatchPause = opts.BatchPause
+	r.MaxFail = opts.MaxFail
+	if opts.FailFast {
+		r.MaxFail = 1
+	}
+	r.Order = opts.Order
+	r.Summary = opts.Summary
+	r.ExitPolicy = opts.ExitPolicy
+	r.ResultFile = opts.ResultFile
+	r.OutputFormat = opts.OutputFormat
+	r.GroupOutput = opts.GroupOutput || opts.DiffFromMajority
+	r.DiffFromMajority = opts.DiffFromMajority
+}
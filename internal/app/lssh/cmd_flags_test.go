@@ -0,0 +1,60 @@
+package lssh
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	sshcmd "github.com/blacknon/lssh/internal/ssh"
+)
+
+func TestValidateCmdOptions(t *testing.T) {
+	valid := []cmdFlagOptions{
+		{},
+		{MaxParallel: 10, Batch: "25%", BatchPause: time.Minute, MaxFail: 2, Order: "random"},
+		{Batch: "5", FailFast: true, Order: "name", ExitPolicy: "majority", Summary: true, ResultFile: "result.json"},
+		{OutputFormat: "ndjson"},
+		{OutputFormat: "text", Term: true},
+		{GroupOutput: true, DiffFromMajority: true},
+	}
+	for _, opts := range valid {
+		if err := validateCmdOptions(opts); err != nil {
+			t.Fatalf("validateCmdOptions(%+v) error = %v", opts, err)
+		}
+	}
+
+	invalid := map[string]cmdFlagOptions{
+		"--max-parallel":        {MaxParallel: -1},
+		"--fail-fast and":       {FailFast: true, MaxFail: 3},
+		"invalid batch size":    {Batch: "0"},
+		"unknown order":         {Order: "addr"},
+		"--batch-pause must be": {BatchPause: -time.Second},
+		"unknown exit policy":   {ExitPolicy: "some"},
+		"unknown output format": {OutputFormat: "yaml"},
+		"cannot be used with":   {OutputFormat: "json", Term: true},
+		"--group-output cannot": {GroupOutput: true, OutputFormat: "ndjson"},
+		"with --term":           {DiffFromMajority: true, Term: true},
+	}
+	for want, opts := range invalid {
+		err := validateCmdOptions(opts)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("validateCmdOptions(%+v) error = %v, want %q", opts, err, want)
+		}
+	}
+}
+
+func TestCmdOptionsApplyFailFast(t *testing.T) {
+	r := &sshcmd.Run{}
+	cmdFlagOptions{FailFast: true, Batch: "2"}.apply(r)
+	if r.MaxFail != 1 || r.Batch != "2" {
+		t.Fatalf("apply() = MaxFail %d, Batch %q", r.MaxFail, r.Batch)
+	}
+}
+
+func TestCmdOptionsApplyDiffFromMajority(t *testing.T) {
+	r := &sshcmd.Run{}
+	cmdFlagOptions{DiffFromMajority: true}.apply(r)
+	if !r.GroupOutput || !r.DiffFromMajority {
+		t.Fatalf("apply() = GroupOutput %v, DiffFromMajority %v", r.GroupOutput, r.DiffFromMajority)
+	}
+}
@@ -0,0 +1,88 @@
+package lssh
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/blacknon/lssh/internal/asciicast"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// replayControls returns the player controls of the keys typed during
+// `--replay`: space pauses, the arrow keys (or h/l) seek, +/- change the
+// speed and q or Ctrl+C quits.
+func replayControls(keys []byte) []asciicast.Control {
+	controls := []asciicast.Control{}
+	for i := 0; i < len(keys); i++ {
+		switch keys[i] {
+		case ' ':
+			controls = append(controls, asciicast.ControlPause)
+		case 'l':
+			controls = append(controls, asciicast.ControlForward)
+		case 'h':
+			controls = append(controls, asciicast.ControlBackward)
+		case '+', '=':
+			controls = append(controls, asciicast.ControlFaster)
+		case '-':
+			controls = append(controls, asciicast.ControlSlower)
+		case 'q', 0x03:
+			controls = append(controls, asciicast.ControlQuit)
+		case 0x1b:
+			// arrow keys: ESC [ C (right) and ESC [ D (left).
+			if i+2 < len(keys) && keys[i+1] == '[' {
+				switch keys[i+2] {
+				case 'C':
+					controls = append(controls, asciicast.ControlForward)
+				case 'D':
+					controls = append(controls, asciicast.ControlBackward)
+				}
+				i += 2
+			}
+		}
+	}
+	return controls
+}
+
+// replaySession plays the asciicast recording of path on the terminal at
+// speed, controlled by the keys of replayControls.
+func replaySession(path string, speed float64) error {
+	if speed <= 0 {
+		return fmt.Errorf("--replay-speed must be greater than 0")
+	}
+
+	header, events, err := asciicast.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	player := asciicast.NewPlayer(os.Stdout, header, events, speed)
+
+	fd := int(os.Stdin.Fd())
+	if !terminal.IsTerminal(fd) {
+		player.Play(nil)
+		return nil
+	}
+
+	state, err := terminal.MakeRaw(fd)
+	if err != nil {
+		return err
+	}
+	defer terminal.Restore(fd, state)
+
+	controls := make(chan asciicast.Control, 16)
+	go func() {
+		buf := make([]byte, 64)
+		for {
+			n, err := os.Stdin.Read(buf)
+			if err != nil {
+				return
+			}
+			for _, control := range replayControls(buf[:n]) {
+				controls <- control
+			}
+		}
+	}()
+
+	player.Play(controls)
+	fmt.Fprint(os.Stdout, "\r\n")
+	return nil
+}
@@ -0,0 +1,44 @@
+package lssh
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/blacknon/lssh/internal/asciicast"
+)
+
+func TestReplayControls(t *testing.T) {
+	got := replayControls([]byte(" l\x1b[Ch\x1b[D+=-x\x1b[Aq\x03"))
+	want := []asciicast.Control{
+		asciicast.ControlPause,
+		asciicast.ControlForward,
+		asciicast.ControlForward,
+		asciicast.ControlBackward,
+		asciicast.ControlBackward,
+		asciicast.ControlFaster,
+		asciicast.ControlFaster,
+		asciicast.ControlSlower,
+		asciicast.ControlQuit,
+		asciicast.ControlQuit,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("replayControls() = %v, want %v", got, want)
+	}
+}
+
+func TestReplaySessionErrors(t *testing.T) {
+	if err := replaySession("session.cast", 0); err == nil || !strings.Contains(err.Error(), "--replay-speed") {
+		t.Fatalf("replaySession() with speed 0 error = %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "session.cast")
+	if err := os.WriteFile(path, []byte("not a recording\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := replaySession(path, 1); err == nil || !strings.Contains(err.Error(), path) {
+		t.Fatalf("replaySession() of an invalid file error = %v", err)
+	}
+}
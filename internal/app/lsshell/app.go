@@ -11,10 +11,11 @@ import (
 	"runtime"
 	"sort"
 
-	"github.com/blacknon/lssh/internal/check"
 	"github.com/blacknon/lssh/internal/common"
 	conf "github.com/blacknon/lssh/internal/config"
+	"github.com/blacknon/lssh/internal/hoststate"
 	"github.com/blacknon/lssh/internal/list"
+	"github.com/blacknon/lssh/internal/output"
 	pshell "github.com/blacknon/lssh/internal/pshell"
 	sshcmd "github.com/blacknon/lssh/internal/ssh"
 	"github.com/blacknon/lssh/internal/version"
@@ -62,7 +63,7 @@ USAGE:
 	// Set options
 	app.Flags = []cli.Flag{
 		// common option
-		cli.StringSliceFlag{Name: "host,H", Usage: "connect `servername`."},
+		cli.StringSliceFlag{Name: "host,H", Usage: "connect `servername` or host selector (ex. tag:web,env=prod,!tag:canary)."},
 		cli.StringFlag{Name: "file,F", Value: defConf, Usage: "config `filepath`."},
 		cli.StringFlag{Name: "generate-lssh-conf", Usage: "print generated lssh config from OpenSSH config to stdout (`~/.ssh/config` by default)."},
 
@@ -73,10 +74,15 @@ USAGE:
 
 		// Other bool
 		cli.BoolFlag{Name: "term,t", Usage: "run specified command at terminal."},
+		cli.StringFlag{Name: "output-format", Value: "text", Usage: "output format of the remote commands. ndjson writes a JSON object per line and per host exit, json a document per command. [text|ndjson|json]"},
 		cli.BoolFlag{Name: "list,l", Usage: "print server list from config."},
 		cli.BoolFlag{Name: "help,h", Usage: "print this help"},
 	}
 	app.Flags = append(app.Flags, common.ControlMasterOverrideFlags()...)
+	app.Flags = append(app.Flags, common.InventoryCacheFlags()...)
+	app.Flags = append(app.Flags, common.HostSelectionFlags()...)
+	app.Flags = append(app.Flags, common.ListFlags()...)
+	app.Flags = append(app.Flags, common.SelectorFlags()...)
 	app.EnableBashCompletion = true
 	app.HideHelp = true
 
@@ -85,10 +91,14 @@ USAGE:
 		// show help messages
 		if c.Bool("help") {
 			cli.ShowAppHelp(c)
-			os.Exit(0)
+			conf.Exit(0)
 		}
 
-		hosts := c.StringSlice("host")
+		hosts, hostsErr := common.GetFlagHosts(c)
+		if hostsErr != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", hostsErr)
+			conf.Exit(1)
+		}
 		confpath := c.String("file")
 		controlMasterOverride, controlMasterErr := common.GetControlMasterOverride(c)
 		if controlMasterErr != nil {
@@ -100,10 +110,16 @@ USAGE:
 		}
 
 		// Get config data
+		conf.SetRefreshInventory(c.Bool("refresh-inventory"))
 		data, configErr := conf.ReadWithFallback(confpath, os.Stderr)
 		if configErr != nil {
 			return configErr
 		}
+		hosts, configErr = list.ResolveHosts(data, hosts, c.StringSlice("select"))
+		if configErr != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", configErr)
+			conf.Exit(1)
+		}
 
 		// Set `exec command` or `shell` flag
 		isMulti := true
@@ -114,25 +130,51 @@ USAGE:
 
 		// Check list flag
 		if c.Bool("list") {
-			fmt.Fprintf(os.Stdout, "lssh Server List:\n")
-			for v := range names {
-				fmt.Fprintf(os.Stdout, "  %s\n", names[v])
+			if err := data.WriteServerListFormat(os.Stdout, names, common.GetListOptions(c)); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+				conf.Exit(1)
 			}
-			os.Exit(0)
+			conf.Exit(0)
+		}
+
+		if err := list.ValidateSortOrder(c.String("sort")); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+			conf.Exit(1)
+		}
+		if err := data.CheckListGroupBy(c.String("group-by")); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+			conf.Exit(1)
+		}
+		if err := output.ValidateFormat(c.String("output-format")); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+			conf.Exit(1)
+		}
+
+		if c.Bool("print-selection") {
+			l := &list.ListInfo{
+				Prompt:    "lssh>>",
+				NameList:  names,
+				DataList:  data,
+				MultiFlag: true,
+				Command:   "lsshell",
+				Sort:      c.String("sort"),
+				GroupBy:   c.String("group-by"),
+				Probe:     c.Bool("probe"),
+			}
+			if err := l.PrintSelection(os.Stdout, hosts); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+				conf.Exit(1)
+			}
+			conf.Exit(0)
 		}
 
 		selected := []string{}
 		if len(hosts) > 0 {
-			if !check.ExistServer(hosts, names) {
-				fmt.Fprintln(os.Stderr, "Input Server not found from list.")
-				os.Exit(1)
-			} else {
-				selected = hosts
-			}
+			selected = hosts
 		} else {
 			if len(names) == 0 {
 				fmt.Fprintln(os.Stderr, "No servers matched the current config conditions.")
-				os.Exit(1)
+				conf.Exit(1)
 			}
 			// View List And Get Select Line
 			l := new(list.ListInfo)
@@ -140,16 +182,20 @@ USAGE:
 			l.NameList = names
 			l.DataList = data
 			l.MultiFlag = isMulti
+			l.Command = "lsshell"
+			l.Sort = c.String("sort")
+			l.GroupBy = c.String("group-by")
+			l.Probe = c.Bool("probe")
 
 			l.View()
 			selected = l.SelectName
 			if len(selected) == 0 {
 				fmt.Fprintln(os.Stderr, "Selection cancelled.")
-				os.Exit(1)
+				conf.Exit(1)
 			}
 			if selected[0] == "ServerName" {
 				fmt.Fprintln(os.Stderr, "Server not selected.")
-				os.Exit(1)
+				conf.Exit(1)
 			}
 		}
 
@@ -160,6 +206,7 @@ USAGE:
 
 		// is tty
 		r.IsTerm = c.Bool("term")
+		r.OutputFormat = c.String("output-format")
 
 		// Set port forwards
 		var err error
@@ -207,8 +254,11 @@ USAGE:
 		// create AuthMap
 		r.CreateAuthMethodMap()
 
-		err = pshell.Shell(r)
-		return err
+		if err = pshell.Shell(r); err != nil {
+			return err
+		}
+		_ = hoststate.Record("lsshell", selected)
+		return nil
 	}
 	return app
 }
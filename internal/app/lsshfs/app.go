@@ -18,6 +18,7 @@ import (
 	"github.com/blacknon/lssh/internal/check"
 	"github.com/blacknon/lssh/internal/common"
 	conf "github.com/blacknon/lssh/internal/config"
+	"github.com/blacknon/lssh/internal/hoststate"
 	"github.com/blacknon/lssh/internal/list"
 	mountfs "github.com/blacknon/lssh/internal/lsshfs"
 	"github.com/blacknon/lssh/internal/version"
@@ -80,7 +81,7 @@ USAGE:
 	app.EnableBashCompletion = true
 	app.HideHelp = true
 	app.Flags = []cli.Flag{
-		cli.StringSliceFlag{Name: "host,H", Usage: "connect `servername`."},
+		cli.StringSliceFlag{Name: "host,H", Usage: "connect `servername` or host selector (ex. tag:web,env=prod,!tag:canary)."},
 		cli.StringFlag{Name: "file,F", Value: defConf, Usage: "config `filepath`."},
 		cli.StringFlag{Name: "generate-lssh-conf", Usage: "print generated lssh config from OpenSSH config to stdout (`~/.ssh/config` by default)."},
 		cli.StringSliceFlag{Name: "mount-option", Usage: "append local mount option (repeatable)."},
@@ -93,6 +94,10 @@ USAGE:
 		cli.BoolFlag{Name: "help,h", Usage: "print this help"},
 	}
 	app.Flags = append(app.Flags, common.ControlMasterOverrideFlags()...)
+	app.Flags = append(app.Flags, common.InventoryCacheFlags()...)
+	app.Flags = append(app.Flags, common.HostSelectionFlags()...)
+	app.Flags = append(app.Flags, common.ListFlags()...)
+	app.Flags = append(app.Flags, common.SelectorFlags()...)
 
 	app.Action = func(c *cli.Context) error {
 		if c.Bool("debug") {
@@ -102,7 +107,7 @@ USAGE:
 
 		if c.Bool("help") {
 			cli.ShowAppHelp(c)
-			os.Exit(0)
+			conf.Exit(0)
 		}
 
 		if handled, err := conf.HandleGenerateConfigMode(c.String("generate-lssh-conf"), os.Stdout); handled {
@@ -125,6 +130,7 @@ USAGE:
 			return controlMasterErr
 		}
 
+		conf.SetRefreshInventory(c.Bool("refresh-inventory"))
 		data, err := conf.ReadWithFallback(c.String("file"), os.Stderr)
 		if err != nil {
 			return err
@@ -133,13 +139,42 @@ USAGE:
 		sort.Strings(names)
 
 		if c.Bool("list") {
-			fmt.Fprintln(os.Stdout, "lssh Server List:")
-			for _, name := range names {
-				fmt.Fprintf(os.Stdout, "  %s\n", name)
+			if err := data.WriteServerListFormat(os.Stdout, names, common.GetListOptions(c)); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+				conf.Exit(1)
 			}
 			return nil
 		}
 
+		if err := list.ValidateSortOrder(c.String("sort")); err != nil {
+			return err
+		}
+		if err := data.CheckListGroupBy(c.String("group-by")); err != nil {
+			return err
+		}
+
+		flagHosts, err := common.GetFlagHosts(c)
+		if err != nil {
+			return err
+		}
+		flagHosts, err = list.ResolveHosts(data, flagHosts, c.StringSlice("select"))
+		if err != nil {
+			return err
+		}
+
+		if c.Bool("print-selection") {
+			l := &list.ListInfo{
+				Prompt:   "lsshfs>>",
+				NameList: names,
+				DataList: data,
+				Command:  "lsshfs",
+				Sort:     c.String("sort"),
+				GroupBy:  c.String("group-by"),
+				Probe:    c.Bool("probe"),
+			}
+			return l.PrintSelection(os.Stdout, flagHosts)
+		}
+
 		if c.NArg() != 2 {
 			cli.ShowAppHelp(c)
 			return fmt.Errorf("lsshfs requires remote_path and mountpoint")
@@ -159,13 +194,9 @@ USAGE:
 			return parseErr
 		}
 
-		flagHosts := c.StringSlice("host")
 		if len(flagHosts) > 1 {
 			return fmt.Errorf("lsshfs only supports a single host")
 		}
-		if len(flagHosts) > 0 && !check.ExistServer(flagHosts, names) {
-			return fmt.Errorf("input server not found from list")
-		}
 		if specHost != "" && !check.ExistServer([]string{specHost}, names) {
 			return fmt.Errorf("input server not found from list")
 		}
@@ -187,6 +218,10 @@ USAGE:
 			l.NameList = names
 			l.DataList = data
 			l.MultiFlag = false
+			l.Command = "lsshfs"
+			l.Sort = c.String("sort")
+			l.GroupBy = c.String("group-by")
+			l.Probe = c.Bool("probe")
 			l.View()
 			if len(l.SelectName) == 0 || l.SelectName[0] == "ServerName" {
 				return fmt.Errorf("selection cancelled")
@@ -207,9 +242,12 @@ USAGE:
 			ReadWrite:             c.Bool("rw") || !c.IsSet("rw"),
 			GOOS:                  runtime.GOOS,
 			ControlMasterOverride: controlMasterOverride,
-			ReadyNotifier:         notifyParentReady,
-			Stdout:                os.Stdout,
-			Stderr:                os.Stderr,
+			ReadyNotifier: func() {
+				_ = hoststate.Record("lsshfs", []string{selectedHost})
+				notifyParentReady()
+			},
+			Stdout: os.Stdout,
+			Stderr: os.Stderr,
 		}
 
 		return runner.Run()
@@ -319,7 +357,7 @@ func spawnBackgroundProcess(selectedHost string, appendHostFlag bool) error {
 					fmt.Fprintf(os.Stderr, "Debug log: %s\n", logPath)
 				}
 			}
-			os.Exit(0)
+			conf.Exit(0)
 		}
 		return fmt.Errorf("background start failed")
 	}
@@ -334,7 +372,7 @@ func spawnBackgroundProcess(selectedHost string, appendHostFlag bool) error {
 			fmt.Fprintf(os.Stderr, "Debug log: %s\n", logPath)
 		}
 	}
-	os.Exit(0)
+	conf.Exit(0)
 	return nil
 }
 
@@ -10,6 +10,7 @@ import (
 	"github.com/blacknon/lssh/internal/check"
 	"github.com/blacknon/lssh/internal/common"
 	conf "github.com/blacknon/lssh/internal/config"
+	"github.com/blacknon/lssh/internal/hoststate"
 	"github.com/blacknon/lssh/internal/list"
 	lsync "github.com/blacknon/lssh/internal/sync"
 	"github.com/blacknon/lssh/internal/version"
@@ -55,7 +56,7 @@ USAGE:
 	app.Copyright = "blacknon(blacknon@orebibou.com)"
 	app.Version = version.AppVersion(app.Name)
 	app.Flags = []cli.Flag{
-		cli.StringSliceFlag{Name: "host,H", Usage: "connect servernames"},
+		cli.StringSliceFlag{Name: "host,H", Usage: "connect servernames or host selectors (ex. tag:web,env=prod,!tag:canary)"},
 		cli.BoolFlag{Name: "list,l", Usage: "print server list from config"},
 		cli.StringFlag{Name: "file,F", Value: defConf, Usage: "config file path"},
 		cli.StringFlag{Name: "generate-lssh-conf", Usage: "print generated lssh config from OpenSSH config to stdout (`~/.ssh/config` by default)."},
@@ -69,16 +70,24 @@ USAGE:
 		cli.BoolFlag{Name: "help,h", Usage: "print this help"},
 	}
 	app.Flags = append(app.Flags, common.ControlMasterOverrideFlags()...)
+	app.Flags = append(app.Flags, common.InventoryCacheFlags()...)
+	app.Flags = append(app.Flags, common.HostSelectionFlags()...)
+	app.Flags = append(app.Flags, common.ListFlags()...)
+	app.Flags = append(app.Flags, common.SelectorFlags()...)
 	app.EnableBashCompletion = true
 	app.HideHelp = true
 
 	app.Action = func(c *cli.Context) error {
 		if c.Bool("help") {
 			cli.ShowAppHelp(c)
-			os.Exit(0)
+			conf.Exit(0)
 		}
 
-		hosts := c.StringSlice("host")
+		hosts, err := common.GetFlagHosts(c)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+			conf.Exit(1)
+		}
 		confpath := c.String("file")
 		controlMasterOverride, controlMasterErr := common.GetControlMasterOverride(c)
 		if controlMasterErr != nil {
@@ -87,10 +96,17 @@ USAGE:
 		if handled, err := conf.HandleGenerateConfigMode(c.String("generate-lssh-conf"), os.Stdout); handled {
 			return err
 		}
+		conf.SetRefreshInventory(c.Bool("refresh-inventory"))
 		data, err := conf.ReadWithFallback(confpath, os.Stderr)
 		if err != nil {
 			return err
 		}
+
+		hosts, err = list.ResolveHosts(data, hosts, c.StringSlice("select"))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+			conf.Exit(1)
+		}
 		allNames := conf.GetNameList(data)
 		names := append([]string(nil), allNames...)
 		names, err = data.FilterServersByOperation(names, "sftp_transport")
@@ -100,17 +116,44 @@ USAGE:
 		sort.Strings(names)
 
 		if c.Bool("list") {
-			fmt.Fprintf(os.Stdout, "lssh Server List:\n")
-			for _, name := range names {
-				fmt.Fprintf(os.Stdout, "  %s\n", name)
+			if err := data.WriteServerListFormat(os.Stdout, names, common.GetListOptions(c)); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+				conf.Exit(1)
 			}
-			os.Exit(0)
+			conf.Exit(0)
+		}
+
+		if err := list.ValidateSortOrder(c.String("sort")); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+			conf.Exit(1)
+		}
+		if err := data.CheckListGroupBy(c.String("group-by")); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+			conf.Exit(1)
+		}
+
+		if c.Bool("print-selection") {
+			l := &list.ListInfo{
+				Prompt:    "lssync>>",
+				NameList:  names,
+				DataList:  data,
+				MultiFlag: true,
+				Command:   "lssync",
+				Sort:      c.String("sort"),
+				GroupBy:   c.String("group-by"),
+				Probe:     c.Bool("probe"),
+			}
+			if err := l.PrintSelection(os.Stdout, hosts); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+				conf.Exit(1)
+			}
+			conf.Exit(0)
 		}
 
 		if len(c.Args()) < 2 {
 			fmt.Fprintln(os.Stderr, "Too few arguments.")
 			cli.ShowAppHelp(c)
-			os.Exit(1)
+			conf.Exit(1)
 		}
 
 		fromArgs := c.Args()[:c.NArg()-1]
@@ -124,7 +167,7 @@ USAGE:
 			spec, err := lsync.ParsePathSpecWithHosts(from, allNames)
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "Error: %s\n", err)
-				os.Exit(1)
+				conf.Exit(1)
 			}
 			if spec.IsRemote {
 				isFromInRemote = true
@@ -138,7 +181,7 @@ USAGE:
 		targetSpec, err := lsync.ParsePathSpecWithHosts(toArg, allNames)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error: %s\n", err)
-			os.Exit(1)
+			conf.Exit(1)
 		}
 		isToRemote := targetSpec.IsRemote
 		check.CheckTypeError(isFromInRemote, isFromInLocal, isToRemote, len(hosts))
@@ -152,13 +195,9 @@ USAGE:
 			if err != nil {
 				return err
 			}
-			if !check.ExistServer(hosts, allNames) {
-				fmt.Fprintln(os.Stderr, "Input Server not found from list.")
-				os.Exit(1)
-			}
 			if len(filteredHosts) != len(hosts) {
 				fmt.Fprintln(os.Stderr, "Input Server does not support SFTP-based transfer.")
-				os.Exit(1)
+				conf.Exit(1)
 			}
 			if isFromInRemote {
 				fromServer = append(fromServer, hosts...)
@@ -176,25 +215,29 @@ USAGE:
 			}
 			if len(filteredSourceHosts) != len(explicitSourceHosts) || len(filteredTargetHosts) != len(targetSpec.Hosts) {
 				fmt.Fprintln(os.Stderr, "Selected host does not support SFTP-based transfer.")
-				os.Exit(1)
+				conf.Exit(1)
 			}
 			fromServer = append(fromServer, explicitSourceHosts...)
 			toServer = append(toServer, targetSpec.Hosts...)
 		case isFromInRemote && isToRemote:
 			if len(names) == 0 {
 				fmt.Fprintln(os.Stderr, "No servers matched the current config conditions.")
-				os.Exit(1)
+				conf.Exit(1)
 			}
 			fromList := new(list.ListInfo)
 			fromList.Prompt = "lssync(from)>>"
 			fromList.NameList = names
 			fromList.DataList = data
 			fromList.MultiFlag = false
+			fromList.Command = "lssync"
+			fromList.Sort = c.String("sort")
+			fromList.GroupBy = c.String("group-by")
+			fromList.Probe = c.Bool("probe")
 			fromList.View()
 			fromServer = fromList.SelectName
 			if len(fromServer) == 0 || fromServer[0] == "ServerName" {
 				fmt.Fprintln(os.Stderr, "Selection cancelled.")
-				os.Exit(1)
+				conf.Exit(1)
 			}
 
 			toList := new(list.ListInfo)
@@ -202,27 +245,35 @@ USAGE:
 			toList.NameList = names
 			toList.DataList = data
 			toList.MultiFlag = true
+			toList.Command = "lssync"
+			toList.Sort = c.String("sort")
+			toList.GroupBy = c.String("group-by")
+			toList.Probe = c.Bool("probe")
 			toList.View()
 			toServer = toList.SelectName
 			if len(toServer) == 0 || toServer[0] == "ServerName" {
 				fmt.Fprintln(os.Stderr, "Selection cancelled.")
-				os.Exit(1)
+				conf.Exit(1)
 			}
 		default:
 			if len(names) == 0 {
 				fmt.Fprintln(os.Stderr, "No servers matched the current config conditions.")
-				os.Exit(1)
+				conf.Exit(1)
 			}
 			l := new(list.ListInfo)
 			l.Prompt = "lssync>>"
 			l.NameList = names
 			l.DataList = data
 			l.MultiFlag = true
+			l.Command = "lssync"
+			l.Sort = c.String("sort")
+			l.GroupBy = c.String("group-by")
+			l.Probe = c.Bool("probe")
 			l.View()
 			selected = l.SelectName
 			if len(selected) == 0 || selected[0] == "ServerName" {
 				fmt.Fprintln(os.Stderr, "Selection cancelled.")
-				os.Exit(1)
+				conf.Exit(1)
 			}
 
 			if isFromInRemote {
@@ -239,7 +290,7 @@ USAGE:
 			if !spec.IsRemote {
 				if _, err := os.Stat(common.GetFullPath(fromPath)); err != nil {
 					fmt.Fprintf(os.Stderr, "not found path %s \n", fromPath)
-					os.Exit(1)
+					conf.Exit(1)
 				}
 				fromPath = common.GetFullPath(fromPath)
 			} else {
@@ -282,6 +333,7 @@ USAGE:
 		}
 
 		s.Start()
+		_ = hoststate.Record("lssync", s.Synced())
 		return nil
 	}
 
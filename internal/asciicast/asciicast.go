@@ -0,0 +1,290 @@
+// Copyright (c) 2026 Blacknon. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+// Package asciicast records terminal sessions in the asciicast v2 format
+// (https://docs.asciinema.org/manual/asciicast/v2/), and plays them back.
+package asciicast
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Version is the asciicast format version written and read by this package.
+const Version = 2
+
+// event types of an asciicast v2 recording.
+const (
+	// EventOutput is data written to the terminal.
+	EventOutput = "o"
+
+	// EventInput is data typed on the terminal.
+	EventInput = "i"
+
+	// EventResize is a change of the terminal size, with `COLSxROWS` as data.
+	EventResize = "r"
+)
+
+// Header is the first line of a recording.
+type Header struct {
+	Version       int               `json:"version"`
+	Width         int               `json:"width"`
+	Height        int               `json:"height"`
+	Timestamp     int64             `json:"timestamp,omitempty"`
+	IdleTimeLimit float64           `json:"idle_time_limit,omitempty"`
+	Title         string            `json:"title,omitempty"`
+	Env           map[string]string `json:"env,omitempty"`
+}
+
+// NewHeader returns the header of a recording of a width x height terminal
+// starting now, with SHELL and TERM of the environment.
+func NewHeader(width, height int) Header {
+	header := Header{
+		Version:   Version,
+		Width:     width,
+		Height:    height,
+		Timestamp: time.Now().Unix(),
+	}
+	for _, name := range []string{"SHELL", "TERM"} {
+		if value := os.Getenv(name); value != "" {
+			if header.Env == nil {
+				header.Env = map[string]string{}
+			}
+			header.Env[name] = value
+		}
+	}
+	return header
+}
+
+// Event is a line after the header: `[time, type, data]`, where time is the
+// seconds since the start of the recording.
+type Event struct {
+	Time float64
+	Type string
+	Data string
+}
+
+func (e Event) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]interface{}{e.Time, e.Type, e.Data})
+}
+
+func (e *Event) UnmarshalJSON(data []byte) error {
+	var fields []json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	if len(fields) != 3 {
+		return fmt.Errorf("event has %d fields, want 3", len(fields))
+	}
+	if err := json.Unmarshal(fields[0], &e.Time); err != nil {
+		return fmt.Errorf("event time: %w", err)
+	}
+	if err := json.Unmarshal(fields[1], &e.Type); err != nil {
+		return fmt.Errorf("event type: %w", err)
+	}
+	if err := json.Unmarshal(fields[2], &e.Data); err != nil {
+		return fmt.Errorf("event data: %w", err)
+	}
+	return nil
+}
+
+// ParseSize returns the size of the data of an EventResize.
+func ParseSize(data string) (cols, rows int, err error) {
+	c, r, ok := strings.Cut(data, "x")
+	if ok {
+		cols, err = strconv.Atoi(c)
+		if err == nil {
+			rows, err = strconv.Atoi(r)
+		}
+	}
+	if !ok || err != nil || cols <= 0 || rows <= 0 {
+		return 0, 0, fmt.Errorf("invalid terminal size %q", data)
+	}
+	return cols, rows, nil
+}
+
+// Read reads a recording. Blank lines are skipped.
+func Read(r io.Reader) (Header, []Event, error) {
+	var header Header
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+
+	line := 0
+	events := []Event{}
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		if header.Version == 0 {
+			if err := json.Unmarshal([]byte(text), &header); err != nil {
+				return header, nil, fmt.Errorf("line %d: invalid header: %w", line, err)
+			}
+			if header.Version != Version {
+				return header, nil, fmt.Errorf("unsupported asciicast version %d", header.Version)
+			}
+			continue
+		}
+
+		var event Event
+		if err := json.Unmarshal([]byte(text), &event); err != nil {
+			return header, nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		events = append(events, event)
+	}
+	if err := scanner.Err(); err != nil {
+		return header, nil, err
+	}
+	if header.Version == 0 {
+		return header, nil, fmt.Errorf("missing asciicast header")
+	}
+	return header, events, nil
+}
+
+// ReadFile reads the recording of path.
+func ReadFile(path string) (Header, []Event, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Header{}, nil, err
+	}
+	defer f.Close()
+	return Read(f)
+}
+
+// Recorder writes the events of a terminal session as they happen. The
+// methods are safe for concurrent use.
+type Recorder struct {
+	mu     sync.Mutex
+	w      io.Writer
+	closer io.Closer
+	start  time.Time
+
+	// pending is the incomplete UTF-8 sequence at the end of the last write
+	// of each event type, completed by the next write.
+	pending map[string][]byte
+}
+
+// NewRecorder writes header to w and returns a Recorder of the events after
+// it.
+func NewRecorder(w io.Writer, header Header) (*Recorder, error) {
+	header.Version = Version
+	data, err := json.Marshal(header)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(append(data, '\n')); err != nil {
+		return nil, err
+	}
+	return &Recorder{w: w, start: time.Now(), pending: map[string][]byte{}}, nil
+}
+
+// Create creates the recording file of path, closed by Close.
+func Create(path string, header Header) (*Recorder, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return nil, err
+	}
+	rec, err := NewRecorder(f, header)
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	rec.closer = f
+	return rec, nil
+}
+
+// Output returns a writer recording EventOutput.
+func (r *Recorder) Output() io.Writer {
+	return &eventWriter{r: r, eventType: EventOutput}
+}
+
+// Input returns a writer recording EventInput.
+func (r *Recorder) Input() io.Writer {
+	return &eventWriter{r: r, eventType: EventInput}
+}
+
+// Resize records an EventResize.
+func (r *Recorder) Resize(cols, rows int) {
+	r.record(EventResize, []byte(fmt.Sprintf("%dx%d", cols, rows)))
+}
+
+// Close closes the file of Create. The events after Close are dropped.
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.w == nil {
+		return nil
+	}
+	r.w = nil
+	if r.closer != nil {
+		return r.closer.Close()
+	}
+	return nil
+}
+
+// record writes an event of p, holding back an incomplete UTF-8 sequence at
+// its end. The data of an event is a JSON string, so the invalid bytes would
+// be replaced.
+func (r *Recorder) record(eventType string, p []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.w == nil {
+		return
+	}
+
+	data := append(r.pending[eventType], p...)
+	cut := incompleteUTF8Suffix(data)
+	r.pending[eventType] = append([]byte(nil), data[len(data)-cut:]...)
+	data = data[:len(data)-cut]
+	if len(data) == 0 {
+		return
+	}
+
+	event := Event{
+		Time: float64(time.Since(r.start).Microseconds()) / 1e6,
+		Type: eventType,
+		Data: string(data),
+	}
+	line, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+	_, _ = r.w.Write(append(line, '\n'))
+}
+
+// incompleteUTF8Suffix returns the length of the incomplete UTF-8 sequence
+// at the end of p.
+func incompleteUTF8Suffix(p []byte) int {
+	for i := 1; i < utf8.UTFMax && i <= len(p); i++ {
+		b := p[len(p)-i]
+		if !utf8.RuneStart(b) {
+			continue
+		}
+		if !utf8.FullRune(p[len(p)-i:]) {
+			return i
+		}
+		return 0
+	}
+	return 0
+}
+
+type eventWriter struct {
+	r         *Recorder
+	eventType string
+}
+
+func (w *eventWriter) Write(p []byte) (int, error) {
+	w.r.record(w.eventType, p)
+	return len(p), nil
+}
@@ -0,0 +1,128 @@
+package asciicast
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRecorderWritesHeaderAndEvents(t *testing.T) {
+	var buf bytes.Buffer
+	rec, err := NewRecorder(&buf, Header{Width: 80, Height: 24, Timestamp: 1700000000})
+	if err != nil {
+		t.Fatalf("NewRecorder() error = %v", err)
+	}
+
+	_, _ = rec.Output().Write([]byte("hello\r\n"))
+	_, _ = rec.Input().Write([]byte("ls\r"))
+	rec.Resize(120, 40)
+	if err := rec.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	_, _ = rec.Output().Write([]byte("dropped"))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("lines = %q, want header and 3 events", lines)
+	}
+	if lines[0] != `{"version":2,"width":80,"height":24,"timestamp":1700000000}` {
+		t.Fatalf("header = %s", lines[0])
+	}
+
+	header, events, err := Read(&buf)
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if header.Width != 80 || header.Height != 24 {
+		t.Fatalf("header = %+v", header)
+	}
+	want := []Event{
+		{Type: EventOutput, Data: "hello\r\n"},
+		{Type: EventInput, Data: "ls\r"},
+		{Type: EventResize, Data: "120x40"},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("events = %+v", events)
+	}
+	for i := range want {
+		if events[i].Type != want[i].Type || events[i].Data != want[i].Data {
+			t.Fatalf("events[%d] = %+v, want %+v", i, events[i], want[i])
+		}
+		if i > 0 && events[i].Time < events[i-1].Time {
+			t.Fatalf("event times are not ordered: %+v", events)
+		}
+	}
+}
+
+func TestRecorderKeepsSplitUTF8Sequence(t *testing.T) {
+	var buf bytes.Buffer
+	rec, err := NewRecorder(&buf, Header{Width: 80, Height: 24})
+	if err != nil {
+		t.Fatalf("NewRecorder() error = %v", err)
+	}
+
+	data := []byte("あい")
+	out := rec.Output()
+	_, _ = out.Write(data[:4])
+	_, _ = out.Write(data[4:])
+
+	_, events, err := Read(&buf)
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if len(events) != 2 || events[0].Data != "あ" || events[1].Data != "い" {
+		t.Fatalf("events = %+v", events)
+	}
+}
+
+func TestCreateAndReadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.cast")
+	rec, err := Create(path, NewHeader(100, 30))
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	_, _ = rec.Output().Write([]byte("$ "))
+	if err := rec.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	header, events, err := ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if header.Version != Version || header.Width != 100 || header.Height != 30 || header.Timestamp == 0 {
+		t.Fatalf("header = %+v", header)
+	}
+	if len(events) != 1 || events[0].Data != "$ " {
+		t.Fatalf("events = %+v", events)
+	}
+}
+
+func TestReadRejectsInvalidRecordings(t *testing.T) {
+	tests := map[string]string{
+		"empty":       "",
+		"version":     `{"version":1,"width":80,"height":24}`,
+		"event":       "{\"version\":2,\"width\":80,\"height\":24}\n[0.1,\"o\"]\n",
+		"event type":  "{\"version\":2,\"width\":80,\"height\":24}\n[0.1,1,\"x\"]\n",
+		"not json":    "{\"version\":2,\"width\":80,\"height\":24}\nfoo\n",
+		"header json": "[0.1,\"o\",\"x\"]\n",
+	}
+	for name, input := range tests {
+		if _, _, err := Read(strings.NewReader(input)); err == nil {
+			t.Fatalf("%s: Read() error = nil", name)
+		}
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	cols, rows, err := ParseSize("120x40")
+	if err != nil || cols != 120 || rows != 40 {
+		t.Fatalf("ParseSize() = %d, %d, %v", cols, rows, err)
+	}
+	for _, data := range []string{"", "120", "0x40", "ax40", "120x"} {
+		if _, _, err := ParseSize(data); err == nil {
+			t.Fatalf("ParseSize(%q) error = nil", data)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+// Copyright (c) 2026 Blacknon. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package asciicast
+
+import (
+	"io"
+	"time"
+)
+
+// Control is a command to a playing Player.
+type Control int
+
+const (
+	// ControlPause pauses or resumes the playback.
+	ControlPause Control = iota
+
+	// ControlForward seeks SeekStep forward.
+	ControlForward
+
+	// ControlBackward seeks SeekStep backward.
+	ControlBackward
+
+	// ControlFaster doubles the speed.
+	ControlFaster
+
+	// ControlSlower halves the speed.
+	ControlSlower
+
+	// ControlQuit stops the playback.
+	ControlQuit
+)
+
+// limits of the speed of a Player.
+const (
+	MinSpeed = 1.0 / 16
+	MaxSpeed = 16.0
+)
+
+// SeekStep is the seconds of the recording skipped by ControlForward and
+// ControlBackward.
+const SeekStep = 5.0
+
+// resetTerminal clears the terminal before the output is written again from
+// the start, on ControlBackward.
+const resetTerminal = "\x1bc"
+
+// Player writes the output events of a recording to a terminal with their
+// timing.
+type Player struct {
+	Events []Event
+
+	// Speed is the playback speed, 1 for the recorded timing.
+	Speed float64
+
+	// IdleTimeLimit is the longest wait between events in seconds of the
+	// recording, without limit if 0.
+	IdleTimeLimit float64
+
+	out  io.Writer
+	pos  float64
+	next int
+}
+
+// NewPlayer returns a Player of events to out, with the idle time limit of
+// header.
+func NewPlayer(out io.Writer, header Header, events []Event, speed float64) *Player {
+	return &Player{
+		Events:        events,
+		Speed:         clampSpeed(speed),
+		IdleTimeLimit: header.IdleTimeLimit,
+		out:           out,
+	}
+}
+
+// Position returns the played seconds of the recording.
+func (p *Player) Position() float64 {
+	return p.pos
+}
+
+// Play plays the recording until the end or ControlQuit. controls may be
+// nil.
+func (p *Player) Play(controls <-chan Control) {
+	paused := false
+	for p.next < len(p.Events) {
+		if paused {
+			control, ok := <-controls
+			if !ok {
+				controls = nil
+				paused = false
+				continue
+			}
+			if control == ControlPause {
+				paused = false
+				continue
+			}
+			if p.handle(control) {
+				return
+			}
+			continue
+		}
+
+		event := p.Events[p.next]
+		wait := p.delay(event)
+		if wait <= 0 {
+			p.write(event)
+			continue
+		}
+
+		started := time.Now()
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+			p.write(event)
+		case control, ok := <-controls:
+			timer.Stop()
+			if !ok {
+				controls = nil
+				continue
+			}
+			p.pos += min(time.Since(started), wait).Seconds() * p.Speed
+			if control == ControlPause {
+				paused = true
+				continue
+			}
+			if p.handle(control) {
+				return
+			}
+		}
+	}
+}
+
+// delay returns the wait before event from the position.
+func (p *Player) delay(event Event) time.Duration {
+	gap := event.Time - p.pos
+	if p.IdleTimeLimit > 0 && gap > p.IdleTimeLimit {
+		// the rest of the gap is skipped.
+		p.pos = event.Time - p.IdleTimeLimit
+		gap = p.IdleTimeLimit
+	}
+	if gap <= 0 {
+		return 0
+	}
+	return time.Duration(gap / p.Speed * float64(time.Second))
+}
+
+// handle runs a control other than ControlPause, and returns that the
+// playback is stopped.
+func (p *Player) handle(control Control) bool {
+	switch control {
+	case ControlQuit:
+		return true
+	case ControlFaster:
+		p.Speed = clampSpeed(p.Speed * 2)
+	case ControlSlower:
+		p.Speed = clampSpeed(p.Speed / 2)
+	case ControlForward:
+		p.Seek(p.pos + SeekStep)
+	case ControlBackward:
+		p.Seek(p.pos - SeekStep)
+	}
+	return false
+}
+
+// Seek moves the position to seconds of the recording. The output up to the
+// position is written at once, from the start of the recording when seeking
+// backward.
+func (p *Player) Seek(seconds float64) {
+	seconds = max(seconds, 0)
+	if seconds < p.pos {
+		_, _ = io.WriteString(p.out, resetTerminal)
+		p.next = 0
+	}
+	for p.next < len(p.Events) && p.Events[p.next].Time <= seconds {
+		p.write(p.Events[p.next])
+	}
+	p.pos = seconds
+}
+
+// write writes event if it is an output, and moves the position to it.
+func (p *Player) write(event Event) {
+	if event.Type == EventOutput {
+		_, _ = io.WriteString(p.out, event.Data)
+	}
+	p.pos = max(p.pos, event.Time)
+	p.next++
+}
+
+func clampSpeed(speed float64) float64 {
+	if speed <= 0 {
+		return 1
+	}
+	return min(max(speed, MinSpeed), MaxSpeed)
+}
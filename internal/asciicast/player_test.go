@@ -0,0 +1,108 @@
+package asciicast
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func testEvents() []Event {
+	return []Event{
+		{Time: 0.01, Type: EventOutput, Data: "a"},
+		{Time: 0.02, Type: EventInput, Data: "x"},
+		{Time: 0.03, Type: EventResize, Data: "100x30"},
+		{Time: 6, Type: EventOutput, Data: "b"},
+		{Time: 12, Type: EventOutput, Data: "c"},
+	}
+}
+
+func TestPlayerPlaysOutputEvents(t *testing.T) {
+	var out bytes.Buffer
+	events := testEvents()[:3]
+	p := NewPlayer(&out, Header{}, events, MaxSpeed)
+
+	p.Play(nil)
+	if out.String() != "a" {
+		t.Fatalf("output = %q", out.String())
+	}
+	if p.Position() != 0.03 {
+		t.Fatalf("Position() = %v", p.Position())
+	}
+}
+
+func TestPlayerIdleTimeLimit(t *testing.T) {
+	var out bytes.Buffer
+	p := NewPlayer(&out, Header{IdleTimeLimit: 0.01}, testEvents(), 1)
+
+	done := make(chan struct{})
+	go func() {
+		p.Play(nil)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Play() did not skip the idle time")
+	}
+	if out.String() != "abc" {
+		t.Fatalf("output = %q", out.String())
+	}
+}
+
+func TestPlayerSeek(t *testing.T) {
+	var out bytes.Buffer
+	p := NewPlayer(&out, Header{}, testEvents(), 1)
+
+	p.Seek(7)
+	if out.String() != "ab" || p.Position() != 7 {
+		t.Fatalf("Seek(7): output = %q, position = %v", out.String(), p.Position())
+	}
+
+	out.Reset()
+	p.Seek(2)
+	if out.String() != resetTerminal+"a" || p.Position() != 2 {
+		t.Fatalf("Seek(2): output = %q, position = %v", out.String(), p.Position())
+	}
+}
+
+func TestPlayerControls(t *testing.T) {
+	var out bytes.Buffer
+	p := NewPlayer(&out, Header{}, testEvents(), 1)
+
+	controls := make(chan Control, 8)
+	controls <- ControlFaster
+	controls <- ControlSlower
+	controls <- ControlSlower
+	controls <- ControlPause
+	controls <- ControlForward
+	controls <- ControlPause
+	controls <- ControlQuit
+
+	done := make(chan struct{})
+	go func() {
+		p.Play(controls)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Play() did not stop on ControlQuit")
+	}
+
+	if p.Speed != 0.5 {
+		t.Fatalf("Speed = %v, want 0.5", p.Speed)
+	}
+	// the forward seek from the pause writes the output up to 5 seconds.
+	if out.String() != "a" || p.Position() < SeekStep {
+		t.Fatalf("output = %q, position = %v", out.String(), p.Position())
+	}
+}
+
+func TestClampSpeed(t *testing.T) {
+	tests := map[float64]float64{0: 1, -1: 1, 0.001: MinSpeed, 100: MaxSpeed, 2: 2}
+	for speed, want := range tests {
+		if got := clampSpeed(speed); got != want {
+			t.Fatalf("clampSpeed(%v) = %v, want %v", speed, got, want)
+		}
+	}
+}
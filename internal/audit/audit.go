@@ -0,0 +1,327 @@
+// Copyright (c) 2026 Blacknon. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+// Package audit appends a JSON line for each action of the lssh commands to
+// the audit log of the [audit] config, a file or syslog.
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	conf "github.com/blacknon/lssh/internal/config"
+)
+
+// actions of a Record.
+const (
+	// ActionShellStart is the start of an interactive shell.
+	ActionShellStart = "shell_start"
+
+	// ActionShellEnd is the end of an interactive shell, with its exit code
+	// and duration.
+	ActionShellEnd = "shell_end"
+
+	// ActionCommand is a command run on a host, in command mode, lsshell or
+	// lspipe.
+	ActionCommand = "command"
+
+	// ActionTransfer is a file transfer to or from a host.
+	ActionTransfer = "transfer"
+
+	// ActionForward is a port forward started on a host.
+	ActionForward = "forward"
+)
+
+// Program is the name of the command in the records.
+var Program = strings.TrimSuffix(filepath.Base(os.Args[0]), ".exe")
+
+// Record is a line of the audit log.
+type Record struct {
+	Time      time.Time `json:"time"`
+	Program   string    `json:"program"`
+	Action    string    `json:"action"`
+	LocalUser string    `json:"local_user"`
+
+	// Host is the server name, Address the `user@addr:port` it is connected
+	// to, and Proxy the proxy hops to it, first hop first.
+	Host      string   `json:"host,omitempty"`
+	Address   string   `json:"address,omitempty"`
+	Proxy     []string `json:"proxy,omitempty"`
+	Connector string   `json:"connector,omitempty"`
+
+	Command string `json:"command,omitempty"`
+
+	// Source and Destination are the paths of a transfer, as `host:path`
+	// for a remote path.
+	Source      []string `json:"source,omitempty"`
+	Destination string   `json:"destination,omitempty"`
+
+	Forward string `json:"forward,omitempty"`
+
+	// Bytes is the size of the output of a command, or the bytes copied by
+	// a transfer.
+	Bytes int64 `json:"bytes,omitempty"`
+
+	// ExitCode is the exit status of a command or a shell, nil if there is
+	// none.
+	ExitCode *int `json:"exit_code,omitempty"`
+
+	Duration time.Duration `json:"-"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// MarshalJSON writes Duration in milliseconds as `duration_ms`, without
+// escaping `<`, `>` and `&`, so the log can be searched for `<redacted>`.
+func (r Record) MarshalJSON() ([]byte, error) {
+	type record Record
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(struct {
+		record
+		DurationMS int64 `json:"duration_ms,omitempty"`
+	}{record(r), r.Duration.Milliseconds()}); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// Finish sets the duration, the exit code and the error of r. A negative
+// exitCode is not set.
+func (r *Record) Finish(duration time.Duration, exitCode int, err error) {
+	r.Duration = duration
+	if exitCode >= 0 {
+		r.ExitCode = &exitCode
+	}
+	if err != nil {
+		r.Error = err.Error()
+	}
+}
+
+// Enabled returns that config has the audit log enabled.
+func Enabled(config conf.Config) bool {
+	return config.Audit.Enable
+}
+
+// Write appends record to the audit log of config, if it is enabled. The
+// time, the program and the local user are set if empty, and the secrets of
+// the host are redacted by conf.Config.RedactSecrets.
+func Write(config conf.Config, record Record) error {
+	if !Enabled(config) {
+		return nil
+	}
+	if err := config.Audit.Check(); err != nil {
+		return err
+	}
+
+	if record.Time.IsZero() {
+		record.Time = time.Now()
+	}
+	if record.Program == "" {
+		record.Program = Program
+	}
+	if record.LocalUser == "" {
+		record.LocalUser = localUser()
+	}
+
+	redact := func(value string) string {
+		return config.RedactSecrets(record.Host, value)
+	}
+	record.Command = redact(record.Command)
+	record.Destination = redact(record.Destination)
+	record.Forward = redact(record.Forward)
+	record.Error = redact(record.Error)
+	if len(record.Source) > 0 {
+		source := make([]string, len(record.Source))
+		for i, path := range record.Source {
+			source[i] = redact(path)
+		}
+		record.Source = source
+	}
+
+	line, err := record.MarshalJSON()
+	if err != nil {
+		return err
+	}
+
+	if path := config.Audit.FilePath(); path != "" {
+		if err := appendFile(path, append(line, '\n')); err != nil {
+			return err
+		}
+	}
+	if config.Audit.Syslog != "" {
+		network, address, _ := config.Audit.SyslogAddr()
+		if err := writeSyslog(network, address, record.Program, string(line)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// errorOutput is where Log reports the first error.
+var errorOutput io.Writer = os.Stderr
+
+var reportOnce sync.Once
+
+// Log is Write, reporting the first error of the process on stderr. A
+// failure of the audit log does not stop the action.
+func Log(config conf.Config, record Record) {
+	if err := Write(config, record); err != nil {
+		reportOnce.Do(func() {
+			fmt.Fprintf(errorOutput, "audit log: %s\n", err)
+		})
+	}
+}
+
+// fileMu serializes the writes of the process. Each record is appended by a
+// single write, so the records of other processes are not mixed in.
+var fileMu sync.Mutex
+
+func appendFile(path string, line []byte) error {
+	fileMu.Lock()
+	defer fileMu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(line); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func localUser() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+	if name := os.Getenv("USER"); name != "" {
+		return name
+	}
+	return os.Getenv("USERNAME")
+}
+
+// Counter counts the bytes written through its writers. The zero value is
+// ready to use.
+type Counter struct {
+	n atomic.Int64
+}
+
+// Writer returns a writer to w counting the bytes.
+func (c *Counter) Writer(w io.Writer) io.Writer {
+	return &countWriter{c: c, w: w}
+}
+
+// Count returns the bytes written.
+func (c *Counter) Count() int64 {
+	return c.n.Load()
+}
+
+type countWriter struct {
+	c *Counter
+	w io.Writer
+}
+
+func (w *countWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.c.n.Add(int64(n))
+	return n, err
+}
+
+// Transfer sums up a transfer to or from a host, and writes its Record on
+// Done. The methods are safe for concurrent use, and do nothing on a nil
+// Transfer.
+type Transfer struct {
+	config conf.Config
+	record Record
+	start  time.Time
+
+	mu    sync.Mutex
+	bytes int64
+	err   error
+}
+
+// StartTransfer returns a Transfer of record, or nil if the audit log of
+// config is disabled.
+func StartTransfer(config conf.Config, record Record) *Transfer {
+	if !Enabled(config) {
+		return nil
+	}
+	record.Action = ActionTransfer
+	return &Transfer{config: config, record: record, start: time.Now()}
+}
+
+// Add adds n copied bytes, and keeps err if it is the first error.
+func (t *Transfer) Add(n int64, err error) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.bytes += n
+	if t.err == nil {
+		t.err = err
+	}
+}
+
+// Copy is io.Copy, adding the copied bytes and the error.
+func (t *Transfer) Copy(dst io.Writer, src io.Reader) (int64, error) {
+	n, err := io.Copy(dst, src)
+	t.Add(n, err)
+	return n, err
+}
+
+// Reader returns a reader of r adding the read bytes and the error, other
+// than io.EOF. It returns r on a nil Transfer.
+func (t *Transfer) Reader(r io.Reader) io.Reader {
+	if t == nil {
+		return r
+	}
+	return &transferReader{t: t, r: r}
+}
+
+type transferReader struct {
+	t *Transfer
+	r io.Reader
+}
+
+func (r *transferReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if err == io.EOF {
+		r.t.Add(int64(n), nil)
+	} else {
+		r.t.Add(int64(n), err)
+	}
+	return n, err
+}
+
+// Done adds err, and writes the record with the bytes, the duration and the
+// first error.
+func (t *Transfer) Done(err error) {
+	if t == nil {
+		return
+	}
+	t.Add(0, err)
+
+	t.mu.Lock()
+	record := t.record
+	record.Bytes = t.bytes
+	record.Finish(time.Since(t.start), -1, t.err)
+	t.mu.Unlock()
+
+	Log(t.config, record)
+}
@@ -0,0 +1,170 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	conf "github.com/blacknon/lssh/internal/config"
+)
+
+func testConfig(t *testing.T) (conf.Config, string) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "audit", "audit.jsonl")
+	config := conf.Config{
+		Audit: conf.AuditConfig{Enable: true, Path: path},
+		Server: map[string]conf.ServerConfig{
+			"web": {Addr: "192.0.2.10", User: "deploy", Pass: "s3cret-pass"},
+		},
+	}
+	return config, path
+}
+
+func readRecords(t *testing.T, path string) []map[string]interface{} {
+	t.Helper()
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("open audit log: %v", err)
+	}
+	defer f.Close()
+
+	records := []map[string]interface{}{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		record := map[string]interface{}{}
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatalf("unmarshal %q: %v", scanner.Text(), err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestWriteAppendsRecords(t *testing.T) {
+	config, path := testConfig(t)
+
+	exitCode := 3
+	if err := Write(config, Record{
+		Action:   ActionCommand,
+		Host:     "web",
+		Command:  "mysql -p s3cret-pass",
+		ExitCode: &exitCode,
+		Duration: 1500 * time.Millisecond,
+	}); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if err := Write(config, Record{Action: ActionShellStart, Host: "web"}); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	records := readRecords(t, path)
+	if len(records) != 2 {
+		t.Fatalf("records = %d, want 2", len(records))
+	}
+
+	got := records[0]
+	if got["action"] != ActionCommand || got["host"] != "web" {
+		t.Fatalf("record = %v", got)
+	}
+	if got["command"] != "mysql -p <redacted>" {
+		t.Fatalf("command = %v, want the password redacted", got["command"])
+	}
+	if got["exit_code"] != float64(3) || got["duration_ms"] != float64(1500) {
+		t.Fatalf("exit_code = %v, duration_ms = %v", got["exit_code"], got["duration_ms"])
+	}
+	if got["program"] == "" || got["time"] == "" {
+		t.Fatalf("program = %v, time = %v", got["program"], got["time"])
+	}
+
+	if _, ok := records[1]["exit_code"]; ok {
+		t.Fatalf("shell_start record has exit_code: %v", records[1])
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("stat audit log: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Fatalf("mode = %v, want 0600", info.Mode().Perm())
+	}
+}
+
+func TestWriteDisabled(t *testing.T) {
+	config, path := testConfig(t)
+	config.Audit.Enable = false
+
+	if err := Write(config, Record{Action: ActionCommand, Host: "web"}); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("audit log exists with the audit log disabled: %v", err)
+	}
+	if StartTransfer(config, Record{Host: "web"}) != nil {
+		t.Fatal("StartTransfer() returns a Transfer with the audit log disabled")
+	}
+}
+
+func TestLogReportsFirstError(t *testing.T) {
+	config := conf.Config{Audit: conf.AuditConfig{Enable: true}}
+
+	var stderr bytes.Buffer
+	errorOutput = &stderr
+	defer func() { errorOutput = os.Stderr }()
+
+	Log(config, Record{Action: ActionCommand})
+	Log(config, Record{Action: ActionCommand})
+
+	if got := strings.Count(stderr.String(), "audit log:"); got != 1 {
+		t.Fatalf("reported %d errors, want 1: %q", got, stderr.String())
+	}
+}
+
+func TestTransferSumsBytesAndFirstError(t *testing.T) {
+	config, path := testConfig(t)
+
+	transfer := StartTransfer(config, Record{Host: "web", Source: []string{"./a"}, Destination: "web:/tmp"})
+	var dst bytes.Buffer
+	if _, err := transfer.Copy(&dst, strings.NewReader("12345")); err != nil {
+		t.Fatalf("Copy() error = %v", err)
+	}
+	if _, err := dst.ReadFrom(transfer.Reader(strings.NewReader("678"))); err != nil {
+		t.Fatalf("Reader() error = %v", err)
+	}
+	transfer.Add(0, errors.New("permission denied"))
+	transfer.Done(errors.New("closed"))
+
+	records := readRecords(t, path)
+	if len(records) != 1 {
+		t.Fatalf("records = %d, want 1", len(records))
+	}
+	got := records[0]
+	if got["action"] != ActionTransfer || got["bytes"] != float64(8) {
+		t.Fatalf("record = %v", got)
+	}
+	if got["error"] != "permission denied" {
+		t.Fatalf("error = %v, want the first error", got["error"])
+	}
+}
+
+func TestNilTransfer(t *testing.T) {
+	var transfer *Transfer
+
+	var dst bytes.Buffer
+	if _, err := transfer.Copy(&dst, strings.NewReader("data")); err != nil {
+		t.Fatalf("Copy() error = %v", err)
+	}
+	transfer.Add(1, nil)
+	transfer.Done(nil)
+
+	if dst.String() != "data" {
+		t.Fatalf("copied %q", dst.String())
+	}
+}
@@ -0,0 +1,16 @@
+//go:build !windows
+
+package audit
+
+import "log/syslog"
+
+// writeSyslog sends line to the syslog of network and address, the local
+// syslog daemon if both are empty.
+func writeSyslog(network, address, tag, line string) error {
+	w, err := syslog.Dial(network, address, syslog.LOG_INFO|syslog.LOG_AUTHPRIV, tag)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+	return w.Info(line)
+}
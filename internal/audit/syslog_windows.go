@@ -0,0 +1,9 @@
+//go:build windows
+
+package audit
+
+import "fmt"
+
+func writeSyslog(network, address, tag, line string) error {
+	return fmt.Errorf("syslog is not supported on windows")
+}
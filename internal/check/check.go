@@ -12,6 +12,8 @@ import (
 	"fmt"
 	"os"
 	"strings"
+
+	conf "github.com/blacknon/lssh/internal/config"
 )
 
 // ExistServer returns true if inputServer exists in nameList.
@@ -79,7 +81,7 @@ func ParseScpPath(arg string) (isRemote bool, path string) {
 
 		// error
 		fmt.Fprintln(os.Stderr, "The format of the specified argument is incorrect.")
-		os.Exit(1)
+		conf.Exit(1)
 	}
 
 	return
@@ -99,18 +101,18 @@ func CheckTypeError(isFromInRemote, isFromInLocal, isToRemote bool, countHosts i
 	// from in local and remote
 	if isFromInRemote && isFromInLocal {
 		fmt.Fprintln(os.Stderr, "Can not set LOCAL and REMOTE to FROM path.")
-		os.Exit(1)
+		conf.Exit(1)
 	}
 
 	// local only
 	if !isFromInRemote && !isToRemote {
 		fmt.Fprintln(os.Stderr, "It does not correspond LOCAL to LOCAL copy.")
-		os.Exit(1)
+		conf.Exit(1)
 	}
 
 	// remote 2 remote and set host option
 	if isFromInRemote && isToRemote && countHosts != 0 {
 		fmt.Fprintln(os.Stderr, "In the case of REMOTE to REMOTE copy, it does not correspond to host option.")
-		os.Exit(1)
+		conf.Exit(1)
 	}
 }
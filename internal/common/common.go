@@ -28,6 +28,7 @@ import (
 	"strings"
 
 	sshlib "github.com/blacknon/go-sshlib"
+	"github.com/blacknon/lssh/internal/hoststate"
 
 	"github.com/urfave/cli"
 	"golang.org/x/crypto/ssh/terminal"
@@ -69,6 +70,166 @@ func GetControlMasterOverride(c *cli.Context) (*bool, error) {
 	return nil, nil
 }
 
+// InventoryCacheFlags returns flags controlling the provider inventory cache.
+func InventoryCacheFlags() []cli.Flag {
+	return []cli.Flag{
+		cli.BoolFlag{
+			Name:  "refresh-inventory",
+			Usage: "ignore cached provider inventory and fetch it again",
+		},
+	}
+}
+
+// ListOptions is the options of the `--list` output.
+type ListOptions struct {
+	// Format is one of text, json, yaml, tsv or table. Empty is text.
+	Format string
+
+	// Fields is the fields to output. Empty outputs the default columns
+	// (tsv/table) or the whole effective config (json/yaml).
+	Fields []string
+
+	// Filter is a host selector (ex. `tag:prod`, `web-*`) that names must match.
+	Filter string
+
+	// Explain is a server name. If set, the source of each field of that
+	// server is written instead of the list.
+	Explain string
+}
+
+// ListFlags returns flags controlling the `--list` output.
+func ListFlags() []cli.Flag {
+	return []cli.Flag{
+		cli.StringFlag{
+			Name:  "format",
+			Usage: "`--list` output format. [text|json|yaml|tsv|table]",
+		},
+		cli.StringFlag{
+			Name:  "fields",
+			Usage: "comma separated `--list` fields. ex) name,addr,user,port,proxy,connector,provider,tags,note,meta.KEY",
+		},
+		cli.StringFlag{
+			Name:  "filter",
+			Usage: "list only servers matching the host `selector`. ex) tag:prod,!name:*-old",
+		},
+		cli.StringFlag{
+			Name:  "explain",
+			Usage: "with `--list`, show which file, provider, template or match set each field of `host`",
+		},
+	}
+}
+
+// GetListOptions returns the `--list` options of c.
+func GetListOptions(c *cli.Context) ListOptions {
+	var fields []string
+	for _, field := range strings.Split(c.String("fields"), ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			fields = append(fields, field)
+		}
+	}
+
+	return ListOptions{
+		Format:  c.String("format"),
+		Fields:  fields,
+		Filter:  c.String("filter"),
+		Explain: c.String("explain"),
+	}
+}
+
+// SelectorFlags returns the flags of the host order, groups and probe in the
+// selector.
+func SelectorFlags() []cli.Flag {
+	return []cli.Flag{
+		cli.StringFlag{
+			Name:  "sort",
+			Value: "name",
+			Usage: "host order in the selector. favorite and recent hosts are shown first. [name|addr|frecency]",
+		},
+		cli.StringFlag{
+			Name:  "group-by",
+			Usage: "fold hosts in the selector under group headers by a --list field (provider, proxy, meta.KEY, tag.KEY...) or note_prefix",
+		},
+		cli.BoolFlag{
+			Name:  "probe",
+			Usage: "show the reachability and round trip time of the hosts in the selector, probed in the background",
+		},
+	}
+}
+
+// HostSelectionFlags returns the flags that select the hosts without the
+// selector: saved host sets, `--select` expressions and a host list file.
+// `--print-selection` prints the selected hosts instead of connecting.
+func HostSelectionFlags() []cli.Flag {
+	return []cli.Flag{
+		cli.StringSliceFlag{
+			Name:  "set",
+			Usage: "use the hosts of the saved host set `name` (saved with Ctrl+S in the selector).",
+		},
+		cli.StringSliceFlag{
+			Name:  "select",
+			Usage: "use the hosts matching `expr`: comma separated glob, /regexp/, tag:, group:, provider:, KEY=VALUE or meta.KEY=VALUE terms, negated with !.",
+		},
+		cli.StringFlag{
+			Name:  "hosts-from",
+			Usage: "read host names from `file`, one per line (- is stdin).",
+		},
+		cli.BoolFlag{
+			Name:  "print-selection",
+			Usage: "print the selected host names, one per line, and exit. opens the selector if no host is given.",
+		},
+	}
+}
+
+// GetFlagHosts returns the `-H` hosts followed by the hosts of the `--set`
+// host sets and of the `--hosts-from` file. Host selector expressions are
+// returned as is.
+func GetFlagHosts(c *cli.Context) ([]string, error) {
+	hosts := append([]string(nil), c.StringSlice("host")...)
+	setHosts, err := hoststate.SetHosts(c.StringSlice("set"))
+	if err != nil {
+		return nil, err
+	}
+	hosts = append(hosts, setHosts...)
+
+	if file := c.String("hosts-from"); file != "" {
+		fileHosts, err := ReadHostList(file)
+		if err != nil {
+			return nil, err
+		}
+		hosts = append(hosts, fileHosts...)
+	}
+	return hosts, nil
+}
+
+// ReadHostList reads host names from file (- is stdin). It takes the first
+// field of each line, and skips empty lines and # comments, so the output of
+// `--print-selection` or `lssh --list` style lists can be used as is.
+func ReadHostList(file string) ([]string, error) {
+	var r io.Reader = os.Stdin
+	if file != "-" {
+		f, err := os.Open(GetFullPath(file))
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+	return parseHostList(r)
+}
+
+func parseHostList(r io.Reader) ([]string, error) {
+	hosts := []string{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		hosts = append(hosts, fields[0])
+	}
+	return hosts, scanner.Err()
+}
+
 // enum
 const (
 	ARCHIVE_NONE = iota
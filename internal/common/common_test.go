@@ -9,6 +9,7 @@ import (
 	"compress/gzip"
 	"io"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	sshlib "github.com/blacknon/go-sshlib"
@@ -310,3 +311,10 @@ func TestParseForwardSpec(t *testing.T) {
 		}
 	}
 }
+
+func TestParseHostList(t *testing.T) {
+	input := "web01\n\n# comment\n  db01  10.0.0.5  db server\nweb02\n"
+	got, err := parseHostList(strings.NewReader(input))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"web01", "db01", "web02"}, got)
+}
@@ -23,3 +23,33 @@ func NormalizeGenerateLSSHConfArgs(args []string) []string {
 
 	return result
 }
+
+// NormalizeConfigValidateArgs rewrites the `config validate` subcommand form
+// (ex. `lssh config validate -F file`, `lssh -F file config validate`) to the
+// --check-config flag. Only the config flags may come before the subcommand,
+// since after a host selection `config validate` is a remote command.
+func NormalizeConfigValidateArgs(args []string) []string {
+	i := 1
+flagloop:
+	for i < len(args) {
+		arg := args[i]
+		switch {
+		case arg == "-F" || arg == "--file":
+			i += 2
+		case strings.HasPrefix(arg, "-F=") || strings.HasPrefix(arg, "--file="), arg == "--refresh-inventory":
+			i++
+		default:
+			break flagloop
+		}
+	}
+
+	if i+1 >= len(args) || args[i] != "config" || args[i+1] != "validate" {
+		return args
+	}
+
+	result := make([]string, 0, len(args))
+	result = append(result, args[0], "--check-config")
+	result = append(result, args[1:i]...)
+	result = append(result, args[i+2:]...)
+	return result
+}
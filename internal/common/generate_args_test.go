@@ -41,3 +41,55 @@ func TestNormalizeGenerateLSSHConfArgs(t *testing.T) {
 		})
 	}
 }
+
+func TestNormalizeConfigValidateArgs(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{
+			name: "subcommand",
+			args: []string{"cmd", "config", "validate", "-F", "/tmp/lssh.toml"},
+			want: []string{"cmd", "--check-config", "-F", "/tmp/lssh.toml"},
+		},
+		{
+			name: "subcommand after config flags",
+			args: []string{"cmd", "-F", "path/to/.lssh.toml", "config", "validate"},
+			want: []string{"cmd", "--check-config", "-F", "path/to/.lssh.toml"},
+		},
+		{
+			name: "subcommand after config flags with values",
+			args: []string{"cmd", "--file=path/to/.lssh.toml", "--refresh-inventory", "config", "validate"},
+			want: []string{"cmd", "--check-config", "--file=path/to/.lssh.toml", "--refresh-inventory"},
+		},
+		{
+			name: "missing config path unchanged",
+			args: []string{"cmd", "-F"},
+			want: []string{"cmd", "-F"},
+		},
+		{
+			name: "remote command after options unchanged",
+			args: []string{"cmd", "-H", "app", "config", "validate"},
+			want: []string{"cmd", "-H", "app", "config", "validate"},
+		},
+		{
+			name: "other args unchanged",
+			args: []string{"cmd", "config"},
+			want: []string{"cmd", "config"},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got := NormalizeConfigValidateArgs(tt.args)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("NormalizeConfigValidateArgs() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
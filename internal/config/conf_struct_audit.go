@@ -0,0 +1,70 @@
+// Copyright (c) 2026 Blacknon. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package conf
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SyslogLocal is the AuditConfig.Syslog value of the local syslog daemon.
+const SyslogLocal = "local"
+
+// AuditConfig store the contents about the audit log, a JSON line appended
+// for each shell, command, file transfer and port forward of the lssh
+// commands.
+type AuditConfig struct {
+	// Enable the audit log.
+	Enable bool `toml:"enable" yaml:"enable"`
+
+	// Path of the file the records are appended to.
+	Path string `toml:"path" yaml:"path"`
+
+	// Syslog the records are sent to. "local" for the local syslog daemon,
+	// or `udp://host:port`, `tcp://host:port`, `unix:///path` or
+	// `unixgram:///path`.
+	Syslog string `toml:"syslog" yaml:"syslog"`
+}
+
+// FilePath returns Path with `~` expanded.
+func (a AuditConfig) FilePath() string {
+	if a.Path == "" {
+		return ""
+	}
+	return expandOpenSSHPath(a.Path)
+}
+
+// SyslogAddr returns the network and the address of Syslog for
+// log/syslog.Dial. Both are empty for the local syslog daemon.
+func (a AuditConfig) SyslogAddr() (network, address string, err error) {
+	if a.Syslog == SyslogLocal {
+		return "", "", nil
+	}
+
+	network, address, ok := strings.Cut(a.Syslog, "://")
+	if ok && address != "" {
+		switch network {
+		case "udp", "tcp", "unix", "unixgram":
+			return network, address, nil
+		}
+	}
+	return "", "", fmt.Errorf("invalid syslog %q. [local|udp://host:port|tcp://host:port|unix:///path|unixgram:///path]", a.Syslog)
+}
+
+// Check checks the audit log settings.
+func (a AuditConfig) Check() error {
+	if !a.Enable {
+		return nil
+	}
+	if a.Path == "" && a.Syslog == "" {
+		return fmt.Errorf("audit log is enabled, but neither path nor syslog is set")
+	}
+	if a.Syslog != "" {
+		if _, _, err := a.SyslogAddr(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,242 @@
+// Copyright (c) 2026 Blacknon. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package conf
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ListConfig stores the host selector columns, key bindings and colors.
+type ListConfig struct {
+	// Columns of the selector. The name column is always shown first.
+	// Empty means name, connect (user@addr) and note.
+	Columns []ListColumnConfig `toml:"columns" yaml:"columns"`
+	Keys    ListKeyConfig      `toml:"keys" yaml:"keys"`
+	// GroupBy folds the hosts under collapsible group headers by a
+	// `--list --fields` field (provider, proxy, meta.KEY, tag.KEY...) or
+	// note_prefix (the first word of the note). Empty is not grouped.
+	GroupBy string `toml:"group_by" yaml:"group_by"`
+
+	// Probe checks the reachability of the hosts in the background and
+	// shows it with the round trip time.
+	Probe bool `toml:"probe" yaml:"probe"`
+	// ProbeTimeout is the timeout of a probe (ex. "2s").
+	ProbeTimeout string `toml:"probe_timeout" yaml:"probe_timeout"`
+	// ProbeConcurrency is the number of hosts probed at the same time.
+	ProbeConcurrency int `toml:"probe_concurrency" yaml:"probe_concurrency"`
+
+	HeaderColor     string `toml:"header_color" yaml:"header_color"`
+	PromptColor     string `toml:"prompt_color" yaml:"prompt_color"`
+	CursorColor     string `toml:"cursor_color" yaml:"cursor_color"`
+	CursorBgColor   string `toml:"cursor_bg_color" yaml:"cursor_bg_color"`
+	SelectedColor   string `toml:"selected_color" yaml:"selected_color"`
+	SelectedBgColor string `toml:"selected_bg_color" yaml:"selected_bg_color"`
+	MatchColor      string `toml:"match_color" yaml:"match_color"`
+}
+
+// ListColumnConfig is a column of the host selector.
+type ListColumnConfig struct {
+	// Field is a `--list --fields` field (name, addr, user, note, tags,
+	// meta.KEY, any server key...), tag.KEY, or connect (user@addr).
+	Field string `toml:"field" yaml:"field"`
+	Title string `toml:"title" yaml:"title"`
+	// Width is the fixed width of the column. 0 fits the values.
+	Width int `toml:"width" yaml:"width"`
+	// Align is left (default), right or center.
+	Align string `toml:"align" yaml:"align"`
+}
+
+// ListKeyConfig stores the host selector key bindings. Each action takes
+// a list of keys (ex. ["Up", "Ctrl+K"]).
+type ListKeyConfig struct {
+	Up        []string `toml:"up" yaml:"up"`
+	Down      []string `toml:"down" yaml:"down"`
+	PageUp    []string `toml:"page_up" yaml:"page_up"`
+	PageDown  []string `toml:"page_down" yaml:"page_down"`
+	Top       []string `toml:"top" yaml:"top"`
+	Bottom    []string `toml:"bottom" yaml:"bottom"`
+	Toggle    []string `toml:"toggle" yaml:"toggle"`
+	ToggleAll []string `toml:"toggle_all" yaml:"toggle_all"`
+	Details   []string `toml:"details" yaml:"details"`
+	Favorite  []string `toml:"favorite" yaml:"favorite"`
+	Fold      []string `toml:"fold" yaml:"fold"`
+	Group     []string `toml:"group" yaml:"group"`
+	SaveSet   []string `toml:"save_set" yaml:"save_set"`
+	// HideUnreachable hides the hosts the probe found down.
+	HideUnreachable []string `toml:"hide_unreachable" yaml:"hide_unreachable"`
+	Confirm         []string `toml:"confirm" yaml:"confirm"`
+	Cancel          []string `toml:"cancel" yaml:"cancel"`
+}
+
+// Column alignments of ListColumnConfig.
+const (
+	ListAlignLeft   = "left"
+	ListAlignRight  = "right"
+	ListAlignCenter = "center"
+)
+
+// ListGroupNotePrefix is the group_by value grouping by the first word of
+// the note.
+const ListGroupNotePrefix = "note_prefix"
+
+// ApplyDefaults fills empty key bindings and colors with the defaults.
+func (l ListConfig) ApplyDefaults() ListConfig {
+	defaultKeys := func(keys *[]string, values ...string) {
+		if len(*keys) == 0 {
+			*keys = values
+		}
+	}
+	defaultKeys(&l.Keys.Up, "Up")
+	defaultKeys(&l.Keys.Down, "Down")
+	defaultKeys(&l.Keys.PageUp, "Left", "PgUp")
+	defaultKeys(&l.Keys.PageDown, "Right", "PgDn")
+	defaultKeys(&l.Keys.Top, "Home")
+	defaultKeys(&l.Keys.Bottom, "End")
+	defaultKeys(&l.Keys.Toggle, "Tab")
+	defaultKeys(&l.Keys.ToggleAll, "Ctrl+A")
+	defaultKeys(&l.Keys.Details, "Ctrl+P")
+	defaultKeys(&l.Keys.Favorite, "Ctrl+F")
+	defaultKeys(&l.Keys.Fold, "Ctrl+O")
+	defaultKeys(&l.Keys.Group, "Ctrl+G")
+	defaultKeys(&l.Keys.SaveSet, "Ctrl+S")
+	defaultKeys(&l.Keys.HideUnreachable, "Ctrl+R")
+	defaultKeys(&l.Keys.Confirm, "Enter")
+	defaultKeys(&l.Keys.Cancel, "Esc", "Ctrl+C")
+
+	defaultColor := func(color *string, value string) {
+		if *color == "" {
+			*color = value
+		}
+	}
+	defaultColor(&l.HeaderColor, "yellow")
+	defaultColor(&l.PromptColor, "yellow")
+	defaultColor(&l.CursorColor, "black")
+	defaultColor(&l.CursorBgColor, "green")
+	defaultColor(&l.SelectedColor, "black")
+	defaultColor(&l.SelectedBgColor, "teal")
+	defaultColor(&l.MatchColor, "#ff69b4")
+
+	if l.ProbeTimeout == "" {
+		l.ProbeTimeout = "2s"
+	}
+	if l.ProbeConcurrency == 0 {
+		l.ProbeConcurrency = 16
+	}
+
+	return l
+}
+
+// ListKeyBinding is the keys of a host selector action.
+type ListKeyBinding struct {
+	Action string
+	Keys   []string
+}
+
+// Bindings returns the key bindings of each action, in the order they are
+// matched.
+func (k ListKeyConfig) Bindings() []ListKeyBinding {
+	return []ListKeyBinding{
+		{"cancel", k.Cancel},
+		{"confirm", k.Confirm},
+		{"up", k.Up},
+		{"down", k.Down},
+		{"page_up", k.PageUp},
+		{"page_down", k.PageDown},
+		{"top", k.Top},
+		{"bottom", k.Bottom},
+		{"toggle", k.Toggle},
+		{"toggle_all", k.ToggleAll},
+		{"details", k.Details},
+		{"favorite", k.Favorite},
+		{"fold", k.Fold},
+		{"group", k.Group},
+		{"save_set", k.SaveSet},
+		{"hide_unreachable", k.HideUnreachable},
+	}
+}
+
+// ProbeTimeoutDuration returns the parsed probe_timeout, or 2 seconds if it
+// is not valid.
+func (l ListConfig) ProbeTimeoutDuration() time.Duration {
+	if d, err := time.ParseDuration(l.ProbeTimeout); err == nil && d > 0 {
+		return d
+	}
+	return 2 * time.Second
+}
+
+// CheckListGroupBy returns an error if field can not group the hosts of
+// the selector.
+func (c Config) CheckListGroupBy(field string) error {
+	if field == "" || field == ListGroupNotePrefix {
+		return nil
+	}
+	if err := c.CheckServerListFields([]string{field}); err != nil {
+		return fmt.Errorf("unknown group field %q", field)
+	}
+	return nil
+}
+
+// ListKey is a parsed key binding of the host selector. Name is a named key
+// (up, down, left, right, pgup, pgdn, home, end, tab, backtab, enter, esc,
+// backspace), else Ctrl+Rune or Rune.
+type ListKey struct {
+	Name string
+	Ctrl bool
+	Rune rune
+}
+
+var listKeyNames = map[string]string{
+	"up":        "up",
+	"down":      "down",
+	"left":      "left",
+	"right":     "right",
+	"pgup":      "pgup",
+	"pageup":    "pgup",
+	"pgdn":      "pgdn",
+	"pagedown":  "pgdn",
+	"home":      "home",
+	"end":       "end",
+	"tab":       "tab",
+	"backtab":   "backtab",
+	"enter":     "enter",
+	"esc":       "esc",
+	"escape":    "esc",
+	"backspace": "backspace",
+	"space":     "space",
+}
+
+// ParseListKey parses a key binding of the host selector, such as `Up`,
+// `PgDn`, `Ctrl+N` (or `ctrl-n`) and `j`.
+func ParseListKey(spec string) (ListKey, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return ListKey{}, fmt.Errorf("empty key binding")
+	}
+
+	lower := strings.ToLower(spec)
+	if name, ok := listKeyNames[lower]; ok {
+		if name == "space" {
+			return ListKey{Rune: ' '}, nil
+		}
+		return ListKey{Name: name}, nil
+	}
+
+	for _, prefix := range []string{"ctrl+", "ctrl-"} {
+		if key, ok := strings.CutPrefix(lower, prefix); ok {
+			runes := []rune(key)
+			if len(runes) == 1 && runes[0] >= 'a' && runes[0] <= 'z' {
+				return ListKey{Ctrl: true, Rune: runes[0]}, nil
+			}
+			return ListKey{}, fmt.Errorf("unsupported key binding %q: Ctrl takes a letter", spec)
+		}
+	}
+
+	if runes := []rune(spec); len(runes) == 1 {
+		return ListKey{Rune: runes[0]}, nil
+	}
+	return ListKey{}, fmt.Errorf("unsupported key binding %q", spec)
+}
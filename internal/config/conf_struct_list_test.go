@@ -0,0 +1,81 @@
+// Copyright (c) 2026 Blacknon. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package conf
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseListKey(t *testing.T) {
+	tests := []struct {
+		spec string
+		want ListKey
+	}{
+		{spec: "Up", want: ListKey{Name: "up"}},
+		{spec: "PageDown", want: ListKey{Name: "pgdn"}},
+		{spec: "Ctrl+N", want: ListKey{Ctrl: true, Rune: 'n'}},
+		{spec: "ctrl-p", want: ListKey{Ctrl: true, Rune: 'p'}},
+		{spec: "j", want: ListKey{Rune: 'j'}},
+		{spec: "J", want: ListKey{Rune: 'J'}},
+		{spec: "Space", want: ListKey{Rune: ' '}},
+	}
+	for _, tt := range tests {
+		got, err := ParseListKey(tt.spec)
+		if err != nil {
+			t.Fatalf("ParseListKey(%q) error = %v", tt.spec, err)
+		}
+		if got != tt.want {
+			t.Fatalf("ParseListKey(%q) = %+v, want %+v", tt.spec, got, tt.want)
+		}
+	}
+
+	for _, spec := range []string{"", "Ctrl+1", "F13", "Alt+x"} {
+		if _, err := ParseListKey(spec); err == nil {
+			t.Fatalf("ParseListKey(%q) error = nil", spec)
+		}
+	}
+}
+
+func TestListConfigApplyDefaults(t *testing.T) {
+	l := ListConfig{Keys: ListKeyConfig{Up: []string{"k"}}, CursorBgColor: "blue"}.ApplyDefaults()
+
+	if len(l.Keys.Up) != 1 || l.Keys.Up[0] != "k" {
+		t.Fatalf("Keys.Up = %v, want [k]", l.Keys.Up)
+	}
+	if len(l.Keys.Down) != 1 || l.Keys.Down[0] != "Down" {
+		t.Fatalf("Keys.Down = %v, want [Down]", l.Keys.Down)
+	}
+	if l.CursorBgColor != "blue" || l.CursorColor != "black" {
+		t.Fatalf("cursor colors = %q/%q", l.CursorColor, l.CursorBgColor)
+	}
+}
+
+func TestCheckListGroupBy(t *testing.T) {
+	c := Config{Server: map[string]ServerConfig{"web": {Addr: "192.0.2.10"}}}
+
+	for _, field := range []string{"", "provider", "proxy", "meta.region", "tag.env", ListGroupNotePrefix} {
+		if err := c.CheckListGroupBy(field); err != nil {
+			t.Fatalf("CheckListGroupBy(%q) error = %v", field, err)
+		}
+	}
+	if err := c.CheckListGroupBy("region"); err == nil {
+		t.Fatalf("CheckListGroupBy(%q) error = nil", "region")
+	}
+}
+
+func TestListConfigProbeTimeoutDuration(t *testing.T) {
+	tests := map[string]time.Duration{
+		"":        2 * time.Second,
+		"500ms":   500 * time.Millisecond,
+		"invalid": 2 * time.Second,
+		"-1s":     2 * time.Second,
+	}
+	for value, want := range tests {
+		if got := (ListConfig{ProbeTimeout: value}).ProbeTimeoutDuration(); got != want {
+			t.Fatalf("ProbeTimeoutDuration(%q) = %v, want %v", value, got, want)
+		}
+	}
+}
@@ -4,8 +4,21 @@
 
 package conf
 
+import "fmt"
+
+// formats of the terminal log (LogConfig.Format).
+const (
+	// LogFormatText is the output of the terminal as text.
+	LogFormatText = "text"
+
+	// LogFormatAsciicast is an asciicast v2 recording of the terminal, with
+	// the timing of the output and the changes of the terminal size.
+	LogFormatAsciicast = "asciicast"
+)
+
 // LogConfig store the contents about the terminal log.
 // The log file name is created in "YYYYmmdd_HHMMSS_servername.log" of the specified directory.
+// With the asciicast format, the extension is ".cast".
 type LogConfig struct {
 	// Enable terminal logging.
 	Enable bool `toml:"enable" yaml:"enable"`
@@ -18,4 +31,33 @@ type LogConfig struct {
 
 	// Logging with remove ANSI code.
 	RemoveAnsiCode bool `toml:"remove_ansi_code" yaml:"remove_ansi_code"`
+
+	// Format of the terminal log. "text" (default) or "asciicast".
+	// Timestamp and RemoveAnsiCode are only used by "text".
+	Format string `toml:"format" yaml:"format"`
+
+	// Record the keyboard input in the asciicast recording.
+	RecordInput bool `toml:"record_input" yaml:"record_input"`
+}
+
+// CheckFormat checks the format of the terminal log. Empty is text.
+func (l LogConfig) CheckFormat() error {
+	switch l.Format {
+	case "", LogFormatText, LogFormatAsciicast:
+		return nil
+	}
+	return fmt.Errorf("unknown log format %q. [text|asciicast]", l.Format)
+}
+
+// IsAsciicast returns that the terminal log is an asciicast recording.
+func (l LogConfig) IsAsciicast() bool {
+	return l.Format == LogFormatAsciicast
+}
+
+// FileExt returns the extension of the terminal log files.
+func (l LogConfig) FileExt() string {
+	if l.IsAsciicast() {
+		return ".cast"
+	}
+	return ".log"
 }
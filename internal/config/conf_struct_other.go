@@ -4,23 +4,42 @@
 
 package conf
 
-// IncludeConfig specify the configuration file to include (ServerConfig only).
+// IncludeConfig specify the configuration file to include.
+// Path can be a file, a directory or a glob pattern.
 type IncludeConfig struct {
 	Path string `toml:"path" yaml:"path"`
 }
 
-// IncludesConfig specify the configuration file to include (ServerConfig only).
-// Struct that can specify multiple files in array.
-// TODO: ワイルドカード指定可能にする
+// IncludesConfig specify the configuration file to include.
+// Struct that can specify multiple files, directories or glob patterns in array.
 type IncludesConfig struct {
 	// example:
 	// 	path = [
 	// 		 "~/.lssh.d/home.conf"
 	// 		,"~/.lssh.d/cloud.conf"
+	// 		,"~/.lssh.d/*.toml"
 	// 	]
 	Path []string `toml:"path" yaml:"path"`
 }
 
+// GroupConfig defines a named host group under [group.<name>].
+// Members can be listed by name (glob allowed) or by a selector expression,
+// and are referenced with `group:<name>` in host selectors.
+type GroupConfig struct {
+	// example:
+	// 	servers = ["web-*", "lb01"]
+	Servers []string `toml:"servers" yaml:"servers"`
+
+	// example:
+	// 	selector = "tag:web,env=prod"
+	Selector string `toml:"selector" yaml:"selector"`
+
+	// Tags added to every member of this group.
+	Tags []string `toml:"tags" yaml:"tags"`
+
+	Note string `toml:"note" yaml:"note"`
+}
+
 // OpenSSHConfig is read OpenSSH configuration file.
 type OpenSSHConfig struct {
 	Path    string                            `toml:"path" yaml:"path"` // This is preferred
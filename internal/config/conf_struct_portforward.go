@@ -4,6 +4,13 @@
 
 package conf
 
+import (
+	"errors"
+	"strings"
+
+	"github.com/blacknon/lssh/internal/common"
+)
+
 // PortForward
 type PortForward struct {
 	Mode          string // L or R.
@@ -12,3 +19,30 @@ type PortForward struct {
 	LocalNetwork  string // tcp or unix
 	RemoteNetwork string // tcp or unix
 }
+
+// ParsePortForward parses a `port_forwards` entry.
+//
+// ex.) `local:8080:localhost:80`, `R:localhost:2222:12222`
+func ParsePortForward(spec string) (*PortForward, error) {
+	farray := strings.SplitN(spec, ":", 2)
+	if len(farray) == 1 {
+		return nil, errors.New("mode is not set (local or remote)")
+	}
+
+	fw := new(PortForward)
+	switch strings.ToLower(farray[0]) {
+	case "local", "l":
+		fw.Mode = "L"
+	case "remote", "r":
+		fw.Mode = "R"
+	default:
+		return nil, errors.New("mode must be local or remote")
+	}
+
+	var err error
+	fw.Local, fw.Remote, err = common.ParseForwardPort(farray[1])
+	if err != nil {
+		return nil, err
+	}
+	return fw, nil
+}
@@ -6,6 +6,11 @@ package conf
 
 // ServerConfig Structure for holding SSH connection information
 type ServerConfig struct {
+	// Inherit settings from [template.<name>] blocks.
+	// Later templates override earlier ones, and the server overrides all of them.
+	// ex.) ["bastioned", "prod-keys"]
+	Extends []string `toml:"extends" yaml:"extends"`
+
 	// Connect basic Setting
 	Addr string `toml:"addr" yaml:"addr"`
 	Port string `toml:"port" yaml:"port"`
@@ -45,6 +50,23 @@ type ServerConfig struct {
 	// post execute command
 	PostCmd string `toml:"post_cmd" yaml:"post_cmd"`
 
+	// environment variables set in the remote session.
+	// ex.) {LANG = "C.UTF-8", APP_ENV = "prod"}
+	SetEnv map[string]string `toml:"set_env" yaml:"set_env"`
+
+	// local environment variables sent to the remote session. `*` and `?`
+	// can be used as wildcard.
+	// ex.) ["LANG", "LC_*"]
+	SendEnv []string `toml:"send_env" yaml:"send_env"`
+
+	// export the set_env/send_env variables the server refuses in the
+	// command, instead of dropping them. The remote shell has to be POSIX
+	// compatible.
+	SetEnvExport bool `toml:"set_env_export" yaml:"set_env_export"`
+
+	// command run on the remote host instead of the login shell.
+	RemoteCommand string `toml:"remote_command" yaml:"remote_command"`
+
 	// proxy setting
 	ProxyType string `toml:"proxy_type" yaml:"proxy_type"`
 
@@ -153,6 +175,14 @@ type ServerConfig struct {
 	// Check KnownHosts File
 	KnownHostsFiles []string `toml:"known_hosts_files" yaml:"known_hosts_files"`
 
+	// Host key checking policy. yes, accept-new, no or ask.
+	// If not set, ask when check_known_hosts is true, no otherwise.
+	StrictHostKeyChecking string `toml:"strict_host_key_checking" yaml:"strict_host_key_checking"`
+
+	// Pinned host key fingerprints.
+	// ex.) ["SHA256:+DiY3wvvV6TuJJhbpZisF/zLDA0zPMSvHdkr4UvCOqU"]
+	HostKeyFingerprints []string `toml:"host_key_fingerprints" yaml:"host_key_fingerprints"`
+
 	// OpenSSH ControlMaster settings
 	ControlMaster  bool                   `toml:"control_master" yaml:"control_master"`
 	ControlPath    string                 `toml:"control_path" yaml:"control_path"`
@@ -161,6 +191,10 @@ type ServerConfig struct {
 	// note
 	Note string `toml:"note" yaml:"note"`
 
+	// tags used by host selector expressions.
+	// ex.) ["web", "env=prod", "dc=tokyo"]
+	Tags []string `toml:"tags" yaml:"tags"`
+
 	// ignore this server from selection / execution targets
 	Ignore bool `toml:"ignore" yaml:"ignore"`
 
@@ -196,6 +230,26 @@ type ServerMatchWhen struct {
 	EnvNotIn      []string `toml:"env_not_in" yaml:"env_not_in"`
 	EnvValueIn    []string `toml:"env_value_in" yaml:"env_value_in"`
 	EnvValueNotIn []string `toml:"env_value_not_in" yaml:"env_value_not_in"`
+
+	// TCP endpoints (`host:port`) that accept a connection within a short timeout.
+	ReachableIn    []string `toml:"reachable_in" yaml:"reachable_in"`
+	ReachableNotIn []string `toml:"reachable_not_in" yaml:"reachable_not_in"`
+
+	// DNS search domains and nameservers (IP/CIDR) from resolv.conf.
+	DNSSearchIn     []string `toml:"dns_search_in" yaml:"dns_search_in"`
+	DNSSearchNotIn  []string `toml:"dns_search_not_in" yaml:"dns_search_not_in"`
+	NameserverIn    []string `toml:"nameserver_in" yaml:"nameserver_in"`
+	NameserverNotIn []string `toml:"nameserver_not_in" yaml:"nameserver_not_in"`
+
+	// Local time ranges (`09:00-18:00`) and weekdays (`mon`, `mon-fri`).
+	TimeIn       []string `toml:"time_in" yaml:"time_in"`
+	TimeNotIn    []string `toml:"time_not_in" yaml:"time_not_in"`
+	WeekdayIn    []string `toml:"weekday_in" yaml:"weekday_in"`
+	WeekdayNotIn []string `toml:"weekday_not_in" yaml:"weekday_not_in"`
+
+	// Local commands that exit with status 0, like OpenSSH `Match exec`.
+	ExecIn    []string `toml:"exec_in" yaml:"exec_in"`
+	ExecNotIn []string `toml:"exec_not_in" yaml:"exec_not_in"`
 }
 
 // Empty reports whether no match conditions are defined.
@@ -215,7 +269,19 @@ func (w ServerMatchWhen) Empty() bool {
 		len(w.EnvIn) == 0 &&
 		len(w.EnvNotIn) == 0 &&
 		len(w.EnvValueIn) == 0 &&
-		len(w.EnvValueNotIn) == 0
+		len(w.EnvValueNotIn) == 0 &&
+		len(w.ReachableIn) == 0 &&
+		len(w.ReachableNotIn) == 0 &&
+		len(w.DNSSearchIn) == 0 &&
+		len(w.DNSSearchNotIn) == 0 &&
+		len(w.NameserverIn) == 0 &&
+		len(w.NameserverNotIn) == 0 &&
+		len(w.TimeIn) == 0 &&
+		len(w.TimeNotIn) == 0 &&
+		len(w.WeekdayIn) == 0 &&
+		len(w.WeekdayNotIn) == 0 &&
+		len(w.ExecIn) == 0 &&
+		len(w.ExecNotIn) == 0
 }
 
 // ServerMatchConfig stores a single conditional override branch.
@@ -254,11 +320,15 @@ type ServerMatchConfig struct {
 	PKCS11PIN         string   `toml:"pkcs11pin" yaml:"pkcs11pin"`
 	PKCS11PINRef      string   `toml:"pkcs11pin_ref" yaml:"pkcs11pin_ref"`
 
-	PreCmd       string `toml:"pre_cmd" yaml:"pre_cmd"`
-	PostCmd      string `toml:"post_cmd" yaml:"post_cmd"`
-	ProxyType    string `toml:"proxy_type" yaml:"proxy_type"`
-	Proxy        string `toml:"proxy" yaml:"proxy"`
-	ProxyCommand string `toml:"proxy_cmd" yaml:"proxy_cmd"`
+	PreCmd        string            `toml:"pre_cmd" yaml:"pre_cmd"`
+	PostCmd       string            `toml:"post_cmd" yaml:"post_cmd"`
+	SetEnv        map[string]string `toml:"set_env" yaml:"set_env"`
+	SendEnv       []string          `toml:"send_env" yaml:"send_env"`
+	SetEnvExport  bool              `toml:"set_env_export" yaml:"set_env_export"`
+	RemoteCommand string            `toml:"remote_command" yaml:"remote_command"`
+	ProxyType     string            `toml:"proxy_type" yaml:"proxy_type"`
+	Proxy         string            `toml:"proxy" yaml:"proxy"`
+	ProxyCommand  string            `toml:"proxy_cmd" yaml:"proxy_cmd"`
 
 	LocalRcUse           string   `toml:"local_rc" yaml:"local_rc"`
 	LocalRcPath          []string `toml:"local_rc_file" yaml:"local_rc_file"`
@@ -292,10 +362,13 @@ type ServerMatchConfig struct {
 	ServerAliveCountInterval int                    `toml:"alive_interval" yaml:"alive_interval"`
 	CheckKnownHosts          bool                   `toml:"check_known_hosts" yaml:"check_known_hosts"`
 	KnownHostsFiles          []string               `toml:"known_hosts_files" yaml:"known_hosts_files"`
+	StrictHostKeyChecking    string                 `toml:"strict_host_key_checking" yaml:"strict_host_key_checking"`
+	HostKeyFingerprints      []string               `toml:"host_key_fingerprints" yaml:"host_key_fingerprints"`
 	ControlMaster            bool                   `toml:"control_master" yaml:"control_master"`
 	ControlPath              string                 `toml:"control_path" yaml:"control_path"`
 	ControlPersist           ControlPersistDuration `toml:"control_persist" yaml:"control_persist"`
 	Note                     string                 `toml:"note" yaml:"note"`
+	Tags                     []string               `toml:"tags" yaml:"tags"`
 	Ignore                   bool                   `toml:"ignore" yaml:"ignore"`
 
 	Priority int             `toml:"priority" yaml:"priority"`
@@ -348,6 +421,10 @@ func (m ServerMatchConfig) OverrideConfig() ServerConfig {
 		PKCS11PINRef:                  m.PKCS11PINRef,
 		PreCmd:                        m.PreCmd,
 		PostCmd:                       m.PostCmd,
+		SetEnv:                        m.SetEnv,
+		SendEnv:                       m.SendEnv,
+		SetEnvExport:                  m.SetEnvExport,
+		RemoteCommand:                 m.RemoteCommand,
 		ProxyType:                     m.ProxyType,
 		Proxy:                         m.Proxy,
 		ProxyCommand:                  m.ProxyCommand,
@@ -380,10 +457,13 @@ func (m ServerMatchConfig) OverrideConfig() ServerConfig {
 		ServerAliveCountInterval:      m.ServerAliveCountInterval,
 		CheckKnownHosts:               m.CheckKnownHosts,
 		KnownHostsFiles:               m.KnownHostsFiles,
+		StrictHostKeyChecking:         m.StrictHostKeyChecking,
+		HostKeyFingerprints:           m.HostKeyFingerprints,
 		ControlMaster:                 m.ControlMaster,
 		ControlPath:                   m.ControlPath,
 		ControlPersist:                m.ControlPersist,
 		Note:                          m.Note,
+		Tags:                          m.Tags,
 		Ignore:                        m.Ignore,
 	}
 }
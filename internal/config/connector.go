@@ -91,6 +91,35 @@ func (c *Config) DescribeConnector(server string) (providerapi.ConnectorDescribe
 	return result, nil
 }
 
+// CheckConnectorHealth calls `health.check` of the connector provider of
+// server.
+func (c *Config) CheckConnectorHealth(server string) (providerapi.HealthCheckResult, error) {
+	serverConfig, ok := c.Server[server]
+	if !ok {
+		return providerapi.HealthCheckResult{}, fmt.Errorf("server %q is not configured", server)
+	}
+
+	connectorName := strings.TrimSpace(c.serverConnectorName(serverConfig))
+	if connectorName == "" || connectorName == "ssh" {
+		return providerapi.HealthCheckResult{}, fmt.Errorf("server %q does not use an external connector", server)
+	}
+
+	providerName, raw, err := c.resolveConnectorProvider(serverConfig, connectorName)
+	if err != nil {
+		return providerapi.HealthCheckResult{}, err
+	}
+
+	var result providerapi.HealthCheckResult
+	if err := c.callProvider(providerName, providerapi.MethodHealthCheck, providerapi.HealthCheckParams{
+		Provider: providerName,
+		Config:   raw,
+	}, &result); err != nil {
+		return providerapi.HealthCheckResult{}, err
+	}
+
+	return result, nil
+}
+
 func (c *Config) ServerSupportsOperation(server string, operation string) (bool, error) {
 	if !c.ServerUsesConnector(server) {
 		return true, nil
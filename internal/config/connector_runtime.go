@@ -48,10 +48,14 @@ func (c *Config) PrepareConnectorRuntime(server string, operation ConnectorOpera
 		return PreparedConnector{}, fmt.Errorf("server %q does not use an external connector", server)
 	}
 
+	// set_env/send_env of the server are passed to the connector, and the
+	// operation env overrides them.
+	env := mergeSetEnv(serverConfig.RemoteEnv(), operation.Env)
+
 	prepared, err := c.PrepareConnector(server, providerapi.ConnectorOperation{
 		Name:    operation.Name,
 		Command: append([]string(nil), operation.Command...),
-		Env:     cloneStringMap(operation.Env),
+		Env:     env,
 		Cwd:     operation.Cwd,
 		PTY:     operation.PTY,
 		Options: cloneInterfaceMap(operation.Options),
@@ -0,0 +1,130 @@
+// Copyright (c) 2026 Blacknon. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package conf
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// FieldSources returns, for each config key of the server name, the layer
+// that set its effective value (ex. `server.web (~/.lssh.toml)`,
+// `template.base`, `common (~/.lssh.toml)`, `default`,
+// `provider.aws`, `server.web.match.vpn`).
+func (c Config) FieldSources(name string) map[string]string {
+	sources := c.fieldSources[name]
+	result := make(map[string]string, len(sources))
+	for key, source := range sources {
+		result[key] = source
+	}
+	return result
+}
+
+// recordFieldSources records source(key) as the origin of each key whose
+// value differs between before and after.
+func (c *Config) recordFieldSources(name string, before, after ServerConfig, source func(key string) string) {
+	beforeMap := serverConfigToTOMLMap(before)
+	afterMap := serverConfigToTOMLMap(after)
+
+	for key, value := range afterMap {
+		if reflect.DeepEqual(beforeMap[key], value) {
+			continue
+		}
+		c.setFieldSource(name, key, source(key))
+	}
+	for key := range beforeMap {
+		if _, ok := afterMap[key]; !ok {
+			c.setFieldSource(name, key, source(key))
+		}
+	}
+}
+
+// MatchedBranches returns the `match` branches applied to the server name,
+// in the order they were applied (ex. `server.web.match.vpn`,
+// `provider.aws.match.windows`, `sshconfig.match.corp`).
+func (c Config) MatchedBranches(name string) []string {
+	return append([]string(nil), c.matchedBranches[name]...)
+}
+
+func (c *Config) addMatchedBranch(name, branch string) {
+	if c.matchedBranches == nil {
+		c.matchedBranches = map[string][]string{}
+	}
+	c.matchedBranches[name] = append(c.matchedBranches[name], branch)
+}
+
+// resetFieldSources forgets the sources and matched branches of name, when
+// it is defined again.
+func (c *Config) resetFieldSources(name string) {
+	delete(c.fieldSources, name)
+	delete(c.matchedBranches, name)
+}
+
+func (c *Config) setFieldSource(name, key, source string) {
+	if c.fieldSources == nil {
+		c.fieldSources = map[string]map[string]string{}
+	}
+	if c.fieldSources[name] == nil {
+		c.fieldSources[name] = map[string]string{}
+	}
+	c.fieldSources[name][key] = source
+}
+
+// fixedFieldSource returns a source func that labels every key with label.
+func fixedFieldSource(label string) func(string) string {
+	return func(string) string { return label }
+}
+
+// fileFieldSource returns the label of a layer read from path.
+func fileFieldSource(layer, path string) string {
+	if path == "" {
+		return layer
+	}
+	return fmt.Sprintf("%s (%s)", layer, path)
+}
+
+// commonFieldSources labels the keys set in common with label, on top of
+// parent. It is used to tell user [common] values from lssh defaults.
+func commonFieldSources(common ServerConfig, label string, parent map[string]string) map[string]string {
+	result := make(map[string]string, len(parent))
+	for key, source := range parent {
+		result[key] = source
+	}
+	for key := range serverConfigToTOMLMap(common) {
+		result[key] = label
+	}
+	return result
+}
+
+// commonFieldSource returns the source func of a [common] layer.
+func commonFieldSource(sources map[string]string) func(string) string {
+	return func(key string) string {
+		if source, ok := sources[key]; ok {
+			return source
+		}
+		return "common"
+	}
+}
+
+// templateFieldSource labels each key with the last template in extends
+// that sets it.
+func (c *Config) templateFieldSource(extends []string) func(string) string {
+	resolver := newTemplateResolver(c.Template)
+	keys := make([]map[string]interface{}, len(extends))
+	for i, name := range extends {
+		if tmpl, err := resolver.resolve(name, []string{"extends"}); err == nil {
+			keys[i] = serverConfigToTOMLMap(tmpl)
+		}
+	}
+
+	return func(key string) string {
+		for i := len(extends) - 1; i >= 0; i-- {
+			if _, ok := keys[i][key]; ok {
+				return "template." + extends[i]
+			}
+		}
+		return "template"
+	}
+}
@@ -0,0 +1,369 @@
+// Copyright (c) 2026 Blacknon. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package conf
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// openSSHHostAliasInvalidChars matches characters that can not be used in an
+// OpenSSH `Host` alias (whitespace, pattern characters and the like).
+var openSSHHostAliasInvalidChars = regexp.MustCompile(`[^A-Za-z0-9._@+-]`)
+
+// openSSHHostAlias returns the `Host` alias used for server name.
+func openSSHHostAlias(name string) string {
+	return openSSHHostAliasInvalidChars.ReplaceAllString(name, "_")
+}
+
+// GenerateOpenSSHConfig renders the effective lssh servers (after includes,
+// providers and `match` branches are applied) as an OpenSSH ssh_config file.
+//
+// Settings that OpenSSH can not express are written as comments. Servers
+// imported from OpenSSH config are skipped, because they already exist there.
+// SSH proxy hosts used by names are exported too.
+func GenerateOpenSSHConfig(c Config, names []string) []byte {
+	targets := map[string]struct{}{}
+	for _, name := range names {
+		for name != "" {
+			if _, ok := targets[name]; ok {
+				break
+			}
+			server, ok := c.Server[name]
+			if !ok {
+				break
+			}
+			targets[name] = struct{}{}
+
+			// follow ssh proxy hops
+			if server.ProxyCommand != "" && server.ProxyCommand != "none" {
+				break
+			}
+			switch server.ProxyType {
+			case "", "ssh":
+				name = server.Proxy
+			default:
+				name = ""
+			}
+		}
+	}
+
+	sorted := make([]string, 0, len(targets))
+	for name := range targets {
+		if c.isOpenSSHImportedServer(name) {
+			continue
+		}
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	// `${secret:...}` references are kept, and written as comments.
+	c, errs := c.InterpolateServersWithoutSecrets(sorted)
+
+	buf := &bytes.Buffer{}
+	buf.WriteString("# Generated by lssh --generate-ssh-config\n")
+	buf.WriteString("# Review and adjust values before long-term use.\n")
+
+	for _, name := range sorted {
+		buf.WriteByte('\n')
+		if err := errs[name]; err != nil {
+			fmt.Fprintf(buf, "Host %s\n    # not exported: %v\n", openSSHHostAlias(name), err)
+			continue
+		}
+		c.writeOpenSSHHost(buf, name)
+	}
+
+	return buf.Bytes()
+}
+
+// isOpenSSHImportedServer reports whether name was imported from an OpenSSH
+// config file (named `<path>:<host>`).
+func (c Config) isOpenSSHImportedServer(name string) bool {
+	origin := c.ServerOrigin(name)
+	return origin != "" && strings.HasPrefix(name, origin+":")
+}
+
+func (c Config) writeOpenSSHHost(buf *bytes.Buffer, name string) {
+	server := c.Server[name]
+	alias := openSSHHostAlias(name)
+
+	comment := func(format string, args ...interface{}) {
+		fmt.Fprintf(buf, "    # "+format+"\n", args...)
+	}
+	// command writes values as they are, for settings that OpenSSH passes
+	// to a shell. option quotes each value that ssh_config would split.
+	command := func(key string, values ...string) {
+		for _, value := range values {
+			if strings.Contains(value, "${secret:") {
+				comment("%s uses a ${secret:...} reference and is not exported", key)
+				return
+			}
+		}
+		fmt.Fprintf(buf, "    %s %s\n", key, strings.Join(values, " "))
+	}
+	option := func(key string, values ...string) {
+		quoted := make([]string, 0, len(values))
+		for _, value := range values {
+			quoted = append(quoted, openSSHQuoteArg(value))
+		}
+		command(key, quoted...)
+	}
+
+	fmt.Fprintf(buf, "Host %s\n", alias)
+	if alias != name {
+		comment("lssh name: %q", name)
+	}
+	if origin := c.ServerOrigin(name); origin != "" {
+		comment("from: %s", origin)
+	}
+	if server.Note != "" {
+		comment("note: %s", server.Note)
+	}
+	if len(server.Tags) > 0 {
+		comment("tags: %s", strings.Join(server.Tags, ", "))
+	}
+
+	if c.ServerUsesConnector(name) {
+		comment("connector %q is not expressible in ssh_config", c.ServerConnectorName(name))
+		return
+	}
+
+	if server.Addr != "" {
+		option("HostName", server.Addr)
+	}
+	if server.User != "" {
+		option("User", server.User)
+	}
+	if server.Port != "" {
+		option("Port", server.Port)
+	}
+
+	// authentication
+	identityFiles := []string{}
+	if server.Key != "" {
+		identityFiles = append(identityFiles, server.Key)
+	}
+	for _, key := range server.Keys {
+		identityFiles = append(identityFiles, strings.SplitN(key, "::", 2)[0])
+	}
+	if server.CertKey != "" {
+		identityFiles = append(identityFiles, server.CertKey)
+	}
+	for _, cert := range server.Certs {
+		if parts := strings.SplitN(cert, "::", 3); len(parts) > 1 && parts[1] != "" {
+			identityFiles = append(identityFiles, parts[1])
+		}
+	}
+	for _, identityFile := range identityFiles {
+		option("IdentityFile", identityFile)
+	}
+	if server.Cert != "" {
+		option("CertificateFile", server.Cert)
+	}
+	for _, cert := range server.Certs {
+		option("CertificateFile", strings.SplitN(cert, "::", 2)[0])
+	}
+	if server.PKCS11Use && server.PKCS11Provider != "" {
+		option("PKCS11Provider", server.PKCS11Provider)
+	}
+	if server.Pass != "" || server.PassRef != "" || len(server.Passes) > 0 {
+		comment("password authentication is not exported")
+	}
+	if server.KeyPass != "" || server.KeyPassRef != "" || server.CertKeyPass != "" || server.CertKeyPassRef != "" {
+		comment("key passphrases are not exported; use ssh-agent")
+	}
+	if server.KeyRef != "" || server.CertRef != "" || server.CertKeyRef != "" {
+		comment("keys from secret providers are not exported")
+	}
+	if server.KeyCommand != "" {
+		comment("keycmd is not expressible in ssh_config")
+	}
+
+	// proxy
+	c.writeOpenSSHProxy(server, option, command, comment)
+
+	// port forwarding
+	for _, fw := range serverPortForwards(server) {
+		switch fw.Mode {
+		case "R":
+			option("RemoteForward", fw.Remote, fw.Local)
+		default:
+			option("LocalForward", fw.Local, fw.Remote)
+		}
+	}
+	for _, spec := range server.PortForwards {
+		if _, err := ParsePortForward(spec); err != nil {
+			comment("invalid port forward %q is not exported", spec)
+		}
+	}
+	if server.DynamicPortForward != "" {
+		option("DynamicForward", server.DynamicPortForward)
+	}
+	if server.ReverseDynamicPortForward != "" {
+		option("RemoteForward", server.ReverseDynamicPortForward)
+	}
+	if server.HTTPDynamicPortForward != "" || server.HTTPReverseDynamicPortForward != "" {
+		comment("http dynamic port forward is not expressible in ssh_config")
+	}
+	if server.NFSDynamicForwardPort != "" || server.NFSReverseDynamicForwardPort != "" {
+		comment("nfs dynamic forward is not expressible in ssh_config")
+	}
+	if server.SMBDynamicForwardPort != "" || server.SMBReverseDynamicForwardPort != "" {
+		comment("smb dynamic forward is not expressible in ssh_config")
+	}
+
+	// other options
+	if server.X11 || server.X11Trusted {
+		option("ForwardX11", "yes")
+	}
+	if server.X11Trusted {
+		option("ForwardX11Trusted", "yes")
+	}
+	if server.ConnectTimeout > 0 {
+		option("ConnectTimeout", fmt.Sprint(server.ConnectTimeout))
+	}
+	if server.ServerAliveCountInterval > 0 {
+		option("ServerAliveInterval", fmt.Sprint(server.ServerAliveCountInterval))
+	}
+	if server.ServerAliveCountMax > 0 {
+		option("ServerAliveCountMax", fmt.Sprint(server.ServerAliveCountMax))
+	}
+	if policy, err := server.HostKeyChecking(); err == nil && (policy != HostKeyCheckingNo || server.StrictHostKeyChecking != "") {
+		option("StrictHostKeyChecking", policy)
+		if len(server.KnownHostsFiles) > 0 {
+			option("UserKnownHostsFile", server.KnownHostsFiles...)
+		}
+	}
+	if len(server.HostKeyFingerprints) > 0 {
+		comment("host_key_fingerprints are not expressible in ssh_config")
+	}
+	if server.ControlMaster {
+		option("ControlMaster", "auto")
+		if server.ControlPath != "" {
+			option("ControlPath", server.ControlPath)
+		}
+		if server.ControlPersist > 0 {
+			option("ControlPersist", fmt.Sprint(int64(server.ControlPersist)))
+		}
+	}
+	if len(server.SetEnv) > 0 {
+		names := make([]string, 0, len(server.SetEnv))
+		for name := range server.SetEnv {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		items := make([]string, 0, len(names))
+		for _, name := range names {
+			items = append(items, name+"="+server.SetEnv[name])
+		}
+		option("SetEnv", items...)
+	}
+	if len(server.SendEnv) > 0 {
+		option("SendEnv", server.SendEnv...)
+	}
+	if server.RemoteCommand != "" {
+		command("RemoteCommand", server.RemoteCommand)
+	}
+	if server.PreCmd != "" || server.PostCmd != "" {
+		comment("pre_cmd/post_cmd are not expressible in ssh_config")
+	}
+	if server.LocalRcUse == "yes" {
+		comment("local_rc is not expressible in ssh_config")
+	}
+}
+
+func (c Config) writeOpenSSHProxy(server ServerConfig, option, command func(string, ...string), comment func(string, ...interface{})) {
+	if server.ProxyCommand != "" && server.ProxyCommand != "none" {
+		command("ProxyCommand", server.ProxyCommand)
+		return
+	}
+	if server.Proxy == "" {
+		return
+	}
+
+	switch server.ProxyType {
+	case "http", "https", "socks", "socks5":
+		proxy, ok := c.Proxy[server.Proxy]
+		if !ok {
+			comment("proxy %q is not defined", server.Proxy)
+			return
+		}
+
+		addr := proxy.Addr
+		if proxy.Port != "" {
+			addr += ":" + proxy.Port
+		}
+		version := "connect"
+		if strings.HasPrefix(server.ProxyType, "socks") {
+			version = "5"
+		}
+		option("ProxyCommand", "nc", "-X", version, "-x", addr, "%h", "%p")
+
+		if proxy.User != "" || proxy.Pass != "" {
+			comment("proxy authentication for %q is not exported", server.Proxy)
+		}
+		if proxy.Proxy != "" {
+			comment("proxy %q is reached through %q, which is not expressible in ssh_config", server.Proxy, proxy.Proxy)
+		}
+
+	default:
+		if _, ok := c.Server[server.Proxy]; !ok {
+			comment("proxy %q is not defined", server.Proxy)
+			return
+		}
+		option("ProxyJump", c.openSSHProxyJumpHost(server.Proxy))
+	}
+}
+
+// openSSHProxyJumpHost returns the host name that ProxyJump uses for the ssh
+// proxy name. Servers imported from OpenSSH config are not exported, so they
+// are referenced by their original `Host` name.
+func (c Config) openSSHProxyJumpHost(name string) string {
+	if c.isOpenSSHImportedServer(name) {
+		return strings.TrimPrefix(name, c.ServerOrigin(name)+":")
+	}
+	return openSSHHostAlias(name)
+}
+
+// serverPortForwards returns the port forwards configured for server, in the
+// same order lssh starts them.
+func serverPortForwards(server ServerConfig) []*PortForward {
+	forwards := []*PortForward{}
+
+	// single port forward settings (Backward compatibility).
+	if server.PortForwardLocal != "" && server.PortForwardRemote != "" {
+		mode := "L"
+		if strings.EqualFold(server.PortForwardMode, "R") || strings.EqualFold(server.PortForwardMode, "remote") {
+			mode = "R"
+		}
+		forwards = append(forwards, &PortForward{
+			Mode:   mode,
+			Local:  server.PortForwardLocal,
+			Remote: server.PortForwardRemote,
+		})
+	}
+
+	for _, spec := range server.PortForwards {
+		fw, err := ParsePortForward(spec)
+		if err != nil {
+			continue
+		}
+		forwards = append(forwards, fw)
+	}
+
+	return forwards
+}
+
+// openSSHQuoteArg double-quotes value if ssh_config would split it.
+func openSSHQuoteArg(value string) string {
+	if value != "" && !strings.ContainsAny(value, " \t\"'\\") {
+		return value
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+}
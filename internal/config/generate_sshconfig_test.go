@@ -0,0 +1,155 @@
+package conf
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGenerateOpenSSHConfig(t *testing.T) {
+	cfg := Config{
+		Server: map[string]ServerConfig{
+			"bastion": {Addr: "192.0.2.1", User: "ops", Key: "~/.ssh/bastion"},
+			"web 01": {
+				Addr:               "192.0.2.10",
+				User:               "deploy",
+				Port:               "2222",
+				Keys:               []string{"~/.ssh/web::secret"},
+				Proxy:              "bastion",
+				PortForwards:       []string{"local:8080:localhost:80", "remote:localhost:9000:19000"},
+				DynamicPortForward: "11080",
+				Pass:               "secret",
+				PreCmd:             "echo hi",
+				SetEnv:             map[string]string{"APP_ENV": "prod", "GREETING": "hello world"},
+				SendEnv:            []string{"LANG", "LC_*"},
+				RemoteCommand:      "tmux new -A -s main",
+				Note:               "web server",
+			},
+			"socks": {Addr: "192.0.2.20", User: "demo", Proxy: "corp", ProxyType: "socks5"},
+			"cmd": {
+				Addr:                  "192.0.2.30",
+				User:                  "demo",
+				ProxyCommand:          "ssh -W %h:%p jump",
+				StrictHostKeyChecking: "accept-new",
+				HostKeyFingerprints:   []string{"SHA256:+DiY3wvvV6TuJJhbpZisF/zLDA0zPMSvHdkr4UvCOqU"},
+			},
+			"ssm": {Addr: "i-0123", ConnectorName: "aws-ssm"},
+		},
+		Proxy: map[string]ProxyConfig{
+			"corp": {Addr: "proxy.example.com", Port: "1080"},
+		},
+	}
+	cfg.addServerSource("/home/demo/.ssh/config:imported", "/home/demo/.ssh/config")
+	cfg.Server["/home/demo/.ssh/config:imported"] = ServerConfig{Addr: "192.0.2.40"}
+
+	got := string(GenerateOpenSSHConfig(cfg, []string{"web 01", "socks", "cmd", "ssm", "/home/demo/.ssh/config:imported"}))
+
+	for _, want := range []string{
+		"Host bastion\n    HostName 192.0.2.1\n    User ops\n    IdentityFile ~/.ssh/bastion\n",
+		"Host web_01\n    # lssh name: \"web 01\"\n    # note: web server\n",
+		"    Port 2222\n",
+		"    IdentityFile ~/.ssh/web\n",
+		"    ProxyJump bastion\n",
+		"    LocalForward localhost:8080 localhost:80\n",
+		"    RemoteForward localhost:19000 localhost:9000\n",
+		"    DynamicForward 11080\n",
+		"    SetEnv APP_ENV=prod \"GREETING=hello world\"\n",
+		"    SendEnv LANG LC_*\n",
+		"    RemoteCommand tmux new -A -s main\n",
+		"    # password authentication is not exported\n",
+		"    # pre_cmd/post_cmd are not expressible in ssh_config\n",
+		"    ProxyCommand nc -X 5 -x proxy.example.com:1080 %h %p\n",
+		"    ProxyCommand ssh -W %h:%p jump\n",
+		"    StrictHostKeyChecking accept-new\n",
+		"    # host_key_fingerprints are not expressible in ssh_config\n",
+		"Host ssm\n    # connector \"aws-ssm\" is not expressible in ssh_config\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("output does not contain %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "secret") {
+		t.Fatalf("output leaks a secret:\n%s", got)
+	}
+	if strings.Contains(got, "imported") {
+		t.Fatalf("servers imported from OpenSSH config should be skipped:\n%s", got)
+	}
+}
+
+func TestGenerateOpenSSHConfigInterpolate(t *testing.T) {
+	t.Setenv("WEB02_IP", "192.0.2.50")
+	cfg := Config{
+		Server: map[string]ServerConfig{
+			"web02":   {Addr: "${env:WEB02_IP}", User: "deploy", Note: "${server.name} (${env:WEB02_IP})"},
+			"secret":  {Addr: "${secret:vault:hosts/db#addr}", User: "demo"},
+			"missing": {Addr: "${env:LSSH_TEST_UNSET_ADDR}", User: "demo"},
+		},
+	}
+
+	got := string(GenerateOpenSSHConfig(cfg, []string{"web02", "secret", "missing"}))
+
+	for _, want := range []string{
+		"Host web02\n    # note: web02 (192.0.2.50)\n    HostName 192.0.2.50\n    User deploy\n",
+		"Host secret\n    # HostName uses a ${secret:...} reference and is not exported\n    User demo\n",
+		"Host missing\n    # not exported: ",
+	} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("generated config missing %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "HostName ${") {
+		t.Fatalf("generated config has a raw HostName reference:\n%s", got)
+	}
+	if _, ok := cfg.interpolated["web02"]; ok {
+		t.Fatalf("GenerateOpenSSHConfig marked web02 as interpolated")
+	}
+}
+
+func TestGenerateOpenSSHConfigQuotesValues(t *testing.T) {
+	cfg := Config{
+		Server: map[string]ServerConfig{
+			"web": {
+				Addr:                  "192.0.2.10",
+				Key:                   "/home/demo/My Keys/id_ed25519",
+				Cert:                  "/home/demo/My Keys/id_ed25519-cert.pub",
+				StrictHostKeyChecking: "yes",
+				KnownHostsFiles:       []string{"/home/demo/known hosts", "~/.ssh/known_hosts"},
+				ControlMaster:         true,
+				ControlPath:           "/tmp/lssh control/%r@%h:%p",
+				ProxyCommand:          "ssh -W %h:%p \"jump host\"",
+			},
+		},
+	}
+
+	got := string(GenerateOpenSSHConfig(cfg, []string{"web"}))
+
+	for _, want := range []string{
+		"    IdentityFile \"/home/demo/My Keys/id_ed25519\"\n",
+		"    CertificateFile \"/home/demo/My Keys/id_ed25519-cert.pub\"\n",
+		"    UserKnownHostsFile \"/home/demo/known hosts\" ~/.ssh/known_hosts\n",
+		"    ControlPath \"/tmp/lssh control/%r@%h:%p\"\n",
+		"    ProxyCommand ssh -W %h:%p \"jump host\"\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("output does not contain %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestGenerateOpenSSHConfigImportedProxy(t *testing.T) {
+	cfg := Config{
+		Server: map[string]ServerConfig{
+			"web": {Addr: "192.0.2.10", Proxy: "/home/demo/.ssh/config:jump.example.com"},
+		},
+	}
+	cfg.addServerSource("/home/demo/.ssh/config:jump.example.com", "/home/demo/.ssh/config")
+	cfg.Server["/home/demo/.ssh/config:jump.example.com"] = ServerConfig{Addr: "192.0.2.1"}
+
+	got := string(GenerateOpenSSHConfig(cfg, []string{"web"}))
+
+	if !strings.Contains(got, "    ProxyJump jump.example.com\n") {
+		t.Fatalf("output does not jump through the OpenSSH host name:\n%s", got)
+	}
+	if strings.Contains(got, "Host _home") || strings.Contains(got, "ProxyJump _home") {
+		t.Fatalf("imported proxy should not be exported or aliased:\n%s", got)
+	}
+}
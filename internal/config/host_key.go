@@ -0,0 +1,51 @@
+// Copyright (c) 2026 Blacknon. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package conf
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+// strict_host_key_checking policies.
+const (
+	HostKeyCheckingYes       = "yes"
+	HostKeyCheckingAcceptNew = "accept-new"
+	HostKeyCheckingNo        = "no"
+	HostKeyCheckingAsk       = "ask"
+)
+
+// HostKeyChecking returns the strict_host_key_checking policy of s. If it is
+// not set, it is ask when check_known_hosts is true and no otherwise.
+func (s ServerConfig) HostKeyChecking() (string, error) {
+	policy := strings.ToLower(strings.TrimSpace(s.StrictHostKeyChecking))
+	switch policy {
+	case "":
+		if s.CheckKnownHosts {
+			return HostKeyCheckingAsk, nil
+		}
+		return HostKeyCheckingNo, nil
+	case HostKeyCheckingYes, HostKeyCheckingAcceptNew, HostKeyCheckingNo, HostKeyCheckingAsk:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("unknown strict_host_key_checking %q: must be one of yes, accept-new, no, ask", s.StrictHostKeyChecking)
+	}
+}
+
+// CheckHostKeyFingerprints checks that host_key_fingerprints of s are
+// OpenSSH style SHA256 fingerprints.
+func (s ServerConfig) CheckHostKeyFingerprints() error {
+	for _, fingerprint := range s.HostKeyFingerprints {
+		hash, ok := strings.CutPrefix(strings.TrimSpace(fingerprint), "SHA256:")
+		if !ok {
+			return fmt.Errorf("fingerprint %q is not a SHA256 fingerprint", fingerprint)
+		}
+		if sum, err := base64.RawStdEncoding.DecodeString(strings.TrimRight(hash, "=")); err != nil || len(sum) != 32 {
+			return fmt.Errorf("fingerprint %q is not a valid SHA256 fingerprint", fingerprint)
+		}
+	}
+	return nil
+}
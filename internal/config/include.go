@@ -0,0 +1,177 @@
+// Copyright (c) 2026 Blacknon. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package conf
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// includeFileExtensions are the file types loaded from include directories.
+var includeFileExtensions = map[string]bool{
+	".toml": true,
+	".yaml": true,
+	".yml":  true,
+	".conf": true,
+}
+
+// defaultConfDDir returns the conf.d directory that is loaded automatically
+// for a config file in a dedicated directory, such as
+// `$XDG_CONFIG_HOME/lssh/lssh.toml` => `$XDG_CONFIG_HOME/lssh/conf.d`.
+// Files like `~/.lssh.toml` have no implicit conf.d directory.
+func defaultConfDDir(confPath string) string {
+	base := filepath.Base(confPath)
+	if strings.TrimSuffix(base, filepath.Ext(base)) != "lssh" {
+		return ""
+	}
+	return filepath.Join(filepath.Dir(confPath), "conf.d")
+}
+
+// includeFilePaths returns the files included by c in load order:
+// [include.<name>] sorted by name, then includes.path, then conf.d.
+// Relative paths are resolved from the directory of the including file.
+func (c *Config) includeFilePaths() ([]string, error) {
+	patterns := []string{}
+
+	names := make([]string, 0, len(c.Include))
+	for name := range c.Include {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		patterns = append(patterns, c.Include[name].Path)
+	}
+	patterns = append(patterns, c.Includes.Path...)
+
+	baseDir := ""
+	if c.confPath != "" {
+		baseDir = filepath.Dir(c.confPath)
+	}
+
+	result := []string{}
+	seen := map[string]struct{}{}
+	appendPaths := func(paths []string) {
+		for _, path := range paths {
+			if _, ok := seen[path]; ok {
+				continue
+			}
+			seen[path] = struct{}{}
+			result = append(result, path)
+		}
+	}
+
+	for _, pattern := range patterns {
+		paths, err := expandIncludePattern(baseDir, pattern)
+		if err != nil {
+			return nil, err
+		}
+		appendPaths(paths)
+	}
+
+	if c.confDDir != "" {
+		if info, err := os.Stat(c.confDDir); err == nil && info.IsDir() {
+			paths, err := expandIncludePattern("", c.confDDir)
+			if err != nil {
+				return nil, err
+			}
+			appendPaths(paths)
+		}
+	}
+
+	return result, nil
+}
+
+// expandIncludePattern expands an include path into files.
+//
+//   - glob pattern (ex. `~/.lssh.d/*.toml`): every matching file, sorted
+//   - directory (ex. `~/.lssh.d`): every config file in it, sorted
+//   - file: the file itself, which must exist
+func expandIncludePattern(baseDir, pattern string) ([]string, error) {
+	pattern = strings.TrimSpace(pattern)
+	if pattern == "" {
+		return nil, nil
+	}
+
+	path := pattern
+	if !strings.HasPrefix(path, "~") && !filepath.IsAbs(path) && baseDir != "" {
+		path = filepath.Join(baseDir, path)
+	}
+	path = expandOpenSSHPath(path)
+
+	if strings.ContainsAny(path, "*?[") {
+		matches, err := filepath.Glob(path)
+		if err != nil {
+			return nil, fmt.Errorf("include %q: %w", pattern, err)
+		}
+		sort.Strings(matches)
+
+		result := []string{}
+		for _, match := range matches {
+			info, err := os.Stat(match)
+			if err != nil {
+				continue
+			}
+			if info.IsDir() {
+				files, err := includeDirFiles(match)
+				if err != nil {
+					return nil, fmt.Errorf("include %q: %w", pattern, err)
+				}
+				result = append(result, files...)
+				continue
+			}
+			result = append(result, resolveIncludeSymlink(match))
+		}
+		return result, nil
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("include %q: %w", pattern, err)
+	}
+	if info.IsDir() {
+		files, err := includeDirFiles(path)
+		if err != nil {
+			return nil, fmt.Errorf("include %q: %w", pattern, err)
+		}
+		return files, nil
+	}
+
+	return []string{resolveIncludeSymlink(path)}, nil
+}
+
+// includeDirFiles returns the config files directly under dir, sorted by
+// name. Hidden files and files with other extensions are skipped.
+func includeDirFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []string{}
+	for _, entry := range entries {
+		name := entry.Name()
+		if strings.HasPrefix(name, ".") || !includeFileExtensions[strings.ToLower(filepath.Ext(name))] {
+			continue
+		}
+
+		path := filepath.Join(dir, name)
+		info, err := os.Stat(path)
+		if err != nil || info.IsDir() {
+			continue
+		}
+		result = append(result, resolveIncludeSymlink(path))
+	}
+	return result, nil
+}
+
+func resolveIncludeSymlink(path string) string {
+	if realPath, err := filepath.EvalSymlinks(path); err == nil {
+		return realPath
+	}
+	return path
+}
@@ -0,0 +1,222 @@
+package conf
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeIncludeTestFile(t *testing.T, path, body string) {
+	t.Helper()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(body), 0o600); err != nil {
+		t.Fatalf("write %s: %v", path, err)
+	}
+}
+
+func TestReadConfigIncludesGlobDirectoryAndNested(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+
+	configPath := filepath.Join(dir, ".lssh.toml")
+	writeIncludeTestFile(t, configPath, `
+[common]
+user = "demo"
+agentauth = true
+
+[includes]
+path = ["lssh.d/*.toml", "team"]
+`)
+	writeIncludeTestFile(t, filepath.Join(dir, "lssh.d", "a.toml"), `
+[server.a]
+addr = "192.0.2.1"
+`)
+	writeIncludeTestFile(t, filepath.Join(dir, "lssh.d", "b.toml"), `
+[include.more]
+path = "nested/c.toml"
+
+[server.b]
+addr = "192.0.2.2"
+`)
+	writeIncludeTestFile(t, filepath.Join(dir, "lssh.d", "skip.txt"), `[server.skip]`)
+	writeIncludeTestFile(t, filepath.Join(dir, "lssh.d", "nested", "c.toml"), `
+[server.c]
+addr = "192.0.2.3"
+`)
+	writeIncludeTestFile(t, filepath.Join(dir, "team", "d.yaml"), `
+server:
+  d:
+    addr: 192.0.2.4
+`)
+	writeIncludeTestFile(t, filepath.Join(dir, "team", ".hidden.toml"), `
+[server.hidden]
+addr = "192.0.2.5"
+`)
+
+	c, err := readConfig(configPath)
+	if err != nil {
+		t.Fatalf("readConfig() error = %v", err)
+	}
+
+	want := map[string]string{
+		"a": filepath.Join(dir, "lssh.d", "a.toml"),
+		"b": filepath.Join(dir, "lssh.d", "b.toml"),
+		"c": filepath.Join(dir, "lssh.d", "nested", "c.toml"),
+		"d": filepath.Join(dir, "team", "d.yaml"),
+	}
+	for name, origin := range want {
+		server, ok := c.Server[name]
+		if !ok {
+			t.Fatalf("server %q was not loaded: %v", name, GetNameList(c))
+		}
+		if server.User != "demo" {
+			t.Fatalf("server %q user = %q, want common user", name, server.User)
+		}
+		if got := c.ServerOrigin(name); got != origin {
+			t.Fatalf("ServerOrigin(%q) = %q, want %q", name, got, origin)
+		}
+	}
+	for _, name := range []string{"skip", "hidden"} {
+		if _, ok := c.Server[name]; ok {
+			t.Fatalf("server %q should not be loaded", name)
+		}
+	}
+}
+
+func TestReadConfigIncludesCarryProxyGroupAndSSHConfig(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+
+	sshConfigPath := filepath.Join(dir, "ssh_config")
+	writeIncludeTestFile(t, sshConfigPath, `
+Host imported
+    HostName 192.0.2.20
+`)
+
+	configPath := filepath.Join(dir, "lssh", "lssh.toml")
+	writeIncludeTestFile(t, configPath, `
+[common]
+user = "demo"
+agentauth = true
+`)
+	writeIncludeTestFile(t, filepath.Join(dir, "lssh", "conf.d", "10-infra.toml"), `
+[common]
+pre_cmd = "echo infra"
+
+[proxy.gateway]
+addr = "192.0.2.10"
+port = "3128"
+
+[group.infra]
+servers = ["web*"]
+
+[sshconfig.team]
+path = "`+sshConfigPath+`"
+
+[server.web01]
+addr = "192.0.2.11"
+proxy = "gateway"
+`)
+
+	c, err := readConfig(configPath)
+	if err != nil {
+		t.Fatalf("readConfig() error = %v", err)
+	}
+
+	if _, ok := c.Proxy["gateway"]; !ok {
+		t.Fatalf("proxy from include was not loaded: %v", c.Proxy)
+	}
+	if _, ok := c.Group["infra"]; !ok {
+		t.Fatalf("group from include was not loaded: %v", c.Group)
+	}
+
+	imported, ok := c.Server[sshConfigPath+":imported"]
+	if !ok {
+		t.Fatalf("sshconfig from include was not loaded: %v", GetNameList(c))
+	}
+	if imported.PreCmd != "echo infra" {
+		t.Fatalf("imported pre_cmd = %q, want include common pre_cmd", imported.PreCmd)
+	}
+	if got := c.ServerOrigin(sshConfigPath + ":imported"); got != sshConfigPath {
+		t.Fatalf("ServerOrigin(imported) = %q", got)
+	}
+}
+
+func TestReadConfigIncludesExtendAcrossFiles(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+
+	configPath := filepath.Join(dir, "lssh", "lssh.toml")
+	writeIncludeTestFile(t, configPath, `
+[common]
+user = "demo"
+agentauth = true
+
+[template.base]
+port = "2222"
+
+[server.main]
+extends = ["bastioned"]
+addr = "192.0.2.30"
+`)
+	writeIncludeTestFile(t, filepath.Join(dir, "lssh", "conf.d", "10-templates.toml"), `
+[template.bastioned]
+extends = ["base"]
+proxy = "bastion"
+`)
+	writeIncludeTestFile(t, filepath.Join(dir, "lssh", "conf.d", "20-servers.toml"), `
+[server.web01]
+extends = ["bastioned"]
+addr = "192.0.2.31"
+`)
+
+	c, err := readConfig(configPath)
+	if err != nil {
+		t.Fatalf("readConfig() error = %v", err)
+	}
+
+	for _, name := range []string{"main", "web01"} {
+		server := c.Server[name]
+		if server.Proxy != "bastion" || server.Port != "2222" || server.User != "demo" {
+			t.Fatalf("server %q = proxy %q, port %q, user %q, want the included template", name, server.Proxy, server.Port, server.User)
+		}
+	}
+}
+
+func TestReadConfigIncludeMissingFile(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+
+	configPath := filepath.Join(dir, ".lssh.toml")
+	writeIncludeTestFile(t, configPath, `
+[includes]
+path = ["missing.toml", "empty/*.toml"]
+`)
+
+	_, err := readConfig(configPath)
+	if err == nil || !strings.Contains(err.Error(), `include "missing.toml"`) {
+		t.Fatalf("readConfig() error = %v, want missing include error", err)
+	}
+}
+
+func TestWriteServerListShowsOrigin(t *testing.T) {
+	c := Config{}
+	c.addServerSource("web01", "/etc/lssh/web.toml")
+	c.addServerSource("db", "provider.aws")
+
+	var out bytes.Buffer
+	c.WriteServerList(&out, []string{"db", "web01", "manual"})
+
+	want := "lssh Server List:\n" +
+		"  db      (provider.aws)\n" +
+		"  web01   (/etc/lssh/web.toml)\n" +
+		"  manual\n"
+	if out.String() != want {
+		t.Fatalf("WriteServerList() = %q, want %q", out.String(), want)
+	}
+}
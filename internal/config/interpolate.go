@@ -0,0 +1,318 @@
+// Copyright (c) 2026 Blacknon. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package conf
+
+import (
+	"fmt"
+	"os"
+	osuser "os/user"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// interpolatedSecrets keeps the values resolved from `${secret:...}`, so that
+// provider debug logs and error messages can redact them.
+var interpolatedSecrets = struct {
+	sync.Mutex
+	values map[string]struct{}
+}{values: map[string]struct{}{}}
+
+func registerInterpolatedSecret(value string) {
+	if value == "" {
+		return
+	}
+	interpolatedSecrets.Lock()
+	defer interpolatedSecrets.Unlock()
+	interpolatedSecrets.values[value] = struct{}{}
+}
+
+func interpolatedSecretValues() []string {
+	interpolatedSecrets.Lock()
+	defer interpolatedSecrets.Unlock()
+
+	values := make([]string, 0, len(interpolatedSecrets.values))
+	for value := range interpolatedSecrets.values {
+		values = append(values, value)
+	}
+	// replace longer values first, so that a secret containing another one
+	// is redacted as a whole.
+	sort.Slice(values, func(i, j int) bool {
+		return len(values[i]) > len(values[j])
+	})
+	return values
+}
+
+// InterpolateServers returns a copy of c in which the `${...}` references in
+// the string settings of names, and of the proxies they connect through, are
+// resolved.
+//
+// Supported references:
+//
+//   - `${env:VAR}`: local environment variable
+//   - `${secret:provider/ref#field}`: secret from a provider (ResolveSecretRef)
+//   - `${server.name}`: server name
+//   - `${server.meta.KEY}`: provider metadata of the server
+//   - `${local.user}`: local user name
+//
+// `$${...}` is written as a literal `${...}`. c itself is not modified, so it
+// can be shared between goroutines. Servers that can not be resolved are
+// returned in the error map and left as is.
+func (c Config) InterpolateServers(names []string) (Config, map[string]error) {
+	return c.interpolateServers(names, false)
+}
+
+// InterpolateServersWithoutSecrets is like InterpolateServers, but keeps the
+// `${secret:...}` references as is, so that no provider is called. It is used
+// to show and export servers. The returned config is only for display: its
+// servers are interpolated again with secrets before connecting.
+func (c Config) InterpolateServersWithoutSecrets(names []string) (Config, map[string]error) {
+	result, errs := c.interpolateServers(names, true)
+	result.interpolated = c.interpolated
+	return result, errs
+}
+
+func (c Config) interpolateServers(names []string, keepSecrets bool) (Config, map[string]error) {
+	errs := map[string]error{}
+
+	servers := make(map[string]ServerConfig, len(c.Server))
+	for key, value := range c.Server {
+		servers[key] = value
+	}
+	proxies := make(map[string]ProxyConfig, len(c.Proxy))
+	for key, value := range c.Proxy {
+		proxies[key] = value
+	}
+	interpolated := make(map[string]struct{}, len(c.interpolated))
+	for key := range c.interpolated {
+		interpolated[key] = struct{}{}
+	}
+
+	for _, name := range names {
+		conName, conType := name, "ssh"
+		visited := map[string]struct{}{}
+		for conName != "" {
+			key := conType + "." + conName
+			if _, ok := visited[key]; ok {
+				break
+			}
+			visited[key] = struct{}{}
+
+			switch conType {
+			case "http", "https", "socks", "socks5":
+				proxy, ok := proxies[conName]
+				if !ok {
+					conName = ""
+					continue
+				}
+				if _, ok := interpolated["proxy."+conName]; !ok {
+					if err := c.interpolateStruct(conName, nil, reflect.ValueOf(&proxy).Elem(), keepSecrets); err != nil {
+						errs[name] = fmt.Errorf("%s: proxy %s: %w", name, conName, err)
+						conName = ""
+						continue
+					}
+					proxies[conName] = proxy
+					interpolated["proxy."+conName] = struct{}{}
+				}
+				conName, conType = proxy.Proxy, proxy.ProxyType
+
+			default:
+				server, ok := servers[conName]
+				if !ok {
+					conName = ""
+					continue
+				}
+				if _, ok := interpolated["server."+conName]; !ok {
+					if err := c.interpolateStruct(conName, server.ProviderMeta, reflect.ValueOf(&server).Elem(), keepSecrets); err != nil {
+						if conName == name {
+							errs[name] = fmt.Errorf("%s: %w", name, err)
+						} else {
+							errs[name] = fmt.Errorf("%s: proxy %s: %w", name, conName, err)
+						}
+						conName = ""
+						continue
+					}
+					servers[conName] = server
+					interpolated["server."+conName] = struct{}{}
+				}
+				if server.ProxyCommand != "" && server.ProxyCommand != "none" {
+					conName = ""
+					continue
+				}
+				conName, conType = server.Proxy, server.ProxyType
+			}
+		}
+	}
+
+	c.Server = servers
+	c.Proxy = proxies
+	c.interpolated = interpolated
+	return c, errs
+}
+
+// interpolateStruct resolves the string, []string and map[string]string
+// fields of value in place. With keepSecrets, `${secret:...}` is kept as is.
+func (c Config) interpolateStruct(server string, meta map[string]string, value reflect.Value, keepSecrets bool) error {
+	typ := value.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		tag := field.Tag.Get("toml")
+		if tag == "" || tag == "-" || tag == "extends" {
+			continue
+		}
+
+		fieldValue := value.Field(i)
+		switch fieldValue.Kind() {
+		case reflect.String:
+			resolved, err := c.interpolateString(server, tag, meta, fieldValue.String(), keepSecrets)
+			if err != nil {
+				return fmt.Errorf("%s: %w", tag, err)
+			}
+			fieldValue.SetString(resolved)
+
+		case reflect.Slice:
+			if fieldValue.Type().Elem().Kind() != reflect.String || fieldValue.Len() == 0 {
+				continue
+			}
+			items := make([]string, fieldValue.Len())
+			for j := range items {
+				resolved, err := c.interpolateString(server, tag, meta, fieldValue.Index(j).String(), keepSecrets)
+				if err != nil {
+					return fmt.Errorf("%s: %w", tag, err)
+				}
+				items[j] = resolved
+			}
+			fieldValue.Set(reflect.ValueOf(items))
+
+		case reflect.Map:
+			values, ok := fieldValue.Interface().(map[string]string)
+			if !ok || len(values) == 0 {
+				continue
+			}
+			items := make(map[string]string, len(values))
+			for key, item := range values {
+				resolved, err := c.interpolateString(server, tag, meta, item, keepSecrets)
+				if err != nil {
+					return fmt.Errorf("%s.%s: %w", tag, key, err)
+				}
+				items[key] = resolved
+			}
+			fieldValue.Set(reflect.ValueOf(items))
+		}
+	}
+	return nil
+}
+
+// interpolateString resolves the `${...}` references in value. field is the
+// config key of value, and is used as the default secret field.
+func (c Config) interpolateString(server, field string, meta map[string]string, value string, keepSecrets bool) (string, error) {
+	return replaceInterpolations(value, func(expr string) (string, error) {
+		if keepSecrets && strings.HasPrefix(expr, "secret:") {
+			return "${" + expr + "}", nil
+		}
+		return c.resolveInterpolation(server, field, meta, expr)
+	})
+}
+
+// replaceInterpolations replaces each `${...}` reference in value with the
+// result of resolve. References lssh does not know (ex. shell `${HOME}` in
+// proxy_cmd) are kept as is, and `$${...}` is written as `${...}`.
+func replaceInterpolations(value string, resolve func(expr string) (string, error)) (string, error) {
+	if !strings.Contains(value, "${") {
+		return value, nil
+	}
+
+	var buf strings.Builder
+	for {
+		start := strings.Index(value, "${")
+		if start < 0 {
+			buf.WriteString(value)
+			break
+		}
+		end := strings.IndexByte(value[start:], '}')
+		if end < 0 {
+			buf.WriteString(value)
+			break
+		}
+		end += start
+
+		expr := value[start+2 : end]
+		switch {
+		case !isInterpolationExpr(expr):
+			buf.WriteString(value[:end+1])
+		case start > 0 && value[start-1] == '$':
+			buf.WriteString(value[:start-1])
+			buf.WriteString(value[start : end+1])
+		default:
+			resolved, err := resolve(expr)
+			if err != nil {
+				return "", err
+			}
+			buf.WriteString(value[:start])
+			buf.WriteString(resolved)
+		}
+		value = value[end+1:]
+	}
+
+	return buf.String(), nil
+}
+
+func isInterpolationExpr(expr string) bool {
+	return strings.HasPrefix(expr, "env:") ||
+		strings.HasPrefix(expr, "secret:") ||
+		strings.HasPrefix(expr, "server.") ||
+		strings.HasPrefix(expr, "local.")
+}
+
+func (c Config) resolveInterpolation(server, field string, meta map[string]string, expr string) (string, error) {
+	switch {
+	case strings.HasPrefix(expr, "env:"):
+		name := strings.TrimPrefix(expr, "env:")
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("${env:%s}: environment variable is not set", name)
+		}
+		return value, nil
+
+	case strings.HasPrefix(expr, "secret:"):
+		spec := strings.TrimPrefix(expr, "secret:")
+		if i := strings.LastIndexByte(spec, '#'); i >= 0 {
+			spec, field = spec[:i], spec[i+1:]
+		}
+		providerName, ref, ok := strings.Cut(spec, "/")
+		if !ok || providerName == "" || ref == "" || field == "" {
+			return "", fmt.Errorf("${secret:%s}: want ${secret:provider/ref#field}", strings.TrimPrefix(expr, "secret:"))
+		}
+		value, err := c.ResolveSecretRef(providerName+":"+ref, server, field)
+		if err != nil {
+			return "", fmt.Errorf("${secret:%s/...}: %w", providerName, err)
+		}
+		registerInterpolatedSecret(value)
+		return value, nil
+
+	case expr == "server.name":
+		return server, nil
+
+	case strings.HasPrefix(expr, "server.meta."):
+		key := strings.TrimPrefix(expr, "server.meta.")
+		value, ok := meta[key]
+		if !ok {
+			return "", fmt.Errorf("${%s}: metadata %q is not set", expr, key)
+		}
+		return value, nil
+
+	case expr == "local.user":
+		if u, err := osuser.Current(); err == nil {
+			return u.Username, nil
+		}
+		if value := os.Getenv("USER"); value != "" {
+			return value, nil
+		}
+		return "", fmt.Errorf("${local.user}: could not get local user")
+	}
+
+	return "", fmt.Errorf("${%s}: unknown reference", expr)
+}
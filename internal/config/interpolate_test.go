@@ -0,0 +1,144 @@
+package conf
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestInterpolateServersResolvesReferences(t *testing.T) {
+	t.Setenv("LSSH_TEST_JUMP_IP", "192.0.2.99")
+	t.Setenv("USER", "")
+
+	cfg := Config{
+		Server: map[string]ServerConfig{
+			"web": {
+				Addr:         "${env:LSSH_TEST_JUMP_IP}",
+				User:         "${local.user}",
+				Proxy:        "jump-${server.meta.zone}",
+				ProxyCommand: "",
+				Note:         "${server.name} $${env:LITERAL} ${HOME}",
+				PortForwards: []string{"local:8080:${env:LSSH_TEST_JUMP_IP}:80"},
+				SetEnv:       map[string]string{"JUMP_IP": "${env:LSSH_TEST_JUMP_IP}"},
+				ProviderMeta: map[string]string{"zone": "a"},
+			},
+			"jump-a": {Addr: "${env:LSSH_TEST_JUMP_IP}", Note: "${server.name}"},
+			"other":  {Addr: "${env:LSSH_TEST_JUMP_IP}"},
+		},
+	}
+
+	got, errs := cfg.InterpolateServers([]string{"web"})
+	if len(errs) > 0 {
+		t.Fatalf("InterpolateServers() errors = %v", errs)
+	}
+
+	web := got.Server["web"]
+	if web.Addr != "192.0.2.99" {
+		t.Fatalf("addr = %q", web.Addr)
+	}
+	if web.User == "" || strings.Contains(web.User, "${") {
+		t.Fatalf("user = %q, want local user", web.User)
+	}
+	if web.Proxy != "jump-a" {
+		t.Fatalf("proxy = %q", web.Proxy)
+	}
+	if web.Note != "web ${env:LITERAL} ${HOME}" {
+		t.Fatalf("note = %q", web.Note)
+	}
+	if web.PortForwards[0] != "local:8080:192.0.2.99:80" {
+		t.Fatalf("port_forwards = %q", web.PortForwards)
+	}
+	if web.SetEnv["JUMP_IP"] != "192.0.2.99" {
+		t.Fatalf("set_env = %v", web.SetEnv)
+	}
+	if jump := got.Server["jump-a"]; jump.Addr != "192.0.2.99" || jump.Note != "jump-a" {
+		t.Fatalf("proxy host was not resolved: %+v", jump)
+	}
+	if other := got.Server["other"]; other.Addr != "${env:LSSH_TEST_JUMP_IP}" {
+		t.Fatalf("unselected server was resolved: %q", other.Addr)
+	}
+
+	// the original config keeps the references
+	if cfg.Server["web"].Addr != "${env:LSSH_TEST_JUMP_IP}" || cfg.Server["web"].PortForwards[0] != "local:8080:${env:LSSH_TEST_JUMP_IP}:80" || cfg.Server["web"].SetEnv["JUMP_IP"] != "${env:LSSH_TEST_JUMP_IP}" {
+		t.Fatalf("original config was modified: %+v", cfg.Server["web"])
+	}
+
+	// resolved values are not resolved again
+	t.Setenv("LSSH_TEST_JUMP_IP", "192.0.2.100")
+	again, _ := got.InterpolateServers([]string{"web"})
+	if again.Server["web"].Note != "web ${env:LITERAL} ${HOME}" || again.Server["web"].Addr != "192.0.2.99" {
+		t.Fatalf("server was resolved twice: %+v", again.Server["web"])
+	}
+}
+
+func TestInterpolateServersReportsErrors(t *testing.T) {
+	os.Unsetenv("LSSH_TEST_MISSING")
+
+	cfg := Config{
+		Server: map[string]ServerConfig{
+			"web":  {Addr: "${env:LSSH_TEST_MISSING}"},
+			"meta": {Addr: "${server.meta.zone}"},
+			"ok":   {Addr: "192.0.2.1", Proxy: "web"},
+		},
+	}
+
+	_, errs := cfg.InterpolateServers([]string{"web", "meta", "ok"})
+	if err := errs["web"]; err == nil || !strings.Contains(err.Error(), "web: addr: ${env:LSSH_TEST_MISSING}: environment variable is not set") {
+		t.Fatalf("web error = %v", err)
+	}
+	if err := errs["meta"]; err == nil || !strings.Contains(err.Error(), `metadata "zone" is not set`) {
+		t.Fatalf("meta error = %v", err)
+	}
+	if err := errs["ok"]; err == nil || !strings.Contains(err.Error(), "ok: proxy web: addr:") {
+		t.Fatalf("ok error = %v", err)
+	}
+}
+
+func TestInterpolateServersResolvesSecretsAndRedacts(t *testing.T) {
+	dir := t.TempDir()
+	requestPath := filepath.Join(dir, "request.json")
+	providerPath := filepath.Join(dir, "lssh-provider-fake-secret")
+	script := `#!/bin/sh
+cat >` + requestPath + `
+printf '%s' '{"version":"v1","result":{"value":"interpolated-token"}}'
+`
+	if err := os.WriteFile(providerPath, []byte(script), 0o755); err != nil {
+		t.Fatalf("write provider: %v", err)
+	}
+
+	cfg := Config{
+		Providers: ProvidersConfig{Paths: []string{providerPath}},
+		Provider: map[string]map[string]interface{}{
+			"vault": {
+				"plugin":       "lssh-provider-fake-secret",
+				"capabilities": []interface{}{"secret"},
+			},
+		},
+		Server: map[string]ServerConfig{
+			"web": {Addr: "192.0.2.1", ProxyCommand: "connect --token ${secret:vault/kv/jump#token} %h %p"},
+		},
+	}
+
+	got, errs := cfg.InterpolateServers([]string{"web"})
+	if len(errs) > 0 {
+		t.Fatalf("InterpolateServers() errors = %v", errs)
+	}
+	if got.Server["web"].ProxyCommand != "connect --token interpolated-token %h %p" {
+		t.Fatalf("proxy_cmd = %q", got.Server["web"].ProxyCommand)
+	}
+
+	request, err := os.ReadFile(requestPath)
+	if err != nil {
+		t.Fatalf("read request: %v", err)
+	}
+	for _, want := range []string{`"ref":"kv/jump"`, `"field":"token"`, `"server":"web"`} {
+		if !strings.Contains(string(request), want) {
+			t.Fatalf("request %s does not contain %s", request, want)
+		}
+	}
+
+	if got := sanitizeProviderDebugString("failed: interpolated-token", nil); got != "failed: <redacted>" {
+		t.Fatalf("sanitizeProviderDebugString() = %q", got)
+	}
+}
@@ -30,38 +30,113 @@ conf is a package used to read configuration file (~/.lssh.conf).
 package conf
 
 import (
-	"crypto/md5"
-	"encoding/hex"
 	"fmt"
 	"github.com/blacknon/lssh/internal/common"
+	"io"
 	"log"
 	"os"
 	"path/filepath"
-	"strings"
-	"time"
 )
 
 // Config is Struct that stores the entire configuration file.
 type Config struct {
 	Log       LogConfig                         `toml:"log" yaml:"log"`
+	Audit     AuditConfig                       `toml:"audit" yaml:"audit"`
 	Mux       MuxConfig                         `toml:"mux" yaml:"mux"`
+	List      ListConfig                        `toml:"list" yaml:"list"`
 	Shell     ShellConfig                       `toml:"shell" yaml:"shell"`
 	Lsshfs    LsshfsConfig                      `toml:"lsshfs" yaml:"lsshfs"`
 	Providers ProvidersConfig                   `toml:"providers" yaml:"providers"`
 	Include   map[string]IncludeConfig          `toml:"include" yaml:"include"`
 	Includes  IncludesConfig                    `toml:"includes" yaml:"includes"`
 	Common    ServerConfig                      `toml:"common" yaml:"common"`
+	Template  map[string]ServerConfig           `toml:"template" yaml:"template"`
 	Server    map[string]ServerConfig           `toml:"server" yaml:"server"`
 	Proxy     map[string]ProxyConfig            `toml:"proxy" yaml:"proxy"`
+	Group     map[string]GroupConfig            `toml:"group" yaml:"group"`
 	Provider  map[string]map[string]interface{} `toml:"provider" yaml:"provider"`
 
 	SSHConfig map[string]OpenSSHConfig `toml:"sshconfig" yaml:"sshconfig"`
+
+	// confPath is the file this config was decoded from, and confDDir the
+	// conf.d directory loaded with it.
+	confPath string
+	confDDir string
+
+	// serverSources records every place (config file path or
+	// `provider.<name>`) that defined each server, in load order.
+	serverSources map[string][]string
+
+	// interpolated records the servers (`server.<name>`) and proxies
+	// (`proxy.<name>`) whose `${...}` references are already resolved.
+	interpolated map[string]struct{}
+
+	// fieldSources records the layer that set each config key of each
+	// server, and commonSources the layer of each [common] key.
+	fieldSources  map[string]map[string]string
+	commonSources map[string]string
+
+	// matchedBranches records the `match` branches applied to each server.
+	matchedBranches map[string][]string
+}
+
+// addServerSource records that source defined server name.
+func (c *Config) addServerSource(name, source string) {
+	if c.serverSources == nil {
+		c.serverSources = map[string][]string{}
+	}
+	c.serverSources[name] = append(c.serverSources[name], source)
+}
+
+// ServerSources returns the places that defined server name, in load order.
+// The last one is the definition in effect.
+func (c Config) ServerSources(name string) []string {
+	return append([]string(nil), c.serverSources[name]...)
+}
+
+// ServerOrigin returns the file (or provider) the server name came from.
+func (c Config) ServerOrigin(name string) string {
+	sources := c.serverSources[name]
+	if len(sources) == 0 {
+		return ""
+	}
+	return sources[len(sources)-1]
+}
+
+// serverLabel returns name with its origin, for error messages.
+func (c Config) serverLabel(name string) string {
+	if origin := c.ServerOrigin(name); origin != "" {
+		return fmt.Sprintf("%s (%s)", name, origin)
+	}
+	return name
+}
+
+// WriteServerList writes the `--list` output of names, with the origin of
+// each server.
+func (c Config) WriteServerList(out io.Writer, names []string) {
+	width := 0
+	for _, name := range names {
+		if len(name) > width {
+			width = len(name)
+		}
+	}
+
+	fmt.Fprintln(out, "lssh Server List:")
+	for _, name := range names {
+		origin := c.ServerOrigin(name)
+		if origin == "" {
+			fmt.Fprintf(out, "  %s\n", name)
+			continue
+		}
+		fmt.Fprintf(out, "  %-*s  (%s)\n", width, name, origin)
+	}
 }
 
 // ReduceCommon reduce common setting (in .lssh.conf servers)
 func (c *Config) ReduceCommon() {
 	for key, value := range c.Server {
 		setValue := serverConfigReduct(c.Common, value)
+		c.recordFieldSources(key, value, setValue, commonFieldSource(c.commonSources))
 		c.Server[key] = setValue
 	}
 }
@@ -81,12 +156,16 @@ func (c *Config) ReadOpenSSHConfig() error {
 
 		// append data
 		for key, value := range openSSHServerConfig {
-			value := serverConfigReduct(c.Common, value)
-			c.Server[key] = value
+			c.resetFieldSources(key)
+			c.recordFieldSources(key, ServerConfig{}, value, fixedFieldSource(fileFieldSource("ssh_config", defaultPath)))
+			setValue := serverConfigReduct(c.Common, value)
+			c.recordFieldSources(key, value, setValue, commonFieldSource(c.commonSources))
+			c.Server[key] = setValue
+			c.addServerSource(key, defaultPath)
 		}
 	} else {
 		for _, sc := range c.activeOpenSSHConfigs() {
-			if err := c.readConfiguredOpenSSHConfig(sc); err != nil {
+			if err := c.readConfiguredOpenSSHConfig(c.Common, c.commonSources, sc); err != nil {
 				return err
 			}
 		}
@@ -102,67 +181,144 @@ func defaultOpenSSHConfigCandidate() string {
 	return "~/.ssh/config"
 }
 
-// ReadIncludeFiles read include files and append to Config.Server.
-func (c *Config) ReadIncludeFiles() {
-	if c.Includes.Path != nil {
-		if c.Include == nil {
-			c.Include = map[string]IncludeConfig{}
-		}
+// ReadIncludeFiles read include files and append to Config.
+//
+// Include files can define servers, templates, proxies, groups, providers and
+// sshconfig blocks, and can include other files.
+func (c *Config) ReadIncludeFiles() error {
+	includeConfs, err := c.readIncludeConfigs()
+	if err != nil {
+		return err
+	}
+	return c.mergeIncludeConfigs(includeConfs)
+}
 
-		for _, includePath := range c.Includes.Path {
-			// get abs path
-			includePath = common.GetFullPath(includePath)
+// readIncludeConfigs reads every include file, and adds their templates to
+// c.Template so that templates defined in any file can be used from every
+// file, including the main config file.
+func (c *Config) readIncludeConfigs() ([]Config, error) {
+	paths, err := c.includeFilePaths()
+	if err != nil {
+		return nil, err
+	}
 
-			unixTime := time.Now().Unix()
-			keyString := strings.Join([]string{fmt.Sprint(unixTime), includePath}, "_")
+	includeConfs := []Config{}
+	seen := map[string]struct{}{}
+	if c.confPath != "" {
+		seen[c.confPath] = struct{}{}
+	}
+	for len(paths) > 0 {
+		path := paths[0]
+		paths = paths[1:]
+		if _, ok := seen[path]; ok {
+			continue
+		}
+		seen[path] = struct{}{}
 
-			// key to md5
-			hasher := md5.New()
-			hasher.Write([]byte(keyString))
-			key := string(hex.EncodeToString(hasher.Sum(nil)))
+		// Read include config file
+		var includeConf Config
+		if err := decodeConfigFile(path, &includeConf); err != nil {
+			return nil, fmt.Errorf("Read config file error: %s: %w", path, err)
+		}
+		includeConf.confPath = path
 
-			// append c.Include[key]
-			c.Include[key] = IncludeConfig{includePath}
+		nested, err := includeConf.includeFilePaths()
+		if err != nil {
+			return nil, fmt.Errorf("Read config file error: %s: %w", path, err)
+		}
+		paths = append(paths, nested...)
+
+		// include file templates can be used from any file
+		if len(includeConf.Template) > 0 {
+			if c.Template == nil {
+				c.Template = map[string]ServerConfig{}
+			}
+			for key, value := range includeConf.Template {
+				c.Template[key] = value
+			}
 		}
+
+		includeConfs = append(includeConfs, includeConf)
 	}
 
-	// Read include files
-	if c.Include != nil {
-		for _, v := range c.Include {
-			var includeConf Config
+	return includeConfs, nil
+}
+
+// mergeIncludeConfigs appends the include files read by readIncludeConfigs to
+// Config.
+func (c *Config) mergeIncludeConfigs(includeConfs []Config) error {
+	for _, includeConf := range includeConfs {
+		path := includeConf.confPath
 
-			// user path
-			path := common.GetFullPath(v.Path)
+		// reduce common setting
+		setCommon := serverConfigReduct(c.Common, includeConf.Common)
+		setCommonSources := commonFieldSources(includeConf.Common, fileFieldSource("common", path), c.commonSources)
 
-			// Read include config file
-			err := decodeConfigFile(path, &includeConf)
+		// add include file serverconf
+		for key, value := range includeConf.Server {
+			c.resetFieldSources(key)
+			c.recordFieldSources(key, ServerConfig{}, value, fixedFieldSource(fileFieldSource("server."+key, path)))
+
+			merged, err := c.applyServerTemplates("server."+key, value)
 			if err != nil {
-				fmt.Fprintf(os.Stderr, "err: Read config file error: %s", err)
-				os.Exit(1)
+				return fmt.Errorf("Read config file error: %s: %w", path, err)
 			}
+			c.recordFieldSources(key, value, merged, c.templateFieldSource(value.Extends))
 
 			// reduce common setting
-			setCommon := serverConfigReduct(c.Common, includeConf.Common)
+			setValue := serverConfigReduct(setCommon, merged)
+			c.recordFieldSources(key, merged, setValue, commonFieldSource(setCommonSources))
+			c.Server[key] = setValue
+			c.addServerSource(key, path)
+		}
 
-			// add include file serverconf
-			for key, value := range includeConf.Server {
-				// reduce common setting
-				setValue := serverConfigReduct(setCommon, value)
-				c.Server[key] = setValue
+		if len(includeConf.Proxy) > 0 {
+			if c.Proxy == nil {
+				c.Proxy = map[string]ProxyConfig{}
 			}
+			for key, value := range includeConf.Proxy {
+				c.Proxy[key] = value
+			}
+		}
 
-			if len(includeConf.Provider) > 0 {
-				if c.Provider == nil {
-					c.Provider = map[string]map[string]interface{}{}
-				}
-				for key, value := range includeConf.Provider {
-					c.Provider[key] = value
+		if len(includeConf.Provider) > 0 {
+			if c.Provider == nil {
+				c.Provider = map[string]map[string]interface{}{}
+			}
+			for key, value := range includeConf.Provider {
+				c.Provider[key] = value
+			}
+		}
+
+		c.Providers = mergeProvidersConfig(c.Providers, includeConf.Providers)
+
+		if len(includeConf.Group) > 0 {
+			if c.Group == nil {
+				c.Group = map[string]GroupConfig{}
+			}
+			for key, value := range includeConf.Group {
+				c.Group[key] = value
+			}
+		}
+
+		// sshconfig blocks in include files use the include file [common]
+		if len(includeConf.SSHConfig) > 0 {
+			for _, sc := range includeConf.activeOpenSSHConfigs() {
+				if err := c.readConfiguredOpenSSHConfig(setCommon, setCommonSources, sc); err != nil {
+					return fmt.Errorf("Read config file error: %s: %w", path, err)
 				}
 			}
 
-			c.Providers = mergeProvidersConfig(c.Providers, includeConf.Providers)
+			if c.SSHConfig == nil {
+				c.SSHConfig = map[string]OpenSSHConfig{}
+			}
+			for key, value := range includeConf.SSHConfig {
+				c.SSHConfig[key] = value
+			}
 		}
 	}
+
+	return nil
 }
 
 // checkFormatServerConf checkes format of server config.
@@ -183,18 +339,18 @@ func (c *Config) checkFormatServerConf() (ok bool) {
 
 		// Address Set Check
 		if v.Addr == "" {
-			log.Printf("%s: 'addr' is not set.\n", k)
+			log.Printf("%s: 'addr' is not set.\n", c.serverLabel(k))
 			ok = false
 		}
 
 		// User Set Check
 		if v.User == "" {
-			log.Printf("%s: 'user' is not set.\n", k)
+			log.Printf("%s: 'user' is not set.\n", c.serverLabel(k))
 			ok = false
 		}
 
 		if !checkFormatServerConfAuth(v) {
-			log.Printf("%s: Authentication information is not set.\n", k)
+			log.Printf("%s: Authentication information is not set.\n", c.serverLabel(k))
 			ok = false
 		}
 	}
@@ -204,20 +360,43 @@ func (c *Config) checkFormatServerConf() (ok bool) {
 // ReadConf load configuration file and return Config structure
 // TODO(blacknon): リファクタリング！(v0.6.5) 外出しや処理のまとめなど
 func Read(confPath string) (c Config) {
+	c, err := readConfig(confPath)
+	if err != nil {
+		log.Println(err)
+		Exit(1)
+	}
+
+	// Check Config Parameter
+	ok := c.checkFormatServerConf()
+	if !ok {
+		Exit(1)
+	}
+
+	return
+}
+
+// readConfig loads confPath and every source it refers to. Unlike Read, it
+// returns errors instead of exiting so that it can be used for validation.
+func readConfig(confPath string) (c Config, err error) {
 	c.Server = map[string]ServerConfig{}
 	c.SSHConfig = map[string]OpenSSHConfig{}
 
 	// TODO(blacknon): ~/.lssh.confがなくても、openssh用のファイルがアレばそれをみるように処理
 	if common.IsExist(confPath) {
 		// Read config file
-		err := decodeConfigFile(confPath, &c)
-		if err != nil {
-			log.Println(err)
-			os.Exit(1)
+		if err = decodeConfigFile(confPath, &c); err != nil {
+			return c, err
+		}
+		c.confPath = common.GetFullPath(confPath)
+		c.confDDir = defaultConfDDir(c.confPath)
+		for key := range c.Server {
+			c.addServerSource(key, c.confPath)
+			c.recordFieldSources(key, ServerConfig{}, c.Server[key], fixedFieldSource(fileFieldSource("server."+key, c.confPath)))
 		}
 	}
 
 	// reduce default setting to common
+	userCommon := c.Common
 	c.Common = serverConfigReduct(
 		ServerConfig{
 			Port:           "22",
@@ -226,39 +405,49 @@ func Read(confPath string) (c Config) {
 		},
 		c.Common,
 	)
+	c.commonSources = commonFieldSources(userCommon, fileFieldSource("common", c.confPath), commonFieldSources(c.Common, "default", nil))
 	c.Mux = c.Mux.ApplyDefaults()
+	c.List = c.List.ApplyDefaults()
+
+	// read include files first, so that servers in this file can extend
+	// templates defined in them.
+	includeConfs, err := c.readIncludeConfigs()
+	if err != nil {
+		return c, err
+	}
+
+	// expand [template.<name>] referenced by `extends`
+	if err = c.ResolveTemplates(); err != nil {
+		return c, err
+	}
 
 	// reduce common setting (in .lssh.conf servers)
 	c.ReduceCommon()
 
 	// Read OpenSSH configs
-	if err := c.ReadOpenSSHConfig(); err != nil {
-		log.Println(err)
-		os.Exit(1)
+	if err = c.ReadOpenSSHConfig(); err != nil {
+		return c, err
 	}
 
 	// for append includes to include.path
-	c.ReadIncludeFiles()
+	if err = c.mergeIncludeConfigs(includeConfs); err != nil {
+		return c, err
+	}
 
 	// Load inventory providers after includes and OpenSSH config are merged.
-	if err := c.ReadInventoryProviders(); err != nil {
-		log.Println(err)
-		os.Exit(1)
+	if err = c.ReadInventoryProviders(); err != nil {
+		return c, err
 	}
 
 	// resolve conditional server overrides after all sources have been merged
-	if err := c.ResolveConditionalMatches(); err != nil {
-		log.Println(err)
-		os.Exit(1)
+	if err = c.ResolveConditionalMatches(); err != nil {
+		return c, err
 	}
 
-	// Check Config Parameter
-	ok := c.checkFormatServerConf()
-	if !ok {
-		os.Exit(1)
-	}
+	// add [group.<name>] tags to member servers
+	c.ApplyGroupTags()
 
-	return
+	return c, nil
 }
 
 // checkFormatServerConfAuth checkes format of server config authentication.
@@ -301,6 +490,7 @@ func serverConfigReduct(perConfig, childConfig ServerConfig) ServerConfig {
 	resultMap := common.MapReduce(perConfigMap, childConfigMap)
 	_ = common.MapToStruct(resultMap, &result)
 	result.ProviderConfig = mergeProviderConfigMaps(perConfig.ProviderConfig, childConfig.ProviderConfig)
+	result.SetEnv = mergeSetEnv(perConfig.SetEnv, childConfig.SetEnv)
 
 	return result
 }
@@ -12,6 +12,7 @@ import (
 	"runtime"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/BurntSushi/toml"
 	"github.com/jackpal/gateway"
@@ -27,20 +28,50 @@ type matchContext struct {
 	Terms       []string
 	EnvNames    []string
 	EnvValues   map[string]string
+	DNSSearch   []string
+	Nameservers []netip.Addr
+	Now         time.Time
+	Probes      *matchProbes
 	LocalIPErr  error
 	GatewayErr  error
 	UsernameErr error
 	HostnameErr error
+	DNSErr      error
 }
 
 type matchRequirements struct {
-	needLocalIP  bool
-	needGateway  bool
-	needUsername bool
-	needHostname bool
-	needOS       bool
-	needTerm     bool
-	needEnv      bool
+	needLocalIP   bool
+	needGateway   bool
+	needUsername  bool
+	needHostname  bool
+	needOS        bool
+	needTerm      bool
+	needEnv       bool
+	needReachable bool
+	needDNS       bool
+	needTime      bool
+	needExec      bool
+}
+
+// add records the context values needed to evaluate when.
+func (r *matchRequirements) add(when ServerMatchWhen) {
+	r.needLocalIP = r.needLocalIP || len(when.LocalIPIn) > 0 || len(when.LocalIPNotIn) > 0
+	r.needGateway = r.needGateway || len(when.GatewayIn) > 0 || len(when.GatewayNotIn) > 0
+	r.needUsername = r.needUsername || len(when.UsernameIn) > 0 || len(when.UsernameNotIn) > 0
+	r.needHostname = r.needHostname || len(when.HostnameIn) > 0 || len(when.HostnameNotIn) > 0
+	r.needOS = r.needOS || len(when.OSIn) > 0 || len(when.OSNotIn) > 0
+	r.needTerm = r.needTerm || len(when.TermIn) > 0 || len(when.TermNotIn) > 0
+	r.needEnv = r.needEnv || len(when.EnvIn) > 0 || len(when.EnvNotIn) > 0 || len(when.EnvValueIn) > 0 || len(when.EnvValueNotIn) > 0
+	r.needReachable = r.needReachable || len(when.ReachableIn) > 0 || len(when.ReachableNotIn) > 0
+	r.needDNS = r.needDNS || len(when.DNSSearchIn) > 0 || len(when.DNSSearchNotIn) > 0 || len(when.NameserverIn) > 0 || len(when.NameserverNotIn) > 0
+	r.needTime = r.needTime || len(when.TimeIn) > 0 || len(when.TimeNotIn) > 0 || len(when.WeekdayIn) > 0 || len(when.WeekdayNotIn) > 0
+	r.needExec = r.needExec || len(when.ExecIn) > 0 || len(when.ExecNotIn) > 0
+}
+
+// any reports whether any context value is needed.
+func (r matchRequirements) any() bool {
+	return r.needLocalIP || r.needGateway || r.needUsername || r.needHostname || r.needOS || r.needTerm || r.needEnv ||
+		r.needReachable || r.needDNS || r.needTime || r.needExec
 }
 
 type namedMatch struct {
@@ -93,14 +124,20 @@ const (
 func applyMatchMetadata(c *Config, md toml.MetaData) {
 	order := 0
 	for _, key := range md.Keys() {
-		if len(key) < 4 || key[0] != "server" || key[2] != "match" {
+		if len(key) < 4 || (key[0] != "server" && key[0] != "template") || key[2] != "match" {
 			continue
 		}
 
+		section := key[0]
 		serverName := key[1]
 		branchName := key[3]
 
-		serverConf, ok := c.Server[serverName]
+		servers := c.Server
+		if section == "template" {
+			servers = c.Template
+		}
+
+		serverConf, ok := servers[serverName]
 		if !ok || serverConf.Match == nil {
 			continue
 		}
@@ -114,13 +151,13 @@ func applyMatchMetadata(c *Config, md toml.MetaData) {
 			order++
 			matchConf.order = order
 		}
-		if md.IsDefined("server", serverName, "match", branchName, "priority") {
+		if md.IsDefined(section, serverName, "match", branchName, "priority") {
 			matchConf.priorityDefined = true
 		}
-		matchConf.definedKeys = collectDefinedMatchKeys(md, serverName, branchName)
+		matchConf.definedKeys = collectDefinedMatchKeys(md, section, serverName, branchName)
 
 		serverConf.Match[branchName] = matchConf
-		c.Server[serverName] = serverConf
+		servers[serverName] = serverConf
 	}
 }
 
@@ -169,12 +206,12 @@ func applyProviderMatchMetadata(c *Config, md toml.MetaData) {
 	}
 }
 
-func collectDefinedMatchKeys(md toml.MetaData, serverName, branchName string) map[string]bool {
+func collectDefinedMatchKeys(md toml.MetaData, section, serverName, branchName string) map[string]bool {
 	keys := []string{
 		"addr", "port", "user", "pass", "pass_ref", "passes", "key", "key_ref", "keycmd", "keycmdpass", "keycmdpass_ref", "keypass", "keypass_ref",
 		"keys", "cert", "cert_ref", "certs", "certkey", "certkey_ref", "certkeypass", "certkeypass_ref", "certpkcs11", "agentauth",
 		"ssh_agent", "ssh_agent_key", "pkcs11", "pkcs11provider", "pkcs11pin", "pkcs11pin_ref", "pre_cmd",
-		"post_cmd", "proxy_type", "proxy", "proxy_cmd", "local_rc", "local_rc_file",
+		"post_cmd", "set_env", "send_env", "set_env_export", "remote_command", "proxy_type", "proxy", "proxy_cmd", "local_rc", "local_rc_file",
 		"local_rc_compress", "local_rc_decode_cmd", "local_rc_uncompress_cmd", "port_forward",
 		"port_forward_local", "port_forward_remote", "port_forwards", "dynamic_port_forward",
 		"reverse_dynamic_port_forward", "http_dynamic_port_forward",
@@ -184,12 +221,13 @@ func collectDefinedMatchKeys(md toml.MetaData, serverName, branchName string) ma
 		"smb_reverse_dynamic_forward", "smb_reverse_dynamic_forward_path",
 		"x11", "x11_trusted",
 		"connect_timeout", "alive_max", "alive_interval", "check_known_hosts",
-		"known_hosts_files", "control_master", "control_path", "control_persist", "note", "ignore",
+		"known_hosts_files", "strict_host_key_checking", "host_key_fingerprints", "control_master",
+		"control_path", "control_persist", "note", "tags", "ignore",
 	}
 
 	defined := make(map[string]bool, len(keys))
 	for _, key := range keys {
-		if md.IsDefined("server", serverName, "match", branchName, key) {
+		if md.IsDefined(section, serverName, "match", branchName, key) {
 			defined[key] = true
 		}
 	}
@@ -216,12 +254,18 @@ func applyYAMLMatchMetadata(data []byte, c *Config) error {
 		return err
 	}
 
-	serversNode := yamlMapValue(root, "server")
+	order := 0
+	applyYAMLServerMatchMetadata(yamlMapValue(root, "server"), c.Server, &order)
+	applyYAMLServerMatchMetadata(yamlMapValue(root, "template"), c.Template, &order)
+
+	return nil
+}
+
+func applyYAMLServerMatchMetadata(serversNode *yaml.Node, servers map[string]ServerConfig, order *int) {
 	if serversNode == nil || serversNode.Kind != yaml.MappingNode {
-		return nil
+		return
 	}
 
-	order := 0
 	for i := 0; i+1 < len(serversNode.Content); i += 2 {
 		serverName := serversNode.Content[i].Value
 		serverNode := serversNode.Content[i+1]
@@ -234,7 +278,7 @@ func applyYAMLMatchMetadata(data []byte, c *Config) error {
 			continue
 		}
 
-		serverConf, ok := c.Server[serverName]
+		serverConf, ok := servers[serverName]
 		if !ok || serverConf.Match == nil {
 			continue
 		}
@@ -249,8 +293,8 @@ func applyYAMLMatchMetadata(data []byte, c *Config) error {
 			}
 
 			if matchConf.order == 0 {
-				order++
-				matchConf.order = order
+				*order++
+				matchConf.order = *order
 			}
 			matchConf.priorityDefined = yamlMapHasKey(branchNode, "priority")
 			matchConf.definedKeys = collectDefinedYAMLMatchKeys(branchNode)
@@ -258,10 +302,8 @@ func applyYAMLMatchMetadata(data []byte, c *Config) error {
 			serverConf.Match[branchName] = matchConf
 		}
 
-		c.Server[serverName] = serverConf
+		servers[serverName] = serverConf
 	}
-
-	return nil
 }
 
 func applyYAMLProviderMatchMetadata(root *yaml.Node, c *Config) error {
@@ -330,7 +372,7 @@ func collectDefinedYAMLMatchKeys(branchNode *yaml.Node) map[string]bool {
 		"addr", "port", "user", "pass", "pass_ref", "passes", "key", "key_ref", "keycmd", "keycmdpass", "keycmdpass_ref", "keypass", "keypass_ref",
 		"keys", "cert", "cert_ref", "certs", "certkey", "certkey_ref", "certkeypass", "certkeypass_ref", "certpkcs11", "agentauth",
 		"ssh_agent", "ssh_agent_key", "pkcs11", "pkcs11provider", "pkcs11pin", "pkcs11pin_ref", "pre_cmd",
-		"post_cmd", "proxy_type", "proxy", "proxy_cmd", "local_rc", "local_rc_file",
+		"post_cmd", "set_env", "send_env", "set_env_export", "remote_command", "proxy_type", "proxy", "proxy_cmd", "local_rc", "local_rc_file",
 		"local_rc_compress", "local_rc_decode_cmd", "local_rc_uncompress_cmd", "port_forward",
 		"port_forward_local", "port_forward_remote", "port_forwards", "dynamic_port_forward",
 		"reverse_dynamic_port_forward", "http_dynamic_port_forward",
@@ -340,7 +382,8 @@ func collectDefinedYAMLMatchKeys(branchNode *yaml.Node) map[string]bool {
 		"smb_reverse_dynamic_forward", "smb_reverse_dynamic_forward_path",
 		"x11", "x11_trusted",
 		"connect_timeout", "alive_max", "alive_interval", "check_known_hosts",
-		"known_hosts_files", "control_master", "control_path", "control_persist", "note", "ignore",
+		"known_hosts_files", "strict_host_key_checking", "host_key_fingerprints", "control_master",
+		"control_path", "control_persist", "note", "tags", "ignore",
 	}
 
 	defined := make(map[string]bool, len(keys))
@@ -376,7 +419,7 @@ func (c *Config) ResolveConditionalMatches() error {
 	if err != nil {
 		return err
 	}
-	if !reqs.needLocalIP && !reqs.needGateway && !reqs.needUsername && !reqs.needHostname && !reqs.needOS && !reqs.needTerm && !reqs.needEnv {
+	if !reqs.any() {
 		return nil
 	}
 
@@ -395,7 +438,11 @@ func (c *Config) ResolveConditionalMatches() error {
 				continue
 			}
 
-			merged = mergeServerMatchConfig(merged, branch.config)
+			label := "server." + serverName + ".match." + branch.name
+			next := mergeServerMatchConfig(merged, branch.config)
+			c.addMatchedBranch(serverName, label)
+			c.recordFieldSources(serverName, merged, next, fixedFieldSource(label))
+			merged = next
 		}
 		merged.Match = serverConf.Match
 		c.Server[serverName] = merged
@@ -413,32 +460,39 @@ func (c *Config) validateMatchConfigs() (matchRequirements, error) {
 				return reqs, fmt.Errorf("server.%s.match.%s: at least one when.* condition is required", serverName, branchName)
 			}
 
-			if err := validateMatchNetworkList(branch.When.LocalIPIn, "local_ip_in", serverName, branchName); err != nil {
-				return reqs, err
-			}
-			if err := validateMatchNetworkList(branch.When.LocalIPNotIn, "local_ip_not_in", serverName, branchName); err != nil {
-				return reqs, err
-			}
-			if err := validateMatchNetworkList(branch.When.GatewayIn, "gateway_in", serverName, branchName); err != nil {
-				return reqs, err
-			}
-			if err := validateMatchNetworkList(branch.When.GatewayNotIn, "gateway_not_in", serverName, branchName); err != nil {
+			if err := validateMatchWhen(branch.When, serverName, branchName); err != nil {
 				return reqs, err
 			}
 
-			reqs.needLocalIP = reqs.needLocalIP || len(branch.When.LocalIPIn) > 0 || len(branch.When.LocalIPNotIn) > 0
-			reqs.needGateway = reqs.needGateway || len(branch.When.GatewayIn) > 0 || len(branch.When.GatewayNotIn) > 0
-			reqs.needUsername = reqs.needUsername || len(branch.When.UsernameIn) > 0 || len(branch.When.UsernameNotIn) > 0
-			reqs.needHostname = reqs.needHostname || len(branch.When.HostnameIn) > 0 || len(branch.When.HostnameNotIn) > 0
-			reqs.needOS = reqs.needOS || len(branch.When.OSIn) > 0 || len(branch.When.OSNotIn) > 0
-			reqs.needTerm = reqs.needTerm || len(branch.When.TermIn) > 0 || len(branch.When.TermNotIn) > 0
-			reqs.needEnv = reqs.needEnv || len(branch.When.EnvIn) > 0 || len(branch.When.EnvNotIn) > 0 || len(branch.When.EnvValueIn) > 0 || len(branch.When.EnvValueNotIn) > 0
+			reqs.add(branch.When)
 		}
 	}
 
 	return reqs, nil
 }
 
+// validateMatchWhen checks the values of when.
+func validateMatchWhen(when ServerMatchWhen, serverName, branchName string) error {
+	networkLists := []struct {
+		key    string
+		values []string
+	}{
+		{"local_ip_in", when.LocalIPIn},
+		{"local_ip_not_in", when.LocalIPNotIn},
+		{"gateway_in", when.GatewayIn},
+		{"gateway_not_in", when.GatewayNotIn},
+		{"nameserver_in", when.NameserverIn},
+		{"nameserver_not_in", when.NameserverNotIn},
+	}
+	for _, list := range networkLists {
+		if err := validateMatchNetworkList(list.values, list.key, serverName, branchName); err != nil {
+			return err
+		}
+	}
+
+	return validateMatchConditionLists(when, serverName, branchName)
+}
+
 func validateMatchNetworkList(values []string, key, serverName, branchName string) error {
 	for _, value := range values {
 		if _, _, err := parseIPorPrefix(value); err != nil {
@@ -537,6 +591,49 @@ func whenMatches(when ServerMatchWhen, serverName, branchName string, ctx matchC
 		return false
 	}
 
+	if len(when.DNSSearchIn)+len(when.DNSSearchNotIn)+len(when.NameserverIn)+len(when.NameserverNotIn) > 0 && ctx.DNSErr != nil {
+		log.Printf("server.%s.match.%s: resolv.conf lookup failed: %v", serverName, branchName, ctx.DNSErr)
+	}
+	if len(when.DNSSearchIn) > 0 && !matchAnyStringList(ctx.DNSSearch, normalizeDNSNames(when.DNSSearchIn)) {
+		return false
+	}
+	if len(when.DNSSearchNotIn) > 0 && matchAnyStringList(ctx.DNSSearch, normalizeDNSNames(when.DNSSearchNotIn)) {
+		return false
+	}
+	if len(when.NameserverIn) > 0 && !matchIPList(ctx.Nameservers, when.NameserverIn) {
+		return false
+	}
+	if len(when.NameserverNotIn) > 0 && matchIPList(ctx.Nameservers, when.NameserverNotIn) {
+		return false
+	}
+
+	if len(when.TimeIn) > 0 && !matchTimeList(ctx.Now, when.TimeIn) {
+		return false
+	}
+	if len(when.TimeNotIn) > 0 && matchTimeList(ctx.Now, when.TimeNotIn) {
+		return false
+	}
+	if len(when.WeekdayIn) > 0 && !matchWeekdayList(ctx.Now, when.WeekdayIn) {
+		return false
+	}
+	if len(when.WeekdayNotIn) > 0 && matchWeekdayList(ctx.Now, when.WeekdayNotIn) {
+		return false
+	}
+
+	// reachable and exec are evaluated last, and only when needed
+	if len(when.ReachableIn) > 0 && !ctx.Probes.anyReachable(when.ReachableIn) {
+		return false
+	}
+	if len(when.ReachableNotIn) > 0 && ctx.Probes.anyReachable(when.ReachableNotIn) {
+		return false
+	}
+	if len(when.ExecIn) > 0 && !ctx.Probes.anyExecSucceeds(when.ExecIn) {
+		return false
+	}
+	if len(when.ExecNotIn) > 0 && ctx.Probes.anyExecSucceeds(when.ExecNotIn) {
+		return false
+	}
+
 	return true
 }
 
@@ -548,11 +645,11 @@ func (c *Config) activeOpenSSHConfigs() []OpenSSHConfig {
 	reqs, err := c.validateOpenSSHConfigWhens()
 	if err != nil {
 		log.Println(err)
-		os.Exit(1)
+		Exit(1)
 	}
 
 	ctx := matchContext{}
-	if reqs.needLocalIP || reqs.needGateway || reqs.needUsername || reqs.needHostname || reqs.needOS || reqs.needTerm || reqs.needEnv {
+	if reqs.any() {
 		ctx = detectMatchContext(reqs)
 	}
 
@@ -588,26 +685,11 @@ func (c *Config) validateOpenSSHConfigWhens() (matchRequirements, error) {
 			continue
 		}
 
-		if err := validateMatchNetworkList(sc.When.LocalIPIn, "local_ip_in", "sshconfig", name); err != nil {
-			return reqs, err
-		}
-		if err := validateMatchNetworkList(sc.When.LocalIPNotIn, "local_ip_not_in", "sshconfig", name); err != nil {
-			return reqs, err
-		}
-		if err := validateMatchNetworkList(sc.When.GatewayIn, "gateway_in", "sshconfig", name); err != nil {
-			return reqs, err
-		}
-		if err := validateMatchNetworkList(sc.When.GatewayNotIn, "gateway_not_in", "sshconfig", name); err != nil {
+		if err := validateMatchWhen(sc.When, "sshconfig", name); err != nil {
 			return reqs, err
 		}
 
-		reqs.needLocalIP = reqs.needLocalIP || len(sc.When.LocalIPIn) > 0 || len(sc.When.LocalIPNotIn) > 0
-		reqs.needGateway = reqs.needGateway || len(sc.When.GatewayIn) > 0 || len(sc.When.GatewayNotIn) > 0
-		reqs.needUsername = reqs.needUsername || len(sc.When.UsernameIn) > 0 || len(sc.When.UsernameNotIn) > 0
-		reqs.needHostname = reqs.needHostname || len(sc.When.HostnameIn) > 0 || len(sc.When.HostnameNotIn) > 0
-		reqs.needOS = reqs.needOS || len(sc.When.OSIn) > 0 || len(sc.When.OSNotIn) > 0
-		reqs.needTerm = reqs.needTerm || len(sc.When.TermIn) > 0 || len(sc.When.TermNotIn) > 0
-		reqs.needEnv = reqs.needEnv || len(sc.When.EnvIn) > 0 || len(sc.When.EnvNotIn) > 0 || len(sc.When.EnvValueIn) > 0 || len(sc.When.EnvValueNotIn) > 0
+		reqs.add(sc.When)
 	}
 
 	return reqs, nil
@@ -723,6 +805,15 @@ func buildMatchContext(reqs matchRequirements) matchContext {
 		ctx.EnvNames = getEnvNames()
 		ctx.EnvValues = getEnvValues()
 	}
+	if reqs.needDNS {
+		ctx.DNSSearch, ctx.Nameservers, ctx.DNSErr = readResolvConf(resolvConfPath)
+	}
+	if reqs.needTime {
+		ctx.Now = matchNow()
+	}
+	if reqs.needReachable || reqs.needExec {
+		ctx.Probes = sharedMatchProbes
+	}
 
 	return ctx
 }
@@ -0,0 +1,296 @@
+// Copyright (c) 2026 Blacknon. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package conf
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/netip"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	resolvConfPath        = "/etc/resolv.conf"
+	matchNow              = time.Now
+	matchReachableTimeout = 500 * time.Millisecond
+	dialMatchEndpoint     = func(addr string, timeout time.Duration) error {
+		conn, err := net.DialTimeout("tcp", addr, timeout)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+	runMatchExec = func(command string) error {
+		var cmd *exec.Cmd
+		if runtime.GOOS == "windows" {
+			cmd = exec.Command("cmd", "/C", command)
+		} else {
+			cmd = exec.Command("sh", "-c", command)
+		}
+		cmd.Stderr = os.Stderr
+		return cmd.Run()
+	}
+)
+
+// sharedMatchProbes caches reachable and exec results for the whole
+// invocation, so each endpoint or command is checked at most once even when
+// several branches, sshconfig blocks and providers use it.
+var sharedMatchProbes = newMatchProbes()
+
+// matchProbes evaluates `when.reachable_*` and `when.exec_*` lazily.
+type matchProbes struct {
+	mu        sync.Mutex
+	reachable map[string]bool
+	exec      map[string]bool
+}
+
+func newMatchProbes() *matchProbes {
+	return &matchProbes{
+		reachable: map[string]bool{},
+		exec:      map[string]bool{},
+	}
+}
+
+// anyReachable reports whether any of addrs accepts a TCP connection.
+func (p *matchProbes) anyReachable(addrs []string) bool {
+	if p == nil {
+		return false
+	}
+
+	for _, addr := range addrs {
+		p.mu.Lock()
+		ok, checked := p.reachable[addr]
+		p.mu.Unlock()
+
+		if !checked {
+			ok = dialMatchEndpoint(addr, matchReachableTimeout) == nil
+			p.mu.Lock()
+			p.reachable[addr] = ok
+			p.mu.Unlock()
+		}
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+// anyExecSucceeds reports whether any of commands exits with status 0.
+func (p *matchProbes) anyExecSucceeds(commands []string) bool {
+	if p == nil {
+		return false
+	}
+
+	for _, command := range commands {
+		p.mu.Lock()
+		ok, checked := p.exec[command]
+		p.mu.Unlock()
+
+		if !checked {
+			ok = runMatchExec(command) == nil
+			p.mu.Lock()
+			p.exec[command] = ok
+			p.mu.Unlock()
+		}
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+// readResolvConf returns the search domains and nameservers in path.
+func readResolvConf(path string) (search []string, nameservers []netip.Addr, err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || strings.HasPrefix(fields[0], "#") || strings.HasPrefix(fields[0], ";") {
+			continue
+		}
+
+		switch fields[0] {
+		case "search", "domain":
+			search = append(search, normalizeDNSNames(fields[1:])...)
+		case "nameserver":
+			// strip IPv6 zone (ex. fe80::1%eth0)
+			value, _, _ := strings.Cut(fields[1], "%")
+			if addr, err := netip.ParseAddr(value); err == nil {
+				nameservers = append(nameservers, addr.Unmap())
+			}
+		}
+	}
+
+	return uniqueStrings(search), uniqueAddrs(nameservers), scanner.Err()
+}
+
+func normalizeDNSNames(values []string) []string {
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		result = append(result, strings.ToLower(strings.TrimSuffix(strings.TrimSpace(value), ".")))
+	}
+	return result
+}
+
+// parseMatchTimeRange parses `HH:MM-HH:MM` into minutes of the day. The end
+// is exclusive, and a range whose end is before its start wraps past midnight.
+func parseMatchTimeRange(value string) (start, end int, err error) {
+	from, to, ok := strings.Cut(value, "-")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid time range %q (want HH:MM-HH:MM)", value)
+	}
+
+	if start, err = parseMatchClock(from); err != nil {
+		return 0, 0, fmt.Errorf("invalid time range %q: %w", value, err)
+	}
+	if end, err = parseMatchClock(to); err != nil {
+		return 0, 0, fmt.Errorf("invalid time range %q: %w", value, err)
+	}
+	return start, end, nil
+}
+
+func parseMatchClock(value string) (int, error) {
+	hour, minute, ok := strings.Cut(strings.TrimSpace(value), ":")
+	if !ok {
+		return 0, fmt.Errorf("invalid time %q", value)
+	}
+
+	h, err := strconv.Atoi(hour)
+	if err != nil || h < 0 || h > 24 {
+		return 0, fmt.Errorf("invalid time %q", value)
+	}
+	m, err := strconv.Atoi(minute)
+	if err != nil || m < 0 || m > 59 || (h == 24 && m != 0) {
+		return 0, fmt.Errorf("invalid time %q", value)
+	}
+	return h*60 + m, nil
+}
+
+func matchTimeList(now time.Time, ranges []string) bool {
+	current := now.Hour()*60 + now.Minute()
+	for _, value := range ranges {
+		start, end, err := parseMatchTimeRange(value)
+		if err != nil {
+			continue
+		}
+
+		if start <= end {
+			if current >= start && current < end {
+				return true
+			}
+		} else if current >= start || current < end {
+			return true
+		}
+	}
+	return false
+}
+
+var matchWeekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday, "sunday": time.Sunday,
+	"mon": time.Monday, "monday": time.Monday,
+	"tue": time.Tuesday, "tuesday": time.Tuesday,
+	"wed": time.Wednesday, "wednesday": time.Wednesday,
+	"thu": time.Thursday, "thursday": time.Thursday,
+	"fri": time.Friday, "friday": time.Friday,
+	"sat": time.Saturday, "saturday": time.Saturday,
+}
+
+// parseMatchWeekdays parses a weekday (`mon`) or a range (`mon-fri`,
+// `fri-mon`) into the weekdays it covers.
+func parseMatchWeekdays(value string) ([7]bool, error) {
+	var days [7]bool
+
+	from, to, isRange := strings.Cut(strings.ToLower(strings.TrimSpace(value)), "-")
+	start, ok := matchWeekdayNames[from]
+	if !ok {
+		return days, fmt.Errorf("invalid weekday %q", value)
+	}
+	end := start
+	if isRange {
+		if end, ok = matchWeekdayNames[to]; !ok {
+			return days, fmt.Errorf("invalid weekday %q", value)
+		}
+	}
+
+	for day := start; ; day = (day + 1) % 7 {
+		days[day] = true
+		if day == end {
+			break
+		}
+	}
+	return days, nil
+}
+
+func matchWeekdayList(now time.Time, values []string) bool {
+	for _, value := range values {
+		days, err := parseMatchWeekdays(value)
+		if err == nil && days[now.Weekday()] {
+			return true
+		}
+	}
+	return false
+}
+
+// validateMatchConditionLists checks reachable, time, weekday and exec values.
+func validateMatchConditionLists(when ServerMatchWhen, serverName, branchName string) error {
+	type conditionList struct {
+		key    string
+		values []string
+		check  func(string) error
+	}
+
+	checkEndpoint := func(value string) error {
+		if _, port, err := net.SplitHostPort(value); err != nil || port == "" {
+			return fmt.Errorf("invalid endpoint %q (want host:port)", value)
+		}
+		return nil
+	}
+	checkTime := func(value string) error {
+		_, _, err := parseMatchTimeRange(value)
+		return err
+	}
+	checkWeekday := func(value string) error {
+		_, err := parseMatchWeekdays(value)
+		return err
+	}
+	checkCommand := func(value string) error {
+		if strings.TrimSpace(value) == "" {
+			return fmt.Errorf("empty command")
+		}
+		return nil
+	}
+
+	lists := []conditionList{
+		{"reachable_in", when.ReachableIn, checkEndpoint},
+		{"reachable_not_in", when.ReachableNotIn, checkEndpoint},
+		{"time_in", when.TimeIn, checkTime},
+		{"time_not_in", when.TimeNotIn, checkTime},
+		{"weekday_in", when.WeekdayIn, checkWeekday},
+		{"weekday_not_in", when.WeekdayNotIn, checkWeekday},
+		{"exec_in", when.ExecIn, checkCommand},
+		{"exec_not_in", when.ExecNotIn, checkCommand},
+	}
+	for _, list := range lists {
+		for _, value := range list.values {
+			if err := list.check(value); err != nil {
+				return fmt.Errorf("server.%s.match.%s.when.%s: %w", serverName, branchName, list.key, err)
+			}
+		}
+	}
+
+	return nil
+}
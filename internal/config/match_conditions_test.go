@@ -0,0 +1,183 @@
+package conf
+
+import (
+	"errors"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWhenMatchesTimeAndWeekday(t *testing.T) {
+	// 2026-10-16 is a Friday
+	ctx := matchContext{Now: time.Date(2026, 10, 16, 23, 30, 0, 0, time.Local)}
+
+	tests := []struct {
+		name string
+		when ServerMatchWhen
+		want bool
+	}{
+		{"inside range", ServerMatchWhen{TimeIn: []string{"09:00-23:45"}}, true},
+		{"end is exclusive", ServerMatchWhen{TimeIn: []string{"09:00-23:30"}}, false},
+		{"wraps midnight", ServerMatchWhen{TimeIn: []string{"22:00-06:00"}}, true},
+		{"not in", ServerMatchWhen{TimeNotIn: []string{"22:00-06:00"}}, false},
+		{"weekday", ServerMatchWhen{WeekdayIn: []string{"Fri"}}, true},
+		{"weekday range", ServerMatchWhen{WeekdayIn: []string{"mon-fri"}}, true},
+		{"weekday wrap range", ServerMatchWhen{WeekdayIn: []string{"sat-mon"}}, false},
+		{"weekday not in", ServerMatchWhen{WeekdayNotIn: []string{"friday"}}, false},
+	}
+	for _, tt := range tests {
+		if got := whenMatches(tt.when, "app", tt.name, ctx); got != tt.want {
+			t.Fatalf("%s: whenMatches() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestWhenMatchesDNS(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "resolv.conf")
+	if err := os.WriteFile(path, []byte(`# comment
+search corp.example.com. lab.example.com
+nameserver 10.0.0.53
+nameserver fe80::1%eth0
+`), 0o600); err != nil {
+		t.Fatalf("write resolv.conf: %v", err)
+	}
+
+	search, nameservers, err := readResolvConf(path)
+	if err != nil {
+		t.Fatalf("readResolvConf() error = %v", err)
+	}
+	ctx := matchContext{DNSSearch: search, Nameservers: nameservers}
+
+	if !whenMatches(ServerMatchWhen{DNSSearchIn: []string{"CORP.example.com"}}, "app", "dns", ctx) {
+		t.Fatalf("dns_search_in did not match %v", search)
+	}
+	if whenMatches(ServerMatchWhen{DNSSearchNotIn: []string{"lab.example.com."}}, "app", "dns", ctx) {
+		t.Fatalf("dns_search_not_in matched %v", search)
+	}
+	if !whenMatches(ServerMatchWhen{NameserverIn: []string{"10.0.0.0/24"}}, "app", "dns", ctx) {
+		t.Fatalf("nameserver_in did not match %v", nameservers)
+	}
+	if !whenMatches(ServerMatchWhen{NameserverIn: []string{"fe80::1"}}, "app", "dns", ctx) {
+		t.Fatalf("nameserver_in did not match IPv6 with zone %v", nameservers)
+	}
+	if whenMatches(ServerMatchWhen{NameserverNotIn: []string{"10.0.0.53"}}, "app", "dns", ctx) {
+		t.Fatalf("nameserver_not_in matched %v", nameservers)
+	}
+}
+
+func TestWhenMatchesReachableAndExecAreCached(t *testing.T) {
+	originalDial, originalExec := dialMatchEndpoint, runMatchExec
+	t.Cleanup(func() { dialMatchEndpoint, runMatchExec = originalDial, originalExec })
+
+	dials := map[string]int{}
+	dialMatchEndpoint = func(addr string, timeout time.Duration) error {
+		dials[addr]++
+		if addr == "10.0.0.1:22" {
+			return nil
+		}
+		return errors.New("timeout")
+	}
+	execs := map[string]int{}
+	runMatchExec = func(command string) error {
+		execs[command]++
+		if command == "true" {
+			return nil
+		}
+		return errors.New("exit status 1")
+	}
+
+	ctx := matchContext{Probes: newMatchProbes()}
+	for i := 0; i < 2; i++ {
+		if !whenMatches(ServerMatchWhen{ReachableIn: []string{"10.0.0.2:22", "10.0.0.1:22"}}, "app", "vpn", ctx) {
+			t.Fatalf("reachable_in did not match")
+		}
+		if whenMatches(ServerMatchWhen{ReachableNotIn: []string{"10.0.0.1:22"}}, "app", "novpn", ctx) {
+			t.Fatalf("reachable_not_in matched")
+		}
+		if !whenMatches(ServerMatchWhen{ExecIn: []string{"false", "true"}}, "app", "exec", ctx) {
+			t.Fatalf("exec_in did not match")
+		}
+		if whenMatches(ServerMatchWhen{ExecNotIn: []string{"true"}}, "app", "exec", ctx) {
+			t.Fatalf("exec_not_in matched")
+		}
+	}
+
+	for addr, count := range dials {
+		if count != 1 {
+			t.Fatalf("%s dialed %d times, want 1", addr, count)
+		}
+	}
+	for command, count := range execs {
+		if count != 1 {
+			t.Fatalf("%q ran %d times, want 1", command, count)
+		}
+	}
+
+	// conditions after a failed one are not evaluated
+	whenMatches(ServerMatchWhen{OSIn: []string{"plan9"}, ExecIn: []string{"never"}}, "app", "skip", ctx)
+	if execs["never"] != 0 {
+		t.Fatalf("exec ran for a branch that already failed")
+	}
+}
+
+func TestResolveConditionalMatchesWithReachable(t *testing.T) {
+	originalDetector := detectMatchContext
+	t.Cleanup(func() { detectMatchContext = originalDetector })
+
+	var gotReqs matchRequirements
+	detectMatchContext = func(reqs matchRequirements) matchContext {
+		gotReqs = reqs
+		return matchContext{
+			Nameservers: []netip.Addr{netip.MustParseAddr("10.0.0.53")},
+			Probes:      &matchProbes{reachable: map[string]bool{"10.0.0.1:22": true}, exec: map[string]bool{}},
+		}
+	}
+
+	cfg := Config{
+		Server: map[string]ServerConfig{
+			"app": {
+				Addr:  "192.0.2.10",
+				Proxy: "bastion",
+				Match: map[string]ServerMatchConfig{
+					"vpn": {
+						When:        ServerMatchWhen{ReachableIn: []string{"10.0.0.1:22"}, NameserverIn: []string{"10.0.0.53"}},
+						Proxy:       "",
+						definedKeys: map[string]bool{"proxy": true},
+					},
+				},
+			},
+		},
+	}
+
+	if err := cfg.ResolveConditionalMatches(); err != nil {
+		t.Fatalf("ResolveConditionalMatches() error = %v", err)
+	}
+	if !gotReqs.needReachable || !gotReqs.needDNS || gotReqs.needExec {
+		t.Fatalf("requirements = %+v", gotReqs)
+	}
+	if cfg.Server["app"].Proxy != "" {
+		t.Fatalf("proxy = %q, want direct connection when vpn is reachable", cfg.Server["app"].Proxy)
+	}
+}
+
+func TestValidateMatchWhenRejectsInvalidConditions(t *testing.T) {
+	tests := []struct {
+		when ServerMatchWhen
+		want string
+	}{
+		{ServerMatchWhen{ReachableIn: []string{"10.0.0.1"}}, "when.reachable_in: invalid endpoint"},
+		{ServerMatchWhen{TimeIn: []string{"9-18"}}, "when.time_in: invalid time range"},
+		{ServerMatchWhen{WeekdayNotIn: []string{"someday"}}, "when.weekday_not_in: invalid weekday"},
+		{ServerMatchWhen{ExecIn: []string{" "}}, "when.exec_in: empty command"},
+		{ServerMatchWhen{NameserverIn: []string{"dns"}}, "when.nameserver_in: invalid IP/CIDR"},
+	}
+	for _, tt := range tests {
+		err := validateMatchWhen(tt.when, "app", "branch")
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Fatalf("validateMatchWhen(%+v) error = %v, want %q", tt.when, err, tt.want)
+		}
+	}
+}
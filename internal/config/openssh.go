@@ -19,6 +19,7 @@ import (
 	"strings"
 
 	"github.com/blacknon/lssh/internal/common"
+	shellquote "github.com/kballard/go-shellquote"
 	"github.com/kevinburke/ssh_config"
 )
 
@@ -73,7 +74,7 @@ func getOpenSSHConfig(path, command string) (config map[string]ServerConfig, err
 	return config, err
 }
 
-func (c *Config) readConfiguredOpenSSHConfig(sc OpenSSHConfig) error {
+func (c *Config) readConfiguredOpenSSHConfig(common ServerConfig, commonSources map[string]string, sc OpenSSHConfig) error {
 	entries, err := loadOpenSSHConfigEntries(sc.Path, sc.Command)
 	if err != nil {
 		return err
@@ -89,13 +90,25 @@ func (c *Config) readConfiguredOpenSSHConfig(sc OpenSSHConfig) error {
 		ele = "generate_sshconfig"
 	}
 
-	base := serverConfigReduct(c.Common, sc.ServerConfig)
+	base := serverConfigReduct(common, sc.ServerConfig)
+	baseSources := commonFieldSources(sc.ServerConfig, "sshconfig", commonSources)
 	for _, entry := range entries {
+		name := ele + ":" + entry.Host
+		c.resetFieldSources(name)
+
 		value := entry.Config
 		value.Note = "from:" + ele
-		value = serverConfigReduct(base, value)
-		value = applyOpenSSHImportMatches(entry.Host, value, matches)
-		c.Server[ele+":"+entry.Host] = value
+		c.recordFieldSources(name, ServerConfig{}, value, fixedFieldSource(fileFieldSource("ssh_config", ele)))
+
+		merged := serverConfigReduct(base, value)
+		c.recordFieldSources(name, value, merged, commonFieldSource(baseSources))
+
+		merged = applyOpenSSHImportMatches(entry.Host, merged, matches, func(branch string, before, after ServerConfig) {
+			c.addMatchedBranch(name, "sshconfig.match."+branch)
+			c.recordFieldSources(name, before, after, fixedFieldSource("sshconfig.match."+branch))
+		})
+		c.Server[name] = merged
+		c.addServerSource(name, ele)
 	}
 
 	return nil
@@ -171,11 +184,17 @@ func decodeOpenSSHImportMatches(raw map[string]map[string]interface{}) ([]openSS
 	return result, nil
 }
 
-func applyOpenSSHImportMatches(host string, base ServerConfig, matches []openSSHImportMatch) ServerConfig {
+// applyOpenSSHImportMatches applies the matching branches to base. onApply,
+// if not nil, is called with the config before and after each branch.
+func applyOpenSSHImportMatches(host string, base ServerConfig, matches []openSSHImportMatch, onApply func(branch string, before, after ServerConfig)) ServerConfig {
 	current := base
 	for _, match := range matches {
 		if openSSHImportMatchApplies(host, current, match) {
-			current = serverConfigReduct(current, match.Config)
+			next := serverConfigReduct(current, match.Config)
+			if onApply != nil {
+				onApply(match.Name, current, next)
+			}
+			current = next
 		}
 	}
 	return current
@@ -261,6 +280,12 @@ func loadOpenSSHConfigEntries(path, command string) ([]openSSHConfigEntry, error
 			User:         getOpenSSHValue(cfg, host, "User"),
 			ProxyCommand: getOpenSSHValue(cfg, host, "ProxyCommand"),
 			PreCmd:       getOpenSSHValue(cfg, host, "LocalCommand"),
+			SetEnv:       getOpenSSHSetEnv(cfg, host),
+			SendEnv:      getOpenSSHSendEnv(cfg, host),
+		}
+
+		if remoteCommand := getOpenSSHValue(cfg, host, "RemoteCommand"); remoteCommand != "none" {
+			serverConfig.RemoteCommand = remoteCommand
 		}
 
 		if serverConfig.Addr == "" {
@@ -475,6 +500,56 @@ func getOpenSSHValues(cfg *ssh_config.Config, host, key string) []string {
 	return result
 }
 
+// getOpenSSHSetEnv parses `SetEnv NAME=VALUE ...`. As in ssh(1), the first
+// value obtained for a name is used.
+func getOpenSSHSetEnv(cfg *ssh_config.Config, host string) map[string]string {
+	var env map[string]string
+	for _, value := range getOpenSSHValues(cfg, host, "SetEnv") {
+		items, err := shellquote.Split(value)
+		if err != nil {
+			continue
+		}
+		for _, item := range items {
+			name, envValue, ok := strings.Cut(item, "=")
+			if !ok || name == "" {
+				continue
+			}
+			if env == nil {
+				env = map[string]string{}
+			}
+			if _, exists := env[name]; !exists {
+				env[name] = envValue
+			}
+		}
+	}
+	return env
+}
+
+// getOpenSSHSendEnv returns the `SendEnv` patterns. A `-PATTERN` item removes
+// the patterns added before it that it matches.
+func getOpenSSHSendEnv(cfg *ssh_config.Config, host string) []string {
+	var patterns []string
+	for _, value := range getOpenSSHValues(cfg, host, "SendEnv") {
+		for _, item := range strings.Fields(value) {
+			if remove, ok := strings.CutPrefix(item, "-"); ok {
+				kept := patterns[:0]
+				for _, pattern := range patterns {
+					if matched, _ := path.Match(remove, pattern); !matched {
+						kept = append(kept, pattern)
+					}
+				}
+				patterns = kept
+				continue
+			}
+			patterns = append(patterns, item)
+		}
+	}
+	if len(patterns) == 0 {
+		return nil
+	}
+	return removeDupString(patterns)
+}
+
 func getOpenSSHIdentityFiles(cfg *ssh_config.Config, host string) []string {
 	values := getOpenSSHValues(cfg, host, "IdentityFile")
 	if len(values) == 0 {
@@ -74,6 +74,7 @@ func (c *Config) ReadInventoryProviders() error {
 			return fmt.Errorf("provider %q matches: %w", item.name, err)
 		}
 		base := serverConfigReduct(c.Common, defaults)
+		providerLabel := "provider." + item.name
 
 		for _, server := range result.inventory.Servers {
 			if server.Name == "" {
@@ -85,12 +86,34 @@ func (c *Config) ReadInventoryProviders() error {
 				return fmt.Errorf("provider %q server %q: %w", item.name, server.Name, err)
 			}
 
-			merged := serverConfigReduct(base, generated)
-			merged = applyProviderInventoryMatches(item.name, server.Name, server.Meta, merged, matches)
+			c.resetFieldSources(server.Name)
+			c.recordFieldSources(server.Name, ServerConfig{}, generated, fixedFieldSource(providerLabel))
+
+			merged := serverConfigReduct(defaults, generated)
+			c.recordFieldSources(server.Name, generated, merged, fixedFieldSource(providerLabel+" defaults"))
+
+			templated, err := c.applyServerTemplates("server."+server.Name, merged)
+			if err != nil {
+				return fmt.Errorf("provider %q server %q: %w", item.name, server.Name, err)
+			}
+			c.recordFieldSources(server.Name, merged, templated, c.templateFieldSource(merged.Extends))
+
+			merged = serverConfigReduct(base, templated)
+			c.recordFieldSources(server.Name, templated, merged, commonFieldSource(commonFieldSources(defaults, providerLabel+" defaults", c.commonSources)))
+
+			merged = applyProviderInventoryMatches(item.name, server.Name, server.Meta, merged, matches, func(branch string, before, after ServerConfig) {
+				c.addMatchedBranch(server.Name, providerLabel+".match."+branch)
+				c.recordFieldSources(server.Name, before, after, fixedFieldSource(providerLabel+".match."+branch))
+			})
 			merged.ProviderName = item.name
 			merged.ProviderPlugin = providerString(result.raw, "plugin")
 			merged.ProviderMeta = cloneProviderMeta(server.Meta)
-			c.Server[server.Name] = merged
+
+			tagged := merged
+			tagged.Tags = appendUniqueTags(merged.Tags, providerMetaTags(result.raw, server.Meta)...)
+			c.recordFieldSources(server.Name, merged, tagged, fixedFieldSource(providerLabel+" tag_meta_keys"))
+			c.Server[server.Name] = tagged
+			c.addServerSource(server.Name, "provider."+item.name)
 		}
 	}
 
@@ -129,17 +152,7 @@ func (c *Config) fetchInventoryProviderResults(providers []namedProviderConfig)
 				}()
 			}
 
-			results[i].raw = raw
-			if providerNeedsDescribeReservedKeys(raw) {
-				_ = c.callProvider(name, providerapi.MethodPluginDescribe, nil, &results[i].describe)
-			}
-			err := c.callProvider(name, providerapi.MethodInventoryList, providerapi.InventoryListParams{
-				Provider: name,
-				Config:   raw,
-			}, &results[i].inventory)
-			if err != nil {
-				results[i].err = err
-			}
+			results[i] = c.cachedInventoryProviderResult(name, raw)
 		}()
 	}
 
@@ -167,11 +180,11 @@ func (c *Config) activeProviders() []namedProviderConfig {
 	reqs, err := c.validateProviderWhens()
 	if err != nil {
 		log.Println(err)
-		os.Exit(1)
+		Exit(1)
 	}
 
 	ctx := matchContext{}
-	if reqs.needLocalIP || reqs.needGateway || reqs.needUsername || reqs.needHostname || reqs.needOS || reqs.needTerm || reqs.needEnv {
+	if reqs.any() {
 		ctx = detectMatchContext(reqs)
 	}
 
@@ -187,7 +200,7 @@ func (c *Config) activeProviders() []namedProviderConfig {
 		when, err := providerWhen(raw)
 		if err != nil {
 			log.Printf("provider.%s.when: %v", name, err)
-			os.Exit(1)
+			Exit(1)
 		}
 		if when.Empty() || whenMatches(when, "provider", name, ctx) {
 			result = append(result, namedProviderConfig{name: name})
@@ -209,26 +222,11 @@ func (c *Config) validateProviderWhens() (matchRequirements, error) {
 			continue
 		}
 
-		if err := validateMatchNetworkList(when.LocalIPIn, "local_ip_in", "provider", name); err != nil {
-			return reqs, err
-		}
-		if err := validateMatchNetworkList(when.LocalIPNotIn, "local_ip_not_in", "provider", name); err != nil {
-			return reqs, err
-		}
-		if err := validateMatchNetworkList(when.GatewayIn, "gateway_in", "provider", name); err != nil {
-			return reqs, err
-		}
-		if err := validateMatchNetworkList(when.GatewayNotIn, "gateway_not_in", "provider", name); err != nil {
+		if err := validateMatchWhen(when, "provider", name); err != nil {
 			return reqs, err
 		}
 
-		reqs.needLocalIP = reqs.needLocalIP || len(when.LocalIPIn) > 0 || len(when.LocalIPNotIn) > 0
-		reqs.needGateway = reqs.needGateway || len(when.GatewayIn) > 0 || len(when.GatewayNotIn) > 0
-		reqs.needUsername = reqs.needUsername || len(when.UsernameIn) > 0 || len(when.UsernameNotIn) > 0
-		reqs.needHostname = reqs.needHostname || len(when.HostnameIn) > 0 || len(when.HostnameNotIn) > 0
-		reqs.needOS = reqs.needOS || len(when.OSIn) > 0 || len(when.OSNotIn) > 0
-		reqs.needTerm = reqs.needTerm || len(when.TermIn) > 0 || len(when.TermNotIn) > 0
-		reqs.needEnv = reqs.needEnv || len(when.EnvIn) > 0 || len(when.EnvNotIn) > 0 || len(when.EnvValueIn) > 0 || len(when.EnvValueNotIn) > 0
+		reqs.add(when)
 	}
 
 	return reqs, nil
@@ -416,6 +414,8 @@ func providerReservedKeys(raw map[string]interface{}) []string {
 		"timeout":                {},
 		"debug_log":              {},
 		"match":                  {},
+		"tag_meta_keys":          {},
+		"inventory_cache_ttl":    {},
 	}
 
 	for _, key := range providerStringSlice(raw, "reserved_keys") {
@@ -439,7 +439,7 @@ func providerNeedsDescribeReservedKeys(raw map[string]interface{}) bool {
 	}
 	for key := range raw {
 		switch key {
-		case "plugin", "capabilities", "default_connector_name", "enabled", "fail_open", "timeout", "debug_log", "match":
+		case "plugin", "capabilities", "default_connector_name", "enabled", "fail_open", "timeout", "debug_log", "match", "tag_meta_keys", "inventory_cache_ttl":
 			continue
 		default:
 			return true
@@ -625,13 +625,20 @@ func decodeProviderInventoryWhen(raw interface{}) (providerInventoryMatchWhen, e
 	return when, nil
 }
 
-func applyProviderInventoryMatches(providerName, serverName string, meta map[string]string, base ServerConfig, matches []providerInventoryMatch) ServerConfig {
+// applyProviderInventoryMatches applies the matching provider branches to
+// base. onApply, if not nil, is called with the config before and after
+// each branch.
+func applyProviderInventoryMatches(providerName, serverName string, meta map[string]string, base ServerConfig, matches []providerInventoryMatch, onApply func(branch string, before, after ServerConfig)) ServerConfig {
 	current := base
 	for _, match := range matches {
 		if providerInventoryMatchApplies(match.When, providerName, serverName, meta) {
-			current = serverConfigReduct(current, match.Config)
-			current.ProviderConfig = mergeProviderConfigMaps(current.ProviderConfig, match.ExtraConfig)
-			current.Note = applyProviderInventoryNoteTemplate(current.Note, providerName, serverName, meta, match)
+			next := serverConfigReduct(current, match.Config)
+			next.ProviderConfig = mergeProviderConfigMaps(next.ProviderConfig, match.ExtraConfig)
+			next.Note = applyProviderInventoryNoteTemplate(next.Note, providerName, serverName, meta, match)
+			if onApply != nil {
+				onApply(match.Name, current, next)
+			}
+			current = next
 		}
 	}
 	return current
@@ -1025,7 +1032,7 @@ func sanitizeProviderDebugBytes(data []byte, sensitiveValues []string) []byte {
 		return data
 	}
 	sanitized := append([]byte(nil), data...)
-	for _, value := range sensitiveValues {
+	for _, value := range append(sensitiveValues, interpolatedSecretValues()...) {
 		if value == "" {
 			continue
 		}
@@ -1039,7 +1046,7 @@ func sanitizeProviderDebugString(value string, sensitiveValues []string) string
 		return value
 	}
 	sanitized := value
-	for _, secret := range sensitiveValues {
+	for _, secret := range append(sensitiveValues, interpolatedSecretValues()...) {
 		if secret == "" {
 			continue
 		}
@@ -1048,6 +1055,50 @@ func sanitizeProviderDebugString(value string, sensitiveValues []string) string
 	return sanitized
 }
 
+// RedactSecrets replaces the secrets in value with "<redacted>": the
+// password, passphrase and token settings of server, and the values resolved
+// from `${secret:...}` references.
+func (c Config) RedactSecrets(server, value string) string {
+	var secrets []string
+	if serverConfig, ok := c.Server[server]; ok {
+		secrets = serverSensitiveValues(serverConfig)
+	}
+	return sanitizeProviderDebugString(value, secrets)
+}
+
+// serverSensitiveValues returns the values of the settings of cfg that
+// shouldRedactProviderDebugField redacts, the longest first.
+func serverSensitiveValues(cfg ServerConfig) []string {
+	values := map[string]struct{}{}
+	for key, value := range serverConfigToTOMLMap(cfg) {
+		if !shouldRedactProviderDebugField("", []string{key}, true) {
+			continue
+		}
+		switch typed := value.(type) {
+		case []string:
+			for _, item := range typed {
+				values[item] = struct{}{}
+			}
+		default:
+			collectProviderSensitiveLeafStrings(typed, values)
+		}
+	}
+
+	result := make([]string, 0, len(values))
+	for value := range values {
+		if value != "" {
+			result = append(result, value)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if len(result[i]) != len(result[j]) {
+			return len(result[i]) > len(result[j])
+		}
+		return result[i] < result[j]
+	})
+	return result
+}
+
 func shouldRedactProviderDebugField(method string, path []string, isRequest bool) bool {
 	if len(path) == 0 {
 		return false
@@ -0,0 +1,278 @@
+// Copyright (c) 2026 Blacknon. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package conf
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/blacknon/lssh/providerapi"
+)
+
+// inventoryCacheEntry is the on-disk format of a cached inventory result.
+type inventoryCacheEntry struct {
+	Provider   string                           `json:"provider"`
+	ConfigHash string                           `json:"config_hash"`
+	FetchedAt  time.Time                        `json:"fetched_at"`
+	Describe   providerapi.PluginDescribeResult `json:"describe"`
+	Inventory  providerapi.InventoryListResult  `json:"inventory"`
+}
+
+var (
+	// refreshInventoryCache bypasses cached inventory results (--refresh-inventory).
+	refreshInventoryCache bool
+
+	// inventoryCacheNow is replaced in tests.
+	inventoryCacheNow = time.Now
+
+	inventoryCacheFileNameRegexp = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+	// inventoryCacheRefreshes tracks the background refreshes of stale entries.
+	inventoryCacheRefreshes sync.WaitGroup
+
+	// inventoryCacheRefreshWait bounds how long WaitInventoryCacheRefresh waits.
+	inventoryCacheRefreshWait = 10 * time.Second
+)
+
+// SetRefreshInventory makes the next config read ignore cached provider
+// inventory results and fetch them again from every provider.
+func SetRefreshInventory(refresh bool) {
+	refreshInventoryCache = refresh
+}
+
+func inventoryCacheDir() (string, error) {
+	if xdg := strings.TrimSpace(os.Getenv("XDG_CACHE_HOME")); xdg != "" {
+		return filepath.Join(xdg, "lssh", "inventory"), nil
+	}
+
+	base, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(base, "lssh", "inventory"), nil
+}
+
+// inventoryCacheConfigHash returns a stable hash of the provider config so that
+// editing the provider block invalidates its cache entry.
+func inventoryCacheConfigHash(name string, raw map[string]interface{}) (string, error) {
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(append([]byte(name+"\x00"), data...))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func inventoryCachePath(name, hash string) (string, error) {
+	dir, err := inventoryCacheDir()
+	if err != nil {
+		return "", err
+	}
+
+	filename := inventoryCacheFileNameRegexp.ReplaceAllString(name, "_") + "-" + hash[:16] + ".json"
+	return filepath.Join(dir, filename), nil
+}
+
+// providerInventoryCacheTTL returns the cache TTL for the provider.
+// A zero value means caching is disabled.
+func providerInventoryCacheTTL(global ProvidersConfig, raw map[string]interface{}) time.Duration {
+	value := providerString(raw, "inventory_cache_ttl")
+	if value == "" {
+		value = global.InventoryCacheTTL
+	}
+	if value == "" {
+		return 0
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return 0
+	}
+	return d
+}
+
+func readInventoryCache(path, hash string) (inventoryCacheEntry, bool) {
+	var entry inventoryCacheEntry
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return entry, false
+	}
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return entry, false
+	}
+	if entry.ConfigHash != hash {
+		return entry, false
+	}
+	return entry, true
+}
+
+func writeInventoryCache(path string, entry inventoryCacheEntry) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	// write to a temporary file first so that concurrent readers never see a
+	// partially written entry.
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Chmod(tmpPath, 0o600); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+// fetchInventoryProviderResult calls the provider and returns its inventory.
+func (c *Config) fetchInventoryProviderResult(name string, raw map[string]interface{}) inventoryProviderResult {
+	result := inventoryProviderResult{raw: raw}
+	if providerNeedsDescribeReservedKeys(raw) {
+		_ = c.callProvider(name, providerapi.MethodPluginDescribe, nil, &result.describe)
+	}
+	err := c.callProvider(name, providerapi.MethodInventoryList, providerapi.InventoryListParams{
+		Provider: name,
+		Config:   raw,
+	}, &result.inventory)
+	if err != nil {
+		result.err = err
+	}
+	return result
+}
+
+// cachedInventoryProviderResult returns the provider inventory, using the
+// on-disk cache when inventory_cache_ttl is set.
+//
+//   - fresh entry: returned as is
+//   - stale entry: returned as is, and refreshed in the background
+//   - no entry or --refresh-inventory: fetched from the provider and cached
+//
+// When the provider fails and fail_open is set, any cached entry is used
+// regardless of its age.
+func (c *Config) cachedInventoryProviderResult(name string, raw map[string]interface{}) inventoryProviderResult {
+	ttl := providerInventoryCacheTTL(c.Providers, raw)
+	if ttl <= 0 {
+		return c.fetchInventoryProviderResult(name, raw)
+	}
+
+	hash, err := inventoryCacheConfigHash(name, raw)
+	if err != nil {
+		return c.fetchInventoryProviderResult(name, raw)
+	}
+	path, err := inventoryCachePath(name, hash)
+	if err != nil {
+		return c.fetchInventoryProviderResult(name, raw)
+	}
+
+	entry, found := readInventoryCache(path, hash)
+	if found && !refreshInventoryCache {
+		if inventoryCacheNow().Sub(entry.FetchedAt) >= ttl {
+			c.refreshInventoryCacheInBackground(name, raw, path, hash)
+		}
+		return inventoryProviderResult{raw: raw, describe: entry.Describe, inventory: entry.Inventory}
+	}
+
+	result := c.fetchInventoryProviderResult(name, raw)
+	if result.err == nil {
+		if err := writeInventoryCache(path, newInventoryCacheEntry(name, hash, result)); err != nil {
+			log.Printf("provider %q inventory cache write failed: %v", name, err)
+		}
+		return result
+	}
+
+	if found && providerFailOpen(c.Providers, raw) {
+		log.Printf("provider %q inventory failed, using cached inventory from %s: %v", name, entry.FetchedAt.Format(time.RFC3339), result.err)
+		return inventoryProviderResult{raw: raw, describe: entry.Describe, inventory: entry.Inventory}
+	}
+
+	return result
+}
+
+// refreshInventoryCacheInBackground fetches the inventory of a stale entry
+// again. The goroutine gets its own copy of the provider config, since c keeps
+// being changed while the config is read.
+func (c *Config) refreshInventoryCacheInBackground(name string, raw map[string]interface{}, path, hash string) {
+	providerConf := make(map[string]interface{}, len(raw))
+	for key, value := range raw {
+		providerConf[key] = value
+	}
+	snapshot := &Config{
+		Providers: c.Providers,
+		Provider:  map[string]map[string]interface{}{name: providerConf},
+	}
+
+	inventoryCacheRefreshes.Add(1)
+	go func() {
+		defer inventoryCacheRefreshes.Done()
+
+		// Errors are not reported here because the selector may already own
+		// the terminal. The stale entry stays until the next successful fetch.
+		result := snapshot.fetchInventoryProviderResult(name, providerConf)
+		if result.err != nil {
+			return
+		}
+		_ = writeInventoryCache(path, newInventoryCacheEntry(name, hash, result))
+	}()
+}
+
+// WaitInventoryCacheRefresh waits for the background refreshes of stale
+// inventory cache entries, so that a short command such as --list does not
+// exit before they are written. The wait is bounded, and a refresh that is
+// still running is retried next time.
+func WaitInventoryCacheRefresh() {
+	done := make(chan struct{})
+	go func() {
+		inventoryCacheRefreshes.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(inventoryCacheRefreshWait):
+	}
+}
+
+// Exit waits for the background refreshes of stale inventory cache entries
+// like WaitInventoryCacheRefresh, and exits with code. Commands use it instead
+// of os.Exit, so that no exit path drops a refresh.
+func Exit(code int) {
+	WaitInventoryCacheRefresh()
+	os.Exit(code)
+}
+
+func newInventoryCacheEntry(name, hash string, result inventoryProviderResult) inventoryCacheEntry {
+	return inventoryCacheEntry{
+		Provider:   name,
+		ConfigHash: hash,
+		FetchedAt:  inventoryCacheNow(),
+		Describe:   result.describe,
+		Inventory:  result.inventory,
+	}
+}
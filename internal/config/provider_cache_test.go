@@ -0,0 +1,207 @@
+package conf
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeCountingInventoryProvider(t *testing.T, dir, response string) (string, string) {
+	t.Helper()
+
+	providerPath := filepath.Join(dir, "lssh-provider-fake-inventory")
+	countPath := filepath.Join(dir, "calls")
+	script := `#!/bin/sh
+cat >/dev/null
+echo call >> "` + countPath + `"
+printf '%s' '` + response + `'
+`
+	if err := os.WriteFile(providerPath, []byte(script), 0o755); err != nil {
+		t.Fatalf("write provider: %v", err)
+	}
+	return providerPath, countPath
+}
+
+func countProviderCalls(t *testing.T, countPath string) int {
+	t.Helper()
+
+	data, err := os.ReadFile(countPath)
+	if os.IsNotExist(err) {
+		return 0
+	}
+	if err != nil {
+		t.Fatalf("read calls: %v", err)
+	}
+	return strings.Count(string(data), "call")
+}
+
+func newInventoryCacheTestConfig(providerPath, ttl string) Config {
+	return Config{
+		Providers: ProvidersConfig{Paths: []string{providerPath}, InventoryCacheTTL: ttl},
+		Server:    map[string]ServerConfig{},
+		Provider: map[string]map[string]interface{}{
+			"aws": {
+				"plugin":       "lssh-provider-fake-inventory",
+				"capabilities": []interface{}{"inventory"},
+			},
+		},
+	}
+}
+
+func TestReadInventoryProvidersUsesFreshCache(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", filepath.Join(dir, "cache"))
+	providerPath, countPath := writeCountingInventoryProvider(t, dir,
+		`{"version":"v1","result":{"servers":[{"name":"aws:web-1","config":{"addr":"10.0.0.10","user":"ubuntu","key":"~/.ssh/id"}}]}}`)
+
+	for i := 0; i < 2; i++ {
+		cfg := newInventoryCacheTestConfig(providerPath, "1h")
+		if err := cfg.ReadInventoryProviders(); err != nil {
+			t.Fatalf("ReadInventoryProviders() error = %v", err)
+		}
+		if got := cfg.Server["aws:web-1"].Addr; got != "10.0.0.10" {
+			t.Fatalf("addr = %q", got)
+		}
+	}
+
+	if got := countProviderCalls(t, countPath); got != 1 {
+		t.Fatalf("provider calls = %d, want 1", got)
+	}
+
+	// changing the provider config invalidates the cache entry
+	cfg := newInventoryCacheTestConfig(providerPath, "1h")
+	cfg.Provider["aws"]["region"] = "us-east-1"
+	if err := cfg.ReadInventoryProviders(); err != nil {
+		t.Fatalf("ReadInventoryProviders() error = %v", err)
+	}
+	if got := countProviderCalls(t, countPath); got < 2 {
+		t.Fatalf("provider calls = %d, want the provider to be called again", got)
+	}
+}
+
+func TestReadInventoryProvidersRefreshInventoryBypassesCache(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", filepath.Join(dir, "cache"))
+	providerPath, countPath := writeCountingInventoryProvider(t, dir,
+		`{"version":"v1","result":{"servers":[{"name":"aws:web-1","config":{"addr":"10.0.0.10","user":"ubuntu","key":"~/.ssh/id"}}]}}`)
+
+	SetRefreshInventory(true)
+	defer SetRefreshInventory(false)
+
+	for i := 0; i < 2; i++ {
+		cfg := newInventoryCacheTestConfig(providerPath, "1h")
+		if err := cfg.ReadInventoryProviders(); err != nil {
+			t.Fatalf("ReadInventoryProviders() error = %v", err)
+		}
+	}
+
+	if got := countProviderCalls(t, countPath); got != 2 {
+		t.Fatalf("provider calls = %d, want 2", got)
+	}
+}
+
+func TestReadInventoryProvidersFallsBackToStaleCacheWhenFailOpen(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", filepath.Join(dir, "cache"))
+
+	providerPath, _ := writeCountingInventoryProvider(t, dir,
+		`{"version":"v1","result":{"servers":[{"name":"aws:web-1","config":{"addr":"10.0.0.10","user":"ubuntu","key":"~/.ssh/id"}}]}}`)
+	cfg := newInventoryCacheTestConfig(providerPath, "1m")
+	if err := cfg.ReadInventoryProviders(); err != nil {
+		t.Fatalf("ReadInventoryProviders() error = %v", err)
+	}
+
+	// the provider now fails, and the cache is ignored because of
+	// --refresh-inventory, so only fail_open can bring the stale data back.
+	writeCountingInventoryProvider(t, dir, `{"version":"v1","error":{"message":"inventory exploded"}}`)
+	SetRefreshInventory(true)
+	defer SetRefreshInventory(false)
+
+	cfg = newInventoryCacheTestConfig(providerPath, "1m")
+	if err := cfg.ReadInventoryProviders(); err == nil {
+		t.Fatal("ReadInventoryProviders() error = nil without fail_open")
+	}
+
+	var logbuf bytes.Buffer
+	originalWriter := log.Writer()
+	originalFlags := log.Flags()
+	log.SetOutput(&logbuf)
+	log.SetFlags(0)
+	defer log.SetOutput(originalWriter)
+	defer log.SetFlags(originalFlags)
+
+	cfg = newInventoryCacheTestConfig(providerPath, "1m")
+	cfg.Providers.FailOpen = true
+	if err := cfg.ReadInventoryProviders(); err != nil {
+		t.Fatalf("ReadInventoryProviders() error = %v", err)
+	}
+	if got := cfg.Server["aws:web-1"].Addr; got != "10.0.0.10" {
+		t.Fatalf("addr = %q, want stale cached value", got)
+	}
+	if !strings.Contains(logbuf.String(), `provider "aws" inventory failed, using cached inventory from`) {
+		t.Fatalf("log = %q", logbuf.String())
+	}
+}
+
+func TestReadInventoryProvidersRefreshesStaleCache(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", filepath.Join(dir, "cache"))
+	providerPath, countPath := writeCountingInventoryProvider(t, dir,
+		`{"version":"v1","result":{"servers":[{"name":"aws:web-1","config":{"addr":"10.0.0.10","user":"ubuntu","key":"~/.ssh/id"}}]}}`)
+
+	cfg := newInventoryCacheTestConfig(providerPath, "1m")
+	if err := cfg.ReadInventoryProviders(); err != nil {
+		t.Fatalf("ReadInventoryProviders() error = %v", err)
+	}
+
+	now := time.Now().Add(time.Hour)
+	inventoryCacheNow = func() time.Time { return now }
+	defer func() { inventoryCacheNow = time.Now }()
+
+	writeCountingInventoryProvider(t, dir,
+		`{"version":"v1","result":{"servers":[{"name":"aws:web-1","config":{"addr":"10.0.0.20","user":"ubuntu","key":"~/.ssh/id"}}]}}`)
+	cfg = newInventoryCacheTestConfig(providerPath, "1m")
+	if err := cfg.ReadInventoryProviders(); err != nil {
+		t.Fatalf("ReadInventoryProviders() error = %v", err)
+	}
+	if got := cfg.Server["aws:web-1"].Addr; got != "10.0.0.10" {
+		t.Fatalf("addr = %q, want the stale cached value", got)
+	}
+
+	// the refresh does not depend on the config that keeps being changed
+	cfg.Provider = nil
+	cfg.Providers = ProvidersConfig{}
+	WaitInventoryCacheRefresh()
+
+	if got := countProviderCalls(t, countPath); got != 2 {
+		t.Fatalf("provider calls = %d, want 2", got)
+	}
+	cfg = newInventoryCacheTestConfig(providerPath, "1m")
+	if err := cfg.ReadInventoryProviders(); err != nil {
+		t.Fatalf("ReadInventoryProviders() error = %v", err)
+	}
+	if got := cfg.Server["aws:web-1"].Addr; got != "10.0.0.20" {
+		t.Fatalf("addr = %q, want the refreshed value", got)
+	}
+}
+
+func TestProviderInventoryCacheTTL(t *testing.T) {
+	global := ProvidersConfig{InventoryCacheTTL: "10m"}
+
+	if got := providerInventoryCacheTTL(global, map[string]interface{}{}); got != 10*time.Minute {
+		t.Fatalf("ttl = %v, want 10m", got)
+	}
+	if got := providerInventoryCacheTTL(global, map[string]interface{}{"inventory_cache_ttl": "30s"}); got != 30*time.Second {
+		t.Fatalf("ttl = %v, want 30s", got)
+	}
+	if got := providerInventoryCacheTTL(global, map[string]interface{}{"inventory_cache_ttl": "0"}); got != 0 {
+		t.Fatalf("ttl = %v, want 0", got)
+	}
+	if got := providerInventoryCacheTTL(ProvidersConfig{InventoryCacheTTL: "soon"}, map[string]interface{}{}); got != 0 {
+		t.Fatalf("ttl = %v, want 0 for invalid value", got)
+	}
+}
@@ -1131,7 +1131,7 @@ func TestApplyProviderInventoryMatchesNoteTemplate(t *testing.T) {
 		"node":   "sv-pve01",
 		"status": "running",
 		"type":   "qemu",
-	}, base, matches)
+	}, base, matches, nil)
 
 	want := "base-note [proxmox:sv-pve01:running] -> pve:sv-pve01:vm1"
 	if got.Note != want {
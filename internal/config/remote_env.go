@@ -0,0 +1,59 @@
+// Copyright (c) 2026 Blacknon. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package conf
+
+import (
+	"os"
+	"path"
+	"regexp"
+	"strings"
+)
+
+var remoteEnvNameRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// RemoteEnv returns the environment variables to set in the remote session
+// of s. Local variables matching send_env are sent first, and set_env
+// overrides them.
+func (s ServerConfig) RemoteEnv() map[string]string {
+	if len(s.SetEnv) == 0 && len(s.SendEnv) == 0 {
+		return nil
+	}
+
+	env := map[string]string{}
+	if len(s.SendEnv) > 0 {
+		for _, kv := range os.Environ() {
+			name, value, ok := strings.Cut(kv, "=")
+			if !ok || !remoteEnvNameRegexp.MatchString(name) {
+				continue
+			}
+			for _, pattern := range s.SendEnv {
+				if matched, _ := path.Match(pattern, name); matched {
+					env[name] = value
+					break
+				}
+			}
+		}
+	}
+
+	for name, value := range s.SetEnv {
+		env[name] = value
+	}
+
+	return env
+}
+
+// mergeSetEnv returns parent overlaid with child, so that set_env in common,
+// templates and includes is inherited per variable.
+func mergeSetEnv(parent, child map[string]string) map[string]string {
+	if len(parent) == 0 {
+		return cloneStringMap(child)
+	}
+
+	result := cloneStringMap(parent)
+	for name, value := range child {
+		result[name] = value
+	}
+	return result
+}
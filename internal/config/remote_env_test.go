@@ -0,0 +1,122 @@
+package conf
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestServerConfigRemoteEnv(t *testing.T) {
+	t.Setenv("LANG", "ja_JP.UTF-8")
+	t.Setenv("LC_TIME", "C")
+	t.Setenv("LSSH_TEST_SECRET", "local")
+
+	server := ServerConfig{
+		SendEnv: []string{"LANG", "LC_*"},
+		SetEnv:  map[string]string{"APP_ENV": "prod", "LC_TIME": "en_US.UTF-8"},
+	}
+
+	got := server.RemoteEnv()
+	if got["LANG"] != "ja_JP.UTF-8" || got["APP_ENV"] != "prod" {
+		t.Fatalf("RemoteEnv() = %v", got)
+	}
+	if got["LC_TIME"] != "en_US.UTF-8" {
+		t.Fatalf("set_env should override send_env: LC_TIME = %q", got["LC_TIME"])
+	}
+	if _, ok := got["LSSH_TEST_SECRET"]; ok {
+		t.Fatalf("RemoteEnv() sent a variable not in send_env: %v", got)
+	}
+
+	if env := (ServerConfig{}).RemoteEnv(); env != nil {
+		t.Fatalf("RemoteEnv() = %v, want nil", env)
+	}
+}
+
+func TestServerConfigReductMergesSetEnv(t *testing.T) {
+	common := ServerConfig{SetEnv: map[string]string{"LANG": "C.UTF-8", "TZ": "UTC"}}
+	server := ServerConfig{Addr: "192.0.2.1", SetEnv: map[string]string{"TZ": "Asia/Tokyo"}}
+
+	got := serverConfigReduct(common, server)
+	want := map[string]string{"LANG": "C.UTF-8", "TZ": "Asia/Tokyo"}
+	if !reflect.DeepEqual(got.SetEnv, want) {
+		t.Fatalf("set_env = %v, want %v", got.SetEnv, want)
+	}
+	if common.SetEnv["TZ"] != "UTC" {
+		t.Fatalf("common set_env was modified: %v", common.SetEnv)
+	}
+}
+
+func TestLoadOpenSSHConfigEntriesMapsEnvAndRemoteCommand(t *testing.T) {
+	sshConfigPath := filepath.Join(t.TempDir(), "config")
+	sshConfig := strings.Join([]string{
+		"Host app",
+		"    HostName 192.0.2.10",
+		`    SetEnv APP_ENV=prod "GREETING=hello world"`,
+		"    SendEnv LANG LC_* XMODIFIERS",
+		"    SendEnv -XMOD*",
+		"    RemoteCommand tmux new -A -s main",
+		"",
+		"Host *",
+		"    SetEnv APP_ENV=dev",
+		"",
+	}, "\n")
+	if err := os.WriteFile(sshConfigPath, []byte(sshConfig), 0o600); err != nil {
+		t.Fatalf("write ssh config: %v", err)
+	}
+
+	entries, err := loadOpenSSHConfigEntries(sshConfigPath, "")
+	if err != nil {
+		t.Fatalf("loadOpenSSHConfigEntries: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("len(entries) = %d, want 1", len(entries))
+	}
+
+	cfg := entries[0].Config
+	if want := map[string]string{"APP_ENV": "prod", "GREETING": "hello world"}; !reflect.DeepEqual(cfg.SetEnv, want) {
+		t.Fatalf("set_env = %v, want %v", cfg.SetEnv, want)
+	}
+	if want := []string{"LANG", "LC_*"}; !reflect.DeepEqual(cfg.SendEnv, want) {
+		t.Fatalf("send_env = %v, want %v", cfg.SendEnv, want)
+	}
+	if cfg.RemoteCommand != "tmux new -A -s main" {
+		t.Fatalf("remote_command = %q", cfg.RemoteCommand)
+	}
+}
+
+func TestValidateConfigReportsInvalidRemoteEnv(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "lssh.toml")
+	data := `[server.app]
+addr = "192.0.2.1"
+user = "demo"
+pass = "secret"
+send_env = ["LC_["]
+
+[server.app.set_env]
+"APP-ENV" = "prod"
+`
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	findings, err := ValidateConfig(path)
+	if err != nil {
+		t.Fatalf("ValidateConfig() error = %v", err)
+	}
+
+	var messages []string
+	for _, finding := range findings {
+		messages = append(messages, finding.String())
+	}
+	got := strings.Join(messages, "\n")
+	for _, want := range []string{
+		`server.app: set_env: invalid variable name "APP-ENV"`,
+		`server.app: send_env: invalid pattern "LC_["`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("findings do not contain %q:\n%s", want, got)
+		}
+	}
+}
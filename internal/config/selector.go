@@ -0,0 +1,343 @@
+// Copyright (c) 2026 Blacknon. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package conf
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Host selector expressions select servers by tag, group, provider or meta
+// instead of by name. An expression is a comma separated list of terms that
+// must all match. Each term can be negated with a leading `!`. Plain server
+// names (globs) without a prefix are alternatives instead: a server must match
+// one of them, so `web01,web02` selects both servers.
+//
+//	tag:web           server has a tag matching `web` (glob)
+//	group:frontend    server is a member of [group.frontend]
+//	provider:aws      server was generated by a provider matching `aws` (glob)
+//	name:web-*        server name matches `web-*` (glob)
+//	env=prod          server has tag `env=prod` or provider meta env=prod (glob on value)
+//
+// ex.) `tag:web,env=prod,!tag:canary`, `web01,web02,env=prod`
+var hostSelectorPrefixes = []string{"tag:", "group:", "provider:", "name:"}
+
+// IsHostSelector reports whether value is a host selector expression rather
+// than a plain server name.
+func IsHostSelector(value string) bool {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return false
+	}
+	if strings.HasPrefix(value, "!") || strings.Contains(value, ",") || strings.Contains(value, "=") {
+		return true
+	}
+	for _, prefix := range hostSelectorPrefixes {
+		if strings.HasPrefix(value, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+type hostSelectorTerm struct {
+	kind    string
+	key     string
+	pattern string
+	negate  bool
+
+	// alternative is set for plain names, which are OR-ed.
+	alternative bool
+}
+
+// parseHostSelector parses expr into AND-ed terms.
+func parseHostSelector(expr string) ([]hostSelectorTerm, error) {
+	terms := []hostSelectorTerm{}
+	for _, raw := range strings.Split(expr, ",") {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue
+		}
+
+		term := hostSelectorTerm{}
+		if strings.HasPrefix(raw, "!") {
+			term.negate = true
+			raw = strings.TrimSpace(raw[1:])
+		}
+
+		switch {
+		case strings.HasPrefix(raw, "tag:"):
+			term.kind, term.pattern = "tag", raw[len("tag:"):]
+		case strings.HasPrefix(raw, "group:"):
+			term.kind, term.pattern = "group", raw[len("group:"):]
+		case strings.HasPrefix(raw, "provider:"):
+			term.kind, term.pattern = "provider", raw[len("provider:"):]
+		case strings.HasPrefix(raw, "name:"):
+			term.kind, term.pattern = "name", raw[len("name:"):]
+		case strings.Contains(raw, "="):
+			key, value, _ := strings.Cut(raw, "=")
+			term.kind, term.key, term.pattern = "meta", strings.TrimSpace(key), strings.TrimSpace(value)
+			if term.key == "" {
+				return nil, fmt.Errorf("invalid host selector term %q: empty key", raw)
+			}
+		default:
+			term.kind, term.pattern = "name", raw
+			term.alternative = !term.negate
+		}
+
+		if term.pattern == "" && term.kind != "meta" {
+			return nil, fmt.Errorf("invalid host selector term %q: empty value", raw)
+		}
+		if _, err := path.Match(term.pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid host selector term %q: %w", raw, err)
+		}
+
+		terms = append(terms, term)
+	}
+
+	if len(terms) == 0 {
+		return nil, fmt.Errorf("empty host selector %q", expr)
+	}
+	return terms, nil
+}
+
+// MatchHostSelector reports whether server name matches the selector expr.
+func (c Config) MatchHostSelector(name, expr string) (bool, error) {
+	terms, err := parseHostSelector(expr)
+	if err != nil {
+		return false, err
+	}
+	return c.matchHostSelectorTerms(name, terms), nil
+}
+
+func (c Config) matchHostSelectorTerms(name string, terms []hostSelectorTerm) bool {
+	server, ok := c.Server[name]
+	if !ok {
+		return false
+	}
+
+	hasAlternative, matchedAlternative := false, false
+	for _, term := range terms {
+		matched := c.matchHostSelectorTerm(name, server, term)
+		if term.alternative {
+			hasAlternative = true
+			matchedAlternative = matchedAlternative || matched
+			continue
+		}
+		if matched == term.negate {
+			return false
+		}
+	}
+	return !hasAlternative || matchedAlternative
+}
+
+func (c Config) matchHostSelectorTerm(name string, server ServerConfig, term hostSelectorTerm) bool {
+	switch term.kind {
+	case "tag":
+		for _, tag := range server.Tags {
+			if globMatch(term.pattern, tag) {
+				return true
+			}
+		}
+	case "group":
+		for groupName := range c.Group {
+			if globMatch(term.pattern, groupName) && c.isGroupMember(groupName, name) {
+				return true
+			}
+		}
+	case "provider":
+		return server.ProviderName != "" && globMatch(term.pattern, server.ProviderName)
+	case "name":
+		return globMatch(term.pattern, name)
+	case "meta":
+		for _, tag := range server.Tags {
+			key, value, ok := strings.Cut(tag, "=")
+			if ok && key == term.key && globMatch(term.pattern, value) {
+				return true
+			}
+		}
+		if value, ok := server.ProviderMeta[term.key]; ok && globMatch(term.pattern, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// isGroupMember reports whether server name belongs to the named group.
+// Selector based groups are evaluated without group terms to avoid loops.
+func (c Config) isGroupMember(groupName, name string) bool {
+	group, ok := c.Group[groupName]
+	if !ok {
+		return false
+	}
+
+	for _, pattern := range group.Servers {
+		if globMatch(pattern, name) {
+			return true
+		}
+	}
+
+	if strings.TrimSpace(group.Selector) == "" {
+		return false
+	}
+	terms, err := parseHostSelector(group.Selector)
+	if err != nil {
+		return false
+	}
+	filtered := make([]hostSelectorTerm, 0, len(terms))
+	for _, term := range terms {
+		if term.kind == "group" {
+			continue
+		}
+		filtered = append(filtered, term)
+	}
+	if len(filtered) == 0 {
+		return false
+	}
+	return c.matchHostSelectorTerms(name, filtered)
+}
+
+// SelectHosts returns the sorted names of non-ignored servers matching expr.
+func (c Config) SelectHosts(expr string) ([]string, error) {
+	terms, err := parseHostSelector(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []string{}
+	for name, server := range c.Server {
+		if server.Ignore {
+			continue
+		}
+		if c.matchHostSelectorTerms(name, terms) {
+			result = append(result, name)
+		}
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+// ResolveHostSelectors expands host selector expressions in hosts into server
+// names. Plain names are passed through unchanged so that callers can keep
+// validating them with check.ExistServer. Duplicates are removed while keeping
+// the first occurrence.
+func (c Config) ResolveHostSelectors(hosts []string) ([]string, error) {
+	if len(hosts) == 0 {
+		return hosts, nil
+	}
+
+	result := make([]string, 0, len(hosts))
+	seen := map[string]struct{}{}
+	appendName := func(name string) {
+		if _, ok := seen[name]; ok {
+			return
+		}
+		seen[name] = struct{}{}
+		result = append(result, name)
+	}
+
+	for _, host := range hosts {
+		if !IsHostSelector(host) {
+			appendName(host)
+			continue
+		}
+
+		names, err := c.SelectHosts(host)
+		if err != nil {
+			return nil, err
+		}
+		if len(names) == 0 {
+			return nil, fmt.Errorf("host selector %q matched no servers", host)
+		}
+		for _, name := range names {
+			appendName(name)
+		}
+	}
+
+	return result, nil
+}
+
+// ApplyGroupTags adds the tags of each [group.<name>] to its members.
+func (c *Config) ApplyGroupTags() {
+	if len(c.Group) == 0 {
+		return
+	}
+
+	groupNames := make([]string, 0, len(c.Group))
+	for name := range c.Group {
+		groupNames = append(groupNames, name)
+	}
+	sort.Strings(groupNames)
+
+	additions := map[string][]string{}
+	groups := map[string][]string{}
+	for _, groupName := range groupNames {
+		tags := c.Group[groupName].Tags
+		if len(tags) == 0 {
+			continue
+		}
+		for serverName := range c.Server {
+			if c.isGroupMember(groupName, serverName) {
+				additions[serverName] = append(additions[serverName], tags...)
+				groups[serverName] = append(groups[serverName], "group."+groupName)
+			}
+		}
+	}
+
+	for serverName, tags := range additions {
+		server := c.Server[serverName]
+		tagged := server
+		tagged.Tags = appendUniqueTags(server.Tags, tags...)
+		c.recordFieldSources(serverName, server, tagged, fixedFieldSource(strings.Join(groups[serverName], ", ")))
+		c.Server[serverName] = tagged
+	}
+}
+
+// providerMetaTags converts provider meta values into `key=value` tags for
+// the keys listed in the provider `tag_meta_keys` setting.
+func providerMetaTags(raw map[string]interface{}, meta map[string]string) []string {
+	keys := providerStringSlice(raw, "tag_meta_keys")
+	if len(keys) == 0 || len(meta) == 0 {
+		return nil
+	}
+
+	tags := make([]string, 0, len(keys))
+	for _, key := range keys {
+		value, ok := meta[key]
+		if !ok || value == "" {
+			continue
+		}
+		tags = append(tags, key+"="+value)
+	}
+	return tags
+}
+
+func appendUniqueTags(base []string, tags ...string) []string {
+	result := append([]string(nil), base...)
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" {
+			continue
+		}
+		exists := false
+		for _, current := range result {
+			if current == tag {
+				exists = true
+				break
+			}
+		}
+		if !exists {
+			result = append(result, tag)
+		}
+	}
+	return result
+}
+
+func globMatch(pattern, value string) bool {
+	ok, err := path.Match(pattern, value)
+	return err == nil && ok
+}
@@ -0,0 +1,93 @@
+package conf
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func selectorTestConfig() Config {
+	return Config{
+		Server: map[string]ServerConfig{
+			"web01":  {Tags: []string{"web", "env=prod"}},
+			"web02":  {Tags: []string{"web", "env=prod", "canary"}},
+			"web03":  {Tags: []string{"web", "env=stg"}},
+			"db01":   {Tags: []string{"db", "env=prod"}},
+			"aws01":  {ProviderName: "aws-prod", ProviderMeta: map[string]string{"region": "ap-northeast-1"}},
+			"hidden": {Tags: []string{"web"}, Ignore: true},
+		},
+		Group: map[string]GroupConfig{
+			"frontend": {Servers: []string{"web0[12]"}, Tags: []string{"team=frontend"}},
+			"prod":     {Selector: "env=prod"},
+		},
+	}
+}
+
+func TestIsHostSelector(t *testing.T) {
+	assert.False(t, IsHostSelector("web01"))
+	assert.False(t, IsHostSelector(""))
+	assert.True(t, IsHostSelector("tag:web"))
+	assert.True(t, IsHostSelector("group:frontend"))
+	assert.True(t, IsHostSelector("env=prod"))
+	assert.True(t, IsHostSelector("!tag:canary"))
+	assert.True(t, IsHostSelector("web01,web02"))
+}
+
+func TestSelectHosts(t *testing.T) {
+	c := selectorTestConfig()
+
+	tds := []struct {
+		expr   string
+		expect []string
+	}{
+		{expr: "tag:web", expect: []string{"web01", "web02", "web03"}},
+		{expr: "tag:web,env=prod,!tag:canary", expect: []string{"web01"}},
+		{expr: "group:frontend", expect: []string{"web01", "web02"}},
+		{expr: "group:prod,!tag:web", expect: []string{"db01"}},
+		{expr: "provider:aws-*", expect: []string{"aws01"}},
+		{expr: "region=ap-*", expect: []string{"aws01"}},
+		{expr: "name:db*", expect: []string{"db01"}},
+		{expr: "web01,web03", expect: []string{"web01", "web03"}},
+		{expr: "web0*,db01,env=prod", expect: []string{"db01", "web01", "web02"}},
+		{expr: "web01,web03,!tag:canary,env=stg", expect: []string{"web03"}},
+	}
+	for _, td := range tds {
+		got, err := c.SelectHosts(td.expr)
+		assert.NoError(t, err, td.expr)
+		assert.Equal(t, td.expect, got, td.expr)
+	}
+}
+
+func TestResolveHostSelectors(t *testing.T) {
+	c := selectorTestConfig()
+
+	got, err := c.ResolveHostSelectors([]string{"db01", "tag:web,env=prod", "web01"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"db01", "web01", "web02"}, got)
+
+	got, err = c.ResolveHostSelectors([]string{"missing"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"missing"}, got)
+
+	_, err = c.ResolveHostSelectors([]string{"tag:nothing"})
+	assert.Error(t, err)
+
+	_, err = c.ResolveHostSelectors([]string{"tag:["})
+	assert.Error(t, err)
+}
+
+func TestApplyGroupTags(t *testing.T) {
+	c := selectorTestConfig()
+	c.ApplyGroupTags()
+
+	assert.Equal(t, []string{"web", "env=prod", "team=frontend"}, c.Server["web01"].Tags)
+	assert.Equal(t, []string{"web", "env=stg"}, c.Server["web03"].Tags)
+}
+
+func TestProviderMetaTags(t *testing.T) {
+	raw := map[string]interface{}{"tag_meta_keys": []interface{}{"region", "env", "missing"}}
+	meta := map[string]string{"region": "us-east-1", "env": "prod"}
+
+	assert.Equal(t, []string{"region=us-east-1", "env=prod"}, providerMetaTags(raw, meta))
+	assert.Nil(t, providerMetaTags(nil, meta))
+}
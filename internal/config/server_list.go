@@ -0,0 +1,384 @@
+// Copyright (c) 2026 Blacknon. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package conf
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/blacknon/lssh/internal/common"
+	"gopkg.in/yaml.v3"
+)
+
+// Server list formats of `--list --format`.
+const (
+	ServerListFormatText  = "text"
+	ServerListFormatJSON  = "json"
+	ServerListFormatYAML  = "yaml"
+	ServerListFormatTSV   = "tsv"
+	ServerListFormatTable = "table"
+)
+
+// DefaultServerListFields is the columns of the tsv/table `--list` output.
+var DefaultServerListFields = []string{"name", "addr", "user", "port", "proxy", "connector", "provider", "tags", "note"}
+
+// serverListRedacted replaces secret values in the `--list` output.
+const serverListRedacted = "<redacted>"
+
+// WriteServerListFormat writes the `--list` output of names in opts.Format.
+// Values are taken from the effective (merged) config with ${env:...},
+// ${server.*} and ${local.user} references interpolated, and secrets redacted.
+func (c Config) WriteServerListFormat(out io.Writer, names []string, opts common.ListOptions) error {
+	if opts.Explain != "" {
+		return c.WriteServerExplain(out, opts.Explain)
+	}
+
+	c, _ = c.InterpolateServersWithoutSecrets(names)
+
+	names, err := c.filterServerListNames(names, opts.Filter)
+	if err != nil {
+		return err
+	}
+	if err := c.checkServerListFields(names, opts.Fields); err != nil {
+		return err
+	}
+
+	format := strings.ToLower(strings.TrimSpace(opts.Format))
+	switch format {
+	case "", ServerListFormatText:
+		if len(opts.Fields) == 0 {
+			c.WriteServerList(out, names)
+			return nil
+		}
+		return c.writeServerListTable(out, names, opts.Fields)
+	case ServerListFormatTable:
+		return c.writeServerListTable(out, names, opts.Fields)
+	case ServerListFormatTSV:
+		return c.writeServerListTSV(out, names, opts.Fields)
+	case ServerListFormatJSON:
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		encoder.SetEscapeHTML(false)
+		return encoder.Encode(c.serverListRecords(names, opts.Fields))
+	case ServerListFormatYAML:
+		encoder := yaml.NewEncoder(out)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(c.serverListRecords(names, opts.Fields)); err != nil {
+			return err
+		}
+		return encoder.Close()
+	default:
+		return fmt.Errorf("unknown list format %q: must be one of text, json, yaml, tsv, table", opts.Format)
+	}
+}
+
+// WriteServerExplain writes each effective field of name, with the file,
+// provider, template or match branch that set it.
+func (c Config) WriteServerExplain(out io.Writer, name string) error {
+	server, ok := c.Server[name]
+	if !ok {
+		return fmt.Errorf("unknown server %q", name)
+	}
+
+	values := redactServerListMap(serverConfigToTOMLMap(server))
+	sources := c.FieldSources(name)
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	fmt.Fprintf(out, "lssh Server Explain: %s\n", c.serverLabel(name))
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  FIELD\tVALUE\tSOURCE")
+	for _, key := range keys {
+		source := sources[key]
+		if source == "" {
+			source = "-"
+		}
+		fmt.Fprintf(w, "  %s\t%s\t%s\n", key, serverListString(values[key]), source)
+	}
+	return w.Flush()
+}
+
+func (c Config) filterServerListNames(names []string, filter string) ([]string, error) {
+	filter = strings.TrimSpace(filter)
+	if filter == "" {
+		return names, nil
+	}
+
+	var result []string
+	for _, name := range names {
+		ok, err := c.MatchHostSelector(name, filter)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			result = append(result, name)
+		}
+	}
+	return result, nil
+}
+
+func (c Config) checkServerListFields(names []string, fields []string) error {
+	known := map[string]bool{"origin": true, "connector": true, "provider": true}
+	for _, field := range DefaultServerListFields {
+		known[field] = true
+	}
+	for _, key := range serverConfigTOMLKeys() {
+		known[key] = true
+	}
+	for _, name := range names {
+		for key := range c.Server[name].ProviderConfig {
+			known[key] = true
+		}
+	}
+
+	for _, field := range fields {
+		if known[field] || strings.HasPrefix(field, "meta.") || strings.HasPrefix(field, "tag.") {
+			continue
+		}
+		return fmt.Errorf("unknown list field %q", field)
+	}
+	return nil
+}
+
+// serverListRecords returns the json/yaml records of names.
+func (c Config) serverListRecords(names []string, fields []string) []map[string]interface{} {
+	records := make([]map[string]interface{}, 0, len(names))
+	for _, name := range names {
+		if len(fields) > 0 {
+			record := make(map[string]interface{}, len(fields))
+			for _, field := range fields {
+				record[field] = c.serverListField(name, field)
+			}
+			records = append(records, record)
+			continue
+		}
+
+		server := c.Server[name]
+		record := redactServerListMap(serverConfigToTOMLMap(server))
+		record["name"] = name
+		if origin := c.ServerOrigin(name); origin != "" {
+			record["origin"] = origin
+		}
+		if server.ProviderName != "" {
+			record["provider"] = server.ProviderName
+		}
+		if len(server.ProviderMeta) > 0 {
+			record["meta"] = server.ProviderMeta
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func (c Config) writeServerListTSV(out io.Writer, names []string, fields []string) error {
+	if len(fields) == 0 {
+		fields = DefaultServerListFields
+	}
+
+	fmt.Fprintln(out, strings.Join(fields, "\t"))
+	for _, name := range names {
+		row := make([]string, len(fields))
+		for i, field := range fields {
+			value := serverListString(c.serverListField(name, field))
+			row[i] = strings.NewReplacer("\t", " ", "\n", " ").Replace(value)
+		}
+		fmt.Fprintln(out, strings.Join(row, "\t"))
+	}
+	return nil
+}
+
+func (c Config) writeServerListTable(out io.Writer, names []string, fields []string) error {
+	if len(fields) == 0 {
+		fields = DefaultServerListFields
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	header := make([]string, len(fields))
+	for i, field := range fields {
+		header[i] = strings.ToUpper(field)
+	}
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+	for _, name := range names {
+		row := make([]string, len(fields))
+		for i, field := range fields {
+			row[i] = strings.ReplaceAll(serverListString(c.serverListField(name, field)), "\n", " ")
+		}
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	return w.Flush()
+}
+
+// serverListField returns the redacted value of field for name.
+func (c Config) serverListField(name, field string) interface{} {
+	server := c.Server[name]
+
+	switch field {
+	case "name":
+		return name
+	case "origin":
+		return c.ServerOrigin(name)
+	case "connector":
+		return server.ConnectorName
+	case "provider":
+		return server.ProviderName
+	case "tags":
+		if server.Tags == nil {
+			return []string{}
+		}
+		return server.Tags
+	}
+	if key, ok := strings.CutPrefix(field, "meta."); ok {
+		return server.ProviderMeta[key]
+	}
+	if key, ok := strings.CutPrefix(field, "tag."); ok {
+		return serverTagValue(server.Tags, key)
+	}
+
+	value, ok := redactServerListMap(serverConfigToTOMLMap(server))[field]
+	if !ok {
+		return ""
+	}
+	return value
+}
+
+// ServerListFieldString returns the redacted value of the `--list` field
+// of name as a string, as it is shown in the table output.
+func (c Config) ServerListFieldString(name, field string) string {
+	return serverListString(c.serverListField(name, field))
+}
+
+// CheckServerListFields returns an error if a field is not a `--list` field.
+func (c Config) CheckServerListFields(fields []string) error {
+	return c.checkServerListFields(GetNameList(c), fields)
+}
+
+// serverTagValue returns VALUE of the `key=VALUE` tag, or key if the tag
+// key has no value.
+func serverTagValue(tags []string, key string) string {
+	for _, tag := range tags {
+		if tag == key {
+			return tag
+		}
+		if k, v, ok := strings.Cut(tag, "="); ok && k == key {
+			return v
+		}
+	}
+	return ""
+}
+
+// redactServerListMap redacts the secrets in values, a map returned by
+// serverConfigToTOMLMap. The passphrase part of `path::passphrase` keys is
+// also redacted.
+func redactServerListMap(values map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{}, len(values))
+	for key, value := range values {
+		switch key {
+		case "pkcs11pin":
+			result[key] = serverListRedacted
+			continue
+		case "keys", "certs", "ssh_agent_key":
+			if items, ok := value.([]string); ok {
+				result[key] = redactServerListKeyPaths(key, items)
+				continue
+			}
+		}
+
+		if shouldRedactProviderDebugField("", []string{key}, false) {
+			result[key] = providerDebugRedactedValue(serverListInterfaceValue(value))
+			continue
+		}
+		result[key] = redactServerListValue(value)
+	}
+	return result
+}
+
+// redactServerListKeyPaths redacts the passphrase of `path::passphrase`.
+// certs are `cert::key::passphrase`.
+func redactServerListKeyPaths(key string, items []string) []string {
+	keep := 1
+	if key == "certs" {
+		keep = 2
+	}
+
+	result := make([]string, len(items))
+	for i, item := range items {
+		parts := strings.SplitN(item, "::", keep+1)
+		if len(parts) > keep && parts[keep] != "" {
+			parts[keep] = serverListRedacted
+		}
+		result[i] = strings.Join(parts, "::")
+	}
+	return result
+}
+
+func redactServerListValue(value interface{}) interface{} {
+	switch typed := value.(type) {
+	case string:
+		return sanitizeProviderDebugString(typed, nil)
+	case map[string]interface{}:
+		return sanitizeProviderDebugValue("", typed, nil, false)
+	default:
+		return value
+	}
+}
+
+// serverListInterfaceValue converts []string to []interface{}, for
+// providerDebugRedactedValue.
+func serverListInterfaceValue(value interface{}) interface{} {
+	items, ok := value.([]string)
+	if !ok {
+		return value
+	}
+	result := make([]interface{}, len(items))
+	for i, item := range items {
+		result[i] = item
+	}
+	return result
+}
+
+// serverListString formats value for the tsv/table/explain output.
+func serverListString(value interface{}) string {
+	switch typed := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return typed
+	case []string:
+		return strings.Join(typed, ",")
+	case []interface{}:
+		items := make([]string, len(typed))
+		for i, item := range typed {
+			items[i] = serverListString(item)
+		}
+		return strings.Join(items, ",")
+	case map[string]string:
+		keys := make([]string, 0, len(typed))
+		for key := range typed {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		items := make([]string, len(keys))
+		for i, key := range keys {
+			items[i] = key + "=" + typed[key]
+		}
+		return strings.Join(items, ",")
+	case map[string]interface{}:
+		data, err := json.Marshal(typed)
+		if err != nil {
+			return fmt.Sprint(typed)
+		}
+		return string(data)
+	default:
+		return fmt.Sprint(typed)
+	}
+}
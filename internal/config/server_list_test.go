@@ -0,0 +1,195 @@
+package conf
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/blacknon/lssh/internal/common"
+)
+
+func TestWriteServerListFormatJSONRedactsSecrets(t *testing.T) {
+	c := Config{Server: map[string]ServerConfig{
+		"web01": {
+			Addr:         "192.0.2.10",
+			User:         "deploy",
+			Pass:         "secret-pass",
+			Keys:         []string{"~/.ssh/web::key-pass", "~/.ssh/plain"},
+			Certs:        []string{"~/.ssh/web-cert.pub::~/.ssh/web::cert-pass"},
+			PKCS11PIN:    "1234",
+			Tags:         []string{"env=prod"},
+			ProviderName: "aws",
+			ProviderMeta: map[string]string{"region": "ap-northeast-1"},
+			ProviderConfig: map[string]interface{}{
+				"client_secret": "provider-secret",
+			},
+		},
+		"db01": {Addr: "192.0.2.20", Tags: []string{"env=dev"}},
+	}}
+
+	var out bytes.Buffer
+	err := c.WriteServerListFormat(&out, []string{"db01", "web01"}, common.ListOptions{Format: "json", Filter: "tag:env=prod"})
+	if err != nil {
+		t.Fatalf("WriteServerListFormat() error = %v", err)
+	}
+
+	var records []map[string]interface{}
+	if err := json.Unmarshal(out.Bytes(), &records); err != nil {
+		t.Fatalf("output is not json: %v\n%s", err, out.String())
+	}
+	if len(records) != 1 || records[0]["name"] != "web01" || records[0]["provider"] != "aws" {
+		t.Fatalf("records = %v", records)
+	}
+	if records[0]["pass"] != "<redacted>" || records[0]["pkcs11pin"] != "<redacted>" {
+		t.Fatalf("secrets are not redacted: %v", records[0])
+	}
+
+	got := out.String()
+	for _, secret := range []string{"secret-pass", "key-pass", "cert-pass", "1234", "provider-secret"} {
+		if strings.Contains(got, secret) {
+			t.Fatalf("output leaks %q:\n%s", secret, got)
+		}
+	}
+	for _, want := range []string{`"~/.ssh/web::<redacted>"`, `"~/.ssh/plain"`, `"~/.ssh/web-cert.pub::~/.ssh/web::<redacted>"`} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("output does not contain %s:\n%s", want, got)
+		}
+	}
+}
+
+func TestWriteServerListFormatTSVFields(t *testing.T) {
+	c := Config{Server: map[string]ServerConfig{
+		"web01": {
+			Addr:         "192.0.2.10",
+			User:         "deploy",
+			Pass:         "secret-pass",
+			Tags:         []string{"env=prod", "role=web"},
+			ProviderMeta: map[string]string{"region": "ap-northeast-1"},
+		},
+	}}
+
+	var out bytes.Buffer
+	opts := common.ListOptions{Format: "tsv", Fields: []string{"name", "addr", "tags", "meta.region", "pass"}}
+	if err := c.WriteServerListFormat(&out, []string{"web01"}, opts); err != nil {
+		t.Fatalf("WriteServerListFormat() error = %v", err)
+	}
+
+	want := "name\taddr\ttags\tmeta.region\tpass\n" +
+		"web01\t192.0.2.10\tenv=prod,role=web\tap-northeast-1\t<redacted>\n"
+	if out.String() != want {
+		t.Fatalf("WriteServerListFormat() = %q, want %q", out.String(), want)
+	}
+
+	err := c.WriteServerListFormat(&out, []string{"web01"}, common.ListOptions{Format: "tsv", Fields: []string{"unknown"}})
+	if err == nil || !strings.Contains(err.Error(), `unknown list field "unknown"`) {
+		t.Fatalf("WriteServerListFormat() error = %v, want unknown field error", err)
+	}
+
+	err = c.WriteServerListFormat(&out, []string{"web01"}, common.ListOptions{Format: "xml"})
+	if err == nil || !strings.Contains(err.Error(), `unknown list format "xml"`) {
+		t.Fatalf("WriteServerListFormat() error = %v, want unknown format error", err)
+	}
+}
+
+func TestWriteServerListFormatInterpolates(t *testing.T) {
+	t.Setenv("WEB02_IP", "192.0.2.50")
+	c := Config{Server: map[string]ServerConfig{
+		"web02": {Addr: "${env:WEB02_IP}", User: "deploy", Note: "${secret:vault:hosts/web02#note}"},
+	}}
+
+	var out bytes.Buffer
+	opts := common.ListOptions{Format: "tsv", Fields: []string{"name", "addr", "note"}}
+	if err := c.WriteServerListFormat(&out, []string{"web02"}, opts); err != nil {
+		t.Fatalf("WriteServerListFormat() error = %v", err)
+	}
+
+	want := "name\taddr\tnote\n" +
+		"web02\t192.0.2.50\t${secret:vault:hosts/web02#note}\n"
+	if out.String() != want {
+		t.Fatalf("WriteServerListFormat() = %q, want %q", out.String(), want)
+	}
+}
+
+func TestReadConfigRecordsFieldSources(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+
+	configPath := filepath.Join(dir, ".lssh.toml")
+	includePath := filepath.Join(dir, "include.toml")
+	writeIncludeTestFile(t, configPath, `
+[common]
+user = "demo"
+
+[template.base]
+port = "2222"
+note = "base template"
+
+[server.web]
+addr = "192.0.2.10"
+extends = ["base"]
+pass = "secret"
+
+[server.web.match.always]
+when.env_in = ["LSSH_FIELD_SOURCE_TEST"]
+note = "matched"
+
+[group.prod]
+servers = ["web"]
+tags = ["env=prod"]
+
+[includes]
+path = ["include.toml"]
+`)
+	writeIncludeTestFile(t, includePath, `
+[common]
+key = "~/.ssh/included"
+
+[server.db]
+addr = "192.0.2.20"
+`)
+	t.Setenv("LSSH_FIELD_SOURCE_TEST", "1")
+
+	c, err := readConfig(configPath)
+	if err != nil {
+		t.Fatalf("readConfig() error = %v", err)
+	}
+
+	web := c.FieldSources("web")
+	for key, want := range map[string]string{
+		"addr": "server.web (" + configPath + ")",
+		"port": "template.base",
+		"user": "common (" + configPath + ")",
+		"note": "server.web.match.always",
+		"tags": "group.prod",
+	} {
+		if web[key] != want {
+			t.Fatalf("FieldSources(web)[%q] = %q, want %q (all: %v)", key, web[key], want, web)
+		}
+	}
+
+	if got := c.MatchedBranches("web"); len(got) != 1 || got[0] != "server.web.match.always" {
+		t.Fatalf("MatchedBranches(web) = %v", got)
+	}
+
+	db := c.FieldSources("db")
+	if want := "server.db (" + includePath + ")"; db["addr"] != want {
+		t.Fatalf("FieldSources(db)[addr] = %q, want %q", db["addr"], want)
+	}
+	if want := "common (" + includePath + ")"; db["key"] != want {
+		t.Fatalf("FieldSources(db)[key] = %q, want %q", db["key"], want)
+	}
+
+	var out bytes.Buffer
+	if err := c.WriteServerListFormat(&out, nil, common.ListOptions{Explain: "web"}); err != nil {
+		t.Fatalf("WriteServerListFormat() error = %v", err)
+	}
+	got := out.String()
+	if strings.Contains(got, "secret") {
+		t.Fatalf("explain leaks a secret:\n%s", got)
+	}
+	if !strings.Contains(got, "template.base") || !strings.Contains(got, "<redacted>") {
+		t.Fatalf("explain output = \n%s", got)
+	}
+}
@@ -0,0 +1,149 @@
+// Copyright (c) 2026 Blacknon. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package conf
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// templateResolver expands `extends` references against [template.<name>]
+// blocks. Resolved templates are cached so that hundreds of servers sharing
+// the same templates are merged only once.
+type templateResolver struct {
+	templates map[string]ServerConfig
+	resolved  map[string]ServerConfig
+}
+
+func newTemplateResolver(templates map[string]ServerConfig) *templateResolver {
+	return &templateResolver{
+		templates: templates,
+		resolved:  map[string]ServerConfig{},
+	}
+}
+
+// ResolveTemplates merges the templates listed in each server's `extends`
+// into c.Server. Values set in the server itself always win. It must run
+// before ReduceCommon so that [common] stays the lowest priority layer.
+func (c *Config) ResolveTemplates() error {
+	if len(c.Server) == 0 {
+		return nil
+	}
+
+	resolver := newTemplateResolver(c.Template)
+
+	names := make([]string, 0, len(c.Server))
+	for name := range c.Server {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		merged, err := resolver.apply("server."+name, c.Server[name])
+		if err != nil {
+			return err
+		}
+		c.recordFieldSources(name, c.Server[name], merged, c.templateFieldSource(merged.Extends))
+		c.Server[name] = merged
+	}
+
+	return nil
+}
+
+// applyServerTemplates merges the templates listed in cfg.Extends under cfg.
+// label is used in error messages (ex. `server.web01`).
+func (c *Config) applyServerTemplates(label string, cfg ServerConfig) (ServerConfig, error) {
+	return newTemplateResolver(c.Template).apply(label, cfg)
+}
+
+func (r *templateResolver) apply(label string, cfg ServerConfig) (ServerConfig, error) {
+	if len(cfg.Extends) == 0 {
+		return cfg, nil
+	}
+
+	base, err := r.merge(cfg.Extends, []string{label})
+	if err != nil {
+		return cfg, err
+	}
+
+	result := serverConfigReduct(base, cfg)
+	result.Match = mergeTemplateMatches(base.Match, cfg.Match)
+	result.Extends = append([]string(nil), cfg.Extends...)
+	return result, nil
+}
+
+// merge combines the named templates in order. Later templates override
+// earlier ones.
+func (r *templateResolver) merge(names []string, stack []string) (ServerConfig, error) {
+	result := ServerConfig{}
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+
+		tmpl, err := r.resolve(name, stack)
+		if err != nil {
+			return result, err
+		}
+
+		matches := mergeTemplateMatches(result.Match, tmpl.Match)
+		result = serverConfigReduct(result, tmpl)
+		result.Match = matches
+	}
+	result.Extends = nil
+	return result, nil
+}
+
+// resolve returns the named template with its own `extends` applied.
+func (r *templateResolver) resolve(name string, stack []string) (ServerConfig, error) {
+	label := "template." + name
+	for i, item := range stack {
+		if item == label {
+			chain := append(append([]string(nil), stack[i:]...), label)
+			return ServerConfig{}, fmt.Errorf("template cycle detected: %s", strings.Join(chain, " -> "))
+		}
+	}
+
+	if resolved, ok := r.resolved[name]; ok {
+		return resolved, nil
+	}
+
+	tmpl, ok := r.templates[name]
+	if !ok {
+		return ServerConfig{}, fmt.Errorf("%s: extends unknown template %q", stack[len(stack)-1], name)
+	}
+
+	if len(tmpl.Extends) > 0 {
+		base, err := r.merge(tmpl.Extends, append(append([]string(nil), stack...), label))
+		if err != nil {
+			return ServerConfig{}, err
+		}
+		matches := mergeTemplateMatches(base.Match, tmpl.Match)
+		tmpl = serverConfigReduct(base, tmpl)
+		tmpl.Match = matches
+	}
+
+	r.resolved[name] = tmpl
+	return tmpl, nil
+}
+
+// mergeTemplateMatches returns the union of match branches. Branches in
+// override replace branches with the same name in base.
+func mergeTemplateMatches(base, override map[string]ServerMatchConfig) map[string]ServerMatchConfig {
+	if len(base) == 0 && len(override) == 0 {
+		return nil
+	}
+
+	result := make(map[string]ServerMatchConfig, len(base)+len(override))
+	for name, branch := range base {
+		result[name] = branch
+	}
+	for name, branch := range override {
+		result[name] = branch
+	}
+	return result
+}
@@ -0,0 +1,157 @@
+package conf
+
+import (
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveTemplatesMergeOrder(t *testing.T) {
+	cfg := Config{
+		Template: map[string]ServerConfig{
+			"base":      {User: "base", Port: "2222", Note: "base note"},
+			"bastioned": {Extends: []string{"base"}, Proxy: "bastion", User: "ops"},
+			"prod-keys": {Key: "~/.ssh/prod", User: "deploy"},
+		},
+		Server: map[string]ServerConfig{
+			"web01": {Extends: []string{"bastioned", "prod-keys"}, Addr: "10.0.0.1"},
+			"web02": {Extends: []string{"prod-keys", "bastioned"}, Addr: "10.0.0.2", Note: "own note"},
+			"plain": {Addr: "10.0.0.3"},
+		},
+	}
+
+	err := cfg.ResolveTemplates()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	web01 := cfg.Server["web01"]
+	assert.Equal(t, "10.0.0.1", web01.Addr)
+	assert.Equal(t, "deploy", web01.User)
+	assert.Equal(t, "bastion", web01.Proxy)
+	assert.Equal(t, "~/.ssh/prod", web01.Key)
+	assert.Equal(t, "2222", web01.Port)
+	assert.Equal(t, "base note", web01.Note)
+
+	web02 := cfg.Server["web02"]
+	assert.Equal(t, "ops", web02.User)
+	assert.Equal(t, "own note", web02.Note)
+
+	assert.Equal(t, ServerConfig{Addr: "10.0.0.3"}, cfg.Server["plain"])
+}
+
+func TestResolveTemplatesDetectsCycle(t *testing.T) {
+	cfg := Config{
+		Template: map[string]ServerConfig{
+			"a": {Extends: []string{"b"}},
+			"b": {Extends: []string{"a"}},
+		},
+		Server: map[string]ServerConfig{
+			"web01": {Extends: []string{"a"}},
+		},
+	}
+
+	err := cfg.ResolveTemplates()
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "template cycle detected: template.a -> template.b -> template.a")
+	}
+}
+
+func TestResolveTemplatesUnknownTemplate(t *testing.T) {
+	cfg := Config{
+		Server: map[string]ServerConfig{
+			"web01": {Extends: []string{"missing"}},
+		},
+	}
+
+	err := cfg.ResolveTemplates()
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), `server.web01: extends unknown template "missing"`)
+	}
+}
+
+func TestResolveTemplatesKeepsMatchBranches(t *testing.T) {
+	originalDetector := detectMatchContext
+	t.Cleanup(func() { detectMatchContext = originalDetector })
+	detectMatchContext = func(reqs matchRequirements) matchContext {
+		return matchContext{
+			LocalIPs: []netip.Addr{netip.MustParseAddr("192.168.100.10")},
+		}
+	}
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.toml")
+	err := os.WriteFile(path, []byte(`
+[template.office]
+user = "demo"
+pass = "secret"
+
+[template.office.match.office_network]
+when.local_ip_in = ["192.168.100.0/24"]
+proxy = "ssh-bastion"
+
+[server.app]
+extends = ["office"]
+addr = "192.168.100.50"
+`), 0o600)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	var cfg Config
+	if !assert.NoError(t, decodeConfigFile(path, &cfg)) {
+		return
+	}
+	if !assert.NoError(t, cfg.ResolveTemplates()) {
+		return
+	}
+	if !assert.NoError(t, cfg.ResolveConditionalMatches()) {
+		return
+	}
+
+	assert.Equal(t, "demo", cfg.Server["app"].User)
+	assert.Equal(t, "ssh-bastion", cfg.Server["app"].Proxy)
+}
+
+func TestReadConfigExtendsIncludedTemplate(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "main.toml")
+	files := map[string]string{
+		path: `
+[includes]
+path = ["conf.d/*.toml"]
+
+[server.web]
+extends = ["bast"]
+addr = "192.0.2.10"
+`,
+		filepath.Join(dir, "conf.d", "t.toml"): `
+[template.bast]
+user = "ops"
+key = "~/.ssh/ops"
+proxy = "bastion"
+`,
+	}
+	for name, body := range files {
+		if !assert.NoError(t, os.MkdirAll(filepath.Dir(name), 0o700)) {
+			return
+		}
+		if !assert.NoError(t, os.WriteFile(name, []byte(body), 0o600)) {
+			return
+		}
+	}
+
+	cfg, err := readConfig(path)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	web := cfg.Server["web"]
+	assert.Equal(t, "192.0.2.10", web.Addr)
+	assert.Equal(t, "ops", web.User)
+	assert.Equal(t, "~/.ssh/ops", web.Key)
+	assert.Equal(t, "bastion", web.Proxy)
+}
@@ -0,0 +1,847 @@
+// Copyright (c) 2026 Blacknon. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package conf
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/blacknon/lssh/internal/common"
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigFinding is a single problem reported by ValidateConfig.
+type ConfigFinding struct {
+	File    string
+	Line    int
+	Message string
+}
+
+func (f ConfigFinding) String() string {
+	switch {
+	case f.File != "" && f.Line > 0:
+		return fmt.Sprintf("%s:%d: %s", f.File, f.Line, f.Message)
+	case f.File != "":
+		return fmt.Sprintf("%s: %s", f.File, f.Message)
+	default:
+		return f.Message
+	}
+}
+
+// CheckConfig validates confPath, writes each finding to out and reports
+// whether the config is free of problems.
+func CheckConfig(confPath string, out io.Writer) (bool, error) {
+	findings, err := ValidateConfig(confPath)
+	if err != nil {
+		return false, err
+	}
+
+	for _, finding := range findings {
+		fmt.Fprintln(out, finding.String())
+	}
+	if len(findings) > 0 {
+		fmt.Fprintf(out, "%d problem(s) found.\n", len(findings))
+		return false, nil
+	}
+
+	fmt.Fprintf(out, "%s: OK\n", confPath)
+	return true, nil
+}
+
+// ValidateConfig loads confPath the same way as Read, and returns every
+// problem found instead of stopping at the first one.
+func ValidateConfig(confPath string) ([]ConfigFinding, error) {
+	v := &configValidator{files: map[string]map[string]int{}}
+
+	if !common.IsExist(expandOpenSSHPath(confPath)) {
+		return nil, fmt.Errorf("config file %s was not found", confPath)
+	}
+	fullPath := common.GetFullPath(confPath)
+
+	// file level checks: syntax and unknown keys
+	root, ok := v.inspectFile(fullPath)
+	if !ok {
+		return v.sorted(), nil
+	}
+	root.confPath = fullPath
+	root.confDDir = defaultConfDDir(fullPath)
+	filesOK := true
+	paths, err := root.includeFilePaths()
+	if err != nil {
+		v.add(ConfigFinding{File: fullPath, Message: err.Error()})
+		filesOK = false
+	}
+	seen := map[string]struct{}{fullPath: {}}
+	for len(paths) > 0 {
+		path := paths[0]
+		paths = paths[1:]
+		if _, ok := seen[path]; ok {
+			continue
+		}
+		seen[path] = struct{}{}
+
+		include, ok := v.inspectFile(path)
+		if !ok {
+			filesOK = false
+			continue
+		}
+		include.confPath = path
+		nested, err := include.includeFilePaths()
+		if err != nil {
+			v.add(ConfigFinding{File: path, Message: err.Error()})
+			filesOK = false
+			continue
+		}
+		paths = append(paths, nested...)
+	}
+	if !filesOK {
+		return v.sorted(), nil
+	}
+
+	// semantic checks on the merged config
+	c, err := readConfig(fullPath)
+	if err != nil {
+		v.add(ConfigFinding{File: fullPath, Message: err.Error()})
+		return v.sorted(), nil
+	}
+	v.config = c
+	v.checkServers()
+	v.checkProxies()
+	v.checkList()
+	v.checkLog()
+	v.checkAudit()
+
+	return v.sorted(), nil
+}
+
+type configValidator struct {
+	config   Config
+	files    map[string]map[string]int
+	findings []ConfigFinding
+	seen     map[string]struct{}
+}
+
+func (v *configValidator) add(finding ConfigFinding) {
+	if v.seen == nil {
+		v.seen = map[string]struct{}{}
+	}
+	key := finding.String()
+	if _, ok := v.seen[key]; ok {
+		return
+	}
+	v.seen[key] = struct{}{}
+	v.findings = append(v.findings, finding)
+}
+
+func (v *configValidator) sorted() []ConfigFinding {
+	sort.SliceStable(v.findings, func(i, j int) bool {
+		if v.findings[i].File != v.findings[j].File {
+			return v.findings[i].File < v.findings[j].File
+		}
+		return v.findings[i].Line < v.findings[j].Line
+	})
+	return v.findings
+}
+
+// inspectFile decodes a single config file, reports syntax errors and
+// unknown keys, and indexes the line of each key.
+func (v *configValidator) inspectFile(path string) (Config, bool) {
+	var c Config
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		v.add(ConfigFinding{File: path, Message: err.Error()})
+		return c, false
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		var node yaml.Node
+		if err := yaml.Unmarshal(data, &node); err != nil {
+			v.add(ConfigFinding{File: path, Line: yamlErrorLine(err), Message: err.Error()})
+			return c, false
+		}
+		if err := node.Decode(&c); err != nil {
+			v.add(ConfigFinding{File: path, Line: yamlErrorLine(err), Message: err.Error()})
+			return c, false
+		}
+
+		positions := map[string]int{}
+		unknown := []yamlUnknownKey{}
+		walkYAMLConfigKeys(&node, reflect.TypeOf(c), nil, positions, &unknown)
+		v.files[path] = positions
+		for _, key := range unknown {
+			v.add(ConfigFinding{File: path, Line: key.line, Message: fmt.Sprintf("unknown key %q", key.name)})
+		}
+
+	default:
+		md, err := toml.Decode(string(data), &c)
+		if err != nil {
+			finding := ConfigFinding{File: path, Message: err.Error()}
+			var parseErr toml.ParseError
+			if errors.As(err, &parseErr) {
+				finding.Line = parseErr.Position.Line
+				finding.Message = parseErr.Message
+			}
+			v.add(finding)
+			return c, false
+		}
+
+		positions := scanTOMLKeyLines(data)
+		v.files[path] = positions
+		reported := []string{}
+	undecoded:
+		for _, key := range md.Undecoded() {
+			name := strings.Join(key, ".")
+			for _, parent := range reported {
+				if strings.HasPrefix(name, parent+".") {
+					continue undecoded
+				}
+			}
+			reported = append(reported, name)
+			v.add(ConfigFinding{File: path, Line: positions[name], Message: fmt.Sprintf("unknown key %q", name)})
+		}
+	}
+
+	return c, true
+}
+
+// locate returns the finding position of key under [server.<name>].
+func (v *configValidator) locate(name, key string) ConfigFinding {
+	sources := v.config.serverSources[name]
+	if len(sources) == 0 {
+		return ConfigFinding{}
+	}
+
+	source := sources[len(sources)-1]
+	positions, ok := v.files[source]
+	if !ok {
+		return ConfigFinding{File: source}
+	}
+
+	line := 0
+	if key != "" {
+		line = positions["server."+name+"."+key]
+	}
+	if line == 0 {
+		line = positions["server."+name]
+	}
+	return ConfigFinding{File: source, Line: line}
+}
+
+func (v *configValidator) addServer(name, key, format string, args ...interface{}) {
+	finding := v.locate(name, key)
+	finding.Message = fmt.Sprintf("server.%s: ", name) + fmt.Sprintf(format, args...)
+	v.add(finding)
+}
+
+// addList adds a finding of the [list] section, at key if it is found.
+func (v *configValidator) addList(key, format string, args ...interface{}) {
+	v.addSection("list", key, format, args...)
+}
+
+// addSection adds a finding of a top level section of the main config file,
+// at key if it is found.
+func (v *configValidator) addSection(section, key, format string, args ...interface{}) {
+	finding := ConfigFinding{File: v.config.confPath}
+	if positions, ok := v.files[v.config.confPath]; ok {
+		finding.Line = positions[section+"."+key]
+		if finding.Line == 0 {
+			finding.Line = positions[section]
+		}
+	}
+	finding.Message = section + "." + key + ": " + fmt.Sprintf(format, args...)
+	v.add(finding)
+}
+
+func (v *configValidator) checkLog() {
+	if err := v.config.Log.CheckFormat(); err != nil {
+		v.addSection("log", "format", "%v", err)
+	}
+}
+
+func (v *configValidator) checkAudit() {
+	audit := v.config.Audit
+	if audit.Enable && audit.Path == "" && audit.Syslog == "" {
+		v.addSection("audit", "enable", "neither path nor syslog is set")
+	}
+	if audit.Syslog != "" {
+		if _, _, err := audit.SyslogAddr(); err != nil {
+			v.addSection("audit", "syslog", "%v", err)
+		}
+	}
+}
+
+func (v *configValidator) checkList() {
+	if err := v.config.CheckListGroupBy(v.config.List.GroupBy); err != nil {
+		v.addList("group_by", "%v", err)
+	}
+	if timeout := v.config.List.ProbeTimeout; timeout != "" {
+		if d, err := time.ParseDuration(timeout); err != nil || d <= 0 {
+			v.addList("probe_timeout", "invalid duration %q", timeout)
+		}
+	}
+	if v.config.List.ProbeConcurrency < 0 {
+		v.addList("probe_concurrency", "must not be negative")
+	}
+
+	for _, column := range v.config.List.Columns {
+		if column.Field != "connect" {
+			if err := v.config.CheckServerListFields([]string{column.Field}); err != nil {
+				v.addList("columns", "%v", err)
+			}
+		}
+		switch column.Align {
+		case "", ListAlignLeft, ListAlignRight, ListAlignCenter:
+		default:
+			v.addList("columns", "unknown align %q of %q: must be one of left, right, center", column.Align, column.Field)
+		}
+		if column.Width < 0 {
+			v.addList("columns", "width of %q must not be negative", column.Field)
+		}
+	}
+
+	for _, binding := range v.config.List.Keys.Bindings() {
+		for _, key := range binding.Keys {
+			if _, err := ParseListKey(key); err != nil {
+				v.addList("keys."+binding.Action, "%v", err)
+			}
+		}
+	}
+}
+
+func (v *configValidator) checkServers() {
+	names := make([]string, 0, len(v.config.Server))
+	for name := range v.config.Server {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	connectorErrors := map[string]error{}
+
+	for _, name := range names {
+		server := v.config.Server[name]
+
+		if sources := v.config.serverSources[name]; len(sources) > 1 {
+			v.addServer(name, "", "defined more than once (%s); the last one is used", strings.Join(sources, ", "))
+		}
+
+		v.checkSecretRefs(name, server)
+		v.checkInterpolations(name, server)
+		v.checkRemoteEnv(name, server)
+
+		if server.Ignore {
+			continue
+		}
+
+		if connectorName := strings.TrimSpace(server.ConnectorName); connectorName != "" && connectorName != "ssh" {
+			// resolving a connector runs plugin.describe, so cache the result
+			cacheKey := server.ProviderName + "\x00" + connectorName
+			err, checked := connectorErrors[cacheKey]
+			if !checked {
+				_, _, err = v.config.resolveConnectorProvider(server, connectorName)
+				connectorErrors[cacheKey] = err
+			}
+			if err != nil {
+				v.addServer(name, "connector_name", "connector_name: %v", err)
+			}
+		}
+
+		if v.config.ServerUsesConnector(name) {
+			continue
+		}
+
+		if server.Addr == "" {
+			v.addServer(name, "", "'addr' is not set")
+		}
+		if server.User == "" {
+			v.addServer(name, "", "'user' is not set")
+		}
+		if !checkFormatServerConfAuth(server) {
+			v.addServer(name, "", "authentication information is not set")
+		}
+
+		v.checkForwards(name, server)
+		v.checkKeyFiles(name, server)
+		v.checkHostKey(name, server)
+	}
+}
+
+func (v *configValidator) checkHostKey(name string, server ServerConfig) {
+	if _, err := server.HostKeyChecking(); err != nil {
+		v.addServer(name, "strict_host_key_checking", "%v", err)
+	}
+	if err := server.CheckHostKeyFingerprints(); err != nil {
+		v.addServer(name, "host_key_fingerprints", "host_key_fingerprints: %v", err)
+	}
+}
+
+func (v *configValidator) checkSecretRefs(name string, server ServerConfig) {
+	refs := []struct {
+		key   string
+		value string
+	}{
+		{"pass_ref", server.PassRef},
+		{"key_ref", server.KeyRef},
+		{"keycmdpass_ref", server.KeyCommandPassRef},
+		{"keypass_ref", server.KeyPassRef},
+		{"cert_ref", server.CertRef},
+		{"certkey_ref", server.CertKeyRef},
+		{"certkeypass_ref", server.CertKeyPassRef},
+		{"pkcs11pin_ref", server.PKCS11PINRef},
+	}
+
+	for _, ref := range refs {
+		if ref.value == "" {
+			continue
+		}
+
+		providerName, _, err := parseSecretRef(ref.value)
+		if err != nil {
+			v.addServer(name, ref.key, "%s: %v", ref.key, err)
+			continue
+		}
+
+		raw, ok := v.config.Provider[providerName]
+		switch {
+		case !ok:
+			v.addServer(name, ref.key, "%s: provider %q is not configured", ref.key, providerName)
+		case !providerEnabled(raw):
+			v.addServer(name, ref.key, "%s: provider %q is disabled", ref.key, providerName)
+		case !providerHasCapability(raw, "secret"):
+			v.addServer(name, ref.key, "%s: provider %q does not support secret capability", ref.key, providerName)
+		}
+	}
+}
+
+// checkInterpolations checks the `${...}` references of server. They are
+// resolved just before connect, so only their syntax and providers are checked.
+func (v *configValidator) checkInterpolations(name string, server ServerConfig) {
+	value := reflect.ValueOf(server)
+	typ := value.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		key := typ.Field(i).Tag.Get("toml")
+		if key == "" || key == "-" {
+			continue
+		}
+
+		values := []string{}
+		switch field := value.Field(i); field.Kind() {
+		case reflect.String:
+			values = append(values, field.String())
+		case reflect.Slice:
+			if field.Type().Elem().Kind() == reflect.String {
+				values = append(values, field.Interface().([]string)...)
+			}
+		case reflect.Map:
+			if m, ok := field.Interface().(map[string]string); ok {
+				for _, item := range m {
+					values = append(values, item)
+				}
+			}
+		}
+
+		for _, item := range values {
+			_, _ = replaceInterpolations(item, func(expr string) (string, error) {
+				if err := v.checkInterpolation(expr); err != nil {
+					v.addServer(name, key, "%s: %v", key, err)
+				}
+				return "", nil
+			})
+		}
+	}
+}
+
+func (v *configValidator) checkInterpolation(expr string) error {
+	switch {
+	case strings.HasPrefix(expr, "env:"):
+		if strings.TrimPrefix(expr, "env:") == "" {
+			return fmt.Errorf("${%s}: variable name is empty", expr)
+		}
+	case strings.HasPrefix(expr, "secret:"):
+		spec := strings.TrimPrefix(expr, "secret:")
+		if i := strings.LastIndexByte(spec, '#'); i >= 0 {
+			spec = spec[:i]
+		}
+		providerName, ref, ok := strings.Cut(spec, "/")
+		if !ok || providerName == "" || ref == "" {
+			return fmt.Errorf("${%s}: want ${secret:provider/ref#field}", expr)
+		}
+		raw, ok := v.config.Provider[providerName]
+		switch {
+		case !ok:
+			return fmt.Errorf("${%s}: provider %q is not configured", expr, providerName)
+		case !providerEnabled(raw):
+			return fmt.Errorf("${%s}: provider %q is disabled", expr, providerName)
+		case !providerHasCapability(raw, "secret"):
+			return fmt.Errorf("${%s}: provider %q does not support secret capability", expr, providerName)
+		}
+	case expr == "server.name", expr == "local.user":
+	case strings.HasPrefix(expr, "server.meta.") && expr != "server.meta.":
+	default:
+		return fmt.Errorf("${%s}: unknown reference", expr)
+	}
+	return nil
+}
+
+func (v *configValidator) checkRemoteEnv(name string, server ServerConfig) {
+	for key := range server.SetEnv {
+		if !remoteEnvNameRegexp.MatchString(key) {
+			v.addServer(name, "set_env", "set_env: invalid variable name %q", key)
+		}
+	}
+	for _, pattern := range server.SendEnv {
+		if _, err := path.Match(pattern, ""); err != nil || strings.TrimSpace(pattern) == "" {
+			v.addServer(name, "send_env", "send_env: invalid pattern %q", pattern)
+		}
+	}
+}
+
+func (v *configValidator) checkForwards(name string, server ServerConfig) {
+	for _, spec := range server.PortForwards {
+		if _, err := ParsePortForward(spec); err != nil {
+			v.addServer(name, "port_forwards", "port_forwards: invalid forward %q: %v", spec, err)
+		}
+	}
+
+	if (server.PortForwardLocal == "") != (server.PortForwardRemote == "") {
+		v.addServer(name, "port_forward_local", "port_forward_local and port_forward_remote must be set together")
+	}
+	switch strings.ToLower(server.PortForwardMode) {
+	case "", "l", "local", "r", "remote":
+	default:
+		v.addServer(name, "port_forward", "port_forward: invalid mode %q", server.PortForwardMode)
+	}
+
+	ports := []struct {
+		key   string
+		value string
+	}{
+		{"dynamic_port_forward", server.DynamicPortForward},
+		{"reverse_dynamic_port_forward", server.ReverseDynamicPortForward},
+		{"http_dynamic_port_forward", server.HTTPDynamicPortForward},
+		{"http_reverse_dynamic_port_forward", server.HTTPReverseDynamicPortForward},
+		{"nfs_dynamic_forward", server.NFSDynamicForwardPort},
+		{"nfs_reverse_dynamic_forward", server.NFSReverseDynamicForwardPort},
+		{"smb_dynamic_forward", server.SMBDynamicForwardPort},
+		{"smb_reverse_dynamic_forward", server.SMBReverseDynamicForwardPort},
+	}
+	for _, port := range ports {
+		if port.value == "" {
+			continue
+		}
+		if n, err := strconv.Atoi(port.value); err != nil || n < 1 || n > 65535 {
+			v.addServer(name, port.key, "%s: invalid port %q", port.key, port.value)
+		}
+	}
+}
+
+func (v *configValidator) checkKeyFiles(name string, server ServerConfig) {
+	type keyFile struct {
+		key  string
+		path string
+	}
+
+	files := []keyFile{}
+	if server.KeyRef == "" {
+		files = append(files, keyFile{"key", server.Key})
+	}
+	if server.CertRef == "" {
+		files = append(files, keyFile{"cert", server.Cert})
+	}
+	if server.CertKeyRef == "" && !server.CertPKCS11 {
+		files = append(files, keyFile{"certkey", server.CertKey})
+	}
+	for _, value := range server.Keys {
+		files = append(files, keyFile{"keys", strings.SplitN(value, "::", 2)[0]})
+	}
+	for _, value := range server.Certs {
+		parts := strings.SplitN(value, "::", 3)
+		files = append(files, keyFile{"certs", parts[0]})
+		if len(parts) > 1 {
+			files = append(files, keyFile{"certs", parts[1]})
+		}
+	}
+	for _, value := range server.SSHAgentKeyPath {
+		files = append(files, keyFile{"ssh_agent_key", strings.SplitN(value, "::", 2)[0]})
+	}
+
+	for _, file := range files {
+		// resolved just before connect
+		if strings.TrimSpace(file.path) == "" || strings.Contains(file.path, "${") {
+			continue
+		}
+
+		f, err := os.Open(expandOpenSSHPath(file.path))
+		if err != nil {
+			v.addServer(name, file.key, "%s: cannot read %s: %v", file.key, file.path, unwrapPathError(err))
+			continue
+		}
+		f.Close()
+	}
+}
+
+func unwrapPathError(err error) error {
+	var pathErr *os.PathError
+	if errors.As(err, &pathErr) {
+		return pathErr.Err
+	}
+	return err
+}
+
+// checkProxies follows the proxy route of every server, the same way
+// getProxyRoute in the ssh package does, and reports dangling references
+// and loops.
+func (v *configValidator) checkProxies() {
+	names := make([]string, 0, len(v.config.Server))
+	for name := range v.config.Server {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if v.config.Server[name].Ignore {
+			continue
+		}
+
+		route := []string{name}
+		visited := map[string]struct{}{"ssh:" + name: {}}
+		conName, conType := name, "ssh"
+
+		for {
+			var proxyName, proxyType string
+			switch conType {
+			case "http", "https", "socks", "socks5":
+				proxyConf := v.config.Proxy[conName]
+				proxyName, proxyType = proxyConf.Proxy, proxyConf.ProxyType
+			default:
+				serverConf := v.config.Server[conName]
+				if serverConf.ProxyCommand != "" && serverConf.ProxyCommand != "none" {
+					proxyName = ""
+				} else {
+					proxyName, proxyType = serverConf.Proxy, serverConf.ProxyType
+				}
+			}
+			if proxyName == "" {
+				break
+			}
+
+			switch proxyType {
+			case "http", "https", "socks", "socks5":
+				if _, ok := v.config.Proxy[proxyName]; !ok {
+					v.addServer(conName, "proxy", "proxy %q is not defined in [proxy]", proxyName)
+					proxyName = ""
+				}
+			default:
+				proxyType = "ssh"
+				if _, ok := v.config.Server[proxyName]; !ok {
+					v.addServer(conName, "proxy", "proxy %q is not defined in [server]", proxyName)
+					proxyName = ""
+				}
+			}
+			if proxyName == "" {
+				break
+			}
+
+			route = append(route, proxyName)
+			if _, ok := visited[proxyType+":"+proxyName]; ok {
+				v.addServer(name, "proxy", "proxy loop detected: %s", strings.Join(route, " -> "))
+				break
+			}
+			visited[proxyType+":"+proxyName] = struct{}{}
+			conName, conType = proxyName, proxyType
+		}
+	}
+}
+
+// scanTOMLKeyLines returns the line number of each table and key in a TOML
+// document, keyed by the dotted key path.
+func scanTOMLKeyLines(data []byte) map[string]int {
+	positions := map[string]int{}
+	table := []string{}
+	multiline := ""
+
+	for i, line := range strings.Split(string(data), "\n") {
+		lineNo := i + 1
+		trimmed := strings.TrimSpace(line)
+
+		if multiline != "" {
+			if strings.Contains(trimmed, multiline) {
+				multiline = ""
+			}
+			continue
+		}
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+
+		if strings.HasPrefix(trimmed, "[") {
+			header := strings.TrimSpace(strings.Trim(stripTOMLComment(trimmed), "[] \t"))
+			table = splitTOMLKey(header)
+			key := strings.Join(table, ".")
+			if _, ok := positions[key]; !ok {
+				positions[key] = lineNo
+			}
+			continue
+		}
+
+		eq := strings.Index(trimmed, "=")
+		if eq <= 0 {
+			continue
+		}
+		key := strings.Join(append(append([]string(nil), table...), splitTOMLKey(trimmed[:eq])...), ".")
+		if _, ok := positions[key]; !ok {
+			positions[key] = lineNo
+		}
+
+		value := trimmed[eq+1:]
+		for _, quote := range []string{`"""`, `'''`} {
+			if strings.Count(value, quote) == 1 {
+				multiline = quote
+			}
+		}
+	}
+
+	return positions
+}
+
+func stripTOMLComment(line string) string {
+	if idx := strings.Index(line, "#"); idx >= 0 && !strings.ContainsAny(line[:idx], `"'`) {
+		return line[:idx]
+	}
+	return line
+}
+
+// splitTOMLKey splits a dotted TOML key, honoring quoted parts.
+func splitTOMLKey(key string) []string {
+	parts := []string{}
+	var current strings.Builder
+	quote := rune(0)
+
+	for _, r := range key {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+				continue
+			}
+			current.WriteRune(r)
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '.':
+			parts = append(parts, strings.TrimSpace(current.String()))
+			current.Reset()
+		default:
+			current.WriteRune(r)
+		}
+	}
+	parts = append(parts, strings.TrimSpace(current.String()))
+	return parts
+}
+
+type yamlUnknownKey struct {
+	name string
+	line int
+}
+
+// walkYAMLConfigKeys records the line of each mapping key and collects keys
+// that do not match any field of t.
+func walkYAMLConfigKeys(node *yaml.Node, t reflect.Type, path []string, positions map[string]int, unknown *[]yamlUnknownKey) {
+	if node == nil {
+		return
+	}
+	if node.Kind == yaml.DocumentNode {
+		for _, child := range node.Content {
+			walkYAMLConfigKeys(child, t, path, positions, unknown)
+		}
+		return
+	}
+
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t != nil {
+		// types with their own decoder (ex. ControlPersistDuration) are leaves
+		if _, ok := reflect.PointerTo(t).MethodByName("UnmarshalYAML"); ok {
+			return
+		}
+	}
+
+	switch node.Kind {
+	case yaml.SequenceNode:
+		var elem reflect.Type
+		if t != nil && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) {
+			elem = t.Elem()
+		}
+		for _, child := range node.Content {
+			walkYAMLConfigKeys(child, elem, path, positions, unknown)
+		}
+
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			keyNode, valueNode := node.Content[i], node.Content[i+1]
+			childPath := append(append([]string(nil), path...), keyNode.Value)
+			name := strings.Join(childPath, ".")
+			if _, ok := positions[name]; !ok {
+				positions[name] = keyNode.Line
+			}
+
+			var childType reflect.Type
+			switch {
+			case t == nil || t.Kind() == reflect.Interface:
+			case t.Kind() == reflect.Map:
+				childType = t.Elem()
+			case t.Kind() == reflect.Struct:
+				field, ok := yamlStructField(t, keyNode.Value)
+				if !ok {
+					*unknown = append(*unknown, yamlUnknownKey{name: name, line: keyNode.Line})
+					continue
+				}
+				childType = field
+			}
+			walkYAMLConfigKeys(valueNode, childType, childPath, positions, unknown)
+		}
+	}
+}
+
+// yamlStructField returns the type of the field of t decoded from key.
+func yamlStructField(t reflect.Type, key string) (reflect.Type, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		name := strings.Split(field.Tag.Get("yaml"), ",")[0]
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		if name == key {
+			return field.Type, true
+		}
+	}
+	return nil, false
+}
+
+var yamlErrorLineRegexp = regexp.MustCompile(`line (\d+)`)
+
+func yamlErrorLine(err error) int {
+	match := yamlErrorLineRegexp.FindStringSubmatch(err.Error())
+	if len(match) != 2 {
+		return 0
+	}
+	line, _ := strconv.Atoi(match[1])
+	return line
+}
@@ -0,0 +1,384 @@
+package conf
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func validateTestConfig(t *testing.T, dir, name, body string) []string {
+	t.Helper()
+
+	t.Setenv("HOME", dir)
+	configPath := filepath.Join(dir, name)
+	if err := os.WriteFile(configPath, []byte(body), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	findings, err := ValidateConfig(configPath)
+	if err != nil {
+		t.Fatalf("ValidateConfig() error = %v", err)
+	}
+
+	result := make([]string, 0, len(findings))
+	for _, finding := range findings {
+		result = append(result, finding.String())
+	}
+	return result
+}
+
+func assertFinding(t *testing.T, findings []string, want string) {
+	t.Helper()
+
+	for _, finding := range findings {
+		if strings.Contains(finding, want) {
+			return
+		}
+	}
+	t.Fatalf("finding %q not found in:\n%s", want, strings.Join(findings, "\n"))
+}
+
+func TestValidateConfigReportsProblemsWithLines(t *testing.T) {
+	dir := t.TempDir()
+	keyPath := filepath.Join(dir, "id_ed25519")
+	if err := os.WriteFile(keyPath, []byte("key"), 0o600); err != nil {
+		t.Fatalf("write key: %v", err)
+	}
+
+	findings := validateTestConfig(t, dir, "lssh.toml", `
+[common]
+user = "demo"
+key = "`+keyPath+`"
+
+[server.web]
+addr = "192.0.2.10"
+proxy = "missing"
+typo_key = "x"
+
+[server.loop1]
+addr = "192.0.2.11"
+proxy = "loop2"
+
+[server.loop2]
+addr = "192.0.2.12"
+proxy = "loop1"
+
+[server.fwd]
+addr = "192.0.2.13"
+port_forwards = ["local:8080:localhost:80", "sideways:1:2"]
+
+[server.badkey]
+addr = "192.0.2.14"
+key = "`+filepath.Join(dir, "missing_key")+`"
+
+[server.secret]
+addr = "192.0.2.15"
+pass_ref = "vault:db/password"
+`)
+
+	path := filepath.Join(dir, "lssh.toml")
+	assertFinding(t, findings, path+`:9: unknown key "server.web.typo_key"`)
+	assertFinding(t, findings, path+`:8: server.web: proxy "missing" is not defined in [server]`)
+	assertFinding(t, findings, "proxy loop detected: loop1 -> loop2 -> loop1")
+	assertFinding(t, findings, path+`:21: server.fwd: port_forwards: invalid forward "sideways:1:2"`)
+	assertFinding(t, findings, path+`:25: server.badkey: key: cannot read`)
+	assertFinding(t, findings, path+`:29: server.secret: pass_ref: provider "vault" is not configured`)
+
+	for _, finding := range findings {
+		if strings.Contains(finding, "local:8080:localhost:80") {
+			t.Fatalf("valid forward reported: %s", finding)
+		}
+	}
+}
+
+func TestValidateConfigReportsDuplicateNamesAcrossIncludes(t *testing.T) {
+	dir := t.TempDir()
+	includePath := filepath.Join(dir, "include.toml")
+	if err := os.WriteFile(includePath, []byte(`
+[server.app]
+addr = "192.0.2.21"
+user = "demo"
+agentauth = true
+`), 0o600); err != nil {
+		t.Fatalf("write include: %v", err)
+	}
+
+	findings := validateTestConfig(t, dir, "lssh.toml", `
+[includes]
+path = ["`+includePath+`"]
+
+[server.app]
+addr = "192.0.2.20"
+user = "demo"
+agentauth = true
+`)
+
+	assertFinding(t, findings, includePath+`:2: server.app: defined more than once (`+filepath.Join(dir, "lssh.toml")+`, `+includePath+`)`)
+}
+
+func TestValidateConfigReportsYAMLUnknownKeys(t *testing.T) {
+	dir := t.TempDir()
+
+	findings := validateTestConfig(t, dir, "lssh.yaml", `
+server:
+  web:
+    addr: 192.0.2.30
+    user: demo
+    agentauth: true
+    control_persist: 10m
+    portt: "22"
+`)
+
+	if len(findings) != 1 {
+		t.Fatalf("findings = %q, want only the unknown key", findings)
+	}
+	assertFinding(t, findings, filepath.Join(dir, "lssh.yaml")+`:8: unknown key "server.web.portt"`)
+}
+
+func TestValidateConfigReportsSyntaxErrorLine(t *testing.T) {
+	dir := t.TempDir()
+
+	findings := validateTestConfig(t, dir, "lssh.toml", `
+[server.web]
+addr = "192.0.2.40
+`)
+
+	assertFinding(t, findings, filepath.Join(dir, "lssh.toml")+":3:")
+}
+
+func TestCheckConfigOK(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	configPath := filepath.Join(dir, "lssh.toml")
+	if err := os.WriteFile(configPath, []byte(`
+[server.web]
+addr = "192.0.2.50"
+user = "demo"
+agentauth = true
+`), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	var out bytes.Buffer
+	ok, err := CheckConfig(configPath, &out)
+	if err != nil {
+		t.Fatalf("CheckConfig() error = %v", err)
+	}
+	if !ok {
+		t.Fatalf("CheckConfig() ok = false, output = %q", out.String())
+	}
+}
+
+func TestScanTOMLKeyLines(t *testing.T) {
+	positions := scanTOMLKeyLines([]byte(`[common]
+user = "demo"
+
+[server."web.example"]
+addr = "192.0.2.1" # comment
+note = """
+key = "not a key"
+"""
+port = "22"
+`))
+
+	want := map[string]int{
+		"common":                  1,
+		"common.user":             2,
+		"server.web.example":      4,
+		"server.web.example.addr": 5,
+		"server.web.example.note": 6,
+		"server.web.example.port": 9,
+	}
+	for key, line := range want {
+		if positions[key] != line {
+			t.Fatalf("positions[%q] = %d, want %d (%v)", key, positions[key], line, positions)
+		}
+	}
+	if _, ok := positions["server.web.example.key"]; ok {
+		t.Fatalf("multiline string content indexed as a key: %v", positions)
+	}
+}
+
+func TestValidateConfigChecksInterpolations(t *testing.T) {
+	dir := t.TempDir()
+
+	findings := validateTestConfig(t, dir, "lssh.toml", `
+[server.web]
+addr = "${env:WEB_IP}"
+user = "${local.user}"
+key = "${env:WEB_KEY}"
+proxy_cmd = "connect ${secret:vault/jump#token} ${HOME} %h %p"
+note = "${server.nmae}"
+`)
+
+	path := filepath.Join(dir, "lssh.toml")
+	assertFinding(t, findings, path+`:6: server.web: proxy_cmd: ${secret:vault/jump#token}: provider "vault" is not configured`)
+	assertFinding(t, findings, path+`:7: server.web: note: ${server.nmae}: unknown reference`)
+	for _, finding := range findings {
+		if strings.Contains(finding, "WEB_") || strings.Contains(finding, "HOME") {
+			t.Fatalf("valid reference reported: %s", finding)
+		}
+	}
+}
+
+func TestValidateConfigChecksList(t *testing.T) {
+	dir := t.TempDir()
+	findings := validateTestConfig(t, dir, "lssh.toml", `
+[common]
+user = "demo"
+pass = "secret"
+
+[server.web]
+addr = "192.0.2.10"
+
+[list.keys]
+up = ["Up", "Ctrl+K"]
+down = ["Ctrl+Down"]
+
+[[list.columns]]
+field = "meta.region"
+
+[[list.columns]]
+field = "unknown_field"
+align = "middle"
+`)
+
+	wantPath := filepath.Join(dir, "lssh.toml")
+	assertFinding(t, findings, wantPath+":11: list.keys.down: unsupported key binding \"Ctrl+Down\": Ctrl takes a letter")
+	assertFinding(t, findings, wantPath+":13: list.columns: unknown list field \"unknown_field\"")
+	assertFinding(t, findings, "list.columns: unknown align \"middle\" of \"unknown_field\"")
+	for _, finding := range findings {
+		if strings.Contains(finding, "Ctrl+K") || strings.Contains(finding, "meta.region") {
+			t.Fatalf("unexpected finding %q", finding)
+		}
+	}
+}
+
+func TestValidateConfigChecksLogFormat(t *testing.T) {
+	dir := t.TempDir()
+	findings := validateTestConfig(t, dir, "lssh.toml", `
+[common]
+user = "demo"
+pass = "secret"
+
+[log]
+enable = true
+format = "ttyrec"
+
+[server.web]
+addr = "192.0.2.10"
+`)
+
+	wantPath := filepath.Join(dir, "lssh.toml")
+	assertFinding(t, findings, wantPath+":8: log.format: unknown log format \"ttyrec\". [text|asciicast]")
+}
+
+func TestValidateConfigChecksAudit(t *testing.T) {
+	dir := t.TempDir()
+	findings := validateTestConfig(t, dir, "lssh.toml", `
+[common]
+user = "demo"
+pass = "secret"
+
+[audit]
+enable = true
+syslog = "udp:192.0.2.1:514"
+
+[server.web]
+addr = "192.0.2.10"
+`)
+
+	wantPath := filepath.Join(dir, "lssh.toml")
+	assertFinding(t, findings, wantPath+":8: audit.syslog: invalid syslog \"udp:192.0.2.1:514\". [local|udp://host:port|tcp://host:port|unix:///path|unixgram:///path]")
+
+	findings = validateTestConfig(t, dir, "lssh.toml", `
+[common]
+user = "demo"
+pass = "secret"
+
+[audit]
+enable = true
+
+[server.web]
+addr = "192.0.2.10"
+`)
+	assertFinding(t, findings, wantPath+":7: audit.enable: neither path nor syslog is set")
+}
+
+func TestValidateConfigChecksHostKey(t *testing.T) {
+	dir := t.TempDir()
+	findings := validateTestConfig(t, dir, "lssh.toml", `
+[common]
+user = "demo"
+pass = "secret"
+
+[server.web]
+addr = "192.0.2.10"
+strict_host_key_checking = "sometimes"
+host_key_fingerprints = ["MD5:16:27:ac:a5:76:28:2d:36:63:1b:56:4d:eb:df:a6:48"]
+
+[server.db]
+addr = "192.0.2.11"
+strict_host_key_checking = "accept-new"
+host_key_fingerprints = ["SHA256:+DiY3wvvV6TuJJhbpZisF/zLDA0zPMSvHdkr4UvCOqU"]
+`)
+
+	wantPath := filepath.Join(dir, "lssh.toml")
+	assertFinding(t, findings, wantPath+":8: server.web: unknown strict_host_key_checking \"sometimes\": must be one of yes, accept-new, no, ask")
+	assertFinding(t, findings, wantPath+":9: server.web: host_key_fingerprints: fingerprint \"MD5:16:27:ac:a5:76:28:2d:36:63:1b:56:4d:eb:df:a6:48\" is not a SHA256 fingerprint")
+	if len(findings) != 2 {
+		t.Fatalf("findings = %v, want only the web server", findings)
+	}
+}
+
+func TestServerConfigHostKeyChecking(t *testing.T) {
+	tests := []struct {
+		config ServerConfig
+		want   string
+	}{
+		{ServerConfig{}, HostKeyCheckingNo},
+		{ServerConfig{CheckKnownHosts: true}, HostKeyCheckingAsk},
+		{ServerConfig{CheckKnownHosts: true, StrictHostKeyChecking: "Yes"}, HostKeyCheckingYes},
+		{ServerConfig{StrictHostKeyChecking: "accept-new"}, HostKeyCheckingAcceptNew},
+	}
+	for _, tt := range tests {
+		if got, err := tt.config.HostKeyChecking(); err != nil || got != tt.want {
+			t.Fatalf("HostKeyChecking() of %+v = %q, %v, want %q", tt.config, got, err, tt.want)
+		}
+	}
+
+	bad := ServerConfig{HostKeyFingerprints: []string{"SHA256:short"}}
+	if err := bad.CheckHostKeyFingerprints(); err == nil {
+		t.Fatal("CheckHostKeyFingerprints() accepts a short fingerprint")
+	}
+}
+
+func TestAuditConfigSyslogAddr(t *testing.T) {
+	network, address, err := AuditConfig{Syslog: "local"}.SyslogAddr()
+	if err != nil || network != "" || address != "" {
+		t.Fatalf("local syslog: network=%q address=%q err=%v", network, address, err)
+	}
+
+	network, address, err = AuditConfig{Syslog: "unix:///dev/log"}.SyslogAddr()
+	if err != nil || network != "unix" || address != "/dev/log" {
+		t.Fatalf("unix syslog: network=%q address=%q err=%v", network, address, err)
+	}
+
+	if _, _, err := (AuditConfig{Syslog: "http://192.0.2.1"}).SyslogAddr(); err == nil {
+		t.Fatal("http syslog: expected error")
+	}
+}
+
+func TestLogConfigFormat(t *testing.T) {
+	text := LogConfig{}
+	if err := text.CheckFormat(); err != nil || text.IsAsciicast() || text.FileExt() != ".log" {
+		t.Fatalf("default log format: err=%v asciicast=%v ext=%s", err, text.IsAsciicast(), text.FileExt())
+	}
+
+	cast := LogConfig{Format: LogFormatAsciicast}
+	if err := cast.CheckFormat(); err != nil || !cast.IsAsciicast() || cast.FileExt() != ".cast" {
+		t.Fatalf("asciicast log format: err=%v asciicast=%v ext=%s", err, cast.IsAsciicast(), cast.FileExt())
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright (c) 2026 Blacknon. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package hoststate
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// HostSet is a saved host selection. Hosts are server names or host selector
+// expressions (ex. `tag:web,env=prod`), which are resolved again against the
+// current inventory each time the set is used.
+type HostSet struct {
+	Hosts []string  `json:"hosts"`
+	Saved time.Time `json:"saved"`
+}
+
+// ValidateSetName returns an error if name can not be a host set name.
+func ValidateSetName(name string) error {
+	if name == "" {
+		return fmt.Errorf("empty host set name")
+	}
+	if strings.ContainsAny(name, " \t\r\n") {
+		return fmt.Errorf("host set name %q must not contain spaces", name)
+	}
+	if strings.HasPrefix(name, "-") {
+		return fmt.Errorf("host set name %q must not start with '-'", name)
+	}
+	return nil
+}
+
+// PutSet saves hosts as the host set name, replacing a set of the same name.
+func (s *State) PutSet(name string, hosts []string, now time.Time) error {
+	if err := ValidateSetName(name); err != nil {
+		return err
+	}
+	if len(hosts) == 0 {
+		return fmt.Errorf("host set %q has no hosts", name)
+	}
+	if s.Sets == nil {
+		s.Sets = map[string]*HostSet{}
+	}
+	s.Sets[name] = &HostSet{Hosts: append([]string(nil), hosts...), Saved: now}
+	return nil
+}
+
+// SetNames returns the names of the host sets in order.
+func (s *State) SetNames() []string {
+	names := make([]string, 0, len(s.Sets))
+	for name := range s.Sets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SetHosts loads the state and returns the hosts of the host sets names, in
+// order. It returns an error if a set is not found.
+func SetHosts(names []string) ([]string, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+	s, err := Load()
+	if err != nil {
+		return nil, err
+	}
+
+	hosts := []string{}
+	for _, name := range names {
+		set, ok := s.Sets[name]
+		if !ok {
+			return nil, fmt.Errorf("host set %q not found", name)
+		}
+		hosts = append(hosts, set.Hosts...)
+	}
+	return hosts, nil
+}
@@ -0,0 +1,234 @@
+// Copyright (c) 2026 Blacknon. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+/*
+hoststate package records the hosts used by each lssh command, the
+favorite hosts and the saved host sets, in a small state file under the user
+state directory.
+*/
+
+package hoststate
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const stateFileName = "hosts.json"
+
+// State is the content of the host state file.
+type State struct {
+	Favorites []string            `json:"favorites,omitempty"`
+	Commands  map[string]*Command `json:"commands,omitempty"`
+	Sets      map[string]*HostSet `json:"sets,omitempty"`
+
+	path string
+}
+
+// Command is the host history of one command (ex. lssh, lscp).
+type Command struct {
+	// Last is the hosts of the last successful connection.
+	Last  []string         `json:"last,omitempty"`
+	Hosts map[string]*Host `json:"hosts,omitempty"`
+}
+
+// Host is the usage of one host.
+type Host struct {
+	Count    int       `json:"count"`
+	LastUsed time.Time `json:"last_used"`
+}
+
+// StateDir returns the lssh state directory
+// ($XDG_STATE_HOME/lssh, or ~/.local/state/lssh).
+func StateDir() (string, error) {
+	if xdg := strings.TrimSpace(os.Getenv("XDG_STATE_HOME")); xdg != "" {
+		return filepath.Join(xdg, "lssh"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".local", "state", "lssh"), nil
+}
+
+// Load reads the state file. A missing file is an empty state.
+func Load() (*State, error) {
+	dir, err := StateDir()
+	if err != nil {
+		return nil, err
+	}
+	return LoadFile(filepath.Join(dir, stateFileName))
+}
+
+// LoadFile reads the state file at path.
+func LoadFile(path string) (*State, error) {
+	s := &State{path: path}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Save writes the state file.
+func (s *State) Save() error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// write and rename, so that another lssh never reads a partial file.
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), stateFileName+".*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
+
+// Record loads the state, records a successful connection of command to
+// hosts and saves it.
+func Record(command string, hosts []string) error {
+	if command == "" || len(hosts) == 0 {
+		return nil
+	}
+	s, err := Load()
+	if err != nil {
+		return err
+	}
+	s.RecordConnect(command, hosts, time.Now())
+	return s.Save()
+}
+
+// RecordConnect records a successful connection of command to hosts.
+func (s *State) RecordConnect(command string, hosts []string, now time.Time) {
+	cmd := s.command(command)
+	cmd.Last = append([]string(nil), hosts...)
+	for _, host := range hosts {
+		h, ok := cmd.Hosts[host]
+		if !ok {
+			h = &Host{}
+			cmd.Hosts[host] = h
+		}
+		h.Count++
+		h.LastUsed = now
+	}
+}
+
+// LastHosts returns the hosts of the last successful connection of command.
+func (s *State) LastHosts(command string) []string {
+	if cmd, ok := s.Commands[command]; ok {
+		return append([]string(nil), cmd.Last...)
+	}
+	return nil
+}
+
+// Recent returns up to limit hosts of command, most recently used first.
+func (s *State) Recent(command string, limit int) []string {
+	cmd, ok := s.Commands[command]
+	if !ok {
+		return nil
+	}
+
+	hosts := make([]string, 0, len(cmd.Hosts))
+	for host := range cmd.Hosts {
+		hosts = append(hosts, host)
+	}
+	sort.Slice(hosts, func(i, j int) bool {
+		a, b := cmd.Hosts[hosts[i]].LastUsed, cmd.Hosts[hosts[j]].LastUsed
+		if !a.Equal(b) {
+			return a.After(b)
+		}
+		return hosts[i] < hosts[j]
+	})
+	if len(hosts) > limit {
+		hosts = hosts[:limit]
+	}
+	return hosts
+}
+
+// Frecency returns the frecency score of host for command: the use count
+// weighted by how recently it was used.
+func (s *State) Frecency(command, host string, now time.Time) float64 {
+	cmd, ok := s.Commands[command]
+	if !ok {
+		return 0
+	}
+	h, ok := cmd.Hosts[host]
+	if !ok {
+		return 0
+	}
+
+	age := now.Sub(h.LastUsed)
+	switch {
+	case age < time.Hour:
+		return float64(h.Count) * 4
+	case age < 24*time.Hour:
+		return float64(h.Count) * 2
+	case age < 7*24*time.Hour:
+		return float64(h.Count) * 0.5
+	default:
+		return float64(h.Count) * 0.25
+	}
+}
+
+// IsFavorite reports whether host is a favorite.
+func (s *State) IsFavorite(host string) bool {
+	for _, favorite := range s.Favorites {
+		if favorite == host {
+			return true
+		}
+	}
+	return false
+}
+
+// ToggleFavorite pins or unpins host, and reports whether it is pinned.
+func (s *State) ToggleFavorite(host string) bool {
+	for i, favorite := range s.Favorites {
+		if favorite == host {
+			s.Favorites = append(s.Favorites[:i], s.Favorites[i+1:]...)
+			return false
+		}
+	}
+	s.Favorites = append(s.Favorites, host)
+	return true
+}
+
+func (s *State) command(command string) *Command {
+	if s.Commands == nil {
+		s.Commands = map[string]*Command{}
+	}
+	cmd, ok := s.Commands[command]
+	if !ok {
+		cmd = &Command{}
+		s.Commands[command] = cmd
+	}
+	if cmd.Hosts == nil {
+		cmd.Hosts = map[string]*Host{}
+	}
+	return cmd
+}
@@ -0,0 +1,147 @@
+// Copyright (c) 2026 Blacknon. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package hoststate
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRecordAndLoad(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+
+	if err := Record("lssh", []string{"web01"}); err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+	if err := Record("lssh", []string{"web01", "web02"}); err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+	if err := Record("lscp", []string{"db01"}); err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+
+	s, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if got := s.LastHosts("lssh"); !reflect.DeepEqual(got, []string{"web01", "web02"}) {
+		t.Fatalf("LastHosts(lssh) = %v", got)
+	}
+	if got := s.LastHosts("lscp"); !reflect.DeepEqual(got, []string{"db01"}) {
+		t.Fatalf("LastHosts(lscp) = %v", got)
+	}
+	if got := s.Commands["lssh"].Hosts["web01"].Count; got != 2 {
+		t.Fatalf("web01 count = %d, want 2", got)
+	}
+	if got := s.LastHosts("lsftp"); got != nil {
+		t.Fatalf("LastHosts(lsftp) = %v, want nil", got)
+	}
+}
+
+func TestLoadFileMissing(t *testing.T) {
+	s, err := LoadFile(filepath.Join(t.TempDir(), "hosts.json"))
+	if err != nil {
+		t.Fatalf("LoadFile() error = %v", err)
+	}
+	if len(s.Favorites) != 0 || len(s.Commands) != 0 {
+		t.Fatalf("LoadFile() = %+v, want empty state", s)
+	}
+}
+
+func TestFrecencyAndRecent(t *testing.T) {
+	now := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	s := &State{}
+	// old is used often but long ago, new is used once just now.
+	for i := 0; i < 10; i++ {
+		s.RecordConnect("lssh", []string{"old"}, now.Add(-30*24*time.Hour))
+	}
+	s.RecordConnect("lssh", []string{"day"}, now.Add(-3*time.Hour))
+	s.RecordConnect("lssh", []string{"new"}, now.Add(-time.Minute))
+
+	if got := s.Frecency("lssh", "old", now); got != 2.5 {
+		t.Fatalf("Frecency(old) = %v, want 2.5", got)
+	}
+	if got := s.Frecency("lssh", "day", now); got != 2 {
+		t.Fatalf("Frecency(day) = %v, want 2", got)
+	}
+	if got := s.Frecency("lssh", "new", now); got != 4 {
+		t.Fatalf("Frecency(new) = %v, want 4", got)
+	}
+	if got := s.Frecency("lscp", "new", now); got != 0 {
+		t.Fatalf("Frecency(lscp, new) = %v, want 0", got)
+	}
+
+	if got := s.Recent("lssh", 2); !reflect.DeepEqual(got, []string{"new", "day"}) {
+		t.Fatalf("Recent() = %v", got)
+	}
+}
+
+func TestToggleFavorite(t *testing.T) {
+	s, err := LoadFile(filepath.Join(t.TempDir(), "state", "hosts.json"))
+	if err != nil {
+		t.Fatalf("LoadFile() error = %v", err)
+	}
+
+	if !s.ToggleFavorite("web01") || !s.IsFavorite("web01") {
+		t.Fatal("web01 is not pinned")
+	}
+	if err := s.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	s, err = LoadFile(s.path)
+	if err != nil {
+		t.Fatalf("LoadFile() error = %v", err)
+	}
+	if !s.IsFavorite("web01") {
+		t.Fatal("web01 is not saved as a favorite")
+	}
+	if s.ToggleFavorite("web01") || s.IsFavorite("web01") {
+		t.Fatal("web01 is not unpinned")
+	}
+}
+
+func TestHostSets(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	now := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+
+	s, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if err := s.PutSet("web", []string{"web01", "web02"}, now); err != nil {
+		t.Fatalf("PutSet(web) error = %v", err)
+	}
+	if err := s.PutSet("prod", []string{"tag:web,env=prod"}, now); err != nil {
+		t.Fatalf("PutSet(prod) error = %v", err)
+	}
+	for _, name := range []string{"", "my set", "-H"} {
+		if err := s.PutSet(name, []string{"web01"}, now); err == nil {
+			t.Fatalf("PutSet(%q) error = nil", name)
+		}
+	}
+	if err := s.PutSet("empty", nil, now); err == nil {
+		t.Fatalf("PutSet(empty) error = nil")
+	}
+	if err := s.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	if got := s.SetNames(); !reflect.DeepEqual(got, []string{"prod", "web"}) {
+		t.Fatalf("SetNames() = %v", got)
+	}
+	got, err := SetHosts([]string{"web", "prod"})
+	if err != nil {
+		t.Fatalf("SetHosts() error = %v", err)
+	}
+	if want := []string{"web01", "web02", "tag:web,env=prod"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("SetHosts() = %v, want %v", got, want)
+	}
+	if _, err := SetHosts([]string{"unknown"}); err == nil {
+		t.Fatalf("SetHosts(unknown) error = nil")
+	}
+}
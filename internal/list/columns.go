@@ -0,0 +1,113 @@
+// Copyright (c) 2026 Blacknon. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package list
+
+import (
+	"strings"
+
+	conf "github.com/blacknon/lssh/internal/config"
+	runewidth "github.com/mattn/go-runewidth"
+)
+
+// column fields of the selector besides the `--list` fields.
+const (
+	columnName    = "name"
+	columnConnect = "connect"
+	columnMark    = "mark"
+)
+
+// defaultListColumns is the columns of the selector without `[list]`
+// columns config.
+var defaultListColumns = []conf.ListColumnConfig{
+	{Field: columnName, Title: "ServerName"},
+	{Field: columnConnect, Title: "Connect Information"},
+	{Field: "note", Title: "Note"},
+}
+
+// listColumns returns the columns of the selector. The name column is
+// always first, so that the first field of a line is the host name, and
+// the Mark column follows it if there are favorite or recent hosts.
+func (l *ListInfo) listColumns() []conf.ListColumnConfig {
+	configured := l.DataList.List.Columns
+	if len(configured) == 0 {
+		configured = defaultListColumns
+	}
+
+	name := conf.ListColumnConfig{Field: columnName, Title: "ServerName"}
+	others := []conf.ListColumnConfig{}
+	for _, column := range configured {
+		if column.Title == "" {
+			column.Title = column.Field
+		}
+		if column.Field == columnName {
+			name = column
+			continue
+		}
+		others = append(others, column)
+	}
+
+	columns := []conf.ListColumnConfig{name}
+	if len(l.marks) > 0 {
+		columns = append(columns, conf.ListColumnConfig{Field: columnMark, Title: "Mark"})
+	}
+	return append(columns, others...)
+}
+
+// columnValue returns the value of column field for the host name.
+func (l *ListInfo) columnValue(name, field string) string {
+	var value string
+	switch field {
+	case columnName:
+		value = name
+	case columnMark:
+		value = l.marks[name]
+	case columnConnect:
+		server := l.displayData().Server[name]
+		value = server.User + "@" + server.Addr
+	default:
+		value = l.displayData().ServerListFieldString(name, field)
+	}
+	return convNewline(value, "")
+}
+
+// displayData returns DataList with the ${env:...}, ${server.*} and
+// ${local.user} references of the hosts interpolated. Secrets are not
+// resolved in the list.
+func (l *ListInfo) displayData() *conf.Config {
+	if l.display == nil {
+		data, _ := l.DataList.InterpolateServersWithoutSecrets(l.NameList)
+		l.display = &data
+	}
+	return l.display
+}
+
+// formatListColumn fits value to width with align. Fixed widths truncate
+// the value, except for the name column.
+func formatListColumn(value string, column conf.ListColumnConfig, width int, last bool) string {
+	if column.Width > 0 && column.Field != columnName && runewidth.StringWidth(value) > width {
+		value = runewidth.Truncate(value, width, "~")
+	}
+
+	padding := width - runewidth.StringWidth(value)
+	if padding < 0 {
+		padding = 0
+	}
+
+	switch column.Align {
+	case conf.ListAlignRight:
+		value = strings.Repeat(" ", padding) + value
+	case conf.ListAlignCenter:
+		value = strings.Repeat(" ", padding/2) + value + strings.Repeat(" ", padding-padding/2)
+	default:
+		// the last column is not padded, as the classic list.
+		if !last {
+			value += strings.Repeat(" ", padding)
+		}
+	}
+	if !last {
+		value += strings.Repeat(" ", listColumnGap)
+	}
+	return value
+}
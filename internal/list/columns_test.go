@@ -0,0 +1,129 @@
+// Copyright (c) 2026 Blacknon. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package list
+
+import (
+	"testing"
+
+	conf "github.com/blacknon/lssh/internal/config"
+	"github.com/gdamore/tcell/v2"
+	termbox "github.com/nsf/termbox-go"
+	"github.com/rivo/tview"
+	"github.com/stretchr/testify/assert"
+)
+
+func newColumnsTestConfig(columns []conf.ListColumnConfig) conf.Config {
+	return conf.Config{
+		List: conf.ListConfig{Columns: columns},
+		Server: map[string]conf.ServerConfig{
+			"web01": {
+				User: "deploy", Addr: "10.0.0.10", Port: "22", Note: "web server",
+				Tags: []string{"env=prod", "web"}, ProviderMeta: map[string]string{"region": "ap-northeast-1"},
+			},
+			"db01": {User: "root", Addr: "10.0.0.5", Port: "2222", Tags: []string{"env=stg"}},
+		},
+	}
+}
+
+func TestGetTextColumns(t *testing.T) {
+	type TestData struct {
+		desc    string
+		columns []conf.ListColumnConfig
+		expect  []string
+	}
+	tds := []TestData{
+		{
+			desc: "fields, meta and tag",
+			columns: []conf.ListColumnConfig{
+				{Field: "name", Title: "Host"},
+				{Field: "port", Align: "right"},
+				{Field: "tag.env", Title: "Env"},
+				{Field: "meta.region", Title: "Region"},
+			},
+			expect: []string{
+				"Host   port  Env   Region",
+				"web01    22  prod  ap-northeast-1",
+				"db01   2222  stg   ",
+			},
+		},
+		{
+			desc: "name is always first, fixed width truncates",
+			columns: []conf.ListColumnConfig{
+				{Field: "note", Title: "Note", Width: 5},
+				{Field: "connect", Title: "Connect", Align: "center"},
+			},
+			expect: []string{
+				"ServerName  Note       Connect     ",
+				"web01       web ~  deploy@10.0.0.10",
+				"db01                root@10.0.0.5  ",
+			},
+		},
+	}
+	for _, v := range tds {
+		l := ListInfo{NameList: []string{"web01", "db01"}, DataList: newColumnsTestConfig(v.columns)}
+		l.getText()
+		assert.Equal(t, v.expect, l.DataText, v.desc)
+	}
+}
+
+func TestGetTextInterpolates(t *testing.T) {
+	t.Setenv("WEB02_IP", "10.0.0.20")
+	l := ListInfo{NameList: []string{"web02"}, DataList: conf.Config{Server: map[string]conf.ServerConfig{
+		"web02": {User: "deploy", Addr: "${env:WEB02_IP}", Note: "${secret:vault:hosts/web02#note}"},
+	}}}
+	l.getText()
+	assert.Equal(t, []string{
+		"ServerName  Connect Information  Note",
+		"web02       deploy@10.0.0.20     ${secret:vault:hosts/web02#note}",
+	}, l.DataText)
+	// the connect path interpolates the original config
+	assert.Equal(t, "${env:WEB02_IP}", l.DataList.Server["web02"].Addr)
+}
+
+func TestListKeyMap(t *testing.T) {
+	keys := newListKeyMap(conf.ListConfig{Keys: conf.ListKeyConfig{
+		Up:   []string{"Up", "k", "Ctrl+K"},
+		Down: []string{"Down", "j", "invalid key"},
+	}})
+
+	assert.Equal(t, actionUp, keys.action(tcell.NewEventKey(tcell.KeyRune, 'k', tcell.ModNone)))
+	assert.Equal(t, actionUp, keys.action(tcell.NewEventKey(tcell.KeyCtrlK, 0, tcell.ModCtrl)))
+	assert.Equal(t, actionDown, keys.action(tcell.NewEventKey(tcell.KeyRune, 'j', tcell.ModNone)))
+	assert.Equal(t, "", keys.action(tcell.NewEventKey(tcell.KeyRune, 'x', tcell.ModNone)))
+	// defaults are kept for the other actions
+	assert.Equal(t, actionDetails, keys.action(tcell.NewEventKey(tcell.KeyCtrlP, 0, tcell.ModCtrl)))
+	assert.Equal(t, actionPageDown, keys.action(tcell.NewEventKey(tcell.KeyPgDn, 0, tcell.ModNone)))
+
+	// the classic selector uses the same key map
+	assert.Equal(t, actionUp, keys.action(termboxEventKey(termbox.Event{Ch: 'k'})))
+	assert.Equal(t, actionDown, keys.action(termboxEventKey(termbox.Event{Key: termbox.KeyArrowDown})))
+	assert.Equal(t, actionToggleAll, keys.action(termboxEventKey(termbox.Event{Key: termbox.KeyCtrlA})))
+	assert.Equal(t, actionConfirm, keys.action(termboxEventKey(termbox.Event{Key: termbox.KeyEnter})))
+}
+
+func TestTviewSelectorKeysAndTheme(t *testing.T) {
+	data := newColumnsTestConfig(nil)
+	data.List.Keys.Down = []string{"Ctrl+N"}
+	data.List.CursorBgColor = "blue"
+	data.List.MatchColor = "unknown-color"
+
+	s := NewTviewSelector(tview.NewApplication(), "QUERY>", data, []string{"db01", "web01"}, false)
+	assert.Equal(t, tcell.ColorBlue, s.theme.cursorBG)
+	assert.Equal(t, tcell.GetColor("#ff69b4"), s.theme.matchFG, "unknown colors fall back to the default")
+
+	s.handleInput(tcell.NewEventKey(tcell.KeyCtrlN, 0, tcell.ModCtrl))
+	assert.Equal(t, "web01", s.cursorName())
+
+	// unbound keys edit the filter
+	s.handleInput(tcell.NewEventKey(tcell.KeyDown, 0, tcell.ModNone))
+	s.handleInput(tcell.NewEventKey(tcell.KeyRune, 'd', tcell.ModNone))
+	assert.Equal(t, "d", s.list.Keyword)
+}
+
+func TestTermboxColor(t *testing.T) {
+	assert.Equal(t, 2, termboxColor(tcell.ColorGreen, 0))
+	assert.Equal(t, 5, termboxColor(tcell.GetColor("#ff69b4"), 5))
+	assert.Equal(t, 3, termboxColor(tcell.ColorDefault, 3))
+}
@@ -0,0 +1,105 @@
+// Copyright (c) 2026 Blacknon. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package list
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	conf "github.com/blacknon/lssh/internal/config"
+	sshcmd "github.com/blacknon/lssh/internal/ssh"
+	"github.com/blacknon/lssh/providerapi"
+	"github.com/rivo/tview"
+)
+
+// details pane layouts, toggled with Ctrl+P.
+const (
+	detailsHidden = iota
+	detailsSide
+	detailsBottom
+)
+
+const detailsLoading = "[gray]loading...[-]"
+
+// hostDetails returns the details pane text of the host name: the effective
+// address and user, auth methods, proxy route, connector, provider meta,
+// matched `match` branches and note. It may call the connector provider, so
+// it is run in the background.
+func hostDetails(data conf.Config, name string) string {
+	server, ok := data.Server[name]
+	if !ok {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("[yellow]" + tview.Escape(name) + "[-]\n")
+
+	row := func(label, value string) {
+		if value == "" {
+			value = "-"
+		}
+		fmt.Fprintf(&b, "[teal]%-10s[-] %s\n", label, tview.Escape(value))
+	}
+
+	addr := server.Addr
+	if server.Port != "" {
+		addr = net.JoinHostPort(server.Addr, server.Port)
+	}
+	row("addr", addr)
+	row("user", server.User)
+	row("origin", data.ServerOrigin(name))
+
+	if data.ServerUsesConnector(name) {
+		row("connector", server.ConnectorName)
+		describe, err := data.DescribeConnector(name)
+		if err != nil {
+			row("ops", "error: "+err.Error())
+		} else {
+			row("ops", strings.Join(supportedConnectorOperations(describe.Capabilities), ", "))
+		}
+	} else {
+		row("connector", "ssh")
+		row("auth", strings.Join(sshcmd.AuthMethodNames(server), ", "))
+
+		route, err := sshcmd.DescribeProxyRoute(name, data)
+		if err != nil {
+			row("route", "error: "+err.Error())
+		} else {
+			row("route", strings.Join(append(route, "ssh "+name), " -> "))
+		}
+	}
+
+	row("provider", server.ProviderName)
+	for _, key := range sortedKeys(server.ProviderMeta) {
+		row("  "+key, server.ProviderMeta[key])
+	}
+	row("tags", strings.Join(server.Tags, ", "))
+	row("match", strings.Join(data.MatchedBranches(name), ", "))
+	row("note", server.Note)
+
+	return b.String()
+}
+
+func supportedConnectorOperations(capabilities map[string]providerapi.ConnectorCapability) []string {
+	var operations []string
+	for name, capability := range capabilities {
+		if capability.Supported {
+			operations = append(operations, name)
+		}
+	}
+	sort.Strings(operations)
+	return operations
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
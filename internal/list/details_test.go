@@ -0,0 +1,102 @@
+// Copyright (c) 2026 Blacknon. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package list
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	conf "github.com/blacknon/lssh/internal/config"
+	"github.com/rivo/tview"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHostDetails(t *testing.T) {
+	data := conf.Config{
+		Server: map[string]conf.ServerConfig{
+			"bastion": {Addr: "192.0.2.1", Port: "2222", User: "ops", Key: "~/.ssh/bastion"},
+			"web01": {
+				Addr:         "10.0.0.10",
+				Port:         "22",
+				User:         "deploy",
+				Pass:         "secret",
+				Keys:         []string{"~/.ssh/web::keypass"},
+				SSHAgentUse:  true,
+				Proxy:        "bastion",
+				Note:         "web server",
+				Tags:         []string{"env=prod"},
+				ProviderName: "aws",
+				ProviderMeta: map[string]string{"region": "ap-northeast-1"},
+			},
+		},
+	}
+
+	got := hostDetails(data, "web01")
+	for _, want := range []string{
+		"10.0.0.10:22",
+		"deploy",
+		"password, publickey ~/.ssh/web, ssh-agent",
+		"ssh bastion:2222 -> ssh web01",
+		"aws",
+		"ap-northeast-1",
+		"env=prod",
+		"web server",
+	} {
+		assert.Contains(t, got, want)
+	}
+	assert.NotContains(t, got, "secret")
+	assert.NotContains(t, got, "keypass")
+
+	assert.Equal(t, "", hostDetails(data, "unknown"))
+}
+
+func TestTviewSelectorDetailsPane(t *testing.T) {
+	data := conf.Config{
+		Server: map[string]conf.ServerConfig{
+			"web01": {Addr: "10.0.0.10", User: "deploy"},
+			"web02": {Addr: "10.0.0.11", User: "deploy"},
+		},
+	}
+	// the app is not run: queued updates are not applied, so the pane shows
+	// the cached details once the cursor comes back.
+	s := NewTviewSelector(tview.NewApplication(), "QUERY>", data, []string{"web01", "web02"}, false)
+
+	calls := make(chan string, 4)
+	release := make(chan struct{})
+	s.detailsFunc = func(_ conf.Config, name string) string {
+		calls <- name
+		<-release
+		return "details of " + name
+	}
+
+	s.render()
+	assert.Equal(t, "", s.details.GetText(false), "details pane is hidden by default")
+
+	s.setDetailsMode(detailsSide)
+	s.render()
+	assert.Equal(t, "web01", <-calls)
+	assert.Contains(t, s.details.GetText(true), "loading")
+
+	close(release)
+	assert.Eventually(t, func() bool {
+		s.detailsMu.Lock()
+		defer s.detailsMu.Unlock()
+		return s.detailsCache["web01"] == "details of web01"
+	}, time.Second, 10*time.Millisecond)
+
+	// cached details are shown without loading again
+	s.list.CursorLine = 1
+	s.render()
+	assert.Equal(t, "web02", <-calls)
+	s.list.CursorLine = 0
+	s.render()
+	assert.True(t, strings.HasPrefix(s.details.GetText(true), "details of web01"))
+	select {
+	case name := <-calls:
+		t.Fatalf("details of %s were loaded again", name)
+	default:
+	}
+}
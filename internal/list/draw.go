@@ -23,46 +23,16 @@ func drawLine(x, y int, str string, colorNum int, backColorNum int) {
 	}
 }
 
-// Highlight lines and draw text based on filtering results
-func drawFilterLine(x, y int, str string, colorNum int, backColorNum int, keywordColorNum int, searchText string) {
-	// SearchText Bounds Space
-	searchWords := strings.Fields(searchText)
-
-	for i := 0; i < len(searchWords); i += 1 {
-		searchLowLine := strings.ToLower(str)
-		searchKeyword := strings.ToLower(searchWords[i])
-		searchKeywordLen := len(searchKeyword)
-		searchKeywordCount := strings.Count(searchLowLine, searchKeyword)
-
-		charLocation := 0
-		for j := 0; j < searchKeywordCount; j += 1 {
-			searchLineData := ""
-
-			// Countermeasure "slice bounds out of range"
-			if charLocation < len(str) {
-				searchLineData = str[charLocation:]
-			}
-			searchLineDataStr := string(searchLineData)
-			searchKeywordIndex := strings.Index(strings.ToLower(searchLineDataStr), searchKeyword)
-
-			charLocation = charLocation + searchKeywordIndex
-			keyword := ""
-
-			// Countermeasure "slice bounds out of range"
-			if charLocation < len(str) {
-				keyword = str[charLocation : charLocation+searchKeywordLen]
-			}
-
-			// Get Multibyte Charctor Location
-			multibyteStrCheckLine := str[:charLocation]
-			multiByteCharLocation := 0
-			for _, multiByteChar := range multibyteStrCheckLine {
-				multiByteCharLocation += runewidth.RuneWidth(multiByteChar)
-			}
-
-			drawLine(x+multiByteCharLocation, y, keyword, keywordColorNum, backColorNum)
-			charLocation = charLocation + searchKeywordLen
+// Highlight the matched spans of the line
+func drawFilterLine(x, y int, str string, backColorNum int, keywordColorNum int, spans []matchSpan) {
+	for _, span := range spans {
+		if span.end > len(str) {
+			continue
 		}
+
+		// Get Multibyte Charctor Location
+		multiByteCharLocation := runewidth.StringWidth(str[:span.start])
+		drawLine(x+multiByteCharLocation, y, str[span.start:span.end], keywordColorNum, backColorNum)
 	}
 }
 
@@ -72,6 +42,7 @@ func (l *ListInfo) draw() {
 	l.Term.LeftMargin = 2
 	l.Term.Color = 255
 	l.Term.BackgroundColor = 255
+	theme := newListTheme(l.DataList.List)
 
 	termbox.Clear(termbox.Attribute(l.Term.Color+1), termbox.Attribute(l.Term.BackgroundColor+1))
 
@@ -92,9 +63,9 @@ func (l *ListInfo) draw() {
 	cursor := l.CursorLine - firstLine + 1
 
 	// View Head
-	drawLine(0, 0, l.Prompt, 3, l.Term.BackgroundColor)
+	drawLine(0, 0, l.Prompt, termboxColor(theme.promptFG, 3), l.Term.BackgroundColor)
 	drawLine(len(l.Prompt), 0, l.Keyword, l.Term.Color, l.Term.BackgroundColor)
-	drawLine(l.Term.LeftMargin, 1, l.ViewText[0], 3, l.Term.BackgroundColor)
+	drawLine(l.Term.LeftMargin, 1, l.ViewText[0], termboxColor(theme.headerFG, 3), l.Term.BackgroundColor)
 
 	// View List
 	for listKey, listValue := range viewList {
@@ -102,26 +73,26 @@ func (l *ListInfo) draw() {
 		// Set cursor color
 		cursorColor := l.Term.Color
 		cursorBackColor := l.Term.BackgroundColor
-		keywordColor := 5
+		keywordColor := termboxColor(theme.matchFG, 5)
 
 		for _, selectedLine := range l.SelectName {
 			if strings.Split(listValue, " ")[0] == selectedLine {
-				cursorColor = 0
-				cursorBackColor = 6
+				cursorColor = termboxColor(theme.selectedFG, 0)
+				cursorBackColor = termboxColor(theme.selectedBG, 6)
 			}
 		}
 
 		if listKey == cursor {
 			// Select line color
-			cursorColor = 0
-			cursorBackColor = 2
+			cursorColor = termboxColor(theme.cursorFG, 0)
+			cursorBackColor = termboxColor(theme.cursorBG, 2)
 		}
 
 		// Draw filter line
 		drawLine(l.Term.LeftMargin, listKey+l.Term.Headline, paddingData, cursorColor, cursorBackColor)
 
 		// Keyword Highlight
-		drawFilterLine(l.Term.LeftMargin, listKey+l.Term.Headline, paddingData, cursorColor, cursorBackColor, keywordColor, l.Keyword)
+		drawFilterLine(l.Term.LeftMargin, listKey+l.Term.Headline, paddingData, cursorBackColor, keywordColor, l.matchSpans(listValue))
 		listKey += 1
 	}
 
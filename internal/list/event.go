@@ -5,9 +5,10 @@
 package list
 
 import (
-	"os"
 	"strings"
 
+	conf "github.com/blacknon/lssh/internal/config"
+	"github.com/gdamore/tcell/v2"
 	termbox "github.com/nsf/termbox-go"
 )
 
@@ -33,6 +34,7 @@ func (l *ListInfo) keyEvent() (lineData []string) {
 
 	l.Keyword = ""
 	allFlag := false // input Ctrl + A flag
+	keys := newListKeyMap(l.DataList.List)
 
 	l.getFilterText()
 	l.draw()
@@ -41,107 +43,18 @@ func (l *ListInfo) keyEvent() (lineData []string) {
 		switch ev := termbox.PollEvent(); ev.Type {
 		// Type Key
 		case termbox.EventKey:
-			switch ev.Key {
+			switch l.classicKey(ev, keys, height, &allFlag) {
 			// ESC or Ctrl + C Key (Exit)
-			case termbox.KeyEsc, termbox.KeyCtrlC:
+			case actionCancel:
 				termbox.Close()
-				os.Exit(0)
-
-			// AllowUp Key
-			case termbox.KeyArrowUp:
-				if l.CursorLine > 0 {
-					l.CursorLine -= 1
-				}
-				l.draw()
-
-			// AllowDown Key
-			case termbox.KeyArrowDown:
-				if l.CursorLine < len(l.ViewText)-headLine {
-					l.CursorLine += 1
-				}
-				l.draw()
-
-			// AllowRight Key
-			case termbox.KeyArrowRight:
-				nextPosition := ((l.CursorLine + height) / height) * height
-				if nextPosition+2 <= len(l.ViewText) {
-					l.CursorLine = nextPosition
-				}
-				l.draw()
-
-			// AllowLeft Key
-			case termbox.KeyArrowLeft:
-				beforePosition := ((l.CursorLine - height) / height) * height
-				if beforePosition >= 0 {
-					l.CursorLine = beforePosition
-				}
-				l.draw()
-
-			// Tab Key(select)
-			case termbox.KeyTab:
-				if l.MultiFlag == true {
-					l.toggle(strings.Fields(l.ViewText[l.CursorLine+1])[0])
-				}
-				if l.CursorLine < len(l.ViewText)-headLine {
-					l.CursorLine += 1
-				}
-				l.draw()
-
-			// Ctrl + a Key(all select)
-			case termbox.KeyCtrlA:
-				if l.MultiFlag == true {
-					l.allToggle(allFlag)
-					// allFlag Toggle
-					if allFlag == false {
-						allFlag = true
-					} else {
-						allFlag = false
-					}
-				}
-				l.draw()
-
-			// Ctrl + h Key(Help Window)
-			//case termbox.KeyCtrlH:
+				conf.Exit(0)
 
 			// Enter Key
-			case termbox.KeyEnter:
+			case actionConfirm:
 				if len(l.SelectName) == 0 {
 					l.SelectName = append(l.SelectName, strings.Fields(l.ViewText[l.CursorLine+1])[0])
 				}
 				return
-
-			// BackSpace Key
-			case termbox.KeyBackspace, termbox.KeyBackspace2:
-				if len(l.Keyword) > 0 {
-					l.deleteRune()
-
-					l.getFilterText()
-					if l.CursorLine > len(l.ViewText) {
-						l.CursorLine = len(l.ViewText)
-					}
-					if l.CursorLine < 0 {
-						l.CursorLine = 0
-					}
-					allFlag = false
-					l.draw()
-				}
-
-			// Space Key
-			case termbox.KeySpace:
-				l.Keyword = l.Keyword + " "
-				l.draw()
-
-			// Other Key
-			default:
-				if ev.Ch != 0 {
-					l.insertRune(ev.Ch)
-					l.getFilterText()
-					if l.CursorLine > len(l.ViewText)-headLine {
-						l.CursorLine = len(l.ViewText) - headLine
-					}
-					allFlag = false
-					l.draw()
-				}
 			}
 
 		// Type Mouse
@@ -174,6 +87,7 @@ func (l *ListInfo) keyEventSelectable() (selected []string, ok bool, err error)
 
 	l.Keyword = ""
 	allFlag := false
+	keys := newListKeyMap(l.DataList.List)
 
 	l.getFilterText()
 	l.draw()
@@ -181,46 +95,10 @@ func (l *ListInfo) keyEventSelectable() (selected []string, ok bool, err error)
 	for {
 		switch ev := termbox.PollEvent(); ev.Type {
 		case termbox.EventKey:
-			switch ev.Key {
-			case termbox.KeyEsc, termbox.KeyCtrlC:
+			switch l.classicKey(ev, keys, height, &allFlag) {
+			case actionCancel:
 				return nil, false, nil
-			case termbox.KeyArrowUp:
-				if l.CursorLine > 0 {
-					l.CursorLine -= 1
-				}
-				l.draw()
-			case termbox.KeyArrowDown:
-				if l.CursorLine < len(l.ViewText)-headLine {
-					l.CursorLine += 1
-				}
-				l.draw()
-			case termbox.KeyArrowRight:
-				nextPosition := ((l.CursorLine + height) / height) * height
-				if nextPosition+2 <= len(l.ViewText) {
-					l.CursorLine = nextPosition
-				}
-				l.draw()
-			case termbox.KeyArrowLeft:
-				beforePosition := ((l.CursorLine - height) / height) * height
-				if beforePosition >= 0 {
-					l.CursorLine = beforePosition
-				}
-				l.draw()
-			case termbox.KeyTab:
-				if l.MultiFlag {
-					l.toggle(strings.Fields(l.ViewText[l.CursorLine+1])[0])
-				}
-				if l.CursorLine < len(l.ViewText)-headLine {
-					l.CursorLine += 1
-				}
-				l.draw()
-			case termbox.KeyCtrlA:
-				if l.MultiFlag {
-					l.allToggle(allFlag)
-					allFlag = !allFlag
-				}
-				l.draw()
-			case termbox.KeyEnter:
+			case actionConfirm:
 				if len(l.ViewText) <= l.CursorLine+1 {
 					return nil, false, nil
 				}
@@ -228,32 +106,6 @@ func (l *ListInfo) keyEventSelectable() (selected []string, ok bool, err error)
 					l.SelectName = append(l.SelectName, strings.Fields(l.ViewText[l.CursorLine+1])[0])
 				}
 				return append([]string(nil), l.SelectName...), true, nil
-			case termbox.KeyBackspace, termbox.KeyBackspace2:
-				if len(l.Keyword) > 0 {
-					l.deleteRune()
-					l.getFilterText()
-					if l.CursorLine > len(l.ViewText) {
-						l.CursorLine = len(l.ViewText)
-					}
-					if l.CursorLine < 0 {
-						l.CursorLine = 0
-					}
-					allFlag = false
-					l.draw()
-				}
-			case termbox.KeySpace:
-				l.Keyword = l.Keyword + " "
-				l.draw()
-			default:
-				if ev.Ch != 0 {
-					l.insertRune(ev.Ch)
-					l.getFilterText()
-					if l.CursorLine > len(l.ViewText)-headLine {
-						l.CursorLine = len(l.ViewText) - headLine
-					}
-					allFlag = false
-					l.draw()
-				}
 			}
 		case termbox.EventMouse:
 			if ev.Key == termbox.MouseLeft {
@@ -271,3 +123,104 @@ func (l *ListInfo) keyEventSelectable() (selected []string, ok bool, err error)
 		}
 	}
 }
+
+// classicKey handles a key event of the classic selector loops with the
+// `[list.keys]` key map, and returns its action. cancel and confirm are
+// left to the caller.
+func (l *ListInfo) classicKey(ev termbox.Event, keys listKeyMap, height int, allFlag *bool) string {
+	headLine := 2
+	event := termboxEventKey(ev)
+
+	action := keys.action(event)
+	switch action {
+	case actionCancel, actionConfirm:
+		return action
+
+	case actionUp:
+		if l.CursorLine > 0 {
+			l.CursorLine -= 1
+		}
+
+	case actionDown:
+		if l.CursorLine < len(l.ViewText)-headLine {
+			l.CursorLine += 1
+		}
+
+	case actionPageDown:
+		nextPosition := ((l.CursorLine + height) / height) * height
+		if nextPosition+2 <= len(l.ViewText) {
+			l.CursorLine = nextPosition
+		}
+
+	case actionPageUp:
+		beforePosition := ((l.CursorLine - height) / height) * height
+		if beforePosition >= 0 {
+			l.CursorLine = beforePosition
+		}
+
+	case actionTop:
+		l.CursorLine = 0
+
+	case actionBottom:
+		l.CursorLine = max(len(l.ViewText)-headLine, 0)
+
+	// select
+	case actionToggle:
+		if l.MultiFlag {
+			l.toggle(strings.Fields(l.ViewText[l.CursorLine+1])[0])
+		}
+		if l.CursorLine < len(l.ViewText)-headLine {
+			l.CursorLine += 1
+		}
+
+	// all select
+	case actionToggleAll:
+		if l.MultiFlag {
+			l.allToggle(*allFlag)
+			*allFlag = !*allFlag
+		}
+
+	case actionFavorite:
+		if len(l.ViewText) > l.CursorLine+1 {
+			_ = l.toggleFavorite(strings.Fields(l.ViewText[l.CursorLine+1])[0])
+		}
+
+	// the classic selector has no details pane.
+	case actionDetails:
+
+	default:
+		switch event.Key() {
+		// BackSpace Key
+		case tcell.KeyBackspace, tcell.KeyBackspace2:
+			if len(l.Keyword) > 0 {
+				l.deleteRune()
+
+				l.getFilterText()
+				if l.CursorLine > len(l.ViewText) {
+					l.CursorLine = len(l.ViewText)
+				}
+				if l.CursorLine < 0 {
+					l.CursorLine = 0
+				}
+				*allFlag = false
+			}
+
+		case tcell.KeyRune:
+			// Space Key
+			if event.Rune() == ' ' {
+				l.Keyword = l.Keyword + " "
+				break
+			}
+
+			l.insertRune(event.Rune())
+			l.getFilterText()
+			if l.CursorLine > len(l.ViewText)-headLine {
+				l.CursorLine = len(l.ViewText) - headLine
+			}
+			*allFlag = false
+		}
+	}
+
+	l.draw()
+	return action
+}
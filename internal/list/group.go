@@ -0,0 +1,181 @@
+// Copyright (c) 2026 Blacknon. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package list
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	conf "github.com/blacknon/lssh/internal/config"
+)
+
+// markers of the group header lines. A header line is
+// `▾ VALUE (COUNT)`, and the host lines of the group follow it indented by
+// groupIndent.
+const (
+	groupExpanded  = "▾"
+	groupCollapsed = "▸"
+	groupIndent    = "  "
+)
+
+// groupNone is the group of the hosts without a value of the group field.
+const groupNone = "-"
+
+// defaultGroupBy is the field the group key groups by without `--group-by`
+// and `[list] group_by`.
+const defaultGroupBy = "provider"
+
+// isGroupLine returns that line is a group header line.
+func isGroupLine(line string) bool {
+	return strings.HasPrefix(line, groupExpanded+" ") || strings.HasPrefix(line, groupCollapsed+" ")
+}
+
+// lineName returns the host name of a list line, or "" for a group header
+// or a host set line.
+func lineName(line string) string {
+	if isGroupLine(line) || isSetLine(line) {
+		return ""
+	}
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return ""
+	}
+	return fields[0]
+}
+
+// lineGroup returns the group of a group header line, or "".
+func lineGroup(line string) string {
+	if !isGroupLine(line) {
+		return ""
+	}
+	_, group, _ := strings.Cut(line, " ")
+	if i := strings.LastIndex(group, " ("); i >= 0 {
+		group = group[:i]
+	}
+	return group
+}
+
+// hostGroup returns the group of the host name.
+func (l *ListInfo) hostGroup(name string) string {
+	var value string
+	if l.GroupBy == conf.ListGroupNotePrefix {
+		if fields := strings.Fields(l.displayData().Server[name].Note); len(fields) > 0 {
+			value = strings.TrimRight(fields[0], ":")
+		}
+	} else {
+		value = convNewline(l.displayData().ServerListFieldString(name, l.GroupBy), " ")
+	}
+
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return groupNone
+	}
+	return value
+}
+
+// groupLines folds the filtered lines of l.ViewText under the group headers.
+// A group is shown if it has matched hosts, or with all of its hosts if its
+// name matches terms. The hosts of a collapsed group are hidden.
+func (l *ListInfo) groupLines(terms []searchTerm) []string {
+	matched := map[string][]string{}
+	for _, line := range l.ViewText[1:] {
+		group := l.hostGroup(lineName(line))
+		matched[group] = append(matched[group], line)
+	}
+	all := map[string][]string{}
+	for _, line := range l.DataText[1:] {
+		if l.hidden[lineName(line)] {
+			continue
+		}
+		group := l.hostGroup(lineName(line))
+		all[group] = append(all[group], line)
+	}
+
+	groups := make([]string, 0, len(all))
+	for group := range all {
+		groups = append(groups, group)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if (groups[i] == groupNone) != (groups[j] == groupNone) {
+			return groups[j] == groupNone
+		}
+		return groups[i] < groups[j]
+	})
+
+	lines := []string{groupIndent + l.ViewText[0]}
+	l.groupHosts = map[string][]string{}
+	for _, group := range groups {
+		hosts := matched[group]
+		if len(terms) > 0 {
+			if _, _, ok := l.matchLine(groupExpanded+" "+group, terms); ok {
+				hosts = all[group]
+			}
+		}
+		if len(hosts) == 0 {
+			continue
+		}
+
+		marker := groupExpanded
+		if l.collapsed[group] {
+			marker = groupCollapsed
+		}
+		lines = append(lines, fmt.Sprintf("%s %s (%d)", marker, group, len(hosts)))
+		for _, line := range hosts {
+			l.groupHosts[group] = append(l.groupHosts[group], lineName(line))
+			if !l.collapsed[group] {
+				lines = append(lines, groupIndent+line)
+			}
+		}
+	}
+	return lines
+}
+
+// toggleFold collapses or expands group.
+func (l *ListInfo) toggleFold(group string) {
+	if l.collapsed == nil {
+		l.collapsed = map[string]bool{}
+	}
+	l.collapsed[group] = !l.collapsed[group]
+	l.getFilterText()
+}
+
+// toggleGrouping turns the grouped view on or off. It groups by the last
+// group field, `[list] group_by` or provider.
+func (l *ListInfo) toggleGrouping() {
+	if l.GroupBy != "" {
+		l.lastGroupBy = l.GroupBy
+		l.GroupBy = ""
+	} else {
+		for _, field := range []string{l.lastGroupBy, l.DataList.List.GroupBy, defaultGroupBy} {
+			if field != "" {
+				l.GroupBy = field
+				break
+			}
+		}
+	}
+	l.getFilterText()
+}
+
+// hostsSelected returns that all of hosts are selected.
+func (l *ListInfo) hostsSelected(hosts []string) bool {
+	for _, name := range hosts {
+		if !arrayContains(l.SelectName, name) {
+			return false
+		}
+	}
+	return len(hosts) > 0
+}
+
+// toggleHosts selects all of hosts (of a group or a host set), or unselects
+// them if they are all selected.
+func (l *ListInfo) toggleHosts(hosts []string) {
+	selected := l.hostsSelected(hosts)
+	for _, name := range hosts {
+		if selected || !arrayContains(l.SelectName, name) {
+			l.toggle(name)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright (c) 2026 Blacknon. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package list
+
+import (
+	"testing"
+
+	conf "github.com/blacknon/lssh/internal/config"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	"github.com/stretchr/testify/assert"
+)
+
+func newGroupTestConfig() conf.Config {
+	return conf.Config{
+		List: conf.ListConfig{Columns: []conf.ListColumnConfig{{Field: "name", Title: "Host"}}},
+		Server: map[string]conf.ServerConfig{
+			"web01":   {Addr: "10.0.0.10", Note: "web: frontend", ProviderMeta: map[string]string{"region": "tokyo"}},
+			"web02":   {Addr: "10.0.0.11", Note: "web: frontend", ProviderMeta: map[string]string{"region": "osaka"}},
+			"db01":    {Addr: "10.0.0.5", Note: "db primary", ProviderMeta: map[string]string{"region": "tokyo"}},
+			"bastion": {Addr: "10.0.0.1"},
+		},
+	}
+}
+
+func TestGroupLines(t *testing.T) {
+	type TestData struct {
+		desc      string
+		groupBy   string
+		keyword   string
+		collapsed map[string]bool
+		expect    []string
+	}
+	tds := []TestData{
+		{
+			desc:    "meta key, hosts without a value last",
+			groupBy: "meta.region",
+			expect: []string{
+				"  Host", "▾ osaka (1)", "  web02", "▾ tokyo (2)", "  db01", "  web01", "▾ - (1)", "  bastion",
+			},
+		},
+		{
+			desc:    "note prefix",
+			groupBy: conf.ListGroupNotePrefix,
+			expect: []string{
+				"  Host", "▾ db (1)", "  db01", "▾ web (2)", "  web01", "  web02", "▾ - (1)", "  bastion",
+			},
+		},
+		{
+			desc:    "matched hosts",
+			groupBy: "meta.region",
+			keyword: "'web",
+			expect:  []string{"  Host", "▾ osaka (1)", "  web02", "▾ tokyo (1)", "  web01"},
+		},
+		{
+			desc:    "group name matches",
+			groupBy: "meta.region",
+			keyword: "'tokyo",
+			expect:  []string{"  Host", "▾ tokyo (2)", "  db01", "  web01"},
+		},
+		{
+			desc:      "collapsed group",
+			groupBy:   "meta.region",
+			collapsed: map[string]bool{"tokyo": true},
+			expect:    []string{"  Host", "▾ osaka (1)", "  web02", "▸ tokyo (2)", "▾ - (1)", "  bastion"},
+		},
+	}
+	for _, v := range tds {
+		l := ListInfo{
+			NameList:  []string{"bastion", "db01", "web01", "web02"},
+			DataList:  newGroupTestConfig(),
+			GroupBy:   v.groupBy,
+			Keyword:   v.keyword,
+			collapsed: v.collapsed,
+		}
+		l.getText()
+		l.getFilterText()
+		assert.Equal(t, v.expect, l.ViewText, v.desc)
+	}
+}
+
+func TestLineNameAndGroup(t *testing.T) {
+	assert.Equal(t, "web01", lineName("  web01  10.0.0.10"))
+	assert.Equal(t, "", lineName("▸ tokyo (2)"))
+	assert.Equal(t, "ap northeast", lineGroup("▾ ap northeast (3)"))
+	assert.Equal(t, "", lineGroup("  web01"))
+}
+
+func TestTviewSelectorGroups(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	data := newGroupTestConfig()
+	data.List.GroupBy = "meta.region"
+	s := NewTviewSelector(tview.NewApplication(), "QUERY>", data, []string{"bastion", "db01", "web01", "web02"}, true)
+
+	// Tab on a group header toggles all of its hosts
+	s.list.CursorLine = 2
+	s.handleInput(tcell.NewEventKey(tcell.KeyTab, 0, tcell.ModNone))
+	assert.Equal(t, []string{"db01", "web01"}, s.list.SelectName)
+	s.list.CursorLine = 2
+	s.handleInput(tcell.NewEventKey(tcell.KeyTab, 0, tcell.ModNone))
+	assert.Empty(t, s.list.SelectName)
+
+	// Enter on a group header folds it, Ctrl+O on a host folds its group
+	s.list.CursorLine = 2
+	s.handleInput(tcell.NewEventKey(tcell.KeyEnter, 0, tcell.ModNone))
+	assert.Equal(t, "▸ tokyo (2)", s.list.ViewText[3])
+	s.list.CursorLine = 1
+	s.handleInput(tcell.NewEventKey(tcell.KeyCtrlO, 0, tcell.ModCtrl))
+	assert.Equal(t, []string{"  Host", "▸ osaka (1)", "▸ tokyo (2)", "▾ - (1)", "  bastion"}, s.list.ViewText)
+	assert.Equal(t, 0, s.list.CursorLine)
+
+	// Ctrl+G turns the groups off and on, keeping the cursor on the host
+	s.list.CursorLine = 3
+	s.handleInput(tcell.NewEventKey(tcell.KeyCtrlG, 0, tcell.ModCtrl))
+	assert.Equal(t, "", s.list.GroupBy)
+	assert.Equal(t, "bastion", s.cursorName())
+	s.handleInput(tcell.NewEventKey(tcell.KeyCtrlG, 0, tcell.ModCtrl))
+	assert.Equal(t, "meta.region", s.list.GroupBy)
+	assert.Equal(t, "bastion", s.cursorName())
+}
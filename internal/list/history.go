@@ -0,0 +1,155 @@
+// Copyright (c) 2026 Blacknon. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package list
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"sort"
+	"time"
+
+	conf "github.com/blacknon/lssh/internal/config"
+	"github.com/blacknon/lssh/internal/hoststate"
+)
+
+// host orders of the selector (--sort).
+const (
+	SortName     = "name"
+	SortAddr     = "addr"
+	SortFrecency = "frecency"
+)
+
+// recentHostsLimit is the number of hosts shown in the recent section.
+const recentHostsLimit = 5
+
+// marks of the pinned hosts, shown in the Mark column.
+const (
+	markFavorite = "fav"
+	markRecent   = "recent"
+)
+
+// ValidateSortOrder returns an error if order is not a selector host order.
+func ValidateSortOrder(order string) error {
+	switch order {
+	case "", SortName, SortAddr, SortFrecency:
+		return nil
+	}
+	return fmt.Errorf("unknown sort order %q (name, addr or frecency)", order)
+}
+
+// loadHistory loads the host state of l.Command. The selector works
+// without it when the state file can not be read.
+func (l *ListInfo) loadHistory() {
+	if l.Command == "" || l.history != nil {
+		return
+	}
+	if state, err := hoststate.Load(); err == nil {
+		l.history = state
+	}
+}
+
+// orderNames sorts l.NameList by l.Sort, then moves the favorite hosts and
+// the recent hosts of l.Command to the top.
+func (l *ListInfo) orderNames() {
+	if l.Command == "" && l.Sort == "" {
+		return
+	}
+
+	l.loadHistory()
+	l.NameList = append([]string(nil), l.NameList...)
+	sortHosts(l.NameList, l.DataList.Server, l.Sort, l.history, l.Command, time.Now())
+
+	l.marks = map[string]string{}
+	if l.history == nil {
+		return
+	}
+
+	pinned := []string{}
+	for _, name := range l.history.Favorites {
+		if arrayContains(l.NameList, name) {
+			pinned = append(pinned, name)
+			l.marks[name] = markFavorite
+		}
+	}
+	for _, name := range l.history.Recent(l.Command, recentHostsLimit) {
+		if _, ok := l.marks[name]; ok || !arrayContains(l.NameList, name) {
+			continue
+		}
+		pinned = append(pinned, name)
+		l.marks[name] = markRecent
+	}
+
+	names := append([]string(nil), pinned...)
+	for _, name := range l.NameList {
+		if _, ok := l.marks[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	l.NameList = names
+}
+
+// toggleFavorite pins or unpins the host name, saves the state and rebuilds
+// the list text.
+func (l *ListInfo) toggleFavorite(name string) error {
+	l.loadHistory()
+	if l.history == nil || name == "" {
+		return nil
+	}
+
+	l.history.ToggleFavorite(name)
+	err := l.history.Save()
+
+	l.orderNames()
+	l.getText()
+	l.getFilterText()
+	return err
+}
+
+// sortHosts sorts names by order. frecency orders by the frecency score of
+// command, and names without history by name.
+func sortHosts(names []string, servers map[string]conf.ServerConfig, order string, state *hoststate.State, command string, now time.Time) {
+	switch order {
+	case SortAddr:
+		sort.SliceStable(names, func(i, j int) bool {
+			if c := compareAddr(servers[names[i]].Addr, servers[names[j]].Addr); c != 0 {
+				return c < 0
+			}
+			return names[i] < names[j]
+		})
+	case SortFrecency:
+		sort.SliceStable(names, func(i, j int) bool {
+			if state != nil {
+				a, b := state.Frecency(command, names[i], now), state.Frecency(command, names[j], now)
+				if a != b {
+					return a > b
+				}
+			}
+			return names[i] < names[j]
+		})
+	default:
+		sort.Strings(names)
+	}
+}
+
+// compareAddr compares IP addresses numerically, and host names as strings.
+func compareAddr(a, b string) int {
+	ipA, ipB := net.ParseIP(a), net.ParseIP(b)
+	switch {
+	case ipA != nil && ipB != nil:
+		return bytes.Compare(ipA.To16(), ipB.To16())
+	case ipA != nil:
+		return -1
+	case ipB != nil:
+		return 1
+	}
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
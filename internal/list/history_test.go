@@ -0,0 +1,113 @@
+// Copyright (c) 2026 Blacknon. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package list
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	conf "github.com/blacknon/lssh/internal/config"
+	"github.com/blacknon/lssh/internal/hoststate"
+	"github.com/rivo/tview"
+	"github.com/stretchr/testify/assert"
+)
+
+func newHistoryTestConfig() conf.Config {
+	return conf.Config{
+		Server: map[string]conf.ServerConfig{
+			"app01": {User: "root", Addr: "10.0.0.20"},
+			"db01":  {User: "root", Addr: "10.0.0.3"},
+			"web01": {User: "root", Addr: "10.0.0.100"},
+			"web02": {User: "root", Addr: "example.com"},
+		},
+	}
+}
+
+func TestSortHosts(t *testing.T) {
+	data := newHistoryTestConfig()
+	now := time.Now()
+	state := &hoststate.State{}
+	state.RecordConnect("lssh", []string{"web02"}, now.Add(-30*24*time.Hour))
+	state.RecordConnect("lssh", []string{"db01"}, now)
+
+	type TestData struct {
+		order  string
+		expect []string
+	}
+	tds := []TestData{
+		{order: SortName, expect: []string{"app01", "db01", "web01", "web02"}},
+		{order: SortAddr, expect: []string{"db01", "app01", "web01", "web02"}},
+		{order: SortFrecency, expect: []string{"db01", "web02", "app01", "web01"}},
+	}
+	for _, v := range tds {
+		names := []string{"web02", "web01", "db01", "app01"}
+		sortHosts(names, data.Server, v.order, state, "lssh", now)
+		assert.Equal(t, v.expect, names, v.order)
+	}
+
+	assert.NoError(t, ValidateSortOrder("frecency"))
+	assert.Error(t, ValidateSortOrder("random"))
+}
+
+func TestOrderNamesPinsFavoritesAndRecent(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	assert.NoError(t, hoststate.Record("lssh", []string{"web02"}))
+
+	l := ListInfo{
+		NameList: []string{"app01", "db01", "web01", "web02"},
+		DataList: newHistoryTestConfig(),
+		Command:  "lssh",
+	}
+	l.orderNames()
+	l.getText()
+	assert.Equal(t, []string{"web02", "app01", "db01", "web01"}, l.NameList)
+	assert.Equal(t, []string{"ServerName", "Mark"}, strings.Fields(l.DataText[0])[:2])
+	assert.Equal(t, []string{"web02", "recent"}, strings.Fields(l.DataText[1])[:2])
+
+	// favorites are saved and shown before the recent hosts.
+	assert.NoError(t, l.toggleFavorite("db01"))
+	assert.Equal(t, []string{"db01", "web02", "app01", "web01"}, l.NameList)
+	assert.Equal(t, []string{"db01", "fav"}, strings.Fields(l.ViewText[1])[:2])
+
+	state, err := hoststate.Load()
+	assert.NoError(t, err)
+	assert.True(t, state.IsFavorite("db01"))
+}
+
+func TestOrderNamesWithoutHistory(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+
+	l := ListInfo{
+		NameList: []string{"web01", "app01"},
+		DataList: newHistoryTestConfig(),
+	}
+	l.orderNames()
+	l.getText()
+	assert.Equal(t, []string{"web01", "app01"}, l.NameList, "no Command and Sort keeps the order")
+	assert.Equal(t, "ServerName  Connect Information  Note", l.DataText[0])
+
+	l.Command = "lssh"
+	l.orderNames()
+	l.getText()
+	assert.Equal(t, []string{"app01", "web01"}, l.NameList)
+	assert.Equal(t, "ServerName  Connect Information  Note", l.DataText[0], "no Mark column without favorites or recent hosts")
+}
+
+func TestTviewSelectorToggleFavorite(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+
+	s := NewTviewSelector(tview.NewApplication(), "QUERY>", newHistoryTestConfig(), []string{"app01", "db01", "web01", "web02"}, false)
+	s.SetHistory("lssh", SortName)
+
+	s.list.CursorLine = 2
+	s.toggleFavorite()
+	assert.Equal(t, "web01", s.cursorName(), "cursor follows the pinned host")
+	assert.Equal(t, 0, s.list.CursorLine)
+
+	s.toggleFavorite()
+	assert.Equal(t, "web01", s.cursorName())
+	assert.Equal(t, 2, s.list.CursorLine)
+}
@@ -0,0 +1,126 @@
+// Copyright (c) 2026 Blacknon. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package list
+
+import (
+	conf "github.com/blacknon/lssh/internal/config"
+	"github.com/gdamore/tcell/v2"
+	termbox "github.com/nsf/termbox-go"
+)
+
+// selector actions of the `[list.keys]` config.
+const (
+	actionCancel    = "cancel"
+	actionConfirm   = "confirm"
+	actionUp        = "up"
+	actionDown      = "down"
+	actionPageUp    = "page_up"
+	actionPageDown  = "page_down"
+	actionTop       = "top"
+	actionBottom    = "bottom"
+	actionToggle    = "toggle"
+	actionToggleAll = "toggle_all"
+	actionDetails   = "details"
+	actionFavorite  = "favorite"
+	actionFold      = "fold"
+	actionGroup     = "group"
+	actionSaveSet   = "save_set"
+	actionHideDown  = "hide_unreachable"
+)
+
+var listKeyNames = map[string]tcell.Key{
+	"up":        tcell.KeyUp,
+	"down":      tcell.KeyDown,
+	"left":      tcell.KeyLeft,
+	"right":     tcell.KeyRight,
+	"pgup":      tcell.KeyPgUp,
+	"pgdn":      tcell.KeyPgDn,
+	"home":      tcell.KeyHome,
+	"end":       tcell.KeyEnd,
+	"tab":       tcell.KeyTab,
+	"backtab":   tcell.KeyBacktab,
+	"enter":     tcell.KeyEnter,
+	"esc":       tcell.KeyEsc,
+	"backspace": tcell.KeyBackspace2,
+}
+
+type listKeyBinding struct {
+	action string
+	key    tcell.Key
+	ch     rune
+}
+
+// listKeyMap is the parsed `[list.keys]` config.
+type listKeyMap []listKeyBinding
+
+// newListKeyMap parses the key bindings of cfg. Invalid keys are ignored,
+// they are reported by `--check-config`.
+func newListKeyMap(cfg conf.ListConfig) listKeyMap {
+	var keys listKeyMap
+	for _, binding := range cfg.ApplyDefaults().Keys.Bindings() {
+		for _, spec := range binding.Keys {
+			key, err := conf.ParseListKey(spec)
+			if err != nil {
+				continue
+			}
+
+			b := listKeyBinding{action: binding.Action}
+			switch {
+			case key.Name != "":
+				b.key = listKeyNames[key.Name]
+			case key.Ctrl:
+				b.key = tcell.KeyCtrlA + tcell.Key(key.Rune-'a')
+			default:
+				b.key = tcell.KeyRune
+				b.ch = key.Rune
+			}
+			keys = append(keys, b)
+		}
+	}
+	return keys
+}
+
+// action returns the selector action bound to event, or "".
+func (k listKeyMap) action(event *tcell.EventKey) string {
+	key := event.Key()
+	for _, b := range k {
+		// some terminals send Backspace as Ctrl+H.
+		if b.key != key && (b.key != tcell.KeyBackspace2 || key != tcell.KeyBackspace) {
+			continue
+		}
+		if key == tcell.KeyRune && b.ch != event.Rune() {
+			continue
+		}
+		return b.action
+	}
+	return ""
+}
+
+var termboxKeys = map[termbox.Key]tcell.Key{
+	termbox.KeyArrowUp:    tcell.KeyUp,
+	termbox.KeyArrowDown:  tcell.KeyDown,
+	termbox.KeyArrowLeft:  tcell.KeyLeft,
+	termbox.KeyArrowRight: tcell.KeyRight,
+	termbox.KeyPgup:       tcell.KeyPgUp,
+	termbox.KeyPgdn:       tcell.KeyPgDn,
+	termbox.KeyHome:       tcell.KeyHome,
+	termbox.KeyEnd:        tcell.KeyEnd,
+}
+
+// termboxEventKey converts a termbox key event to a tcell one, so that the
+// classic selector uses the same key map.
+func termboxEventKey(ev termbox.Event) *tcell.EventKey {
+	if ev.Ch != 0 {
+		return tcell.NewEventKey(tcell.KeyRune, ev.Ch, tcell.ModNone)
+	}
+	if key, ok := termboxKeys[ev.Key]; ok {
+		return tcell.NewEventKey(key, 0, tcell.ModNone)
+	}
+	if ev.Key == termbox.KeySpace {
+		return tcell.NewEventKey(tcell.KeyRune, ' ', tcell.ModNone)
+	}
+	// control keys have the same codes in termbox and tcell.
+	return tcell.NewEventKey(tcell.Key(ev.Key), 0, tcell.ModNone)
+}
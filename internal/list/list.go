@@ -9,20 +9,17 @@ list package creates a TUI list based on the contents specified in a structure,
 package list
 
 import (
-	"regexp"
+	"sort"
 	"strings"
 
 	conf "github.com/blacknon/lssh/internal/config"
+	"github.com/blacknon/lssh/internal/hoststate"
 	runewidth "github.com/mattn/go-runewidth"
 )
 
 // TODO(blacknon):
-//     - tomlやjsonなどを渡して、出力項目を指定できるようにする
-//     - 指定した項目だけでの検索などができるようにする
-//     - 検索方法の充実化(regexでの検索など)
 //     - 内部でのウィンドウの実装
 //         - 項目について、更新や閲覧ができるようにする
-//     - キーバインドの設定変更
 
 // ListInfo is Struct at view list.
 type ListInfo struct {
@@ -38,6 +35,34 @@ type ListInfo struct {
 	Keyword    string      // input keyword
 	CursorLine int         // cursor line
 	Term       TermInfo
+
+	// Command is the command name of the host history (ex. lssh). If set,
+	// favorite and recent hosts are shown at the top with a Mark column.
+	Command string
+	// Sort is the host order: name (default), addr or frecency.
+	Sort string
+	// GroupBy folds the hosts under group headers by a `--list` field or
+	// note_prefix (see conf.ListConfig.GroupBy). Empty is not grouped.
+	GroupBy string
+	// Probe shows the reachability of the hosts (see conf.ListConfig.Probe).
+	Probe bool
+
+	history *hoststate.State
+	marks   map[string]string
+
+	collapsed   map[string]bool
+	groupHosts  map[string][]string
+	lastGroupBy string
+
+	sets     map[string][]string
+	setNames []string
+
+	// hidden is the hosts hidden from the list, such as unreachable hosts.
+	hidden map[string]bool
+
+	// display is DataList with the non-secret `${...}` references
+	// interpolated, for the list text only.
+	display *conf.Config
 }
 
 type TermInfo struct {
@@ -47,12 +72,6 @@ type TermInfo struct {
 	BackgroundColor int
 }
 
-type ListArrayInfo struct {
-	Name    string
-	Connect string
-	Note    string
-}
-
 const listColumnGap = 2
 
 // arrayContains returns that arr contains str.
@@ -98,7 +117,10 @@ func (l *ListInfo) allToggle(allFlag bool) {
 		// On each lines that except a header line and are not selected line,
 		// toggles left end fields
 		for _, addLine := range l.ViewText[1:] {
-			addName := strings.Fields(addLine)[0]
+			addName := lineName(addLine)
+			if addName == "" {
+				continue
+			}
 			if !arrayContains(SelectedList, addName) {
 				allSelectedList = append(allSelectedList, addName)
 				l.toggle(addName)
@@ -108,7 +130,10 @@ func (l *ListInfo) allToggle(allFlag bool) {
 	} else {
 		// On each lines that except a header line, toggles left end fields
 		for _, addLine := range l.ViewText[1:] {
-			addName := strings.Fields(addLine)[0]
+			addName := lineName(addLine)
+			if addName == "" {
+				continue
+			}
 			l.toggle(addName)
 		}
 		return
@@ -124,88 +149,119 @@ func padDisplayWidth(str string, width int) string {
 	return str + strings.Repeat(" ", padding)
 }
 
-func formatListRow(columns []string, widths []int) string {
-	formatted := make([]string, 0, len(columns))
-	for i, column := range columns {
-		if i == len(columns)-1 {
-			formatted = append(formatted, column)
-			continue
-		}
-
-		formatted = append(formatted, padDisplayWidth(column, widths[i])+strings.Repeat(" ", listColumnGap))
-	}
-
-	return strings.Join(formatted, "")
-}
-
 // getText is create view text with display-width aware columns.
 func (l *ListInfo) getText() {
-	rows := []ListArrayInfo{{
-		Name:    "ServerName",
-		Connect: "Connect Information",
-		Note:    "Note",
-	}}
+	columns := l.listColumns()
 
+	rows := [][]string{make([]string, len(columns))}
+	for i, column := range columns {
+		rows[0][i] = column.Title
+	}
 	for _, key := range l.NameList {
-		rows = append(rows, ListArrayInfo{
-			Name:    convNewline(key, ""),
-			Connect: convNewline(l.DataList.Server[key].User+"@"+l.DataList.Server[key].Addr, ""),
-			Note:    convNewline(l.DataList.Server[key].Note, ""),
-		})
+		row := make([]string, len(columns))
+		for i, column := range columns {
+			row[i] = l.columnValue(key, column.Field)
+		}
+		rows = append(rows, row)
 	}
 
-	widths := []int{0, 0, 0}
-	for _, row := range rows {
-		widths[0] = max(widths[0], runewidth.StringWidth(row.Name))
-		widths[1] = max(widths[1], runewidth.StringWidth(row.Connect))
-		widths[2] = max(widths[2], runewidth.StringWidth(row.Note))
+	widths := make([]int, len(columns))
+	for i, column := range columns {
+		if column.Width > 0 {
+			widths[i] = column.Width
+		}
+		if column.Width > 0 && column.Field != columnName {
+			continue
+		}
+		for _, row := range rows {
+			widths[i] = max(widths[i], runewidth.StringWidth(row[i]))
+		}
 	}
 
 	l.DataText = l.DataText[:0]
 	for _, row := range rows {
-		l.DataText = append(l.DataText, formatListRow([]string{row.Name, row.Connect, row.Note}, widths))
+		var line strings.Builder
+		for i, column := range columns {
+			line.WriteString(formatListColumn(row[i], column, widths[i], i == len(columns)-1))
+		}
+		l.DataText = append(l.DataText, line.String())
 	}
 }
 
-// getFilterText updates l.ViewText with matching keyword (ignore case).
-// DataText sets ViewText if keyword is empty.
+// getFilterText updates l.ViewText with the lines matching l.Keyword.
+// Terms are fuzzy, `'exact`, `/regex/`, field-scoped (`user:root`) or host
+// selector terms, and `!term` negates them. Lines are ordered by fuzzy score,
+// then by the position of the first match.
+// DataText sets ViewText if keyword is empty. If l.GroupBy is set, the lines
+// are folded under the group headers. The host sets are shown first.
 func (l *ListInfo) getFilterText() {
-	// Initialization ViewText
-	l.ViewText = []string{}
-
-	// SearchText Bounds Space
-	keywords := strings.Fields(l.Keyword)
-	r := l.DataText[1:]
-	line := ""
-	tmpText := []string{}
-	l.ViewText = append(l.ViewText, l.DataText[0])
+	terms := parseSearchTerms(l.Keyword)
+	l.filterText(terms)
+	if len(l.ViewText) == 0 {
+		return
+	}
+	if len(l.hidden) > 0 {
+		view := []string{l.ViewText[0]}
+		for _, line := range l.ViewText[1:] {
+			if !l.hidden[lineName(line)] {
+				view = append(view, line)
+			}
+		}
+		l.ViewText = view
+	}
+	if l.GroupBy != "" {
+		l.ViewText = l.groupLines(terms)
+	}
+	if sets := l.setLines(terms); len(sets) > 0 {
+		view := append([]string{l.ViewText[0]}, sets...)
+		l.ViewText = append(view, l.ViewText[1:]...)
+	}
+}
 
+func (l *ListInfo) filterText(terms []searchTerm) {
 	// if No words
-	if len(keywords) == 0 {
+	if len(terms) == 0 {
 		l.ViewText = l.DataText
 		return
 	}
 
-	for i := 0; i < len(keywords); i++ {
-		lowKeyword := regexp.QuoteMeta(strings.ToLower(keywords[i]))
-		re := regexp.MustCompile(lowKeyword)
-		tmpText = []string{}
+	type scoredLine struct {
+		line  string
+		score int
+		begin int
+	}
 
-		for j := 0; j < len(r); j++ {
-			line += string(r[j])
-			if re.MatchString(strings.ToLower(line)) {
-				tmpText = append(tmpText, line)
-			}
-			line = ""
+	matched := []scoredLine{}
+	for _, line := range l.DataText[1:] {
+		score, spans, ok := l.matchLine(line, terms)
+		if !ok {
+			continue
 		}
-		r = tmpText
+		item := scoredLine{line: line, score: score}
+		if len(spans) > 0 {
+			item.begin = spans[0].start
+		}
+		matched = append(matched, item)
+	}
+	if hasFuzzyTerm(terms) {
+		sort.SliceStable(matched, func(i, j int) bool {
+			if matched[i].score != matched[j].score {
+				return matched[i].score > matched[j].score
+			}
+			return matched[i].begin < matched[j].begin
+		})
+	}
+
+	l.ViewText = make([]string, 0, len(matched)+1)
+	l.ViewText = append(l.ViewText, l.DataText[0])
+	for _, item := range matched {
+		l.ViewText = append(l.ViewText, item.line)
 	}
-	l.ViewText = append(l.ViewText, tmpText...)
-	return
 }
 
 // View is display the list in TUI
 func (l *ListInfo) View() {
+	l.orderNames()
 	l.getText()
 	if len(l.DataText) == 1 {
 		return
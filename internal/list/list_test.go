@@ -257,27 +257,57 @@ func TestGetFilterText(t *testing.T) {
 				"dev_web2           user1@192.168.101.2        WebServer",
 			},
 		},
-		// { // Can't use regexp
-		// 	desc: "Regexp \\d",
-		// 	l: ListInfo{
-		// 		Keyword: `dev_web\d+`,
-		// 		DataText: []string{
-		// 			"ServerName         Connect Information        Note",
-		// 			"dev_web            user1@192.168.101.99       WebServer",
-		// 			"dev_web1           user1@192.168.101.1        WebServer",
-		// 			"dev_web2           user1@192.168.101.2        WebServer",
-		// 			"dev_webX           user1@192.168.101.31       WebServer",
-		// 		},
-		// 	},
-		// 	expect: []string{
-		// 		"ServerName         Connect Information        Note",
-		// 		"dev_web1           user1@192.168.101.1        WebServer",
-		// 		"dev_web2           user1@192.168.101.2        WebServer",
-		// 	},
-		// },
+		{
+			desc: "Regexp \\d",
+			l: ListInfo{
+				Keyword: `/dev_web\d+/`,
+				DataText: []string{
+					"ServerName         Connect Information        Note",
+					"dev_web            user1@192.168.101.99       WebServer",
+					"dev_web1           user1@192.168.101.1        WebServer",
+					"dev_web2           user1@192.168.101.2        WebServer",
+					"dev_webX           user1@192.168.101.31       WebServer",
+				},
+			},
+			expect: []string{
+				"ServerName         Connect Information        Note",
+				"dev_web1           user1@192.168.101.1        WebServer",
+				"dev_web2           user1@192.168.101.2        WebServer",
+			},
+		},
 	}
 	for _, v := range tds {
 		v.l.getFilterText()
 		assert.Equal(t, v.expect, v.l.ViewText, v.desc)
 	}
 }
+
+func TestGetFilterTextWithHostSelector(t *testing.T) {
+	l := ListInfo{
+		DataText: []string{
+			"ServerName  Connect Information  Note",
+			"web01       user@10.0.0.1        web",
+			"web02       user@10.0.0.2        web",
+			"db01        user@10.0.0.3        db",
+		},
+		DataList: conf.Config{
+			Server: map[string]conf.ServerConfig{
+				"web01": {Tags: []string{"web", "env=prod"}},
+				"web02": {Tags: []string{"web", "canary"}},
+				"db01":  {Tags: []string{"db", "env=prod"}},
+			},
+		},
+	}
+
+	l.Keyword = "env=prod"
+	l.getFilterText()
+	assert.Equal(t, []string{l.DataText[0], l.DataText[1], l.DataText[3]}, l.ViewText)
+
+	l.Keyword = "tag:web !tag:canary"
+	l.getFilterText()
+	assert.Equal(t, []string{l.DataText[0], l.DataText[1]}, l.ViewText)
+
+	l.Keyword = "tag:web 10.0.0.2"
+	l.getFilterText()
+	assert.Equal(t, []string{l.DataText[0], l.DataText[2]}, l.ViewText)
+}
@@ -0,0 +1,124 @@
+// Copyright (c) 2026 Blacknon. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package list
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	conf "github.com/blacknon/lssh/internal/config"
+	sshcmd "github.com/blacknon/lssh/internal/ssh"
+	"github.com/gdamore/tcell/v2"
+)
+
+// probeCache caches the probe results for the whole invocation, so that a
+// host is probed at most once even when lsmux opens the selector again.
+type probeCache struct {
+	mu      sync.Mutex
+	results map[string]sshcmd.ProbeResult
+	pending map[string]bool
+}
+
+func newProbeCache() *probeCache {
+	return &probeCache{
+		results: map[string]sshcmd.ProbeResult{},
+		pending: map[string]bool{},
+	}
+}
+
+var sharedProbeCache = newProbeCache()
+
+// prober probes the hosts of the selector in the background, with at most
+// limit probes at the same time.
+type prober struct {
+	cache *probeCache
+	probe func(name string) sshcmd.ProbeResult
+	limit int
+}
+
+func newProber(data conf.Config) *prober {
+	cfg := data.List.ApplyDefaults()
+	timeout := cfg.ProbeTimeoutDuration()
+	return &prober{
+		cache: sharedProbeCache,
+		probe: func(name string) sshcmd.ProbeResult {
+			return sshcmd.Probe(name, data, timeout)
+		},
+		limit: max(cfg.ProbeConcurrency, 1),
+	}
+}
+
+// start probes the names that are not probed yet, and calls notify after
+// each result. It does not wait for the probes.
+func (p *prober) start(names []string, notify func()) {
+	queue := []string{}
+	p.cache.mu.Lock()
+	for _, name := range names {
+		if _, ok := p.cache.results[name]; ok || p.cache.pending[name] {
+			continue
+		}
+		p.cache.pending[name] = true
+		queue = append(queue, name)
+	}
+	p.cache.mu.Unlock()
+
+	go func() {
+		limit := make(chan struct{}, p.limit)
+		for _, name := range queue {
+			limit <- struct{}{}
+			go func(name string) {
+				defer func() { <-limit }()
+				result := p.probe(name)
+
+				p.cache.mu.Lock()
+				p.cache.results[name] = result
+				delete(p.cache.pending, name)
+				p.cache.mu.Unlock()
+				notify()
+			}(name)
+		}
+	}()
+}
+
+// result returns the probe result of name, and false while it is probed.
+func (p *prober) result(name string) (sshcmd.ProbeResult, bool) {
+	p.cache.mu.Lock()
+	defer p.cache.mu.Unlock()
+	result, ok := p.cache.results[name]
+	return result, ok
+}
+
+// down returns the hosts of names that are down.
+func (p *prober) down(names []string) map[string]bool {
+	down := map[string]bool{}
+	for _, name := range names {
+		if result, ok := p.result(name); ok && result.Status == sshcmd.ProbeDown {
+			down[name] = true
+		}
+	}
+	return down
+}
+
+// probeStatus returns the status cell text and color of a probe result.
+func probeStatus(result sshcmd.ProbeResult, ok bool) (string, tcell.Color) {
+	switch {
+	case !ok:
+		return "…", tcell.ColorGray
+	case result.Status == sshcmd.ProbeUp:
+		return "● " + formatRTT(result.RTT), tcell.ColorGreen
+	case result.Status == sshcmd.ProbeDown:
+		return "✕ down", tcell.ColorRed
+	default:
+		return "? unknown", tcell.ColorGray
+	}
+}
+
+func formatRTT(rtt time.Duration) string {
+	if rtt < time.Millisecond {
+		return "<1ms"
+	}
+	return fmt.Sprintf("%dms", rtt.Milliseconds())
+}
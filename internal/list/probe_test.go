@@ -0,0 +1,98 @@
+// Copyright (c) 2026 Blacknon. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package list
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	sshcmd "github.com/blacknon/lssh/internal/ssh"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProberLimitAndCache(t *testing.T) {
+	var mu sync.Mutex
+	running, maxRunning, calls := 0, 0, 0
+	p := &prober{
+		cache: newProbeCache(),
+		limit: 2,
+		probe: func(name string) sshcmd.ProbeResult {
+			mu.Lock()
+			running++
+			calls++
+			maxRunning = max(maxRunning, running)
+			mu.Unlock()
+			time.Sleep(10 * time.Millisecond)
+			mu.Lock()
+			running--
+			mu.Unlock()
+			return sshcmd.ProbeResult{Status: sshcmd.ProbeUp}
+		},
+	}
+
+	names := []string{"a", "b", "c", "d", "e"}
+	var wg sync.WaitGroup
+	wg.Add(len(names))
+	p.start(names, wg.Done)
+	wg.Wait()
+
+	// cached hosts are not probed again
+	p.start(names, func() { t.Error("cached host was probed again") })
+	time.Sleep(20 * time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, len(names), calls)
+	assert.LessOrEqual(t, maxRunning, 2)
+}
+
+func TestTviewSelectorProbe(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	names := []string{"bastion", "db01", "web01", "web02"}
+	s := NewTviewSelector(tview.NewApplication(), "QUERY>", newGroupTestConfig(), names, true)
+
+	results := map[string]sshcmd.ProbeResult{
+		"bastion": {Status: sshcmd.ProbeUnknown},
+		"db01":    {Status: sshcmd.ProbeDown},
+		"web01":   {Status: sshcmd.ProbeUp, RTT: 12 * time.Millisecond},
+	}
+	release := make(chan struct{})
+	p := &prober{
+		cache: newProbeCache(),
+		limit: 4,
+		probe: func(name string) sshcmd.ProbeResult {
+			if name == "web02" {
+				<-release
+			}
+			return results[name]
+		},
+	}
+	s.runProbe(p)
+	assert.Eventually(t, func() bool {
+		_, ok := p.result("web01")
+		_, ok2 := p.result("db01")
+		_, ok3 := p.result("bastion")
+		return ok && ok2 && ok3
+	}, time.Second, time.Millisecond)
+	s.applyProbe()
+
+	status := func(row int) string { return s.table.GetCell(row, 0).Text }
+	assert.Equal(t, "Status", status(0))
+	assert.Equal(t, "? unknown ", status(1))
+	assert.Equal(t, "✕ down ", status(2))
+	assert.Equal(t, "● 12ms ", status(3))
+	assert.Equal(t, "… ", status(4), "web02 is still probed")
+	assert.Equal(t, "db01", s.table.GetCell(2, 1).Text)
+
+	// Ctrl+R hides the unreachable hosts, and shows them again
+	s.handleInput(tcell.NewEventKey(tcell.KeyCtrlR, 0, tcell.ModCtrl))
+	assert.Equal(t, []string{"Host", "bastion", "web01", "web02"}, s.list.ViewText)
+	s.handleInput(tcell.NewEventKey(tcell.KeyCtrlR, 0, tcell.ModCtrl))
+	assert.Equal(t, []string{"Host", "bastion", "db01", "web01", "web02"}, s.list.ViewText)
+	close(release)
+}
@@ -0,0 +1,383 @@
+// Copyright (c) 2026 Blacknon. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package list
+
+import (
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	conf "github.com/blacknon/lssh/internal/config"
+)
+
+// Search keyword terms are separated by spaces and combined as AND.
+//
+//	web            fuzzy match (fzf style) over the whole line, ranked by score
+//	'web           exact substring match
+//	/web\d+/       regular expression (ignore case)
+//	user:root      field-scoped match. fields are user, addr, note and provider.
+//	               the value is a substring, a glob (`addr:10.1.*`) or `/regex/`.
+//	tag:web        host selector term (tag:, group:, name:, key=value)
+//	!term          negates any of the above
+const (
+	searchKindFuzzy    = "fuzzy"
+	searchKindExact    = "exact"
+	searchKindRegex    = "regex"
+	searchKindField    = "field"
+	searchKindSelector = "selector"
+)
+
+// searchFields is the field-scoped search prefixes.
+var searchFields = []string{"user", "addr", "note", "provider"}
+
+// fuzzy match score parameters
+const (
+	fuzzyScoreMatch       = 16
+	fuzzyBonusBoundary    = 8
+	fuzzyBonusConsecutive = 4
+	fuzzyPenaltyGapStart  = 3
+	fuzzyPenaltyGapExtend = 1
+)
+
+type searchTerm struct {
+	kind    string
+	field   string
+	pattern string
+	negate  bool
+	re      *regexp.Regexp
+}
+
+// matchSpan is a highlighted byte range of a list line.
+type matchSpan struct {
+	start int
+	end   int
+}
+
+// parseSearchTerms parses keyword into search terms.
+func parseSearchTerms(keyword string) []searchTerm {
+	var terms []searchTerm
+	for _, raw := range strings.Fields(keyword) {
+		// host selectors handle their own `!` negation.
+		body := strings.TrimPrefix(raw, "!")
+		if body == "" {
+			continue
+		}
+		if isSelectorTerm(body) {
+			terms = append(terms, searchTerm{kind: searchKindSelector, pattern: raw})
+			continue
+		}
+
+		term := searchTerm{negate: body != raw}
+		switch {
+		case fieldSearchPrefix(body) != "":
+			term.kind = searchKindField
+			term.field = fieldSearchPrefix(body)
+			term.pattern = body[len(term.field)+1:]
+			if strings.HasPrefix(term.pattern, "/") && len(term.pattern) > 1 {
+				term.re = compileSearchRegexp(term.pattern)
+			}
+		case strings.HasPrefix(body, "/") && len(body) > 1:
+			term.kind = searchKindRegex
+			term.pattern = body
+			term.re = compileSearchRegexp(body)
+		case strings.HasPrefix(body, "'") && len(body) > 1:
+			term.kind = searchKindExact
+			term.pattern = body[1:]
+			term.re = regexp.MustCompile("(?i)" + regexp.QuoteMeta(term.pattern))
+		default:
+			term.kind = searchKindFuzzy
+			term.pattern = body
+		}
+		terms = append(terms, term)
+	}
+	return terms
+}
+
+// isSelectorTerm reports whether body (without `!`) is a host selector term.
+// Terms joined with `,` are always a selector expression.
+func isSelectorTerm(body string) bool {
+	if strings.Contains(body, ",") {
+		return conf.IsHostSelector(body)
+	}
+	if fieldSearchPrefix(body) != "" || strings.HasPrefix(body, "/") || strings.HasPrefix(body, "'") {
+		return false
+	}
+	return conf.IsHostSelector(body)
+}
+
+func fieldSearchPrefix(body string) string {
+	for _, field := range searchFields {
+		if strings.HasPrefix(body, field+":") {
+			return field
+		}
+	}
+	return ""
+}
+
+// compileSearchRegexp compiles `/pattern/` (the closing `/` may be omitted
+// while typing). An invalid pattern is matched literally.
+func compileSearchRegexp(value string) *regexp.Regexp {
+	pattern := strings.TrimPrefix(value, "/")
+	if len(pattern) > 0 && strings.HasSuffix(pattern, "/") {
+		pattern = pattern[:len(pattern)-1]
+	}
+	re, err := regexp.Compile("(?i)" + pattern)
+	if err != nil {
+		return regexp.MustCompile("(?i)" + regexp.QuoteMeta(pattern))
+	}
+	return re
+}
+
+// hasFuzzyTerm reports whether terms have a positive fuzzy term, so the
+// result should be ordered by score.
+func hasFuzzyTerm(terms []searchTerm) bool {
+	for _, term := range terms {
+		if term.kind == searchKindFuzzy && !term.negate {
+			return true
+		}
+	}
+	return false
+}
+
+// matchLine matches line against terms. It returns the fuzzy score and the
+// spans to highlight.
+func (l *ListInfo) matchLine(line string, terms []searchTerm) (score int, spans []matchSpan, ok bool) {
+	name := ""
+	if fields := strings.Fields(line); len(fields) > 0 {
+		name = fields[0]
+	}
+
+	for _, term := range terms {
+		if term.kind == searchKindSelector {
+			matched, err := l.DataList.MatchHostSelector(name, term.pattern)
+			if err != nil {
+				// an incomplete selector does not filter while typing.
+				continue
+			}
+			if !matched {
+				return 0, nil, false
+			}
+			continue
+		}
+
+		termScore, termSpans, matched := l.matchTerm(name, line, term)
+		if matched == term.negate {
+			return 0, nil, false
+		}
+		if !term.negate {
+			score += termScore
+			spans = append(spans, termSpans...)
+		}
+	}
+
+	return score, mergeMatchSpans(spans), true
+}
+
+func (l *ListInfo) matchTerm(name, line string, term searchTerm) (score int, spans []matchSpan, ok bool) {
+	switch term.kind {
+	case searchKindFuzzy:
+		return fuzzyMatch(line, term.pattern)
+	case searchKindExact, searchKindRegex:
+		for _, loc := range term.re.FindAllStringIndex(line, -1) {
+			if loc[0] < loc[1] {
+				spans = append(spans, matchSpan{start: loc[0], end: loc[1]})
+			}
+		}
+		return 0, spans, term.re.MatchString(line)
+	case searchKindField:
+		value := l.searchFieldValue(name, term.field)
+		matched, text := matchFieldValue(value, term)
+		if !matched {
+			return 0, nil, false
+		}
+		if text != "" {
+			re := regexp.MustCompile("(?i)" + regexp.QuoteMeta(text))
+			for _, loc := range re.FindAllStringIndex(line, -1) {
+				spans = append(spans, matchSpan{start: loc[0], end: loc[1]})
+			}
+		}
+		return 0, spans, true
+	}
+	return 0, nil, false
+}
+
+func (l *ListInfo) searchFieldValue(name, field string) string {
+	server := l.displayData().Server[name]
+	switch field {
+	case "user":
+		return server.User
+	case "addr":
+		return server.Addr
+	case "note":
+		return server.Note
+	case "provider":
+		return server.ProviderName
+	}
+	return ""
+}
+
+// matchFieldValue matches value by the field term, and returns the text to
+// highlight in the line.
+func matchFieldValue(value string, term searchTerm) (matched bool, text string) {
+	switch {
+	case term.re != nil:
+		text = term.re.FindString(value)
+		return term.re.MatchString(value), text
+	case term.pattern == "":
+		return value != "", ""
+	case strings.ContainsAny(term.pattern, "*?["):
+		matched, _ = path.Match(strings.ToLower(term.pattern), strings.ToLower(value))
+		if matched {
+			return true, value
+		}
+		return false, ""
+	default:
+		text = regexp.MustCompile("(?i)" + regexp.QuoteMeta(term.pattern)).FindString(value)
+		return text != "", text
+	}
+}
+
+// fuzzyMatch reports whether the runes of pattern appear in line in order
+// (ignore case). Like fzf, it narrows the match to the shortest window that
+// ends at the first full match, and scores matches on word boundaries and
+// consecutive runes higher.
+func fuzzyMatch(line, pattern string) (score int, spans []matchSpan, ok bool) {
+	type lineRune struct {
+		r      rune // lower case
+		orig   rune
+		offset int
+		size   int
+	}
+
+	runes := make([]lineRune, 0, len(line))
+	for offset, r := range line {
+		runes = append(runes, lineRune{r: unicode.ToLower(r), orig: r, offset: offset, size: len(string(r))})
+	}
+	patternRunes := []rune(strings.ToLower(pattern))
+	if len(patternRunes) == 0 {
+		return 0, nil, true
+	}
+
+	// forward scan: find the end of the first full match.
+	p := 0
+	end := -1
+	for i, lr := range runes {
+		if lr.r == patternRunes[p] {
+			p++
+			if p == len(patternRunes) {
+				end = i
+				break
+			}
+		}
+	}
+	if end < 0 {
+		return 0, nil, false
+	}
+
+	// backward scan: find the latest start of a match ending at end.
+	p = len(patternRunes) - 1
+	start := end
+	for i := end; i >= 0; i-- {
+		if runes[i].r == patternRunes[p] {
+			if p == 0 {
+				start = i
+				break
+			}
+			p--
+		}
+	}
+
+	// forward again within the window, to record positions and score.
+	p = 0
+	previous := -2
+	for i := start; i <= end && p < len(patternRunes); i++ {
+		if runes[i].r != patternRunes[p] {
+			continue
+		}
+
+		score += fuzzyScoreMatch
+		if i == 0 || isFuzzyBoundary(runes[i-1].orig, runes[i].orig) {
+			score += fuzzyBonusBoundary
+		}
+		switch {
+		case previous == i-1:
+			score += fuzzyBonusConsecutive
+		case previous >= 0:
+			gap := i - previous - 1
+			score -= fuzzyPenaltyGapStart + (gap-1)*fuzzyPenaltyGapExtend
+		}
+
+		span := matchSpan{start: runes[i].offset, end: runes[i].offset + runes[i].size}
+		if len(spans) > 0 && spans[len(spans)-1].end == span.start {
+			spans[len(spans)-1].end = span.end
+		} else {
+			spans = append(spans, span)
+		}
+
+		previous = i
+		p++
+	}
+
+	return score, spans, true
+}
+
+// isFuzzyBoundary reports whether current starts a word after before.
+func isFuzzyBoundary(before, current rune) bool {
+	switch {
+	case unicode.IsSpace(before), strings.ContainsRune("_-.@:/", before):
+		return true
+	case unicode.IsLower(before) && unicode.IsUpper(current):
+		return true
+	case !unicode.IsDigit(before) && unicode.IsDigit(current):
+		return true
+	}
+	return false
+}
+
+// mergeMatchSpans sorts spans and merges the overlapping ones.
+func mergeMatchSpans(spans []matchSpan) []matchSpan {
+	if len(spans) == 0 {
+		return nil
+	}
+
+	sort.Slice(spans, func(i, j int) bool {
+		if spans[i].start == spans[j].start {
+			return spans[i].end < spans[j].end
+		}
+		return spans[i].start < spans[j].start
+	})
+
+	merged := make([]matchSpan, 0, len(spans))
+	for _, current := range spans {
+		if len(merged) == 0 {
+			merged = append(merged, current)
+			continue
+		}
+		last := &merged[len(merged)-1]
+		if current.start <= last.end {
+			if current.end > last.end {
+				last.end = current.end
+			}
+			continue
+		}
+		merged = append(merged, current)
+	}
+	return merged
+}
+
+// matchSpans returns the spans of line to highlight for l.Keyword.
+func (l *ListInfo) matchSpans(line string) []matchSpan {
+	terms := parseSearchTerms(l.Keyword)
+	if len(terms) == 0 {
+		return nil
+	}
+	_, spans, ok := l.matchLine(line, terms)
+	if !ok {
+		return nil
+	}
+	return spans
+}
@@ -0,0 +1,95 @@
+// Copyright (c) 2026 Blacknon. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package list
+
+import (
+	"strings"
+	"testing"
+
+	conf "github.com/blacknon/lssh/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func newSearchTestList() ListInfo {
+	l := ListInfo{
+		NameList: []string{"app-db01", "prd_web01", "stg_web02", "webmail"},
+		DataList: conf.Config{
+			Server: map[string]conf.ServerConfig{
+				"app-db01":  {User: "root", Addr: "10.1.0.5", Note: "database"},
+				"prd_web01": {User: "deploy", Addr: "10.1.0.10", Note: "web server", ProviderName: "aws"},
+				"stg_web02": {User: "root", Addr: "10.2.0.10", Note: "web server", ProviderName: "gcp"},
+				"webmail":   {User: "mail", Addr: "192.0.2.25", Note: "mail"},
+			},
+		},
+	}
+	l.getText()
+	return l
+}
+
+func viewNames(l ListInfo) []string {
+	names := []string{}
+	for _, line := range l.ViewText[1:] {
+		names = append(names, strings.Fields(line)[0])
+	}
+	return names
+}
+
+func TestGetFilterTextSearchTerms(t *testing.T) {
+	type TestData struct {
+		desc    string
+		keyword string
+		expect  []string
+	}
+	tds := []TestData{
+		{desc: "fuzzy, ranked by score", keyword: "pw01", expect: []string{"prd_web01"}},
+		{desc: "fuzzy boundary match ranks first", keyword: "web", expect: []string{"webmail", "prd_web01", "stg_web02"}},
+		{desc: "exact", keyword: "'web0", expect: []string{"prd_web01", "stg_web02"}},
+		{desc: "regex", keyword: `/^(app|stg)/`, expect: []string{"app-db01", "stg_web02"}},
+		{desc: "regex while typing", keyword: `/db0`, expect: []string{"app-db01"}},
+		{desc: "user field", keyword: "user:root", expect: []string{"app-db01", "stg_web02"}},
+		{desc: "addr field prefix", keyword: "addr:10.1.", expect: []string{"app-db01", "prd_web01"}},
+		{desc: "addr field glob", keyword: "addr:10.*.0.10", expect: []string{"prd_web01", "stg_web02"}},
+		{desc: "note field", keyword: "note:data", expect: []string{"app-db01"}},
+		{desc: "provider field", keyword: "provider:aws", expect: []string{"prd_web01"}},
+		{desc: "negated field", keyword: "user:root !note:db", expect: []string{"app-db01", "stg_web02"}},
+		{desc: "negated term", keyword: "!'web", expect: []string{"app-db01"}},
+		{desc: "negated regex", keyword: `web !/^stg/`, expect: []string{"webmail", "prd_web01"}},
+		{desc: "selector", keyword: "name:*web*", expect: []string{"prd_web01", "stg_web02", "webmail"}},
+		{desc: "selector expression", keyword: "provider:gcp,name:stg_*", expect: []string{"stg_web02"}},
+	}
+	for _, v := range tds {
+		l := newSearchTestList()
+		l.Keyword = v.keyword
+		l.getFilterText()
+		assert.Equal(t, v.expect, viewNames(l), v.desc)
+	}
+}
+
+func TestFuzzyMatch(t *testing.T) {
+	score, spans, ok := fuzzyMatch("prd_web01  deploy@10.1.0.10", "pw01")
+	assert.True(t, ok)
+	assert.Equal(t, []matchSpan{{start: 0, end: 1}, {start: 4, end: 5}, {start: 7, end: 9}}, spans)
+
+	looseScore, _, _ := fuzzyMatch("prdxweb01", "pw01")
+	assert.Greater(t, score, looseScore)
+
+	_, _, ok = fuzzyMatch("prd_web01", "xyz")
+	assert.False(t, ok)
+
+	_, spans, ok = fuzzyMatch("サーバー web", "バw")
+	assert.True(t, ok)
+	assert.Equal(t, []matchSpan{{start: 6, end: 9}, {start: 13, end: 14}}, spans)
+}
+
+func TestMatchSpans(t *testing.T) {
+	l := newSearchTestList()
+
+	l.Keyword = "user:root 'db !stg"
+	line := "app-db01  root@10.1.0.5  database"
+	assert.Equal(t, []matchSpan{{start: 4, end: 6}, {start: 10, end: 14}}, l.matchSpans(line))
+
+	l.Keyword = "/[/"
+	assert.Nil(t, l.matchSpans(line), "invalid regex is matched literally")
+}
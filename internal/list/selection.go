@@ -0,0 +1,224 @@
+// Copyright (c) 2026 Blacknon. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package list
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/blacknon/lssh/internal/check"
+	conf "github.com/blacknon/lssh/internal/config"
+)
+
+// A `--select` expression is a comma separated list of terms that must all
+// match. Each term can be negated with a leading `!`. Host names and name
+// globs are alternatives instead: a host must match one of them.
+//
+//	web-*            host name glob
+//	/^web\d+$/       host name regular expression
+//	web01            host name
+//	tag:web          host selector term (tag:, group:, provider:, name:)
+//	env=prod         tag or provider meta predicate (glob on value)
+//	meta.region=ap-* provider meta predicate
+//
+// ex.) `web-*,env=prod,!tag:canary`
+type selectTerm struct {
+	negate   bool
+	name     string
+	glob     string
+	re       *regexp.Regexp
+	selector string
+}
+
+// parseSelectExpr parses a `--select` expression into terms.
+func parseSelectExpr(expr string) ([]selectTerm, error) {
+	terms := []selectTerm{}
+	for _, raw := range splitSelectExpr(expr) {
+		raw = strings.TrimSpace(raw)
+		body := strings.TrimPrefix(raw, "!")
+		if body == "" {
+			continue
+		}
+
+		term := selectTerm{negate: body != raw}
+		switch {
+		case len(body) > 1 && strings.HasPrefix(body, "/") && strings.HasSuffix(body, "/"):
+			re, err := regexp.Compile(body[1 : len(body)-1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid regexp %q: %w", body, err)
+			}
+			term.re = re
+		case strings.HasPrefix(body, "meta.") && strings.Contains(body, "="):
+			term.selector = strings.TrimPrefix(body, "meta.")
+		case conf.IsHostSelector(body):
+			term.selector = body
+		case strings.ContainsAny(body, "*?["):
+			if _, err := path.Match(body, ""); err != nil {
+				return nil, fmt.Errorf("invalid glob %q: %w", body, err)
+			}
+			term.glob = body
+		default:
+			term.name = body
+		}
+		terms = append(terms, term)
+	}
+	if len(terms) == 0 {
+		return nil, fmt.Errorf("empty select expression")
+	}
+	return terms, nil
+}
+
+// splitSelectExpr splits expr on the commas outside of `/regexp/`.
+func splitSelectExpr(expr string) []string {
+	var parts []string
+	start, inRegexp := 0, false
+	for i, r := range expr {
+		switch {
+		case r == '/' && (inRegexp || i == start || (i == start+1 && expr[start] == '!')):
+			inRegexp = !inRegexp
+		case r == ',' && !inRegexp:
+			parts = append(parts, expr[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, expr[start:])
+}
+
+func (t selectTerm) match(data conf.Config, name string) (bool, error) {
+	var matched bool
+	switch {
+	case t.re != nil:
+		matched = t.re.MatchString(name)
+	case t.glob != "":
+		matched, _ = path.Match(t.glob, name)
+	case t.selector != "":
+		var err error
+		if matched, err = data.MatchHostSelector(name, t.selector); err != nil {
+			return false, err
+		}
+	default:
+		matched = t.name == name
+	}
+	return matched != t.negate, nil
+}
+
+// alternative reports whether t is a host name or name glob, which are OR-ed.
+func (t selectTerm) alternative() bool {
+	return !t.negate && (t.name != "" || t.glob != "")
+}
+
+// SelectExpr returns the servers of data matching the `--select`
+// expression expr, in order.
+func SelectExpr(data conf.Config, expr string) ([]string, error) {
+	terms, err := parseSelectExpr(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	names := conf.GetNameList(data)
+	sort.Strings(names)
+
+	result := []string{}
+	for _, name := range names {
+		matched := true
+		hasAlternative, matchedAlternative := false, false
+		for _, term := range terms {
+			ok, err := term.match(data, name)
+			if err != nil {
+				return nil, err
+			}
+			if term.alternative() {
+				hasAlternative = true
+				matchedAlternative = matchedAlternative || ok
+				continue
+			}
+			if !ok {
+				matched = false
+				break
+			}
+		}
+		if matched && (!hasAlternative || matchedAlternative) {
+			result = append(result, name)
+		}
+	}
+	return result, nil
+}
+
+// ResolveHosts resolves the hosts of `-H`, `--set` and `--hosts-from`, and the
+// `--select` expressions, to server names. Every command takes its hosts
+// from here: host selectors of hosts are expanded (see
+// conf.ResolveHostSelectors), the servers of the `--select` expressions are
+// added, and the names are checked with check.ExistServer. Duplicates are
+// removed while keeping the first occurrence.
+func ResolveHosts(data conf.Config, hosts []string, selects []string) ([]string, error) {
+	resolved, err := data.ResolveHostSelectors(hosts)
+	if err != nil {
+		return nil, err
+	}
+
+	names := conf.GetNameList(data)
+	for _, host := range resolved {
+		if !check.ExistServer([]string{host}, names) {
+			return nil, fmt.Errorf("input server %q not found from list", host)
+		}
+	}
+	if len(selects) == 0 {
+		return resolved, nil
+	}
+
+	result := []string{}
+	seen := map[string]bool{}
+	add := func(names []string) {
+		for _, name := range names {
+			if !seen[name] {
+				seen[name] = true
+				result = append(result, name)
+			}
+		}
+	}
+	add(resolved)
+	for _, expr := range selects {
+		names, err := SelectExpr(data, expr)
+		if err != nil {
+			return nil, fmt.Errorf("--select %q: %w", expr, err)
+		}
+		if len(names) == 0 {
+			return nil, fmt.Errorf("--select %q matched no servers", expr)
+		}
+		add(names)
+	}
+	return result, nil
+}
+
+// PrintSelection is the `--print-selection` mode. It writes hosts to w, one
+// per line, or the hosts chosen in the selector if hosts is empty, so that
+// shell pipelines can use the selector.
+func (l *ListInfo) PrintSelection(w io.Writer, hosts []string) error {
+	selected := hosts
+	if len(selected) == 0 {
+		l.View()
+		selected = l.SelectName
+		if len(selected) == 0 {
+			return fmt.Errorf("selection cancelled")
+		}
+	} else {
+		for _, host := range selected {
+			if !arrayContains(l.NameList, host) {
+				return fmt.Errorf("server %q not found from list", host)
+			}
+		}
+	}
+
+	for _, host := range selected {
+		if _, err := fmt.Fprintln(w, host); err != nil {
+			return err
+		}
+	}
+	return nil
+}
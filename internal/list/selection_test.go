@@ -0,0 +1,88 @@
+// Copyright (c) 2026 Blacknon. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package list
+
+import (
+	"bytes"
+	"testing"
+
+	conf "github.com/blacknon/lssh/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func newSelectionTestConfig() conf.Config {
+	return conf.Config{
+		Server: map[string]conf.ServerConfig{
+			"web-01": {Tags: []string{"web", "env=prod"}, ProviderMeta: map[string]string{"region": "ap-northeast-1"}},
+			"web-02": {Tags: []string{"web", "env=prod", "canary"}, ProviderMeta: map[string]string{"region": "us-east-1"}},
+			"web-03": {Tags: []string{"web", "env=stg"}},
+			"db01":   {Tags: []string{"db", "env=prod"}, ProviderMeta: map[string]string{"region": "ap-northeast-1"}},
+			"hidden": {Tags: []string{"web"}, Ignore: true},
+		},
+	}
+}
+
+func TestSelectExpr(t *testing.T) {
+	data := newSelectionTestConfig()
+
+	tds := []struct {
+		expr   string
+		expect []string
+	}{
+		{expr: "web-*", expect: []string{"web-01", "web-02", "web-03"}},
+		{expr: "/^web-0[12]$/", expect: []string{"web-01", "web-02"}},
+		{expr: "/^(db|web-03)/,!/1$/", expect: []string{"web-03"}},
+		{expr: "/a{1,2}|db/", expect: []string{"db01"}},
+		{expr: "db01", expect: []string{"db01"}},
+		{expr: "tag:web,env=prod,!tag:canary", expect: []string{"web-01"}},
+		{expr: "meta.region=ap-*", expect: []string{"db01", "web-01"}},
+		{expr: "web-*,!meta.region=us-*", expect: []string{"web-01", "web-03"}},
+		{expr: "nothing-*", expect: []string{}},
+		{expr: "web-01,db01", expect: []string{"db01", "web-01"}},
+		{expr: "web-*,db01,env=prod,!tag:canary", expect: []string{"db01", "web-01"}},
+	}
+	for _, td := range tds {
+		got, err := SelectExpr(data, td.expr)
+		assert.NoError(t, err, td.expr)
+		assert.Equal(t, td.expect, got, td.expr)
+	}
+
+	for _, expr := range []string{"", "/[/", "web-[", "!"} {
+		_, err := SelectExpr(data, expr)
+		assert.Error(t, err, expr)
+	}
+}
+
+func TestResolveHosts(t *testing.T) {
+	data := newSelectionTestConfig()
+
+	got, err := ResolveHosts(data, []string{"db01", "tag:canary"}, []string{"web-*,env=prod"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"db01", "web-02", "web-01"}, got)
+
+	got, err = ResolveHosts(data, []string{"db01"}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"db01"}, got)
+
+	_, err = ResolveHosts(data, nil, []string{"nothing-*"})
+	assert.EqualError(t, err, `--select "nothing-*" matched no servers`)
+
+	got, err = ResolveHosts(data, []string{"web-01,db01"}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"db01", "web-01"}, got)
+
+	_, err = ResolveHosts(data, []string{"db01", "missing"}, nil)
+	assert.EqualError(t, err, `input server "missing" not found from list`)
+}
+
+func TestPrintSelection(t *testing.T) {
+	l := &ListInfo{NameList: []string{"db01", "web-01"}, DataList: newSelectionTestConfig()}
+
+	var buf bytes.Buffer
+	assert.NoError(t, l.PrintSelection(&buf, []string{"web-01", "db01"}))
+	assert.Equal(t, "web-01\ndb01\n", buf.String())
+
+	assert.EqualError(t, l.PrintSelection(&buf, []string{"web-03"}), `server "web-03" not found from list`)
+}
@@ -0,0 +1,130 @@
+// Copyright (c) 2026 Blacknon. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package list
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	conf "github.com/blacknon/lssh/internal/config"
+	"github.com/blacknon/lssh/internal/hoststate"
+)
+
+// setPrefix is the prefix of the host set lines, which are shown at the top
+// of the multi-select list as `@NAME  (COUNT hosts)`.
+const setPrefix = "@"
+
+// isSetLine returns that line is a host set line.
+func isSetLine(line string) bool {
+	return strings.HasPrefix(line, setPrefix)
+}
+
+// lineSet returns the host set of a host set line, or "".
+func lineSet(line string) string {
+	if !isSetLine(line) {
+		return ""
+	}
+	return strings.TrimPrefix(strings.Fields(line)[0], setPrefix)
+}
+
+// lineHosts returns the hosts of a group header or a host set line, or nil
+// for a host line.
+func (l *ListInfo) lineHosts(line string) []string {
+	if group := lineGroup(line); group != "" {
+		return l.groupHosts[group]
+	}
+	if set := lineSet(line); set != "" {
+		return l.sets[set]
+	}
+	return nil
+}
+
+// loadSets resolves the saved host sets against the hosts of the list. Host
+// selectors of a set are evaluated again, and hosts not in the list are
+// left out. Sets are only offered in multi-select lists.
+func (l *ListInfo) loadSets() {
+	l.sets = nil
+	l.setNames = nil
+	if !l.MultiFlag {
+		return
+	}
+	if l.history == nil {
+		state, err := hoststate.Load()
+		if err != nil {
+			return
+		}
+		l.history = state
+	}
+
+	l.sets = map[string][]string{}
+	for _, name := range l.history.SetNames() {
+		hosts, err := l.DataList.ResolveHostSelectors(l.history.Sets[name].Hosts)
+		if err != nil {
+			continue
+		}
+		names := []string{}
+		for _, host := range hosts {
+			if arrayContains(l.NameList, host) {
+				names = append(names, host)
+			}
+		}
+		if len(names) > 0 {
+			l.sets[name] = names
+			l.setNames = append(l.setNames, name)
+		}
+	}
+}
+
+// setLines returns the host set lines matching terms.
+func (l *ListInfo) setLines(terms []searchTerm) []string {
+	lines := []string{}
+	for _, name := range l.setNames {
+		unit := "hosts"
+		if len(l.sets[name]) == 1 {
+			unit = "host"
+		}
+		line := fmt.Sprintf("%s%s  (%d %s)", setPrefix, name, len(l.sets[name]), unit)
+		if len(terms) > 0 {
+			if _, _, ok := l.matchLine(line, terms); !ok {
+				continue
+			}
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// setHostsToSave returns the hosts to save as a host set: the selected hosts,
+// or the filter if it is a host selector expression, so that the set is
+// evaluated again on each use.
+func (l *ListInfo) setHostsToSave() []string {
+	if len(l.SelectName) > 0 {
+		return append([]string(nil), l.SelectName...)
+	}
+	if keyword := strings.TrimSpace(l.Keyword); conf.IsHostSelector(keyword) {
+		return []string{keyword}
+	}
+	return nil
+}
+
+// saveSet saves hosts as the host set name and shows it in the list.
+func (l *ListInfo) saveSet(name string, hosts []string) error {
+	state, err := hoststate.Load()
+	if err != nil {
+		return err
+	}
+	if err := state.PutSet(name, hosts, time.Now()); err != nil {
+		return err
+	}
+	if err := state.Save(); err != nil {
+		return err
+	}
+
+	l.history = state
+	l.loadSets()
+	l.getFilterText()
+	return nil
+}
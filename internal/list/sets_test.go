@@ -0,0 +1,65 @@
+// Copyright (c) 2026 Blacknon. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package list
+
+import (
+	"testing"
+	"time"
+
+	"github.com/blacknon/lssh/internal/hoststate"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTviewSelectorHostSets(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	state, err := hoststate.Load()
+	assert.NoError(t, err)
+	now := time.Now()
+	assert.NoError(t, state.PutSet("web", []string{"web01", "web02", "removed"}, now))
+	assert.NoError(t, state.PutSet("db", []string{"name:db*"}, now))
+	assert.NoError(t, state.PutSet("none", []string{"name:none*"}, now))
+	assert.NoError(t, state.Save())
+
+	names := []string{"bastion", "db01", "web01", "web02"}
+	s := NewTviewSelector(tview.NewApplication(), "QUERY>", newGroupTestConfig(), names, true)
+	assert.Equal(t, []string{"Host", "@db  (1 host)", "@web  (2 hosts)", "bastion", "db01", "web01", "web02"}, s.list.ViewText)
+
+	// Tab on a host set toggles its hosts
+	s.list.CursorLine = 1
+	s.handleInput(tcell.NewEventKey(tcell.KeyTab, 0, tcell.ModNone))
+	assert.Equal(t, []string{"web01", "web02"}, s.list.SelectName)
+
+	// Ctrl+S saves the selected hosts
+	s.handleInput(tcell.NewEventKey(tcell.KeyCtrlS, 0, tcell.ModCtrl))
+	for _, r := range "front end" {
+		s.handleInput(tcell.NewEventKey(tcell.KeyRune, r, tcell.ModNone))
+	}
+	s.handleInput(tcell.NewEventKey(tcell.KeyEnter, 0, tcell.ModNone))
+	assert.Nil(t, s.setHosts)
+	hosts, err := hoststate.SetHosts([]string{"frontend"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"web01", "web02"}, hosts)
+	assert.Equal(t, "@frontend  (2 hosts)", s.list.ViewText[2])
+
+	// without a selection, a host selector filter is saved as is
+	s.list.SelectName = nil
+	s.list.Keyword = "name:web*"
+	s.list.getFilterText()
+	s.handleInput(tcell.NewEventKey(tcell.KeyCtrlS, 0, tcell.ModCtrl))
+	assert.Equal(t, []string{"name:web*"}, s.setHosts)
+	s.handleInput(tcell.NewEventKey(tcell.KeyEsc, 0, tcell.ModNone))
+	assert.Nil(t, s.setHosts)
+
+	// Enter on a host set selects its hosts
+	var selected []string
+	s.SetDoneFunc(func(names []string) { selected = names })
+	s.list.Keyword = ""
+	s.list.getFilterText()
+	s.list.CursorLine = 0
+	s.handleInput(tcell.NewEventKey(tcell.KeyEnter, 0, tcell.ModNone))
+	assert.Equal(t, []string{"db01"}, selected)
+}
@@ -0,0 +1,67 @@
+// Copyright (c) 2026 Blacknon. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package list
+
+import (
+	"strings"
+
+	conf "github.com/blacknon/lssh/internal/config"
+	"github.com/gdamore/tcell/v2"
+)
+
+// listTheme is the parsed colors of the `[list]` config.
+type listTheme struct {
+	normalFG   tcell.Color
+	headerFG   tcell.Color
+	promptFG   tcell.Color
+	cursorFG   tcell.Color
+	cursorBG   tcell.Color
+	selectedFG tcell.Color
+	selectedBG tcell.Color
+	matchFG    tcell.Color
+}
+
+// newListTheme parses the colors of cfg. Unknown colors fall back to the
+// defaults.
+func newListTheme(cfg conf.ListConfig) listTheme {
+	defaults := conf.ListConfig{}.ApplyDefaults()
+	cfg = cfg.ApplyDefaults()
+
+	color := func(value, fallback string) tcell.Color {
+		if c := parseListColor(value); c != tcell.ColorDefault {
+			return c
+		}
+		return parseListColor(fallback)
+	}
+
+	return listTheme{
+		normalFG:   tcell.ColorDefault,
+		headerFG:   color(cfg.HeaderColor, defaults.HeaderColor),
+		promptFG:   color(cfg.PromptColor, defaults.PromptColor),
+		cursorFG:   color(cfg.CursorColor, defaults.CursorColor),
+		cursorBG:   color(cfg.CursorBgColor, defaults.CursorBgColor),
+		selectedFG: color(cfg.SelectedColor, defaults.SelectedColor),
+		selectedBG: color(cfg.SelectedBgColor, defaults.SelectedBgColor),
+		matchFG:    color(cfg.MatchColor, defaults.MatchColor),
+	}
+}
+
+func parseListColor(value string) tcell.Color {
+	return tcell.GetColor(strings.ToLower(strings.TrimSpace(value)))
+}
+
+// colorTag returns the tview color tag of c.
+func colorTag(c tcell.Color) string {
+	return "[" + c.String() + "]"
+}
+
+// termboxColor returns the termbox color number (the palette index, see
+// drawLine) of c, or fallback if c is not a palette color.
+func termboxColor(c tcell.Color, fallback int) int {
+	if c&tcell.ColorIsRGB != 0 || c < tcell.ColorValid || c >= tcell.ColorValid+256 {
+		return fallback
+	}
+	return int(c - tcell.ColorValid)
+}
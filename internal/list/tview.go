@@ -6,9 +6,9 @@ package list
 
 import (
 	"fmt"
-	"regexp"
-	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 
 	conf "github.com/blacknon/lssh/internal/config"
 	"github.com/gdamore/tcell/v2"
@@ -22,6 +22,13 @@ func (l *ListInfo) viewWithTview() {
 func (l *ListInfo) selectWithTview() (selected []string, ok bool, err error) {
 	app := tview.NewApplication().EnableMouse(true)
 	selector := NewTviewSelector(app, l.Prompt, l.DataList, l.NameList, l.MultiFlag)
+	selector.SetHistory(l.Command, l.Sort)
+	if l.GroupBy != "" {
+		selector.SetGroupBy(l.GroupBy)
+	}
+	if l.Probe {
+		selector.startProbe()
+	}
 	selector.list.Keyword = l.Keyword
 	selector.list.CursorLine = l.CursorLine
 	selector.list.SelectName = append([]string(nil), l.SelectName...)
@@ -56,24 +63,38 @@ type TviewSelector struct {
 	app     *tview.Application
 	list    *ListInfo
 	prompt  *tview.TextView
+	body    *tview.Flex
 	table   *tview.Table
 	syncing bool
 
+	// colors and key bindings of the `[list]` config.
+	theme listTheme
+	keys  listKeyMap
+
+	// details pane of the host under the cursor. texts are built by
+	// detailsFunc in the background and cached per host.
+	details        *tview.TextView
+	detailsMode    int
+	detailsName    string
+	detailsFunc    func(conf.Config, string) string
+	detailsMu      sync.Mutex
+	detailsCache   map[string]string
+	detailsLoading map[string]bool
+
+	// reachability prober, started with `--probe` or `[list] probe`.
+	prober      *prober
+	hideDown    bool
+	probeQueued atomic.Bool
+
+	// hosts being saved as a host set, while the set name is typed.
+	setHosts []string
+	setName  string
+	setErr   string
+
 	done   func([]string)
 	cancel func()
 }
 
-var (
-	selectorNormalFG = tcell.ColorDefault
-	selectorHeaderFG = tcell.ColorYellow
-	selectorCursorBG = tcell.ColorGreen
-	selectorCursorFG = tcell.ColorBlack
-	selectorMarkedBG = tcell.ColorTeal
-	selectorMarkedFG = tcell.ColorBlack
-	selectorPromptFG = tcell.ColorYellow
-	selectorMatchTag = "[#ff69b4]"
-)
-
 // NewTviewSelector creates a host selector widget.
 func NewTviewSelector(app *tview.Application, prompt string, data conf.Config, names []string, multi bool) *TviewSelector {
 	l := &ListInfo{
@@ -81,14 +102,18 @@ func NewTviewSelector(app *tview.Application, prompt string, data conf.Config, n
 		NameList:  append([]string(nil), names...),
 		DataList:  data,
 		MultiFlag: multi,
+		GroupBy:   data.List.GroupBy,
 	}
+	l.loadSets()
 	l.getText()
 	l.getFilterText()
 
 	s := &TviewSelector{
-		Flex: tview.NewFlex().SetDirection(tview.FlexRow),
-		app:  app,
-		list: l,
+		Flex:  tview.NewFlex().SetDirection(tview.FlexRow),
+		app:   app,
+		list:  l,
+		theme: newListTheme(data.List),
+		keys:  newListKeyMap(data.List),
 	}
 	s.SetBackgroundColor(tcell.ColorDefault)
 
@@ -104,8 +129,8 @@ func NewTviewSelector(app *tview.Application, prompt string, data conf.Config, n
 	s.table.SetBorder(false)
 	s.table.SetBackgroundColor(tcell.ColorDefault)
 	s.table.SetSelectedStyle(tcell.StyleDefault.
-		Foreground(selectorCursorFG).
-		Background(selectorCursorBG))
+		Foreground(s.theme.cursorFG).
+		Background(s.theme.cursorBG))
 	s.table.SetInputCapture(s.handleInput)
 	s.table.SetSelectionChangedFunc(func(row, _ int) {
 		if s.syncing {
@@ -127,8 +152,24 @@ func NewTviewSelector(app *tview.Application, prompt string, data conf.Config, n
 		s.confirm()
 	})
 
+	s.details = tview.NewTextView().
+		SetDynamicColors(true).
+		SetWrap(true)
+	s.details.SetBorder(true)
+	s.details.SetTitle(" details (Ctrl+P) ")
+	s.details.SetBackgroundColor(tcell.ColorDefault)
+	s.detailsFunc = hostDetails
+	s.detailsCache = map[string]string{}
+	s.detailsLoading = map[string]bool{}
+
+	s.body = tview.NewFlex()
+	s.setDetailsMode(detailsHidden)
+
 	s.AddItem(s.prompt, 1, 0, false)
-	s.AddItem(s.table, 0, 1, true)
+	s.AddItem(s.body, 0, 1, true)
+	if data.List.Probe {
+		s.startProbe()
+	}
 	s.render()
 
 	return s
@@ -147,6 +188,27 @@ func (s *TviewSelector) FocusTarget() tview.Primitive {
 	return s.table
 }
 
+// SetHistory sets the command name of the host history and the host order
+// (see ListInfo.Command and ListInfo.Sort).
+func (s *TviewSelector) SetHistory(command, order string) {
+	s.list.Command = command
+	s.list.Sort = order
+	s.list.orderNames()
+	s.list.getText()
+	s.list.getFilterText()
+	s.clampCursor()
+	s.render()
+}
+
+// SetGroupBy folds the hosts under group headers by field (see
+// ListInfo.GroupBy).
+func (s *TviewSelector) SetGroupBy(field string) {
+	s.list.GroupBy = field
+	s.list.getFilterText()
+	s.clampCursor()
+	s.render()
+}
+
 func (s *TviewSelector) ListInfo() *ListInfo {
 	return s.list
 }
@@ -156,46 +218,83 @@ func (s *TviewSelector) Refresh() {
 }
 
 func (s *TviewSelector) handleInput(event *tcell.EventKey) *tcell.EventKey {
+	if s.setHosts != nil {
+		return s.handleSetNameInput(event)
+	}
+
 	headLine := 2
 	height := s.pageHeight()
 	allFlag := s.allSelectedVisible()
 
-	switch event.Key() {
-	case tcell.KeyEsc, tcell.KeyCtrlC:
+	switch s.keys.action(event) {
+	case actionCancel:
 		s.fireCancel()
 		return nil
-	case tcell.KeyUp:
+	case actionConfirm:
+		s.confirm()
+		return nil
+	case actionUp:
 		if s.list.CursorLine > 0 {
 			s.list.CursorLine--
 		}
-	case tcell.KeyDown:
+	case actionDown:
 		if s.list.CursorLine < len(s.list.ViewText)-headLine {
 			s.list.CursorLine++
 		}
-	case tcell.KeyRight:
+	case actionPageDown:
 		nextPosition := ((s.list.CursorLine + height) / height) * height
 		if nextPosition+2 <= len(s.list.ViewText) {
 			s.list.CursorLine = nextPosition
 		}
-	case tcell.KeyLeft:
+	case actionPageUp:
 		beforePosition := ((s.list.CursorLine - height) / height) * height
 		if beforePosition >= 0 {
 			s.list.CursorLine = beforePosition
 		}
-	case tcell.KeyTab:
+	case actionTop:
+		s.list.CursorLine = 0
+	case actionBottom:
+		s.list.CursorLine = maxInt(len(s.list.ViewText)-headLine, 0)
+	case actionToggle:
 		if s.list.MultiFlag && len(s.list.ViewText) > s.list.CursorLine+1 {
-			s.list.toggle(strings.Fields(s.list.ViewText[s.list.CursorLine+1])[0])
+			line := s.list.ViewText[s.list.CursorLine+1]
+			if hosts := s.list.lineHosts(line); hosts != nil {
+				s.list.toggleHosts(hosts)
+			} else {
+				s.list.toggle(lineName(line))
+			}
 		}
 		if s.list.CursorLine < len(s.list.ViewText)-headLine {
 			s.list.CursorLine++
 		}
-	case tcell.KeyCtrlA:
+	case actionToggleAll:
 		if s.list.MultiFlag {
 			s.list.allToggle(allFlag)
 		}
-	case tcell.KeyEnter:
-		s.confirm()
-		return nil
+	case actionDetails:
+		s.setDetailsMode((s.detailsMode + 1) % 3)
+	case actionFavorite:
+		s.toggleFavorite()
+	case actionFold:
+		s.toggleFold()
+	case actionGroup:
+		s.toggleGrouping()
+	case actionSaveSet:
+		s.startSaveSet()
+	case actionHideDown:
+		s.toggleHideDown()
+	default:
+		return s.handleFilterInput(event)
+	}
+
+	s.render()
+	return nil
+}
+
+// handleFilterInput edits the filter text with the keys not bound to an
+// action.
+func (s *TviewSelector) handleFilterInput(event *tcell.EventKey) *tcell.EventKey {
+	switch event.Key() {
 	case tcell.KeyBackspace, tcell.KeyBackspace2:
 		if len(s.list.Keyword) > 0 {
 			s.list.deleteRune()
@@ -222,7 +321,15 @@ func (s *TviewSelector) handleInput(event *tcell.EventKey) *tcell.EventKey {
 
 func (s *TviewSelector) render() {
 	s.prompt.Clear()
-	s.prompt.SetText("[yellow]" + s.list.Prompt + "[white]" + tview.Escape(s.list.Keyword))
+	if s.setHosts != nil {
+		text := colorTag(s.theme.promptFG) + fmt.Sprintf("save %d hosts as set>", len(s.setHosts)) + "[white]" + tview.Escape(s.setName)
+		if s.setErr != "" {
+			text += "  [red]" + tview.Escape(s.setErr)
+		}
+		s.prompt.SetText(text)
+	} else {
+		s.prompt.SetText(colorTag(s.theme.promptFG) + s.list.Prompt + "[white]" + tview.Escape(s.list.Keyword))
+	}
 
 	s.table.Clear()
 	if len(s.list.ViewText) == 0 {
@@ -232,40 +339,54 @@ func (s *TviewSelector) render() {
 	header := s.list.ViewText[0]
 	headerCell := tview.NewTableCell(header).
 		SetSelectable(false).
-		SetTextColor(selectorHeaderFG).
-		SetSelectedStyle(tcell.StyleDefault.Foreground(selectorHeaderFG))
-	s.table.SetCell(0, 0, headerCell)
+		SetTextColor(s.theme.headerFG).
+		SetSelectedStyle(tcell.StyleDefault.Foreground(s.theme.headerFG))
+	column := 0
+	if s.prober != nil {
+		column = 1
+		s.table.SetCell(0, 0, tview.NewTableCell("Status").
+			SetSelectable(false).
+			SetTextColor(s.theme.headerFG).
+			SetSelectedStyle(tcell.StyleDefault.Foreground(s.theme.headerFG)))
+	}
+	s.table.SetCell(0, column, headerCell)
 
 	for i, line := range s.list.ViewText[1:] {
 		cell := tview.NewTableCell(s.highlightLine(line))
 		cell.SetExpansion(1)
 
-		name := ""
-		fields := strings.Fields(line)
-		if len(fields) > 0 {
-			name = fields[0]
-		}
+		name := lineName(line)
+		hosts := s.list.lineHosts(line)
+		selected := (name != "" && arrayContains(s.list.SelectName, name)) || s.list.hostsSelected(hosts)
 
 		if i == s.list.CursorLine {
-			cell.SetTextColor(selectorCursorFG)
-			cell.SetBackgroundColor(selectorCursorBG)
+			cell.SetTextColor(s.theme.cursorFG)
+			cell.SetBackgroundColor(s.theme.cursorBG)
 			cell.SetSelectedStyle(tcell.StyleDefault.
-				Foreground(selectorCursorFG).
-				Background(selectorCursorBG))
-		} else if arrayContains(s.list.SelectName, name) {
-			cell.SetTextColor(selectorMarkedFG)
-			cell.SetBackgroundColor(selectorMarkedBG)
+				Foreground(s.theme.cursorFG).
+				Background(s.theme.cursorBG))
+		} else if selected {
+			cell.SetTextColor(s.theme.selectedFG)
+			cell.SetBackgroundColor(s.theme.selectedBG)
 			cell.SetSelectedStyle(tcell.StyleDefault.
-				Foreground(selectorMarkedFG).
-				Background(selectorMarkedBG))
+				Foreground(s.theme.selectedFG).
+				Background(s.theme.selectedBG))
+		} else if hosts != nil {
+			cell.SetTextColor(s.theme.headerFG)
+			cell.SetSelectedStyle(tcell.StyleDefault.
+				Foreground(s.theme.cursorFG).
+				Background(s.theme.cursorBG))
 		} else {
-			cell.SetTextColor(selectorNormalFG)
+			cell.SetTextColor(s.theme.normalFG)
 			cell.SetSelectedStyle(tcell.StyleDefault.
-				Foreground(selectorCursorFG).
-				Background(selectorCursorBG))
+				Foreground(s.theme.cursorFG).
+				Background(s.theme.cursorBG))
 		}
 
-		s.table.SetCell(i+1, 0, cell)
+		if s.prober != nil {
+			s.table.SetCell(i+1, 0, s.statusCell(name, cell, i == s.list.CursorLine || selected))
+		}
+		s.table.SetCell(i+1, column, cell)
 	}
 
 	if len(s.list.ViewText) > 1 {
@@ -273,66 +394,206 @@ func (s *TviewSelector) render() {
 		s.table.Select(s.list.CursorLine+1, 0)
 		s.syncing = false
 	}
+
+	s.updateDetails()
 }
 
-func (s *TviewSelector) highlightLine(line string) string {
-	keywords := strings.Fields(s.list.Keyword)
-	if len(keywords) == 0 {
-		return tview.Escape(line)
+// statusCell returns the probe status cell of the host name, in the style of
+// its row cell. The status color is used unless the row is highlighted.
+func (s *TviewSelector) statusCell(name string, row *tview.TableCell, highlighted bool) *tview.TableCell {
+	cell := *row
+	cell.Text = ""
+	cell.Expansion = 0
+	if name == "" {
+		return &cell
 	}
 
-	type span struct {
-		start int
-		end   int
+	result, ok := s.prober.result(name)
+	text, color := probeStatus(result, ok)
+	cell.Text = text + " "
+	if !highlighted {
+		cell.Color = color
 	}
+	return &cell
+}
 
-	var spans []span
-	for _, keyword := range keywords {
-		re, err := regexp.Compile("(?i)" + regexp.QuoteMeta(keyword))
-		if err != nil {
-			continue
-		}
-		for _, loc := range re.FindAllStringIndex(line, -1) {
-			if len(loc) != 2 || loc[0] == loc[1] {
-				continue
-			}
-			spans = append(spans, span{start: loc[0], end: loc[1]})
-		}
-	}
-	if len(spans) == 0 {
-		return tview.Escape(line)
+// startProbe starts probing the hosts of the list in the background, and
+// adds the status column.
+func (s *TviewSelector) startProbe() {
+	if s.prober != nil {
+		return
 	}
+	s.runProbe(newProber(s.list.DataList))
+}
 
-	sort.Slice(spans, func(i, j int) bool {
-		if spans[i].start == spans[j].start {
-			return spans[i].end < spans[j].end
+func (s *TviewSelector) runProbe(p *prober) {
+	s.prober = p
+	s.prober.start(s.list.NameList, func() {
+		// results are applied at most once per draw.
+		if s.probeQueued.CompareAndSwap(false, true) {
+			s.queueUpdateDraw(func() {
+				s.probeQueued.Store(false)
+				s.applyProbe()
+			})
 		}
-		return spans[i].start < spans[j].start
 	})
+}
 
-	merged := make([]span, 0, len(spans))
-	for _, current := range spans {
-		if len(merged) == 0 {
-			merged = append(merged, current)
-			continue
+// applyProbe hides the hosts that became unreachable, and draws the status.
+func (s *TviewSelector) applyProbe() {
+	if s.hideDown {
+		name := s.cursorName()
+		s.list.hidden = s.prober.down(s.list.NameList)
+		s.list.getFilterText()
+		if name != "" {
+			s.moveCursor(func(line string) bool { return lineName(line) == name })
 		}
-		last := &merged[len(merged)-1]
-		if current.start <= last.end {
-			if current.end > last.end {
-				last.end = current.end
+		s.clampCursor()
+	}
+	s.render()
+}
+
+// toggleHideDown hides or shows the unreachable hosts. It starts the probe
+// if it is not running.
+func (s *TviewSelector) toggleHideDown() {
+	s.startProbe()
+	s.hideDown = !s.hideDown
+	if !s.hideDown {
+		s.list.hidden = nil
+		s.list.getFilterText()
+		s.clampCursor()
+		return
+	}
+	s.applyProbe()
+}
+
+// setDetailsMode lays out the table and the details pane: hidden, on the
+// right side or at the bottom.
+func (s *TviewSelector) setDetailsMode(mode int) {
+	s.detailsMode = mode
+	s.body.Clear()
+
+	switch mode {
+	case detailsSide:
+		s.body.SetDirection(tview.FlexColumn)
+		s.body.AddItem(s.table, 0, 3, true)
+		s.body.AddItem(s.details, 0, 2, false)
+	case detailsBottom:
+		s.body.SetDirection(tview.FlexRow)
+		s.body.AddItem(s.table, 0, 2, true)
+		s.body.AddItem(s.details, 0, 1, false)
+	default:
+		s.body.SetDirection(tview.FlexRow)
+		s.body.AddItem(s.table, 0, 1, true)
+	}
+	s.detailsName = ""
+}
+
+// updateDetails shows the details of the host under the cursor, loading
+// them in the background if they are not cached yet.
+func (s *TviewSelector) updateDetails() {
+	if s.detailsMode == detailsHidden {
+		return
+	}
+
+	name := s.cursorName()
+	if name == s.detailsName {
+		return
+	}
+	s.detailsName = name
+	if name == "" {
+		s.details.SetText("")
+		return
+	}
+
+	s.detailsMu.Lock()
+	text, cached := s.detailsCache[name]
+	loading := s.detailsLoading[name]
+	if !cached && !loading {
+		s.detailsLoading[name] = true
+	}
+	s.detailsMu.Unlock()
+
+	if cached {
+		s.details.SetText(text)
+		return
+	}
+	s.details.SetText(detailsLoading)
+	if loading {
+		return
+	}
+
+	data := s.list.DataList
+	go func() {
+		text := s.detailsFunc(data, name)
+
+		s.detailsMu.Lock()
+		s.detailsCache[name] = text
+		delete(s.detailsLoading, name)
+		s.detailsMu.Unlock()
+
+		s.queueUpdateDraw(func() {
+			if s.detailsName == name {
+				s.details.SetText(text)
 			}
-			continue
+		})
+	}()
+}
+
+func (s *TviewSelector) queueUpdateDraw(fn func()) {
+	if s.app == nil {
+		fn()
+		return
+	}
+	s.app.QueueUpdateDraw(fn)
+}
+
+// cursorName returns the host name under the cursor, or "" on a group
+// header.
+func (s *TviewSelector) cursorName() string {
+	if len(s.list.ViewText) <= s.list.CursorLine+1 {
+		return ""
+	}
+	return lineName(s.list.ViewText[s.list.CursorLine+1])
+}
+
+// cursorGroup returns the group of the header or the host under the cursor,
+// or "" if the hosts are not grouped.
+func (s *TviewSelector) cursorGroup() string {
+	if s.list.GroupBy == "" || len(s.list.ViewText) <= s.list.CursorLine+1 {
+		return ""
+	}
+	line := s.list.ViewText[s.list.CursorLine+1]
+	if group := lineGroup(line); group != "" {
+		return group
+	}
+	return s.list.hostGroup(lineName(line))
+}
+
+// moveCursor moves the cursor to the first line matching fn.
+func (s *TviewSelector) moveCursor(fn func(line string) bool) {
+	for i, line := range s.list.ViewText[1:] {
+		if fn(line) {
+			s.list.CursorLine = i
+			break
 		}
-		merged = append(merged, current)
+	}
+	s.clampCursor()
+}
+
+func (s *TviewSelector) highlightLine(line string) string {
+	spans := s.list.matchSpans(line)
+	if len(spans) == 0 {
+		return tview.Escape(line)
 	}
 
 	var builder strings.Builder
 	cursor := 0
-	for _, item := range merged {
+	for _, item := range spans {
 		if item.start > cursor {
 			builder.WriteString(tview.Escape(line[cursor:item.start]))
 		}
-		builder.WriteString(selectorMatchTag)
+		builder.WriteString(colorTag(s.theme.matchFG))
 		builder.WriteString(tview.Escape(line[item.start:item.end]))
 		builder.WriteString("[-]")
 		cursor = item.end
@@ -344,18 +605,105 @@ func (s *TviewSelector) highlightLine(line string) string {
 	return builder.String()
 }
 
+// toggleFavorite pins or unpins the host under the cursor, and keeps the
+// cursor on it.
+func (s *TviewSelector) toggleFavorite() {
+	name := s.cursorName()
+	if name == "" || s.list.Command == "" {
+		return
+	}
+
+	// a save error is ignored, the favorite is kept in this selector.
+	_ = s.list.toggleFavorite(name)
+	s.moveCursor(func(line string) bool { return lineName(line) == name })
+}
+
+// toggleFold collapses or expands the group under the cursor, and moves the
+// cursor to its header.
+func (s *TviewSelector) toggleFold() {
+	group := s.cursorGroup()
+	if group == "" {
+		return
+	}
+	s.list.toggleFold(group)
+	s.moveCursor(func(line string) bool { return lineGroup(line) == group })
+}
+
+// toggleGrouping turns the grouped view on or off, and keeps the cursor on
+// the same host.
+func (s *TviewSelector) toggleGrouping() {
+	name := s.cursorName()
+	s.list.toggleGrouping()
+	if name != "" {
+		s.moveCursor(func(line string) bool { return lineName(line) == name })
+	} else {
+		s.clampCursor()
+	}
+}
+
 func (s *TviewSelector) confirm() {
 	if len(s.list.ViewText) <= s.list.CursorLine+1 {
 		return
 	}
+	// Enter on a group header folds it.
+	if group := lineGroup(s.list.ViewText[s.list.CursorLine+1]); group != "" {
+		s.toggleFold()
+		s.render()
+		return
+	}
 	if len(s.list.SelectName) == 0 {
-		s.list.SelectName = append(s.list.SelectName, strings.Fields(s.list.ViewText[s.list.CursorLine+1])[0])
+		// Enter on a host set selects its hosts.
+		if set := lineSet(s.list.ViewText[s.list.CursorLine+1]); set != "" {
+			s.list.SelectName = append(s.list.SelectName, s.list.sets[set]...)
+		} else {
+			s.list.SelectName = append(s.list.SelectName, s.cursorName())
+		}
 	}
 	if s.done != nil {
 		s.done(append([]string(nil), s.list.SelectName...))
 	}
 }
 
+// startSaveSet asks the name of a host set for the selected hosts, or for
+// the filter if it is a host selector expression.
+func (s *TviewSelector) startSaveSet() {
+	if !s.list.MultiFlag {
+		return
+	}
+	s.setHosts = s.list.setHostsToSave()
+	s.setName = ""
+	s.setErr = ""
+}
+
+// handleSetNameInput edits the name of the host set being saved. Enter saves
+// it, and Esc or Ctrl+C goes back to the list.
+func (s *TviewSelector) handleSetNameInput(event *tcell.EventKey) *tcell.EventKey {
+	switch event.Key() {
+	case tcell.KeyEsc, tcell.KeyCtrlC:
+		s.setHosts = nil
+	case tcell.KeyEnter:
+		if err := s.list.saveSet(s.setName, s.setHosts); err != nil {
+			s.setErr = err.Error()
+			break
+		}
+		s.setHosts = nil
+		s.clampCursor()
+	case tcell.KeyBackspace, tcell.KeyBackspace2:
+		if runes := []rune(s.setName); len(runes) > 0 {
+			s.setName = string(runes[:len(runes)-1])
+		}
+	case tcell.KeyRune:
+		if event.Rune() != ' ' {
+			s.setName += string(event.Rune())
+		}
+	default:
+		return nil
+	}
+
+	s.render()
+	return nil
+}
+
 func (s *TviewSelector) fireCancel() {
 	if s.cancel != nil {
 		s.cancel()
@@ -388,11 +736,11 @@ func (s *TviewSelector) allSelectedVisible() bool {
 		return false
 	}
 	for _, line := range s.list.ViewText[1:] {
-		fields := strings.Fields(line)
-		if len(fields) == 0 {
+		name := lineName(line)
+		if name == "" {
 			continue
 		}
-		if !arrayContains(s.list.SelectName, fields[0]) {
+		if !arrayContains(s.list.SelectName, name) {
 			return false
 		}
 	}
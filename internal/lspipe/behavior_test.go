@@ -355,6 +355,39 @@ func TestDaemonExecRequestSingleTargetDoesNotPrefixErrors(t *testing.T) {
 	}
 }
 
+func TestDaemonExecRequestStructuredSendsExitEvents(t *testing.T) {
+	daemon := NewDaemon("default", "", conf.Config{}, []string{"web01", "web02"}, nil)
+	daemon.runCommandFn = func(host string, req Request, sendEvent func(Event)) (int, error) {
+		if host == "web01" {
+			sendEvent(Event{Type: "stdout", Host: host, Data: []byte("ok\n")})
+			return 3, nil
+		}
+		return 1, errors.New("boom")
+	}
+
+	var buf bytes.Buffer
+	err := daemon.execRequest(json.NewEncoder(&buf), Request{Action: actionExec, Command: "hostname", Structured: true})
+	if err != nil {
+		t.Fatalf("execRequest() error = %v", err)
+	}
+
+	exits := map[string]Event{}
+	for _, event := range decodeEvents(t, buf.Bytes()) {
+		if event.Type == "stderr" {
+			t.Fatalf("unexpected stderr event: %#v", event)
+		}
+		if event.Type == "exit" {
+			exits[event.Host] = event
+		}
+	}
+	if exits["web01"].ExitCode != 3 || exits["web01"].Message != "" {
+		t.Fatalf("web01 exit = %#v", exits["web01"])
+	}
+	if exits["web02"].ExitCode != -1 || exits["web02"].Message != "boom" {
+		t.Fatalf("web02 exit = %#v", exits["web02"])
+	}
+}
+
 func TestDaemonExecRequestRejectsRawMultiHost(t *testing.T) {
 	daemon := NewDaemon("default", "", conf.Config{}, []string{"web01", "web02"}, nil)
 	var buf bytes.Buffer
@@ -13,6 +13,8 @@ import (
 	"os"
 	"strings"
 	"time"
+
+	"github.com/blacknon/lssh/internal/output"
 )
 
 type ExecOptions struct {
@@ -23,6 +25,9 @@ type ExecOptions struct {
 	Stdin   []byte
 	Stdout  io.Writer
 	Stderr  io.Writer
+
+	// Format is the output format. [text|ndjson|json], empty is text.
+	Format string
 }
 
 func Execute(opts ExecOptions) error {
@@ -53,6 +58,8 @@ func Execute(opts ExecOptions) error {
 		Hosts:   resolvedHosts,
 		Raw:     opts.Raw,
 		Stdin:   opts.Stdin,
+
+		Structured: output.IsStructured(opts.Format),
 	}); err != nil {
 		return err
 	}
@@ -70,6 +77,24 @@ func Execute(opts ExecOptions) error {
 		stderr = os.Stderr
 	}
 
+	// structured output writes the events of the hosts to stdout.
+	var structured *output.Structured
+	writers := map[string]io.Writer{}
+	if output.IsStructured(opts.Format) {
+		hosts := resolvedHosts
+		if len(hosts) == 0 {
+			hosts = session.Hosts
+		}
+		structured = output.NewStructured(stdout, opts.Format, strings.TrimSpace(opts.Command), hosts)
+	}
+	writer := func(host, stream string) io.Writer {
+		key := host + "\x00" + stream
+		if _, ok := writers[key]; !ok {
+			writers[key] = structured.Writer(host, stream)
+		}
+		return writers[key]
+	}
+
 	dec := json.NewDecoder(conn)
 	exitCode := 0
 	for {
@@ -81,22 +106,36 @@ func Execute(opts ExecOptions) error {
 			return err
 		}
 
-		switch event.Type {
-		case "stdout":
+		switch {
+		case structured != nil && (event.Type == "stdout" || event.Type == "stderr"):
+			_, _ = writer(event.Host, event.Type).Write(event.Data)
+		case event.Type == "stdout":
 			if _, err := stdout.Write(event.Data); err != nil {
 				return err
 			}
-		case "stderr":
+		case event.Type == "stderr":
 			if _, err := stderr.Write(event.Data); err != nil {
 				return err
 			}
-		case "error":
+		case event.Type == "exit" && structured != nil:
+			var runErr error
+			if event.Message != "" {
+				runErr = errors.New(event.Message)
+			}
+			structured.Finish(event.Host, event.ExitCode, time.Duration(event.DurationMS)*time.Millisecond, runErr)
+		case event.Type == "error":
 			return errors.New(event.Message)
-		case "done":
+		case event.Type == "done":
 			exitCode = event.ExitCode
 		}
 	}
 
+	if structured != nil {
+		if err := structured.Close(); err != nil {
+			return err
+		}
+	}
+
 	if exitCode != 0 {
 		return fmt.Errorf("lspipe command failed with exit code %d", exitCode)
 	}
@@ -17,6 +17,7 @@ import (
 	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
@@ -43,6 +44,13 @@ type sessionRunner interface {
 
 type sshConnectAdapter struct {
 	conn *sshlib.Connect
+
+	// host and config of the connection, for set_env/send_env.
+	host   string
+	config conf.ServerConfig
+
+	// warned is set once the refused set_env/send_env were reported.
+	warned atomic.Bool
 }
 
 func (a *sshConnectAdapter) CreateSession() (sessionRunner, error) {
@@ -50,7 +58,11 @@ func (a *sshConnectAdapter) CreateSession() (sessionRunner, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &sshSessionAdapter{session: session}, nil
+	env, notice := lssh.ExportRefusedRemoteEnv(a.host, a.config, lssh.RequestRemoteEnv(session, a.config.RemoteEnv()))
+	if notice != "" && a.warned.Swap(true) {
+		notice = ""
+	}
+	return &sshSessionAdapter{session: session, env: env, notice: notice}, nil
 }
 
 func (a *sshConnectAdapter) CheckClientAlive() error { return a.conn.CheckClientAlive() }
@@ -58,14 +70,22 @@ func (a *sshConnectAdapter) Close() error            { return a.conn.Close() }
 
 type sshSessionAdapter struct {
 	session *gossh.Session
+
+	// env is exported by the command, because the server refused it.
+	env map[string]string
+
+	// notice reports the set_env/send_env the server refused.
+	notice string
 }
 
 func (a *sshSessionAdapter) StdoutPipe() (io.Reader, error)     { return a.session.StdoutPipe() }
 func (a *sshSessionAdapter) StderrPipe() (io.Reader, error)     { return a.session.StderrPipe() }
 func (a *sshSessionAdapter) StdinPipe() (io.WriteCloser, error) { return a.session.StdinPipe() }
-func (a *sshSessionAdapter) Start(command string) error         { return a.session.Start(command) }
-func (a *sshSessionAdapter) Wait() error                        { return a.session.Wait() }
-func (a *sshSessionAdapter) Close() error                       { return a.session.Close() }
+func (a *sshSessionAdapter) Start(command string) error {
+	return a.session.Start(lssh.RemoteEnvCommand(a.env, command))
+}
+func (a *sshSessionAdapter) Wait() error  { return a.session.Wait() }
+func (a *sshSessionAdapter) Close() error { return a.session.Close() }
 
 type Daemon struct {
 	Name                  string
@@ -231,7 +251,30 @@ func (d *Daemon) execRequest(enc *json.Encoder, req Request) error {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			code, runErr := runCommand(host, req, sendEvent)
+			start := time.Now()
+			var outputBytes int64
+			hostEvent := func(event Event) {
+				if event.Type == "stdout" || event.Type == "stderr" {
+					atomic.AddInt64(&outputBytes, int64(len(event.Data)))
+				}
+				sendEvent(event)
+			}
+			code, runErr := runCommand(host, req, hostEvent)
+			auditCode := code
+			if runErr != nil {
+				auditCode = -1
+			}
+			lssh.AuditCommand(d.Config, host, req.Command, time.Since(start), atomic.LoadInt64(&outputBytes), auditCode, runErr)
+			if req.Structured {
+				exit := Event{Type: "exit", Host: host, ExitCode: code, DurationMS: time.Since(start).Milliseconds()}
+				if runErr != nil {
+					exit.ExitCode = -1
+					exit.Message = runErr.Error()
+				}
+				sendEvent(exit)
+				results <- code
+				return
+			}
 			if runErr != nil {
 				message := fmt.Sprintf("%s :: %v\n", host, runErr)
 				if singleTarget {
@@ -305,7 +348,7 @@ func (d *Daemon) runCommand(host string, req Request, sendEvent func(Event)) (in
 
 	var stdoutWriter io.Writer
 	var stderrWriter io.Writer
-	if req.Raw {
+	if req.Raw || req.Structured {
 		stdoutWriter = &eventWriter{host: host, stream: "stdout", raw: true, send: sendEvent}
 		stderrWriter = &eventWriter{host: host, stream: "stderr", raw: true, send: sendEvent}
 	} else {
@@ -333,7 +376,7 @@ func (d *Daemon) runCommand(host string, req Request, sendEvent func(Event)) (in
 func (d *Daemon) runConnectorCommand(host string, req Request, sendEvent func(Event)) (int, error) {
 	var stdoutWriter io.Writer
 	var stderrWriter io.Writer
-	if req.Raw {
+	if req.Raw || req.Structured {
 		stdoutWriter = &eventWriter{host: host, stream: "stdout", raw: true, send: sendEvent}
 		stderrWriter = &eventWriter{host: host, stream: "stderr", raw: true, send: sendEvent}
 	} else {
@@ -393,6 +436,10 @@ func runSessionCommand(conn sessionConn, command string, stdin []byte, stdout io
 		}
 	}
 
+	if adapter, ok := session.(*sshSessionAdapter); ok && adapter.notice != "" && stderr != nil {
+		fmt.Fprintf(stderr, "Warning: %s\n", adapter.notice)
+	}
+
 	if err := session.Start(command); err != nil {
 		if stdinPipe != nil {
 			_ = stdinPipe.Close()
@@ -486,7 +533,7 @@ func (d *Daemon) connect(host string) (sessionConn, error) {
 		d.setHealth(host, HostHealth{Connected: false, Error: err.Error()})
 		return nil, err
 	}
-	adapted := &sshConnectAdapter{conn: conn}
+	adapted := &sshConnectAdapter{conn: conn, host: host, config: run.Conf.Server[host]}
 
 	d.mu.Lock()
 	d.conns[host] = adapted
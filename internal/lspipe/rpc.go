@@ -28,6 +28,10 @@ type Request struct {
 	Hosts   []string `json:"hosts,omitempty"`
 	Raw     bool     `json:"raw,omitempty"`
 	Stdin   []byte   `json:"stdin,omitempty"`
+
+	// Structured sends the output of each host without the host prefix,
+	// followed by an "exit" event of the host.
+	Structured bool `json:"structured,omitempty"`
 }
 
 type Event struct {
@@ -37,6 +41,9 @@ type Event struct {
 	Data     []byte `json:"data,omitempty"`
 	Message  string `json:"message,omitempty"`
 	ExitCode int    `json:"exit_code,omitempty"`
+
+	// DurationMS is the duration of the command of an "exit" event.
+	DurationMS int64 `json:"duration_ms,omitempty"`
 }
 
 func listenerSpec(name string) (network, address string, err error) {
@@ -5,14 +5,17 @@
 package mux
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	conf "github.com/blacknon/lssh/internal/config"
 	"github.com/blacknon/lssh/internal/list"
+	sshcmd "github.com/blacknon/lssh/internal/ssh"
 	"github.com/blacknon/tvxterm"
 	"github.com/gdamore/tcell/v2"
 	"github.com/rivo/tview"
@@ -309,6 +312,7 @@ func (m *Manager) newSelectorPane(selector *list.TviewSelector) *pane {
 	container := tview.NewFlex().SetDirection(tview.FlexRow)
 	container.SetBorder(true)
 	container.SetTitle("select hosts")
+	container.SetTitleColor(parseMuxColor(m.conf.List.HeaderColor, tcell.ColorDefault))
 	container.AddItem(selector, 0, 1, true)
 
 	return &pane{
@@ -512,6 +516,10 @@ func (m *Manager) startPaneConnect(targetPage *page, p *pane) {
 			if m.currentPage == targetPage {
 				m.refreshMainPage()
 			}
+			if session.Warning != "" {
+				m.updateStatus(fmt.Sprintf("[yellow]Warning[-]: %s", session.Warning))
+				return
+			}
 			if len(session.Notices) > 0 {
 				m.updateStatus(fmt.Sprintf("[yellow]Information[-]: %s ignores %s in parallel mode", host, strings.Join(session.Notices, ", ")))
 				return
@@ -534,6 +542,7 @@ func (m *Manager) activatePane(targetPage *page, p *pane, session *RemoteSession
 	p.badgeColor = tcell.ColorDefault
 	p.title = m.paneTitle(p, "")
 	p.term.SetBorder(true).SetTitle(p.title)
+	auditEnd := m.auditPane(p.server)
 	p.term.SetTitleHandler(func(_ *tvxterm.View, title string) {
 		m.app.QueueUpdateDraw(func() {
 			p.title = m.paneTitle(p, title)
@@ -541,6 +550,7 @@ func (m *Manager) activatePane(targetPage *page, p *pane, session *RemoteSession
 		})
 	})
 	p.term.SetBackendExitHandler(func(_ *tvxterm.View, err error) {
+		auditEnd(err)
 		m.app.QueueUpdateDraw(func() {
 			if m.hold && len(m.command) > 0 {
 				p.exited = true
@@ -584,6 +594,31 @@ func (m *Manager) activatePane(targetPage *page, p *pane, session *RemoteSession
 	m.applyPaneStyle(p)
 }
 
+// auditPane writes the audit record of the start of the shell of a pane on
+// server, and returns the func writing the record of its end, or of its
+// command, from the exit error. The end of the output of the pane is a
+// normal exit.
+func (m *Manager) auditPane(server string) func(err error) {
+	var end func(err error)
+	if len(m.command) == 0 {
+		end = sshcmd.AuditShell(m.conf, server)
+	} else {
+		start := time.Now()
+		command := strings.Join(m.command, " ")
+		end = func(err error) {
+			code, err := sshcmd.CommandExitCode(err)
+			sshcmd.AuditCommand(m.conf, server, command, time.Since(start), 0, code, err)
+		}
+	}
+
+	return func(err error) {
+		if errors.Is(err, io.EOF) {
+			err = nil
+		}
+		end(err)
+	}
+}
+
 func (m *Manager) replacePaneWithError(p *pane, err error) {
 	if p == nil {
 		return
@@ -19,6 +19,7 @@ import (
 
 	"github.com/acarl005/stripansi"
 	sshlib "github.com/blacknon/go-sshlib"
+	"github.com/blacknon/lssh/internal/asciicast"
 	"github.com/blacknon/lssh/internal/common"
 	conf "github.com/blacknon/lssh/internal/config"
 	sshcmd "github.com/blacknon/lssh/internal/ssh"
@@ -36,6 +37,9 @@ type RemoteSession struct {
 	Server  string
 	Config  conf.ServerConfig
 	Notices []string
+	// Warning is shown in the status line when the pane is connected, such
+	// as the set_env/send_env variables the server refused.
+	Warning string
 
 	Connect  *sshlib.Connect
 	Terminal *sshlib.Terminal
@@ -76,7 +80,7 @@ func NewSessionFactory(cfg conf.Config, command []string, options SessionOptions
 			IsNotBashrc:                   options.IsNotBashrc,
 		}
 		run.CreateAuthMethodMap()
-		serverConf := cfg.Server[server]
+		serverConf := run.Conf.Server[server]
 		if options.IsBashrc {
 			serverConf.LocalRcUse = "yes"
 		}
@@ -192,6 +196,11 @@ type terminalLogWriter struct {
 	timestamp  bool
 	removeAnsi bool
 	pending    string
+
+	// cast is the recording of the pane in the asciicast format, instead of
+	// file.
+	cast        *asciicast.Recorder
+	recordInput bool
 }
 
 type resizeDeduper struct {
@@ -243,7 +252,45 @@ func newTerminalLogWriter(path string, timestamp, removeAnsi bool) (*terminalLog
 	}, nil
 }
 
+// newPaneLogWriter returns the log writer of a pane of cols x rows in the
+// format of logConf.
+func newPaneLogWriter(logConf conf.LogConfig, path string, cols, rows int) (*terminalLogWriter, error) {
+	if err := logConf.CheckFormat(); err != nil {
+		return nil, err
+	}
+	if !logConf.IsAsciicast() {
+		return newTerminalLogWriter(path, logConf.Timestamp, logConf.RemoveAnsiCode)
+	}
+
+	cast, err := asciicast.Create(path, asciicast.NewHeader(maxInt(cols, 1), maxInt(rows, 1)))
+	if err != nil {
+		return nil, err
+	}
+	return &terminalLogWriter{cast: cast, recordInput: logConf.RecordInput}, nil
+}
+
+// Resize records the size of the pane in the asciicast format. w may be nil.
+func (w *terminalLogWriter) Resize(cols, rows int) {
+	if w == nil || w.cast == nil {
+		return
+	}
+	w.cast.Resize(cols, rows)
+}
+
+// inputWriter returns input, recording the keyboard input of the pane if
+// it is enabled in the asciicast format. w may be nil.
+func (w *terminalLogWriter) inputWriter(input io.Writer) io.Writer {
+	if w == nil || w.cast == nil || !w.recordInput {
+		return input
+	}
+	return io.MultiWriter(input, w.cast.Input())
+}
+
 func (w *terminalLogWriter) Write(p []byte) (int, error) {
+	if w.cast != nil {
+		return w.cast.Output().Write(p)
+	}
+
 	w.mu.Lock()
 	defer w.mu.Unlock()
 
@@ -286,6 +333,10 @@ func (w *terminalLogWriter) Write(p []byte) (int, error) {
 }
 
 func (w *terminalLogWriter) Close() error {
+	if w.cast != nil {
+		return w.cast.Close()
+	}
+
 	w.mu.Lock()
 	defer w.mu.Unlock()
 
@@ -397,7 +448,11 @@ func newSSHRemoteSession(cfg conf.Config, server string, serverConf conf.ServerC
 	} else {
 		opts.Command = termenv.WrapShellExec(shellquote.Join(command...))
 	}
-	if len(command) == 0 && serverConf.LocalRcUse == "yes" {
+	switch {
+	case len(command) == 0 && serverConf.RemoteCommand != "":
+		opts.StartShell = false
+		opts.Command = serverConf.RemoteCommand
+	case len(command) == 0 && serverConf.LocalRcUse == "yes":
 		opts.StartShell = false
 		opts.Command = buildLocalRcCommand(
 			serverConf.LocalRcPath,
@@ -408,7 +463,7 @@ func newSSHRemoteSession(cfg conf.Config, server string, serverConf conf.ServerC
 		startupMarker = sshcmd.InteractiveLocalRCStartupMarker()
 	}
 
-	terminal, err := connect.OpenTerminal(opts)
+	terminal, envNotice, err := sshcmd.OpenRemoteEnvTerminal(connect, opts, server, serverConf)
 	if err != nil {
 		if connect.Client != nil {
 			_ = connect.Client.Close()
@@ -429,7 +484,7 @@ func newSSHRemoteSession(cfg conf.Config, server string, serverConf conf.ServerC
 			}
 			return nil, err
 		}
-		logWriter, err = newTerminalLogWriter(logPath, cfg.Log.Timestamp, cfg.Log.RemoveAnsiCode)
+		logWriter, err = newPaneLogWriter(cfg.Log, logPath, opts.Cols, opts.Rows)
 		if err != nil {
 			_ = outputWriter.CloseWithError(err)
 			_ = terminal.Close()
@@ -474,13 +529,15 @@ func newSSHRemoteSession(cfg conf.Config, server string, serverConf conf.ServerC
 		Server:   server,
 		Config:   serverConf,
 		Notices:  append([]string(nil), notices...),
+		Warning:  envNotice,
 		Connect:  connect,
 		Terminal: terminal,
 		LogPath:  logPath,
 		Backend: tvxterm.NewStreamBackend(
 			filterStartupMarkerReader(outputReader, startupMarker),
-			terminal.Stdin,
+			logWriter.inputWriter(terminal.Stdin),
 			dedupeResizeFunc(opts.Cols, opts.Rows, func(cols, rows int) error {
+				logWriter.Resize(cols, rows)
 				return terminal.Resize(cols, rows)
 			}),
 			closeFn,
@@ -515,7 +572,7 @@ func newCommandShellRemoteSession(cfg conf.Config, server string, serverConf con
 			_ = cmd.Wait()
 			return nil, err
 		}
-		logWriter, err = newTerminalLogWriter(logPath, cfg.Log.Timestamp, cfg.Log.RemoveAnsiCode)
+		logWriter, err = newPaneLogWriter(cfg.Log, logPath, maxInt(cols, 80), maxInt(rows, 24))
 		if err != nil {
 			_ = outputWriter.CloseWithError(err)
 			_ = tty.Close()
@@ -577,8 +634,9 @@ func newCommandShellRemoteSession(cfg conf.Config, server string, serverConf con
 		LogPath: logPath,
 		Backend: tvxterm.NewStreamBackend(
 			outputReader,
-			tty,
+			logWriter.inputWriter(tty),
 			dedupeResizeFunc(maxInt(cols, 80), maxInt(rows, 24), func(cols, rows int) error {
+				logWriter.Resize(cols, rows)
 				return pty.Setsize(tty, &pty.Winsize{
 					Cols: uint16(cols),
 					Rows: uint16(rows),
@@ -619,7 +677,7 @@ func newCommandExecRemoteSession(cfg conf.Config, server string, serverConf conf
 			_ = cmd.Wait()
 			return nil, err
 		}
-		logWriter, err = newTerminalLogWriter(logPath, cfg.Log.Timestamp, cfg.Log.RemoveAnsiCode)
+		logWriter, err = newPaneLogWriter(cfg.Log, logPath, 80, 24)
 		if err != nil {
 			_ = outputWriter.CloseWithError(err)
 			_ = cmd.Process.Kill()
@@ -783,7 +841,7 @@ func newProviderRuntimePTYRemoteSession(cfg conf.Config, server string, serverCo
 			}
 			return nil, err
 		}
-		logWriter, err = newTerminalLogWriter(logPath, cfg.Log.Timestamp, cfg.Log.RemoveAnsiCode)
+		logWriter, err = newPaneLogWriter(cfg.Log, logPath, maxInt(cols, 80), maxInt(rows, 24))
 		if err != nil {
 			_ = outputWriter.CloseWithError(err)
 			_ = tty.Close()
@@ -864,8 +922,9 @@ func newProviderRuntimePTYRemoteSession(cfg conf.Config, server string, serverCo
 		LogPath: logPath,
 		Backend: tvxterm.NewStreamBackend(
 			outputReader,
-			tty,
+			logWriter.inputWriter(tty),
 			dedupeResizeFunc(maxInt(cols, 80), maxInt(rows, 24), func(cols, rows int) error {
+				logWriter.Resize(cols, rows)
 				return pty.Setsize(tty, &pty.Winsize{
 					Cols: uint16(cols),
 					Rows: uint16(rows),
@@ -890,7 +949,7 @@ func newReaderBackedRemoteSession(cfg conf.Config, server string, serverConf con
 			}
 			return nil, err
 		}
-		logWriter, err = newTerminalLogWriter(logPath, cfg.Log.Timestamp, cfg.Log.RemoveAnsiCode)
+		logWriter, err = newPaneLogWriter(cfg.Log, logPath, 80, 24)
 		if err != nil {
 			_ = outputWriter.CloseWithError(err)
 			if closeResource != nil {
@@ -962,8 +1021,11 @@ func newReaderBackedRemoteSession(cfg conf.Config, server string, serverConf con
 		LogPath: logPath,
 		Backend: tvxterm.NewStreamBackend(
 			outputReader,
-			input,
-			func(cols, rows int) error { return nil },
+			logWriter.inputWriter(input),
+			func(cols, rows int) error {
+				logWriter.Resize(cols, rows)
+				return nil
+			},
 			closeFn,
 		),
 	}, nil
@@ -1034,7 +1096,7 @@ func buildMuxLogPath(logConf conf.LogConfig, server string) (string, error) {
 		return "", err
 	}
 
-	file := time.Now().Format("20060102_150405") + "_" + name + ".log"
+	file := time.Now().Format("20060102_150405") + "_" + name + logConf.FileExt()
 	return dir + "/" + file, nil
 }
 
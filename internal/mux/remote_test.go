@@ -2,8 +2,13 @@ package mux
 
 import (
 	"bytes"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/blacknon/lssh/internal/asciicast"
+	conf "github.com/blacknon/lssh/internal/config"
 )
 
 func TestBuildLocalRcCommandExportsTERM(t *testing.T) {
@@ -92,3 +97,69 @@ func TestDedupeResizeFunc(t *testing.T) {
 		t.Fatalf("resize calls[0] = %v, want [100 30]", calls[0])
 	}
 }
+
+func TestPaneLogWriterRecordsAsciicast(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pane.cast")
+	logConf := conf.LogConfig{Enable: true, Format: conf.LogFormatAsciicast, RecordInput: true}
+	w, err := newPaneLogWriter(logConf, path, 100, 30)
+	if err != nil {
+		t.Fatalf("newPaneLogWriter() error = %v", err)
+	}
+
+	var input bytes.Buffer
+	_, _ = w.Write([]byte("$ "))
+	_, _ = w.inputWriter(&input).Write([]byte("ls\r"))
+	w.Resize(120, 40)
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	if input.String() != "ls\r" {
+		t.Fatalf("input = %q", input.String())
+	}
+	header, events, err := asciicast.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if header.Width != 100 || header.Height != 30 {
+		t.Fatalf("header = %+v", header)
+	}
+	got := []string{}
+	for _, event := range events {
+		got = append(got, event.Type+":"+event.Data)
+	}
+	if strings.Join(got, ",") != "o:$ ,i:ls\r,r:120x40" {
+		t.Fatalf("events = %q", got)
+	}
+}
+
+func TestPaneLogWriterText(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pane.log")
+	w, err := newPaneLogWriter(conf.LogConfig{Enable: true, RecordInput: true}, path, 80, 24)
+	if err != nil {
+		t.Fatalf("newPaneLogWriter() error = %v", err)
+	}
+
+	var input bytes.Buffer
+	if got := w.inputWriter(&input); got != &input {
+		t.Fatalf("inputWriter() of a text log = %T, want the input", got)
+	}
+	w.Resize(120, 40)
+	_, _ = w.Write([]byte("hello\n"))
+	_ = w.Close()
+
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != "hello\n" {
+		t.Fatalf("log = %q, %v", data, err)
+	}
+
+	if _, err := newPaneLogWriter(conf.LogConfig{Format: "ttyrec"}, path, 80, 24); err == nil {
+		t.Fatal("newPaneLogWriter() with an unknown format error = nil")
+	}
+
+	var nilWriter *terminalLogWriter
+	nilWriter.Resize(80, 24)
+	if got := nilWriter.inputWriter(&input); got != &input {
+		t.Fatalf("inputWriter() of nil = %T, want the input", got)
+	}
+}
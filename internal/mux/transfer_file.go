@@ -6,12 +6,12 @@ package mux
 
 import (
 	"fmt"
-	"io"
 	"os"
 	"path"
 	"path/filepath"
 	"strings"
 
+	"github.com/blacknon/lssh/internal/audit"
 	sshcmd "github.com/blacknon/lssh/internal/ssh"
 	"github.com/pkg/sftp"
 )
@@ -69,7 +69,7 @@ func (w *transferWizard) openTransferSFTPForPane(p *pane) (*transferSFTPConn, er
 	}, nil
 }
 
-func copyRemotePathToLocal(client *sftp.Client, sourcePath, targetPath string) error {
+func copyRemotePathToLocal(client *sftp.Client, sourcePath, targetPath string, transfer *audit.Transfer) error {
 	sourceInfo, err := client.Stat(sourcePath)
 	if err != nil {
 		return err
@@ -77,12 +77,12 @@ func copyRemotePathToLocal(client *sftp.Client, sourcePath, targetPath string) e
 
 	targetPath = resolveLocalTargetPath(targetPath, sourcePath, sourceInfo.IsDir())
 	if sourceInfo.IsDir() {
-		return copyRemoteDirToLocal(client, sourcePath, targetPath)
+		return copyRemoteDirToLocal(client, sourcePath, targetPath, transfer)
 	}
-	return copyRemoteFileToLocal(client, sourcePath, targetPath)
+	return copyRemoteFileToLocal(client, sourcePath, targetPath, transfer)
 }
 
-func copyLocalPathToRemote(client *sftp.Client, sourcePath, targetPath string) error {
+func copyLocalPathToRemote(client *sftp.Client, sourcePath, targetPath string, transfer *audit.Transfer) error {
 	sourceInfo, err := os.Lstat(sourcePath)
 	if err != nil {
 		return err
@@ -93,12 +93,12 @@ func copyLocalPathToRemote(client *sftp.Client, sourcePath, targetPath string) e
 		return err
 	}
 	if sourceInfo.IsDir() {
-		return copyLocalDirToRemote(client, sourcePath, targetPath)
+		return copyLocalDirToRemote(client, sourcePath, targetPath, transfer)
 	}
-	return copyLocalFileToRemote(client, sourcePath, targetPath)
+	return copyLocalFileToRemote(client, sourcePath, targetPath, transfer)
 }
 
-func copyRemotePathToRemote(srcClient, dstClient *sftp.Client, sourcePath, targetPath string) error {
+func copyRemotePathToRemote(srcClient, dstClient *sftp.Client, sourcePath, targetPath string, transfer *audit.Transfer) error {
 	sourceInfo, err := srcClient.Stat(sourcePath)
 	if err != nil {
 		return err
@@ -109,9 +109,9 @@ func copyRemotePathToRemote(srcClient, dstClient *sftp.Client, sourcePath, targe
 		return err
 	}
 	if sourceInfo.IsDir() {
-		return copyRemoteDirToRemote(srcClient, dstClient, sourcePath, targetPath)
+		return copyRemoteDirToRemote(srcClient, dstClient, sourcePath, targetPath, transfer)
 	}
-	return copyRemoteFileToRemote(srcClient, dstClient, sourcePath, targetPath)
+	return copyRemoteFileToRemote(srcClient, dstClient, sourcePath, targetPath, transfer)
 }
 
 func resolveLocalTargetPath(targetPath, sourcePath string, sourceIsDir bool) string {
@@ -152,7 +152,7 @@ func resolveRemoteTargetPath(client *sftp.Client, targetPath, baseName string, s
 	return resolved, nil
 }
 
-func copyRemoteFileToLocal(client *sftp.Client, remotePath, localPath string) error {
+func copyRemoteFileToLocal(client *sftp.Client, remotePath, localPath string, transfer *audit.Transfer) error {
 	if err := os.MkdirAll(filepath.Dir(localPath), 0755); err != nil {
 		return err
 	}
@@ -169,11 +169,11 @@ func copyRemoteFileToLocal(client *sftp.Client, remotePath, localPath string) er
 	}
 	defer dst.Close()
 
-	_, err = io.Copy(dst, src)
+	_, err = transfer.Copy(dst, src)
 	return err
 }
 
-func copyRemoteDirToLocal(client *sftp.Client, remotePath, localPath string) error {
+func copyRemoteDirToLocal(client *sftp.Client, remotePath, localPath string, transfer *audit.Transfer) error {
 	base := remotePath
 	walker := client.Walk(remotePath)
 	for walker.Step() {
@@ -192,14 +192,14 @@ func copyRemoteDirToLocal(client *sftp.Client, remotePath, localPath string) err
 			}
 			continue
 		}
-		if err := copyRemoteFileToLocal(client, current, target); err != nil {
+		if err := copyRemoteFileToLocal(client, current, target, transfer); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-func copyLocalFileToRemote(client *sftp.Client, localPath, remotePath string) error {
+func copyLocalFileToRemote(client *sftp.Client, localPath, remotePath string, transfer *audit.Transfer) error {
 	if err := client.MkdirAll(path.Dir(remotePath)); err != nil {
 		return err
 	}
@@ -216,11 +216,11 @@ func copyLocalFileToRemote(client *sftp.Client, localPath, remotePath string) er
 	}
 	defer dst.Close()
 
-	_, err = io.Copy(dst, src)
+	_, err = transfer.Copy(dst, src)
 	return err
 }
 
-func copyLocalDirToRemote(client *sftp.Client, localPath, remotePath string) error {
+func copyLocalDirToRemote(client *sftp.Client, localPath, remotePath string, transfer *audit.Transfer) error {
 	base := localPath
 	return filepath.Walk(localPath, func(current string, info os.FileInfo, walkErr error) error {
 		if walkErr != nil {
@@ -234,11 +234,11 @@ func copyLocalDirToRemote(client *sftp.Client, localPath, remotePath string) err
 		if info.IsDir() {
 			return client.MkdirAll(target)
 		}
-		return copyLocalFileToRemote(client, current, target)
+		return copyLocalFileToRemote(client, current, target, transfer)
 	})
 }
 
-func copyRemoteFileToRemote(srcClient, dstClient *sftp.Client, sourcePath, targetPath string) error {
+func copyRemoteFileToRemote(srcClient, dstClient *sftp.Client, sourcePath, targetPath string, transfer *audit.Transfer) error {
 	if err := dstClient.MkdirAll(path.Dir(targetPath)); err != nil {
 		return err
 	}
@@ -255,11 +255,11 @@ func copyRemoteFileToRemote(srcClient, dstClient *sftp.Client, sourcePath, targe
 	}
 	defer dst.Close()
 
-	_, err = io.Copy(dst, src)
+	_, err = transfer.Copy(dst, src)
 	return err
 }
 
-func copyRemoteDirToRemote(srcClient, dstClient *sftp.Client, sourcePath, targetPath string) error {
+func copyRemoteDirToRemote(srcClient, dstClient *sftp.Client, sourcePath, targetPath string, transfer *audit.Transfer) error {
 	base := sourcePath
 	walker := srcClient.Walk(sourcePath)
 	for walker.Step() {
@@ -278,7 +278,7 @@ func copyRemoteDirToRemote(srcClient, dstClient *sftp.Client, sourcePath, target
 			}
 			continue
 		}
-		if err := copyRemoteFileToRemote(srcClient, dstClient, current, target); err != nil {
+		if err := copyRemoteFileToRemote(srcClient, dstClient, current, target, transfer); err != nil {
 			return err
 		}
 	}
@@ -8,6 +8,9 @@ import (
 	"fmt"
 	"sort"
 	"strings"
+
+	"github.com/blacknon/lssh/internal/audit"
+	sshcmd "github.com/blacknon/lssh/internal/ssh"
 )
 
 type transferJob struct {
@@ -19,6 +22,10 @@ type transferJob struct {
 	DoneItems  int
 	TotalItems int
 	Err        string
+
+	// auditLog is the audit log transfer of the job, nil if the audit log
+	// is disabled. It is set on creation, and is not changed after.
+	auditLog *audit.Transfer
 }
 
 func (j *transferJob) statusText() string {
@@ -60,7 +67,7 @@ func (w *transferWizard) startTransfer() {
 	w.manager.updateStatus("[green]transfer started[-]")
 }
 
-func (m *Manager) newTransferJob(mode, source, target string, total int) *transferJob {
+func (m *Manager) newTransferJob(mode, source, target string, total int, auditLog *audit.Transfer) *transferJob {
 	m.transferMu.Lock()
 	defer m.transferMu.Unlock()
 
@@ -75,6 +82,7 @@ func (m *Manager) newTransferJob(mode, source, target string, total int) *transf
 		Target:     target,
 		Status:     "running",
 		TotalItems: total,
+		auditLog:   auditLog,
 	}
 	m.transfers = append([]*transferJob{job}, m.transfers...)
 	return job
@@ -146,14 +154,20 @@ func (w *transferWizard) launchTransferJobs() error {
 
 	switch w.activeMode {
 	case transferModeGet:
+		host, destination := w.pane.server, w.getTargetPath
+		if w.getTargetServer != "" {
+			host, destination = w.getTargetServer, w.getTargetServer+":"+w.getTargetPath
+		}
 		for _, source := range sources {
-			job := w.manager.newTransferJob("get", source, w.currentTargetLabel()+":"+w.getTargetPath, 1)
+			auditLog := sshcmd.StartAuditTransfer(w.manager.conf, host, []string{w.pane.server + ":" + source}, destination)
+			job := w.manager.newTransferJob("get", source, w.currentTargetLabel()+":"+w.getTargetPath, 1, auditLog)
 			go w.runGetJob(job, source)
 		}
 		return nil
 	case transferModePut:
 		for _, source := range sources {
-			job := w.manager.newTransferJob("put", source, w.pane.server+":"+w.putTargetPath, 1)
+			auditLog := sshcmd.StartAuditTransfer(w.manager.conf, w.pane.server, []string{source}, w.pane.server+":"+w.putTargetPath)
+			job := w.manager.newTransferJob("put", source, w.pane.server+":"+w.putTargetPath, 1, auditLog)
 			go w.runPutJob(job, source)
 		}
 		return nil
@@ -163,7 +177,8 @@ func (w *transferWizard) launchTransferJobs() error {
 		}
 		for _, server := range w.copyTargets {
 			for _, source := range sources {
-				job := w.manager.newTransferJob("copy", source, server+":"+w.copyTargetPath, 1)
+				auditLog := sshcmd.StartAuditTransfer(w.manager.conf, server, []string{w.pane.server + ":" + source}, server+":"+w.copyTargetPath)
+				job := w.manager.newTransferJob("copy", source, server+":"+w.copyTargetPath, 1, auditLog)
 				go w.runCopyJob(job, source, server)
 			}
 		}
@@ -182,7 +197,7 @@ func (w *transferWizard) runGetJob(job *transferJob, source string) {
 	defer srcConn.Close()
 
 	if w.getTargetServer == "" {
-		err = copyRemotePathToLocal(srcConn.client, source, w.getTargetPath)
+		err = copyRemotePathToLocal(srcConn.client, source, w.getTargetPath, job.auditLog)
 		w.finishTransferJob(job, err)
 		return
 	}
@@ -200,7 +215,7 @@ func (w *transferWizard) runGetJob(job *transferJob, source string) {
 	}
 	defer dstConn.Close()
 
-	err = copyRemotePathToRemote(srcConn.client, dstConn.client, source, w.getTargetPath)
+	err = copyRemotePathToRemote(srcConn.client, dstConn.client, source, w.getTargetPath, job.auditLog)
 	w.finishTransferJob(job, err)
 }
 
@@ -212,7 +227,7 @@ func (w *transferWizard) runPutJob(job *transferJob, source string) {
 	}
 	defer dstConn.Close()
 
-	err = copyLocalPathToRemote(dstConn.client, source, w.putTargetPath)
+	err = copyLocalPathToRemote(dstConn.client, source, w.putTargetPath, job.auditLog)
 	w.finishTransferJob(job, err)
 }
 
@@ -237,11 +252,12 @@ func (w *transferWizard) runCopyJob(job *transferJob, source, server string) {
 	}
 	defer dstConn.Close()
 
-	err = copyRemotePathToRemote(srcConn.client, dstConn.client, source, w.copyTargetPath)
+	err = copyRemotePathToRemote(srcConn.client, dstConn.client, source, w.copyTargetPath, job.auditLog)
 	w.finishTransferJob(job, err)
 }
 
 func (w *transferWizard) finishTransferJob(job *transferJob, err error) {
+	job.auditLog.Done(err)
 	w.manager.updateTransferJob(job, func(j *transferJob) {
 		j.DoneItems = j.TotalItems
 		if err != nil {
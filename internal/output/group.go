@@ -0,0 +1,214 @@
+// Copyright (c) 2026 Blacknon. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package output
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// HostOutput is the whole output of a command on a host.
+type HostOutput struct {
+	Host   string
+	Output string
+
+	// ExitCode is the exit status of the command, or -1 if there is none.
+	ExitCode int
+}
+
+// OutputGroup is the hosts with the same output and exit code.
+type OutputGroup struct {
+	Hosts    []string
+	Output   string
+	ExitCode int
+}
+
+// GroupOutputs groups the hosts by the hash of their output and their exit
+// code. The groups are sorted by size, the largest first, and then by the
+// order of their first host in outputs.
+func GroupOutputs(outputs []HostOutput) []OutputGroup {
+	index := map[string]int{}
+	groups := []OutputGroup{}
+	for _, o := range outputs {
+		key := fmt.Sprintf("%x:%d", sha256.Sum256([]byte(o.Output)), o.ExitCode)
+		i, ok := index[key]
+		if !ok {
+			i = len(groups)
+			index[key] = i
+			groups = append(groups, OutputGroup{Output: o.Output, ExitCode: o.ExitCode})
+		}
+		groups[i].Hosts = append(groups[i].Hosts, o.Host)
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		return len(groups[i].Hosts) > len(groups[j].Hosts)
+	})
+	return groups
+}
+
+// groupRule is the rule around the host list of a group.
+const groupRule = "----------------"
+
+// groupHeader returns the host list line of group.
+func groupHeader(group OutputGroup) string {
+	header := fmt.Sprintf("%s (%d)", strings.Join(group.Hosts, ","), len(group.Hosts))
+	switch {
+	case group.ExitCode < 0:
+		header += " [error]"
+	case group.ExitCode > 0:
+		header += fmt.Sprintf(" [exit %d]", group.ExitCode)
+	}
+	return header
+}
+
+// PrintGroups writes each group once under the list of its hosts, in the
+// style of dshbak. With diffFromMajority, only the hosts of the largest
+// group are listed, and the other groups are written as a diff from it.
+func PrintGroups(w io.Writer, groups []OutputGroup, diffFromMajority bool) {
+	if len(groups) == 0 {
+		return
+	}
+
+	if !diffFromMajority {
+		for _, group := range groups {
+			fmt.Fprintf(w, "%s\n%s\n%s\n", groupRule, groupHeader(group), groupRule)
+			writeGroupOutput(w, group.Output)
+		}
+		return
+	}
+
+	majority := groups[0]
+	fmt.Fprintf(w, "%s\nmajority: %s\n%s\n", groupRule, groupHeader(majority), groupRule)
+	if len(groups) == 1 {
+		fmt.Fprintln(w, "(all hosts have the same output)")
+		return
+	}
+	base := splitOutputLines(majority.Output)
+	for _, group := range groups[1:] {
+		fmt.Fprintf(w, "%s\n%s\n%s\n", groupRule, groupHeader(group), groupRule)
+		for _, line := range diffLines(base, splitOutputLines(group.Output)) {
+			fmt.Fprintln(w, line)
+		}
+	}
+}
+
+func writeGroupOutput(w io.Writer, output string) {
+	if output == "" {
+		fmt.Fprintln(w, "(no output)")
+		return
+	}
+	fmt.Fprint(w, output)
+	if !strings.HasSuffix(output, "\n") {
+		fmt.Fprintln(w)
+	}
+}
+
+func splitOutputLines(output string) []string {
+	output = strings.TrimSuffix(output, "\n")
+	if output == "" {
+		return nil
+	}
+	return strings.Split(output, "\n")
+}
+
+// maxDiffCells limits the size of the table of diffLines. Larger outputs are
+// written as a whole replacement.
+const maxDiffCells = 4 * 1024 * 1024
+
+// diffLines returns the lines of a diff from a to b: the removed lines with
+// `-`, the added lines with `+` and the common lines with a space.
+func diffLines(a, b []string) []string {
+	if (len(a)+1)*(len(b)+1) > maxDiffCells {
+		lines := make([]string, 0, len(a)+len(b))
+		for _, line := range a {
+			lines = append(lines, "- "+line)
+		}
+		for _, line := range b {
+			lines = append(lines, "+ "+line)
+		}
+		return lines
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:]
+	// and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	lines := []string{}
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			lines = append(lines, "  "+a[i])
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			lines = append(lines, "- "+a[i])
+			i++
+		default:
+			lines = append(lines, "+ "+b[j])
+			j++
+		}
+	}
+	return lines
+}
+
+// HostBuffers buffers the output of each host, for GroupOutputs.
+type HostBuffers struct {
+	mu   sync.Mutex
+	bufs map[string]*bytes.Buffer
+}
+
+func NewHostBuffers() *HostBuffers {
+	return &HostBuffers{bufs: map[string]*bytes.Buffer{}}
+}
+
+// Writer returns a writer to the buffer of host. The writers of a host share
+// the buffer, so stdout and stderr keep their order.
+func (b *HostBuffers) Writer(host string) io.Writer {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.bufs[host]; !ok {
+		b.bufs[host] = &bytes.Buffer{}
+	}
+	return &hostBufferWriter{b: b, host: host}
+}
+
+// Output returns the buffered output of host.
+func (b *HostBuffers) Output(host string) string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if buf, ok := b.bufs[host]; ok {
+		return buf.String()
+	}
+	return ""
+}
+
+type hostBufferWriter struct {
+	b    *HostBuffers
+	host string
+}
+
+func (w *hostBufferWriter) Write(p []byte) (int, error) {
+	w.b.mu.Lock()
+	defer w.b.mu.Unlock()
+	return w.b.bufs[w.host].Write(p)
+}
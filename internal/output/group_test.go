@@ -0,0 +1,90 @@
+package output
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGroupOutputs(t *testing.T) {
+	groups := GroupOutputs([]HostOutput{
+		{Host: "web01", Output: "a\n"},
+		{Host: "web02", Output: "b\n"},
+		{Host: "web03", Output: "b\n"},
+		{Host: "web04", Output: "b\n", ExitCode: 1},
+		{Host: "web05", Output: "a\n"},
+		{Host: "web06", Output: "b\n"},
+	})
+
+	want := []OutputGroup{
+		{Hosts: []string{"web02", "web03", "web06"}, Output: "b\n"},
+		{Hosts: []string{"web01", "web05"}, Output: "a\n"},
+		{Hosts: []string{"web04"}, Output: "b\n", ExitCode: 1},
+	}
+	if !reflect.DeepEqual(groups, want) {
+		t.Fatalf("GroupOutputs() = %#v, want %#v", groups, want)
+	}
+}
+
+func TestPrintGroups(t *testing.T) {
+	groups := []OutputGroup{
+		{Hosts: []string{"web01", "web02"}, Output: "ok\n"},
+		{Hosts: []string{"web03"}, ExitCode: -1},
+	}
+
+	var buf bytes.Buffer
+	PrintGroups(&buf, groups, false)
+	want := strings.Join([]string{
+		groupRule, "web01,web02 (2)", groupRule, "ok",
+		groupRule, "web03 (1) [error]", groupRule, "(no output)",
+	}, "\n") + "\n"
+	if buf.String() != want {
+		t.Fatalf("PrintGroups() =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestPrintGroupsDiffFromMajority(t *testing.T) {
+	groups := []OutputGroup{
+		{Hosts: []string{"web01", "web02"}, Output: "a\nb\nc\n"},
+		{Hosts: []string{"web03"}, Output: "a\nx\nc\n", ExitCode: 2},
+	}
+
+	var buf bytes.Buffer
+	PrintGroups(&buf, groups, true)
+	want := strings.Join([]string{
+		groupRule, "majority: web01,web02 (2)", groupRule,
+		groupRule, "web03 (1) [exit 2]", groupRule,
+		"  a", "- b", "+ x", "  c",
+	}, "\n") + "\n"
+	if buf.String() != want {
+		t.Fatalf("PrintGroups() =\n%s\nwant\n%s", buf.String(), want)
+	}
+
+	buf.Reset()
+	PrintGroups(&buf, groups[:1], true)
+	if !strings.Contains(buf.String(), "all hosts have the same output") {
+		t.Fatalf("PrintGroups() = %s", buf.String())
+	}
+}
+
+func TestDiffLines(t *testing.T) {
+	got := diffLines([]string{"a", "b"}, []string{"b", "c"})
+	want := []string{"- a", "  b", "+ c"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("diffLines() = %q, want %q", got, want)
+	}
+}
+
+func TestHostBuffers(t *testing.T) {
+	b := NewHostBuffers()
+	stdout, stderr := b.Writer("web01"), b.Writer("web01")
+	_, _ = stdout.Write([]byte("out\n"))
+	_, _ = stderr.Write([]byte("err\n"))
+	if got := b.Output("web01"); got != "out\nerr\n" {
+		t.Fatalf("Output() = %q", got)
+	}
+	if got := b.Output("web02"); got != "" {
+		t.Fatalf("Output(web02) = %q", got)
+	}
+}
@@ -0,0 +1,298 @@
+// Copyright (c) 2026 Blacknon. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// output formats of command execution (`--output-format`).
+const (
+	// FormatText is the prefixed lines of Output.
+	FormatText = "text"
+
+	// FormatNDJSON writes a LineEvent per line, and an ExitEvent when a host
+	// finishes.
+	FormatNDJSON = "ndjson"
+
+	// FormatJSON buffers the output of each host, and writes a single
+	// StructuredDocument at the end.
+	FormatJSON = "json"
+)
+
+// ValidateFormat checks the output format. Empty is text.
+func ValidateFormat(format string) error {
+	switch format {
+	case "", FormatText, FormatNDJSON, FormatJSON:
+		return nil
+	}
+	return fmt.Errorf("unknown output format %q. [text|ndjson|json]", format)
+}
+
+// IsStructured returns that format is ndjson or json.
+func IsStructured(format string) bool {
+	return format == FormatNDJSON || format == FormatJSON
+}
+
+// LineEvent is a line of output of a host.
+type LineEvent struct {
+	Host   string    `json:"host"`
+	Stream string    `json:"stream"`
+	TS     time.Time `json:"ts"`
+	Line   string    `json:"line"`
+}
+
+// ExitEvent is the last record of a host. ExitCode is -1 if the command did
+// not return an exit status.
+type ExitEvent struct {
+	Host       string `json:"host"`
+	ExitCode   int    `json:"exit_code"`
+	DurationMS int64  `json:"duration_ms"`
+	Error      string `json:"error,omitempty"`
+}
+
+// StructuredHost is the output and the exit of a host in FormatJSON.
+type StructuredHost struct {
+	ExitEvent
+	Stdout []string `json:"stdout"`
+	Stderr []string `json:"stderr"`
+}
+
+// StructuredDocument is the FormatJSON document.
+type StructuredDocument struct {
+	Command string           `json:"command,omitempty"`
+	Hosts   []StructuredHost `json:"hosts"`
+}
+
+// Structured writes the output of the hosts of a command as NDJSON or JSON
+// to w.
+type Structured struct {
+	// Settle is how long Finish waits for the writers of the host to be idle,
+	// for the output still being copied when the command has returned.
+	Settle time.Duration
+
+	format  string
+	command string
+
+	mu        sync.Mutex
+	w         io.Writer
+	order     []string
+	hosts     map[string]*StructuredHost
+	lastWrite map[string]time.Time
+	writers   map[string][]*structuredWriter
+	closed    bool
+}
+
+// NewStructured returns a Structured writing the output of command in format
+// to w. The hosts of the JSON document are in the order of hosts, followed
+// by the others in the order of their first output.
+func NewStructured(w io.Writer, format, command string, hosts []string) *Structured {
+	return &Structured{
+		format:    format,
+		command:   command,
+		w:         w,
+		order:     append([]string(nil), hosts...),
+		hosts:     map[string]*StructuredHost{},
+		lastWrite: map[string]time.Time{},
+		writers:   map[string][]*structuredWriter{},
+	}
+}
+
+// host returns the entry of name. s.mu must be held.
+func (s *Structured) host(name string) *StructuredHost {
+	h, ok := s.hosts[name]
+	if !ok {
+		h = &StructuredHost{ExitEvent: ExitEvent{Host: name, ExitCode: -1}, Stdout: []string{}, Stderr: []string{}}
+		s.hosts[name] = h
+		if !containsString(s.order, name) {
+			s.order = append(s.order, name)
+		}
+	}
+	return h
+}
+
+// Writer returns a writer of the stream ("stdout" or "stderr") of host. The
+// lines are recorded as they are completed, and the rest on Finish.
+func (s *Structured) Writer(host, stream string) io.Writer {
+	w := &structuredWriter{s: s, host: host, stream: stream}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.host(host)
+	s.writers[host] = append(s.writers[host], w)
+	return w
+}
+
+// line records a line of output.
+func (s *Structured) line(host, stream, line string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastWrite[host] = time.Now()
+	if s.closed {
+		return
+	}
+
+	if s.format == FormatNDJSON {
+		s.encode(LineEvent{Host: host, Stream: stream, TS: time.Now(), Line: line})
+		return
+	}
+
+	h := s.host(host)
+	if stream == "stderr" {
+		h.Stderr = append(h.Stderr, line)
+	} else {
+		h.Stdout = append(h.Stdout, line)
+	}
+}
+
+// encode writes v as a line of JSON. s.mu must be held.
+func (s *Structured) encode(v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	_, _ = s.w.Write(append(data, '\n'))
+}
+
+// settle waits until the writers of host have been idle for s.Settle, at
+// most 20 times s.Settle.
+func (s *Structured) settle(host string) {
+	if s.Settle <= 0 {
+		return
+	}
+	deadline := time.Now().Add(20 * s.Settle)
+	for time.Now().Before(deadline) {
+		s.mu.Lock()
+		idle := time.Since(s.lastWrite[host])
+		s.mu.Unlock()
+		if idle >= s.Settle {
+			return
+		}
+		time.Sleep(s.Settle - idle)
+	}
+}
+
+// Finish flushes the writers of host and records its exit. exitCode is -1
+// if the command did not return an exit status.
+func (s *Structured) Finish(host string, exitCode int, duration time.Duration, err error) {
+	s.settle(host)
+
+	s.mu.Lock()
+	writers := s.writers[host]
+	s.mu.Unlock()
+	for _, w := range writers {
+		w.flush()
+	}
+
+	event := ExitEvent{Host: host, ExitCode: exitCode, DurationMS: duration.Milliseconds()}
+	if err != nil {
+		event.Error = err.Error()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	if s.format == FormatNDJSON {
+		s.encode(event)
+		return
+	}
+	s.host(host).ExitEvent = event
+}
+
+// Close flushes all writers, and writes the JSON document in FormatJSON.
+// The output written after Close is dropped.
+func (s *Structured) Close() error {
+	s.mu.Lock()
+	writers := []*structuredWriter{}
+	for _, ws := range s.writers {
+		writers = append(writers, ws...)
+	}
+	s.mu.Unlock()
+	for _, w := range writers {
+		w.flush()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	if s.format != FormatJSON {
+		return nil
+	}
+
+	doc := StructuredDocument{Command: s.command, Hosts: []StructuredHost{}}
+	for _, name := range s.order {
+		if h, ok := s.hosts[name]; ok {
+			doc.Hosts = append(doc.Hosts, *h)
+		}
+	}
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = s.w.Write(append(data, '\n'))
+	return err
+}
+
+// structuredWriter splits the output of a stream of a host into lines.
+type structuredWriter struct {
+	s      *Structured
+	host   string
+	stream string
+
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (w *structuredWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.buf.Write(p)
+	for {
+		i := bytes.IndexByte(w.buf.Bytes(), '\n')
+		if i < 0 {
+			break
+		}
+		line := string(w.buf.Next(i + 1))
+		w.s.line(w.host, w.stream, trimLine(line))
+	}
+	return len(p), nil
+}
+
+// flush records the rest of the output without a newline.
+func (w *structuredWriter) flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.buf.Len() == 0 {
+		return
+	}
+	line := w.buf.String()
+	w.buf.Reset()
+	w.s.line(w.host, w.stream, trimLine(line))
+}
+
+// trimLine removes the line ending, including the carriage return of a pty.
+func trimLine(line string) string {
+	return strings.TrimRight(line, "\r\n")
+}
+
+func containsString(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
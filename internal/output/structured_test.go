@@ -0,0 +1,77 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateFormat(t *testing.T) {
+	for _, format := range []string{"", "text", "ndjson", "json"} {
+		if err := ValidateFormat(format); err != nil {
+			t.Fatalf("ValidateFormat(%q) error = %v", format, err)
+		}
+	}
+	if err := ValidateFormat("yaml"); err == nil {
+		t.Fatal("ValidateFormat(yaml) error = nil")
+	}
+}
+
+func TestStructuredNDJSON(t *testing.T) {
+	var buf bytes.Buffer
+	s := NewStructured(&buf, FormatNDJSON, "uptime", []string{"web01"})
+	stdout := s.Writer("web01", "stdout")
+	_, _ = stdout.Write([]byte("line1\r\nli"))
+	_, _ = stdout.Write([]byte("ne2\nrest"))
+	s.Finish("web01", 2, 1500*time.Millisecond, nil)
+	_ = s.Close()
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("lines = %q", lines)
+	}
+	for i, want := range []string{"line1", "line2", "rest"} {
+		var event LineEvent
+		if err := json.Unmarshal([]byte(lines[i]), &event); err != nil {
+			t.Fatalf("Unmarshal(%q) error = %v", lines[i], err)
+		}
+		if event.Host != "web01" || event.Stream != "stdout" || event.Line != want || event.TS.IsZero() {
+			t.Fatalf("line %d = %+v", i, event)
+		}
+	}
+	if lines[3] != `{"host":"web01","exit_code":2,"duration_ms":1500}` {
+		t.Fatalf("exit = %s", lines[3])
+	}
+}
+
+func TestStructuredJSON(t *testing.T) {
+	var buf bytes.Buffer
+	s := NewStructured(&buf, FormatJSON, "uptime", []string{"web01", "web02"})
+	_, _ = s.Writer("web02", "stderr").Write([]byte("denied\n"))
+	s.Finish("web02", -1, time.Second, errors.New("connection lost"))
+	_, _ = s.Writer("web01", "stdout").Write([]byte("up\n"))
+	s.Finish("web01", 0, time.Second, nil)
+	if buf.Len() != 0 {
+		t.Fatalf("output before Close = %s", buf.String())
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	var doc StructuredDocument
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if doc.Command != "uptime" || len(doc.Hosts) != 2 {
+		t.Fatalf("doc = %+v", doc)
+	}
+	if h := doc.Hosts[0]; h.Host != "web01" || h.ExitCode != 0 || len(h.Stdout) != 1 || h.Stdout[0] != "up" {
+		t.Fatalf("web01 = %+v", h)
+	}
+	if h := doc.Hosts[1]; h.Host != "web02" || h.ExitCode != -1 || h.Error != "connection lost" || h.Stderr[0] != "denied" {
+		t.Fatalf("web02 = %+v", h)
+	}
+}
@@ -21,7 +21,9 @@ import (
 	"time"
 
 	"github.com/blacknon/go-sshlib"
+	"github.com/blacknon/lssh/internal/audit"
 	"github.com/blacknon/lssh/internal/output"
+	sshcmd "github.com/blacknon/lssh/internal/ssh"
 	lsync "github.com/blacknon/lssh/internal/sync"
 	pkgsftp "github.com/pkg/sftp"
 	"github.com/vbauerster/mpb/v8"
@@ -58,7 +60,7 @@ func checkBuildInCommand(cmd string) (isBuildInCmd bool) {
 
 	case
 		"%history",
-		"%out", "%outlist", "%outexec",
+		"%out", "%outlist", "%outexec", "%group",
 		"%get", "%put", "%sync", "%diff",
 		"%status", "%reconnect",
 		"%save",
@@ -97,6 +99,10 @@ func normalizeLocalCommand(cmd string) string {
 	return strings.TrimPrefix(cmd, "+")
 }
 
+// structuredSettle is how long the structured output waits for the output of
+// a host after its command returned, as sshlib copies it in the background.
+const structuredSettle = 50 * time.Millisecond
+
 type progressReader struct {
 	io.Reader
 }
@@ -150,6 +156,11 @@ func (s *shell) run(pline pipeLine, in *io.PipeReader, out *io.PipeWriter, ch ch
 		s.buildin_out(num, out, ch)
 		return
 
+	// %group [num] [--diff-from-majority]
+	case "%group":
+		s.buildin_group(pline.Args, out, ch)
+		return
+
 	// %outexec [num]
 	case "%outexec":
 		s.buildin_outexec(pline, in, out, ch, kill)
@@ -1221,6 +1232,17 @@ func (s *shell) executeRemotePipeLine(pline pipeLine, in *io.PipeReader, out *io
 		}
 	}
 
+	// structured output of the command line (--output-format)
+	var structured *output.Structured
+	if stdout == os.Stdout && s.Run != nil && output.IsStructured(s.Run.OutputFormat) {
+		names := make([]string, 0, len(connects))
+		for _, c := range connects {
+			names = append(names, c.Name)
+		}
+		structured = output.NewStructured(os.Stdout, s.Run.OutputFormat, command, names)
+		structured.Settle = structuredSettle
+	}
+
 	for _, c := range connects {
 		if c == nil {
 			continue
@@ -1228,12 +1250,20 @@ func (s *shell) executeRemotePipeLine(pline pipeLine, in *io.PipeReader, out *io
 
 		// Build output writer for this connection
 		var ow io.Writer
+		var ew io.Writer = os.Stderr
 		ow = stdout
 		if ow == os.Stdout {
 			// create Output Writer
 			c.Output.Count = s.Count
-			w := c.Output.NewWriter()
-			defer w.CloseWithError(io.ErrClosedPipe)
+			var w io.Writer
+			if structured != nil {
+				w = structured.Writer(c.Name, "stdout")
+				ew = structured.Writer(c.Name, "stderr")
+			} else {
+				pw := c.Output.NewWriter()
+				defer pw.CloseWithError(io.ErrClosedPipe)
+				w = pw
+			}
 
 			// create pShellHistory Writer
 			hw := s.NewHistoryWriter(c.Output.Server, c.Output)
@@ -1242,9 +1272,16 @@ func (s *shell) executeRemotePipeLine(pline pipeLine, in *io.PipeReader, out *io
 			ow = io.MultiWriter(w, hw)
 		}
 
+		// count the output for the audit log
+		var counter *audit.Counter
+		if s.Run != nil && audit.Enabled(s.Run.Conf) {
+			counter = &audit.Counter{}
+			ow, ew = counter.Writer(ow), counter.Writer(ew)
+		}
+
 		if c.Connector {
 			runCount++
-			go func(conn *sConnect, outputWriter io.Writer, commandArgs []string) {
+			go func(conn *sConnect, outputWriter, errorWriter io.Writer, commandArgs []string) {
 				defer func() {
 					exit <- true
 					if stdout == os.Stdout {
@@ -1256,11 +1293,24 @@ func (s *shell) executeRemotePipeLine(pline pipeLine, in *io.PipeReader, out *io
 					_, _ = io.WriteString(outputWriter, "connector execution requires a command\n")
 					return
 				}
-				if _, err := s.Run.RunConnectorCommand(conn.Name, append([]string(nil), commandArgs...), nil, outputWriter, outputWriter); err != nil {
+				if structured == nil {
+					errorWriter = outputWriter
+				}
+				start := time.Now()
+				code, err := s.Run.RunConnectorCommand(conn.Name, append([]string(nil), commandArgs...), nil, outputWriter, errorWriter)
+				if err != nil {
+					code = -1
+				}
+				s.auditCommand(conn.Name, command, start, counter, code, err)
+				if structured != nil {
+					structured.Finish(conn.Name, code, time.Since(start), err)
+					return
+				}
+				if err != nil {
 					_, _ = fmt.Fprintf(outputWriter, "%s\n", err)
 					return
 				}
-			}(c, ow, args)
+			}(c, ow, ew, args)
 			continue
 		}
 		if c.Connect == nil {
@@ -1273,7 +1323,7 @@ func (s *shell) executeRemotePipeLine(pline pipeLine, in *io.PipeReader, out *io
 		clone := *c.Connect
 		clone.Stdin = stdinR
 		clone.Stdout = ow
-		clone.Stderr = os.Stderr
+		clone.Stderr = ew
 		clone.TTY = stdin == os.Stdin && stdout == os.Stdout
 
 		if clone.IsControlClient() {
@@ -1297,8 +1347,14 @@ func (s *shell) executeRemotePipeLine(pline pipeLine, in *io.PipeReader, out *io
 		}
 
 		runCount++
+		name := c.Name
 		go func(conn sshlib.Connect, r *io.PipeReader) {
-			conn.Command(command)
+			start := time.Now()
+			code, err := sshcmd.CommandExitCode(conn.Command(command))
+			s.auditCommand(name, command, start, counter, code, err)
+			if structured != nil {
+				structured.Finish(name, code, time.Since(start), err)
+			}
 			r.CloseWithError(io.ErrClosedPipe)
 			exit <- true
 			if stdout == os.Stdout {
@@ -1330,6 +1386,10 @@ func (s *shell) executeRemotePipeLine(pline pipeLine, in *io.PipeReader, out *io
 	// wait time (0.050 sec)
 	time.Sleep(500 * time.Millisecond)
 
+	if structured != nil {
+		_ = structured.Close()
+	}
+
 	// send exit
 	ch <- true
 
@@ -1352,6 +1412,15 @@ func (s *shell) executeRemotePipeLine(pline pipeLine, in *io.PipeReader, out *io
 
 // executePipeLineLocal is exec command in local machine.
 // TODO(blacknon): 利用中のShellでの実行+functionや環境変数、aliasの引き継ぎを行えるように実装
+// auditCommand writes the audit record of command on server, with the
+// output bytes of counter. Nothing is written without counter.
+func (s *shell) auditCommand(server, command string, start time.Time, counter *audit.Counter, code int, err error) {
+	if counter == nil {
+		return
+	}
+	sshcmd.AuditCommand(s.Run.Conf, server, command, time.Since(start), counter.Count(), code, err)
+}
+
 func (s *shell) executeLocalPipeLine(pline pipeLine, in *io.PipeReader, out *io.PipeWriter, ch chan<- bool, kill chan bool, envrionment []string) (err error) {
 	// set stdin/stdout
 	stdin := setInput(in)
@@ -61,6 +61,7 @@ func TestCheckBuildInCommand(t *testing.T) {
 	}{
 		{name: "sync", in: "%sync", want: true},
 		{name: "diff", in: "%diff", want: true},
+		{name: "group", in: "%group", want: true},
 		{name: "get", in: "%get", want: true},
 		{name: "status", in: "%status", want: true},
 		{name: "reconnect", in: "%reconnect", want: true},
@@ -162,6 +162,7 @@ func (s *shell) Completer(t prompt.Document) []prompt.Suggest {
 				{Text: "%history", Description: "show history"},
 				{Text: "%out", Description: "%out [num], show history result."},
 				{Text: "%outlist", Description: "%outlist, show history result list."},
+				{Text: "%group", Description: "%group [num] [--diff-from-majority], show history result grouped by identical output."},
 				{Text: "%outexec", Description: "%outexec <-n num> command..., exec local command with output result. result is in env variable."},
 				{Text: "%get", Description: "%get remote local, copy files from remote hosts to localhost."},
 				{Text: "%put", Description: "%put local... remote, copy local files to remote hosts."},
@@ -213,6 +214,15 @@ func (s *shell) getBuildInCommandSuggest(command string, t prompt.Document, targ
 	case "%out":
 		return s.getHistorySuggest()
 
+	case "%group":
+		if contains([]string{"-"}, char) {
+			return []prompt.Suggest{
+				{Text: "--diff-from-majority", Description: "show only the outputs that differ from the most common output"},
+				{Text: "--help", Description: "help message"},
+			}
+		}
+		return s.getHistorySuggest()
+
 	case "%outexec":
 		switch {
 		case contains([]string{"-"}, char):
@@ -0,0 +1,96 @@
+// Copyright (c) 2026 Blacknon. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package pshell
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/blacknon/lssh/internal/output"
+)
+
+// parseGroupArgs parses the arguments of `%group [num] [--diff-from-majority]`.
+// num is the latest history without it.
+func parseGroupArgs(args []string, latest int) (num int, diff bool, err error) {
+	num = latest
+	for _, arg := range args {
+		switch arg {
+		case "--diff-from-majority", "-d":
+			diff = true
+		default:
+			num, err = strconv.Atoi(arg)
+			if err != nil {
+				return 0, false, fmt.Errorf("invalid history number: %s", arg)
+			}
+		}
+	}
+	return num, diff, nil
+}
+
+// historyOutputs returns the outputs of histories for output.GroupOutputs,
+// in the order of the host names.
+func historyOutputs(histories map[string]*shellHistory) []output.HostOutput {
+	names := make([]string, 0, len(histories))
+	for name := range histories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	outputs := make([]output.HostOutput, 0, len(names))
+	for _, name := range names {
+		// the output of the pty ends the lines with "\r\n".
+		result := strings.ReplaceAll(histories[name].Result, "\r\n", "\n")
+		outputs = append(outputs, output.HostOutput{Host: name, Output: result})
+	}
+	return outputs
+}
+
+// buildin_group prints the history result like %out, with each distinct
+// output once under the hosts that produced it.
+//
+//	%group [num] [--diff-from-majority]
+func (s *shell) buildin_group(args []string, out *io.PipeWriter, ch chan<- bool) {
+	stdout := setOutput(out)
+	defer func() {
+		// close out
+		if _, ok := stdout.(*io.PipeWriter); ok {
+			out.CloseWithError(io.ErrClosedPipe)
+		}
+
+		// send exit
+		ch <- true
+	}()
+
+	if len(args) > 1 && (args[1] == "--help" || args[1] == "-h") {
+		_, _ = io.WriteString(stdout, "%group [num] [--diff-from-majority]\n")
+		return
+	}
+
+	num, diff, err := parseGroupArgs(args[1:], s.Count-1)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		return
+	}
+
+	s.HistoryMu.Lock()
+	outputs := historyOutputs(s.History[num])
+	command := ""
+	for _, h := range s.History[num] {
+		command = h.Command
+		break
+	}
+	s.HistoryMu.Unlock()
+	if len(outputs) == 0 {
+		fmt.Fprintf(os.Stderr, "Error: history %d has no output\n", num)
+		return
+	}
+
+	fmt.Fprintf(os.Stderr, "[History:%s ]\n", command)
+	output.PrintGroups(stdout, output.GroupOutputs(outputs), diff)
+}
@@ -0,0 +1,31 @@
+package pshell
+
+import (
+	"testing"
+)
+
+func TestParseGroupArgs(t *testing.T) {
+	num, diff, err := parseGroupArgs(nil, 4)
+	if err != nil || num != 4 || diff {
+		t.Fatalf("parseGroupArgs(nil) = %d, %v, %v", num, diff, err)
+	}
+
+	num, diff, err = parseGroupArgs([]string{"--diff-from-majority", "2"}, 4)
+	if err != nil || num != 2 || !diff {
+		t.Fatalf("parseGroupArgs(diff, 2) = %d, %v, %v", num, diff, err)
+	}
+
+	if _, _, err := parseGroupArgs([]string{"two"}, 4); err == nil {
+		t.Fatal("parseGroupArgs(two) error = nil")
+	}
+}
+
+func TestHistoryOutputs(t *testing.T) {
+	outputs := historyOutputs(map[string]*shellHistory{
+		"web02": {Result: "ok\r\n"},
+		"web01": {Result: "ok\n"},
+	})
+	if len(outputs) != 2 || outputs[0].Host != "web01" || outputs[1].Output != "ok\n" {
+		t.Fatalf("historyOutputs() = %#v", outputs)
+	}
+}
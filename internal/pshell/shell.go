@@ -400,7 +400,7 @@ func (s *shell) exit(exitCode int, message string) {
 	}
 
 	execLocalCommand(s.Config.PostCmd)
-	os.Exit(exitCode)
+	conf.Exit(exitCode)
 }
 
 // runCmdLocal exec command local machine.
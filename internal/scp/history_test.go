@@ -0,0 +1,22 @@
+package scp
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestScpSucceeded(t *testing.T) {
+	cp := &Scp{From: ScpInfo{Server: []string{"src"}}, To: ScpInfo{Server: []string{"web01", "web02", "web03"}}}
+	cp.fail("web01", nil)
+	cp.fail("web02", errors.New("dial tcp: connection refused"))
+
+	if got := cp.Succeeded(); strings.Join(got, ",") != "src,web01,web03" {
+		t.Fatalf("Succeeded() = %v, want [src web01 web03]", got)
+	}
+
+	cp.failTransfers(errors.New("src connect error"))
+	if got := cp.Succeeded(); len(got) != 0 {
+		t.Fatalf("Succeeded() after failTransfers = %v, want none", got)
+	}
+}
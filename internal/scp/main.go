@@ -16,6 +16,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/blacknon/lssh/internal/audit"
 	"github.com/blacknon/lssh/internal/common"
 	conf "github.com/blacknon/lssh/internal/config"
 	"github.com/blacknon/lssh/internal/output"
@@ -55,6 +56,15 @@ type Scp struct {
 	// progress bar
 	Progress   *mpb.Progress
 	ProgressWG *sync.WaitGroup
+
+	// audit log transfer of each host. It is created by Start, and is not
+	// changed after.
+	transfers map[string]*audit.Transfer
+
+	// failed is the hosts that could not be connected or where a copy
+	// failed.
+	failed   map[string]bool
+	failedMu sync.Mutex
 }
 
 func (cp *Scp) logWriter() io.Writer {
@@ -143,6 +153,8 @@ func (cp *Scp) Start() {
 		mpb.PopCompletedMode(),
 	)
 
+	cp.startTransfers()
+
 	switch {
 	// remote to remote
 	case cp.From.IsRemote && cp.To.IsRemote:
@@ -156,6 +168,86 @@ func (cp *Scp) Start() {
 	case !cp.From.IsRemote && cp.To.IsRemote:
 		cp.push()
 	}
+
+	for _, transfer := range cp.transfers {
+		transfer.Done(nil)
+	}
+}
+
+// startTransfers creates the audit log transfer of each host. A remote to
+// remote copy is recorded on the destination hosts.
+func (cp *Scp) startTransfers() {
+	cp.transfers = map[string]*audit.Transfer{}
+	if cp.DryRun || !audit.Enabled(cp.Config) {
+		return
+	}
+
+	remotePaths := func(server string, paths []string) []string {
+		result := make([]string, 0, len(paths))
+		for _, path := range paths {
+			result = append(result, server+":"+path)
+		}
+		return result
+	}
+
+	switch {
+	case cp.From.IsRemote && cp.To.IsRemote:
+		source := remotePaths(cp.From.Server[0], cp.From.Path)
+		for _, server := range cp.To.Server {
+			cp.transfers[server] = sshl.StartAuditTransfer(cp.Config, server, source, server+":"+cp.To.Path[0])
+		}
+	case cp.From.IsRemote:
+		for _, server := range cp.From.Server {
+			cp.transfers[server] = sshl.StartAuditTransfer(cp.Config, server, remotePaths(server, cp.From.Path), cp.To.Path[0])
+		}
+	default:
+		for _, server := range cp.To.Server {
+			cp.transfers[server] = sshl.StartAuditTransfer(cp.Config, server, cp.From.Path, server+":"+cp.To.Path[0])
+		}
+	}
+}
+
+// transfer returns the audit log transfer of server, nil if there is none.
+func (cp *Scp) transfer(server string) *audit.Transfer {
+	return cp.transfers[server]
+}
+
+// fail records err of a copy with server to its audit log transfer and
+// marks server as failed. A nil err is ignored.
+func (cp *Scp) fail(server string, err error) {
+	if err == nil {
+		return
+	}
+	cp.transfer(server).Add(0, err)
+
+	cp.failedMu.Lock()
+	defer cp.failedMu.Unlock()
+	if cp.failed == nil {
+		cp.failed = map[string]bool{}
+	}
+	cp.failed[server] = true
+}
+
+// failTransfers records err of a copy with all hosts.
+func (cp *Scp) failTransfers(err error) {
+	for _, server := range append(append([]string(nil), cp.From.Server...), cp.To.Server...) {
+		cp.fail(server, err)
+	}
+}
+
+// Succeeded returns the hosts that were connected and where no copy failed,
+// after Start.
+func (cp *Scp) Succeeded() []string {
+	cp.failedMu.Lock()
+	defer cp.failedMu.Unlock()
+
+	result := []string{}
+	for _, server := range append(append([]string(nil), cp.From.Server...), cp.To.Server...) {
+		if !cp.failed[server] {
+			result = append(result, server)
+		}
+	}
+	return result
 }
 
 func (cp *Scp) progressEnabled() bool { return true }
@@ -179,6 +271,7 @@ func (cp *Scp) push() {
 		info, err := os.Lstat(p)
 		if err != nil {
 			cp.logf("scp: failed to stat source path %q: %v\n", p, err)
+			cp.failTransfers(err)
 			return
 		}
 
@@ -307,6 +400,7 @@ func (cp *Scp) pushPath(ftp *sftp.Client, ow io.Writer, output *output.Output, r
 		lf, err := os.Open(p)
 		if err != nil {
 			fmt.Fprintf(ow, "%s\n", err)
+			cp.fail(output.Server, err)
 			return err
 		}
 		defer lf.Close()
@@ -341,6 +435,9 @@ func (cp *Scp) pushFile(lf io.Reader, ftp *sftp.Client, output *output.Output, s
 		return nil
 	}
 
+	transfer := cp.transfer(output.Server)
+	defer func() { cp.fail(output.Server, err) }()
+
 	// get output writer
 	ow := output.NewWriter()
 	defer ow.Close()
@@ -391,13 +488,13 @@ func (cp *Scp) pushFile(lf io.Reader, ftp *sftp.Client, output *output.Output, s
 			reader = proxy
 		}
 
-		_, err = io.Copy(rf, reader)
+		_, err = transfer.Copy(rf, reader)
 		if err != nil {
 			fmt.Fprintf(ow, "%s\n", err)
 			return
 		}
 	} else {
-		_, err = io.Copy(rf, lf)
+		_, err = transfer.Copy(rf, lf)
 		if err != nil {
 			fmt.Fprintf(ow, "%s\n", err)
 			return
@@ -419,6 +516,9 @@ func (cp *Scp) viaPush() {
 		cp.closeScpClients(fclient)
 		cp.closeScpClients(tclient)
 		cp.logf("There is no host to connect to\n")
+		if len(fclient) == 0 {
+			cp.failTransfers(fmt.Errorf("%s connect error", from))
+		}
 		return
 	}
 	defer cp.closeScpClients(fclient)
@@ -490,6 +590,7 @@ func (cp *Scp) viaPushPath(paths []string, fclient *ScpConnect, tclients []*ScpC
 						tow := tclient.Output.NewWriter()
 						fmt.Fprintf(tow, "Error: %s\n", err)
 						tow.Close()
+						cp.fail(tclient.Server, err)
 						continue
 					}
 
@@ -607,6 +708,7 @@ func (cp *Scp) pullPath(client *ScpConnect) {
 		} else {
 			if err := os.MkdirAll(destinationRoot, 0755); err != nil {
 				fmt.Fprintf(ow, "Error: %s\n", err)
+				cp.fail(client.Server, err)
 				return
 			}
 		}
@@ -636,11 +738,13 @@ func (cp *Scp) pullPath(client *ScpConnect) {
 			wclient.Output.Create(wclient.Server)
 			wow := wclient.Output.NewWriter()
 			defer wow.Close()
+			transfer := cp.transfer(wclient.Server)
 			for task := range tasks {
 				// open remote file
 				rf, err := wclient.Connect.Open(task.remotePath)
 				if err != nil {
 					fmt.Fprintf(wow, "Error: %s\n", err)
+					cp.fail(wclient.Server, err)
 					continue
 				}
 
@@ -648,6 +752,7 @@ func (cp *Scp) pullPath(client *ScpConnect) {
 				if err != nil {
 					rf.Close()
 					fmt.Fprintf(wow, "Error: %s\n", err)
+					cp.fail(wclient.Server, err)
 					continue
 				}
 
@@ -665,6 +770,7 @@ func (cp *Scp) pullPath(client *ScpConnect) {
 				if err != nil {
 					rf.Close()
 					fmt.Fprintf(wow, "Error: %s\n", err)
+					cp.fail(wclient.Server, err)
 					continue
 				}
 
@@ -674,6 +780,7 @@ func (cp *Scp) pullPath(client *ScpConnect) {
 					rf.Close()
 					lf.Close()
 					fmt.Fprintf(wow, "Error: %s\n", err)
+					cp.fail(wclient.Server, err)
 					continue
 				}
 
@@ -686,19 +793,21 @@ func (cp *Scp) pullPath(client *ScpConnect) {
 						reader = proxy
 					}
 
-					_, err = io.Copy(lf, reader)
+					_, err = transfer.Copy(lf, reader)
 					if err != nil {
 						rf.Close()
 						lf.Close()
 						fmt.Fprintf(wow, "Error: %s\n", err)
+						cp.fail(wclient.Server, err)
 						continue
 					}
 				} else {
-					_, err = io.Copy(lf, rf)
+					_, err = transfer.Copy(lf, rf)
 					if err != nil {
 						rf.Close()
 						lf.Close()
 						fmt.Fprintf(wow, "Error: %s\n", err)
+						cp.fail(wclient.Server, err)
 						continue
 					}
 				}
@@ -809,11 +918,13 @@ func (cp *Scp) createScpConnect(server string, serverList []string) (result *Scp
 	client, closer, err := cp.Run.CreateSFTPClient(server)
 	if err != nil {
 		cp.logf("%s connect error: %s\n", server, err)
+		cp.fail(server, err)
 		return nil
 	}
 	if client == nil {
 		_ = closeCloser(closer)
 		cp.logf("%s connect error: sftp client is not available\n", server)
+		cp.fail(server, errors.New("sftp client is not available"))
 		return nil
 	}
 
@@ -110,16 +110,24 @@ func (r *RunSftp) copy(args []string) {
 			target.Output = newWorkerOutput(target.Output, serverName)
 		}
 
+		sourcePaths := make([]string, 0, len(sources))
+		for _, src := range sources {
+			sourcePaths = append(sourcePaths, src.Host+":"+src.Path)
+		}
+
 		targetIsDir := len(sources) > 1
 		exit := make(chan bool, len(targets))
 		for _, target := range targets {
 			targetClient := target
+			r.startTransfer(targetClient, sourcePaths, targetClient.Server+":"+targetPath)
 			go func() {
 				for _, src := range sources {
 					if err := r.copyRemoteToRemote(src, targetClient, targetPath, targetIsDir); err != nil {
 						fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+						targetClient.Transfer.Add(0, err)
 					}
 				}
+				targetClient.Transfer.Done(nil)
 				exit <- true
 			}()
 		}
@@ -388,7 +396,7 @@ func (r *RunSftp) copyRemoteFileToRemote(srcClient *SftpConnect, dst *TargetConn
 		size = stat.Size()
 	}
 
-	rd := io.TeeReader(srcFile, dstFile)
+	rd := dst.Transfer.Reader(io.TeeReader(srcFile, dstFile))
 	r.ProgressWG.Add(1)
 	dst.Output.ProgressPrinter(size, rd, fmt.Sprintf("%s:%s -> %s:%s", srcClient.Output.Server, sourcePath, dst.Output.Server, targetPath))
 
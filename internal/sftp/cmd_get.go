@@ -184,6 +184,8 @@ func (r *RunSftp) get(args []string) {
 				}
 			}
 
+			r.startTransfer(client, remotePaths(server, client.Path), targetDestinationPath)
+
 			// pullDataをホスト台数分だけ並列実行する.
 			go func() {
 				tasks := make(chan pullTask)
@@ -200,6 +202,7 @@ func (r *RunSftp) get(args []string) {
 							worker := &TargetConnectMap{
 								SftpConnect: *extraClient,
 								Path:        client.Path,
+								Transfer:    client.Transfer,
 							}
 							worker.Output = newWorkerOutput(client.Output, server)
 							workers = append(workers, worker)
@@ -251,6 +254,7 @@ func (r *RunSftp) get(args []string) {
 						if err != context.Canceled {
 							fmt.Fprintf(os.Stderr, "Error: %s\n", err)
 						}
+						client.Transfer.Done(err)
 						exit <- true
 						return
 					}
@@ -261,6 +265,7 @@ func (r *RunSftp) get(args []string) {
 					<-workerExit
 				}
 
+				client.Transfer.Done(nil)
 				exit <- true
 			}()
 		}
@@ -407,6 +412,8 @@ func (r *RunSftp) pullData(ctx context.Context, client *TargetConnectMap, task p
 		return nil
 	}
 
+	defer func() { client.Transfer.Add(0, err) }()
+
 	err = os.MkdirAll(filepath.Dir(task.localPath), 0755)
 	if err != nil {
 		fmt.Fprintf(ow, "Error: %s\n", err)
@@ -427,7 +434,7 @@ func (r *RunSftp) pullData(ctx context.Context, client *TargetConnectMap, task p
 	}
 	defer localfile.Close()
 
-	rd := io.TeeReader(remotefile, localfile)
+	rd := client.Transfer.Reader(io.TeeReader(remotefile, localfile))
 
 	r.ProgressWG.Add(1)
 	client.Output.ProgressPrinter(task.size, rd, fmt.Sprintf("%s:%s -> local:%s", client.Output.Server, task.remotePath, task.localPath))
@@ -158,11 +158,17 @@ func (r *RunSftp) put(args []string) {
 			return o
 		}
 
+		sourcePaths := make([]string, 0, len(pathset))
+		for _, p := range pathset {
+			sourcePaths = append(sourcePaths, p.Root)
+		}
+
 		// parallel push data
 		exit := make(chan bool, len(targetmap))
 		for s, c := range targetmap {
 			server := s
 			client := c
+			r.startTransfer(client, sourcePaths, strings.Join(remotePaths(server, client.Path), " "))
 			go func() {
 				type pushTask struct {
 					root      string
@@ -184,6 +190,7 @@ func (r *RunSftp) put(args []string) {
 							worker := &TargetConnectMap{
 								SftpConnect: *extraClient,
 								Path:        client.Path,
+								Transfer:    client.Transfer,
 							}
 							worker.Output = newWorkerOutput(client.Output, server)
 							workers = append(workers, worker)
@@ -230,6 +237,7 @@ func (r *RunSftp) put(args []string) {
 								<-workerExit
 							}
 
+							client.Transfer.Done(ctx.Err())
 							exit <- true
 							return
 						case tasks <- pushTask{root: p.Root, rootIsDir: p.RootIsDir, path: path}:
@@ -243,6 +251,7 @@ func (r *RunSftp) put(args []string) {
 				}
 
 				// exit
+				client.Transfer.Done(nil)
 				exit <- true
 			}()
 		}
@@ -267,6 +276,8 @@ func (r *RunSftp) put(args []string) {
 }
 
 func (r *RunSftp) pushData(client *TargetConnectMap, isMultiple bool, root string, rootIsDir bool, path string) (err error) {
+	defer func() { client.Transfer.Add(0, err) }()
+
 	if err = ensureTargetConnectAvailable(client); err != nil {
 		return
 	}
@@ -395,7 +406,7 @@ func (r *RunSftp) pushFile(client *TargetConnectMap, localfile io.Reader, source
 	}
 
 	// set tee reader
-	rd := io.TeeReader(localfile, remotefile)
+	rd := client.Transfer.Reader(io.TeeReader(localfile, remotefile))
 
 	// copy to data
 	r.ProgressWG.Add(1)
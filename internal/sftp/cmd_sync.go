@@ -8,7 +8,9 @@ import (
 	syncpkg "sync"
 	"time"
 
+	"github.com/blacknon/lssh/internal/audit"
 	"github.com/blacknon/lssh/internal/common"
+	sshl "github.com/blacknon/lssh/internal/ssh"
 	lsync "github.com/blacknon/lssh/internal/sync"
 	"github.com/urfave/cli"
 	"github.com/vbauerster/mpb/v8"
@@ -163,7 +165,10 @@ func (r *RunSftp) syncLocalToRemote(sourceSpecs []lsync.PathSpec, targetSpec lsy
 
 	for server, target := range targets {
 		target := target
-		if err := r.syncBidirectionalLoop(daemon, daemonInterval, []string{server}, func(ctx context.Context, _ string) error {
+		if err := r.syncBidirectionalLoop(daemon, daemonInterval, []string{server}, func(ctx context.Context, _ string) (err error) {
+			transfer := r.startSyncTransfer(server, sourcePaths, server+":"+target.Path[0])
+			defer func() { transfer.Done(err) }()
+
 			target.Output.Create(server)
 			target.Output.Progress = r.Progress
 			target.Output.ProgressWG = r.ProgressWG
@@ -180,6 +185,7 @@ func (r *RunSftp) syncLocalToRemote(sourceSpecs []lsync.PathSpec, targetSpec lsy
 				Permission:  permission,
 				ParallelNum: parallelNum,
 				Output:      target.Output,
+				Transfer:    transfer,
 				SourceLabel: "local",
 				TargetLabel: server,
 			})
@@ -207,7 +213,10 @@ func (r *RunSftp) syncRemoteToLocal(sourceSpecs []lsync.PathSpec, targetSpec lsy
 
 	for server, source := range sources {
 		source := source
-		if err := r.syncBidirectionalLoop(daemon, daemonInterval, []string{server}, func(ctx context.Context, _ string) error {
+		if err := r.syncBidirectionalLoop(daemon, daemonInterval, []string{server}, func(ctx context.Context, _ string) (err error) {
+			transfer := r.startSyncTransfer(server, remotePaths(server, source.Path), targetSpec.Path)
+			defer func() { transfer.Done(err) }()
+
 			source.Output.Create(server)
 			source.Output.Progress = r.Progress
 			source.Output.ProgressWG = r.ProgressWG
@@ -229,6 +238,7 @@ func (r *RunSftp) syncRemoteToLocal(sourceSpecs []lsync.PathSpec, targetSpec lsy
 				Permission:  permission,
 				ParallelNum: parallelNum,
 				Output:      source.Output,
+				Transfer:    transfer,
 				SourceLabel: server,
 				TargetLabel: "local",
 			})
@@ -273,7 +283,10 @@ func (r *RunSftp) syncRemoteToRemote(sourceSpecs []lsync.PathSpec, targetSpec ls
 	sourceFS := lsync.NewRemoteFS(source.Connect, source.Pwd)
 	for server, target := range targets {
 		target := target
-		if err := r.syncLoop(daemon, daemonInterval, func(ctx context.Context) error {
+		if err := r.syncLoop(daemon, daemonInterval, func(ctx context.Context) (err error) {
+			transfer := r.startSyncTransfer(server, remotePaths(source.Output.Server, source.Path), server+":"+target.Path[0])
+			defer func() { transfer.Done(err) }()
+
 			target.Output.Create(server)
 			target.Output.Progress = r.Progress
 			target.Output.ProgressWG = r.ProgressWG
@@ -290,6 +303,7 @@ func (r *RunSftp) syncRemoteToRemote(sourceSpecs []lsync.PathSpec, targetSpec ls
 				Permission:  permission,
 				ParallelNum: parallelNum,
 				Output:      target.Output,
+				Transfer:    transfer,
 				SourceLabel: source.Output.Server,
 				TargetLabel: server,
 			})
@@ -314,7 +328,10 @@ func (r *RunSftp) syncBidirectionalLocalToRemote(sourceSpec lsync.PathSpec, targ
 
 	for server, target := range targets {
 		target := target
-		if err := r.syncLoop(daemon, daemonInterval, func(ctx context.Context) error {
+		if err := r.syncLoop(daemon, daemonInterval, func(ctx context.Context) (err error) {
+			transfer := r.startSyncTransfer(server, []string{sourceSpec.Path}, server+":"+target.Path[0])
+			defer func() { transfer.Done(err) }()
+
 			target.Output.Create(server)
 			target.Output.Progress = r.Progress
 			target.Output.ProgressWG = r.ProgressWG
@@ -330,6 +347,7 @@ func (r *RunSftp) syncBidirectionalLocalToRemote(sourceSpec lsync.PathSpec, targ
 				Permission:  permission,
 				ParallelNum: parallelNum,
 				Output:      target.Output,
+				Transfer:    transfer,
 				SourceLabel: "local",
 				TargetLabel: server,
 			}); err != nil {
@@ -341,6 +359,7 @@ func (r *RunSftp) syncBidirectionalLocalToRemote(sourceSpec lsync.PathSpec, targ
 				Permission:  permission,
 				ParallelNum: parallelNum,
 				Output:      target.Output,
+				Transfer:    transfer,
 				SourceLabel: server,
 				TargetLabel: "local",
 			})
@@ -365,7 +384,10 @@ func (r *RunSftp) syncBidirectionalRemoteToLocal(sourceSpec lsync.PathSpec, targ
 
 	for server, source := range sources {
 		source := source
-		if err := r.syncLoop(daemon, daemonInterval, func(ctx context.Context) error {
+		if err := r.syncLoop(daemon, daemonInterval, func(ctx context.Context) (err error) {
+			transfer := r.startSyncTransfer(server, remotePaths(server, source.Path), targetSpec.Path)
+			defer func() { transfer.Done(err) }()
+
 			source.Output.Create(server)
 			source.Output.Progress = r.Progress
 			source.Output.ProgressWG = r.ProgressWG
@@ -386,6 +408,7 @@ func (r *RunSftp) syncBidirectionalRemoteToLocal(sourceSpec lsync.PathSpec, targ
 				Permission:  permission,
 				ParallelNum: parallelNum,
 				Output:      source.Output,
+				Transfer:    transfer,
 				SourceLabel: server,
 				TargetLabel: "local",
 			}); err != nil {
@@ -397,6 +420,7 @@ func (r *RunSftp) syncBidirectionalRemoteToLocal(sourceSpec lsync.PathSpec, targ
 				Permission:  permission,
 				ParallelNum: parallelNum,
 				Output:      source.Output,
+				Transfer:    transfer,
 				SourceLabel: "local",
 				TargetLabel: server,
 			})
@@ -436,7 +460,10 @@ func (r *RunSftp) syncBidirectionalRemoteToRemote(sourceSpec lsync.PathSpec, tar
 	sourceFS := lsync.NewRemoteFS(source.Connect, source.Pwd)
 	for server, target := range targets {
 		target := target
-		if err := r.syncBidirectionalLoop(daemon, daemonInterval, []string{server}, func(ctx context.Context, _ string) error {
+		if err := r.syncBidirectionalLoop(daemon, daemonInterval, []string{server}, func(ctx context.Context, _ string) (err error) {
+			transfer := r.startSyncTransfer(server, remotePaths(sourceServer, source.Path), server+":"+target.Path[0])
+			defer func() { transfer.Done(err) }()
+
 			source.Output.Create(sourceServer)
 			source.Output.Progress = r.Progress
 			source.Output.ProgressWG = r.ProgressWG
@@ -456,6 +483,7 @@ func (r *RunSftp) syncBidirectionalRemoteToRemote(sourceSpec lsync.PathSpec, tar
 				Permission:  permission,
 				ParallelNum: parallelNum,
 				Output:      target.Output,
+				Transfer:    transfer,
 				SourceLabel: sourceServer,
 				TargetLabel: server,
 			}); err != nil {
@@ -467,6 +495,7 @@ func (r *RunSftp) syncBidirectionalRemoteToRemote(sourceSpec lsync.PathSpec, tar
 				Permission:  permission,
 				ParallelNum: parallelNum,
 				Output:      source.Output,
+				Transfer:    transfer,
 				SourceLabel: server,
 				TargetLabel: sourceServer,
 			})
@@ -478,6 +507,15 @@ func (r *RunSftp) syncBidirectionalRemoteToRemote(sourceSpec lsync.PathSpec, tar
 	return nil
 }
 
+// startSyncTransfer returns the audit log transfer of a sync run with server,
+// or nil on a dry run.
+func (r *RunSftp) startSyncTransfer(server string, source []string, destination string) *audit.Transfer {
+	if r.DryRun {
+		return nil
+	}
+	return sshl.StartAuditTransfer(r.Config, server, source, destination)
+}
+
 func (r *RunSftp) syncLoop(daemon bool, daemonInterval time.Duration, fn func(context.Context) error) error {
 	run := func(ctx context.Context) error {
 		if err := fn(ctx); err != nil {
@@ -13,6 +13,7 @@ import (
 	"time"
 
 	"github.com/blacknon/go-sshlib"
+	"github.com/blacknon/lssh/internal/audit"
 	"github.com/blacknon/lssh/internal/common"
 	conf "github.com/blacknon/lssh/internal/config"
 	"github.com/blacknon/lssh/internal/output"
@@ -103,6 +104,10 @@ type TargetConnectMap struct {
 
 	// Target Path list
 	Path []string
+
+	// Transfer is the audit log transfer of the running get, put or copy
+	// command on the server.
+	Transfer *audit.Transfer
 }
 
 // PathSet struct at path data
@@ -112,6 +117,24 @@ type PathSet struct {
 	PathSlice []string
 }
 
+// startTransfer starts the audit log transfer of client, unless on a dry run.
+func (r *RunSftp) startTransfer(client *TargetConnectMap, source []string, destination string) {
+	client.Transfer = nil
+	if r.DryRun {
+		return
+	}
+	client.Transfer = sshl.StartAuditTransfer(r.Config, client.Server, source, destination)
+}
+
+// remotePaths returns paths as `server:path`.
+func remotePaths(server string, paths []string) []string {
+	result := make([]string, 0, len(paths))
+	for _, path := range paths {
+		result = append(result, server+":"+path)
+	}
+	return result
+}
+
 func (r *RunSftp) printAction(printer *output.Output, action, target string) {
 	if printer != nil {
 		ow := printer.NewWriter()
@@ -15,6 +15,7 @@ import (
 	"sync"
 
 	"github.com/blacknon/lssh/internal/common"
+	conf "github.com/blacknon/lssh/internal/config"
 	"github.com/c-bata/go-prompt"
 	"github.com/kballard/go-shellquote"
 )
@@ -96,7 +97,7 @@ func (r *RunSftp) Executor(command string) {
 	// switch command
 	switch cmdline[0] {
 	case "bye", "exit", "quit":
-		os.Exit(0)
+		conf.Exit(0)
 	case "help", "?":
 	case "status":
 		r.status(cmdline)
@@ -869,7 +870,7 @@ func (r *RunSftp) exitChecker(in string, breakline bool) bool {
 		fmt.Printf("Error: No valid connections\n")
 
 		// TODO: 再接続が発生する場合はexitせずに返す？
-		os.Exit(1)
+		conf.Exit(1)
 
 		return true
 	}
@@ -0,0 +1,158 @@
+// Copyright (c) 2026 Blacknon. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package ssh
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/blacknon/lssh/internal/audit"
+	conf "github.com/blacknon/lssh/internal/config"
+)
+
+// AuditRecord returns an audit record of action on server, with the
+// address, the proxy route and the connector of server.
+func AuditRecord(config conf.Config, action, server string) audit.Record {
+	record := audit.Record{Action: action, Host: server}
+	if !audit.Enabled(config) {
+		return record
+	}
+
+	if serverConfig, ok := config.Server[server]; ok {
+		port := serverConfig.Port
+		if port == "" {
+			port = "22"
+		}
+		record.Address = net.JoinHostPort(serverConfig.Addr, port)
+		if serverConfig.User != "" {
+			record.Address = serverConfig.User + "@" + record.Address
+		}
+	}
+	if config.ServerUsesConnector(server) {
+		record.Connector = config.ServerConnectorName(server)
+	}
+	record.Proxy, _ = DescribeProxyRoute(server, config)
+	return record
+}
+
+// StartAuditTransfer returns the audit Transfer of a transfer to or from
+// server, or nil if the audit log is disabled.
+func StartAuditTransfer(config conf.Config, server string, source []string, destination string) *audit.Transfer {
+	if !audit.Enabled(config) {
+		return nil
+	}
+	record := AuditRecord(config, audit.ActionTransfer, server)
+	record.Source = source
+	record.Destination = destination
+	return audit.StartTransfer(config, record)
+}
+
+// AuditCommand writes the audit record of command run on server, with the
+// bytes of its output and its exit code. A negative exitCode has no exit
+// status.
+func AuditCommand(config conf.Config, server, command string, duration time.Duration, bytes int64, exitCode int, err error) {
+	if !audit.Enabled(config) {
+		return
+	}
+	record := AuditRecord(config, audit.ActionCommand, server)
+	record.Command = command
+	record.Bytes = bytes
+	record.Finish(duration, exitCode, err)
+	audit.Log(config, record)
+}
+
+// auditCommandResult writes the audit record of the command mode result of
+// a host. Skipped hosts have none.
+func (r *Run) auditCommandResult(result HostResult) {
+	if result.Status == ResultSkipped {
+		return
+	}
+	var err error
+	if result.Error != "" {
+		err = errors.New(result.Error)
+	}
+	var bytes int64
+	if counter, ok := r.auditOutput[result.Host]; ok {
+		bytes = counter.Count()
+	}
+	AuditCommand(r.Conf, result.Host, strings.Join(r.ExecCmd, " "), result.Duration, bytes, result.ExitCode, err)
+}
+
+// AuditShell writes the shell_start record of server, and returns the func
+// writing its shell_end record from the error of the shell.
+func AuditShell(config conf.Config, server string) func(err error) {
+	if !audit.Enabled(config) {
+		return func(error) {}
+	}
+
+	start := time.Now()
+	record := AuditRecord(config, audit.ActionShellStart, server)
+	audit.Log(config, record)
+
+	return func(err error) {
+		code, err := CommandExitCode(err)
+		end := record
+		end.Action = audit.ActionShellEnd
+		end.Finish(time.Since(start), code, err)
+		audit.Log(config, end)
+	}
+}
+
+// auditForwards writes a forward record for each port forward of config on
+// server. failed has the errors of the local/remote forwards that did not
+// start.
+func (r *Run) auditForwards(server string, config conf.ServerConfig, failed map[*conf.PortForward]error) {
+	if !audit.Enabled(r.Conf) {
+		return
+	}
+
+	write := func(forward string, err error) {
+		record := AuditRecord(r.Conf, audit.ActionForward, server)
+		record.Forward = forward
+		if err != nil {
+			record.Error = err.Error()
+		}
+		audit.Log(r.Conf, record)
+	}
+
+	for _, fw := range config.Forwards {
+		if fw == nil {
+			continue
+		}
+		mode := "local"
+		if strings.ToUpper(fw.Mode) == "R" {
+			mode = "remote"
+		}
+		write(fmt.Sprintf("%s %s -> %s", mode, fw.Local, fw.Remote), failed[fw])
+	}
+
+	// the NFS and SMB forwards are started with a path only.
+	dynamic := []struct {
+		name, port, path string
+		withPath         bool
+	}{
+		{"dynamic", config.DynamicPortForward, "", false},
+		{"reverse-dynamic", config.ReverseDynamicPortForward, "", false},
+		{"http-dynamic", config.HTTPDynamicPortForward, "", false},
+		{"http-reverse-dynamic", config.HTTPReverseDynamicPortForward, "", false},
+		{"nfs-dynamic", config.NFSDynamicForwardPort, config.NFSDynamicForwardPath, true},
+		{"nfs-reverse-dynamic", config.NFSReverseDynamicForwardPort, config.NFSReverseDynamicForwardPath, true},
+		{"smb-dynamic", config.SMBDynamicForwardPort, config.SMBDynamicForwardPath, true},
+		{"smb-reverse-dynamic", config.SMBReverseDynamicForwardPort, config.SMBReverseDynamicForwardPath, true},
+	}
+	for _, d := range dynamic {
+		if d.port == "" || (d.withPath && d.path == "") {
+			continue
+		}
+		forward := d.name + " " + d.port
+		if d.path != "" {
+			forward += ":" + d.path
+		}
+		write(forward, nil)
+	}
+}
@@ -0,0 +1,82 @@
+package ssh
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/blacknon/lssh/internal/audit"
+	conf "github.com/blacknon/lssh/internal/config"
+)
+
+func TestAuditRecordDescribesServer(t *testing.T) {
+	config := conf.Config{
+		Audit: conf.AuditConfig{Enable: true, Path: filepath.Join(t.TempDir(), "audit.jsonl")},
+		Server: map[string]conf.ServerConfig{
+			"bastion": {Addr: "192.0.2.1", User: "jump"},
+			"web":     {Addr: "10.0.0.5", Port: "2222", User: "deploy", Proxy: "bastion"},
+		},
+	}
+
+	record := AuditRecord(config, audit.ActionShellStart, "web")
+	if record.Host != "web" || record.Address != "deploy@10.0.0.5:2222" {
+		t.Fatalf("record = %+v", record)
+	}
+	if want := []string{"ssh bastion"}; !reflect.DeepEqual(record.Proxy, want) {
+		t.Fatalf("proxy = %v, want %v", record.Proxy, want)
+	}
+
+	config.Audit.Enable = false
+	record = AuditRecord(config, audit.ActionShellStart, "web")
+	if record.Address != "" || record.Proxy != nil {
+		t.Fatalf("record with the audit log disabled = %+v", record)
+	}
+}
+
+func TestAuditForwardsWritesEachForward(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	config := conf.ServerConfig{
+		Addr: "192.0.2.10",
+		Forwards: []*conf.PortForward{
+			{Mode: "L", Local: "localhost:8080", Remote: "localhost:80"},
+			{Mode: "R", Local: "localhost:9000", Remote: "localhost:9000"},
+		},
+		DynamicPortForward:    "1080",
+		NFSDynamicForwardPort: "2049",
+	}
+	r := &Run{Conf: conf.Config{
+		Audit:  conf.AuditConfig{Enable: true, Path: path},
+		Server: map[string]conf.ServerConfig{"web": config},
+	}}
+
+	r.auditForwards("web", config, nil)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read audit log: %v", err)
+	}
+
+	var forwards []string
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var record audit.Record
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("unmarshal %q: %v", line, err)
+		}
+		if record.Action != audit.ActionForward || record.Host != "web" {
+			t.Fatalf("record = %+v", record)
+		}
+		forwards = append(forwards, record.Forward)
+	}
+
+	want := []string{
+		"local localhost:8080 -> localhost:80",
+		"remote localhost:9000 -> localhost:9000",
+		"dynamic 1080",
+	}
+	if !reflect.DeepEqual(forwards, want) {
+		t.Fatalf("forwards = %q, want %q", forwards, want)
+	}
+}
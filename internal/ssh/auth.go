@@ -20,6 +20,10 @@ const SSH_AUTH_SOCK = "SSH_AUTH_SOCK"
 
 // CreateAuthMethodMap Create ssh.AuthMethod, into r.AuthMethodMap.
 func (r *Run) CreateAuthMethodMap() {
+	// resolve `${...}` references just before connect. failed servers are
+	// reported by the connect path.
+	r.Conf, r.interpolateErrs = r.Conf.InterpolateServers(r.ServerList)
+
 	srvs := r.ServerList
 	for _, server := range r.ServerList {
 		proxySrvs, _ := getProxyRoute(server, r.Conf)
@@ -39,6 +43,10 @@ func (r *Run) CreateAuthMethodMap() {
 
 serverLoop:
 	for _, server := range srvs {
+		if _, ok := r.interpolateErrs[server]; ok {
+			continue serverLoop
+		}
+
 		// get server config
 		config := r.Conf.Server[server]
 
@@ -0,0 +1,154 @@
+// Copyright (c) 2026 Blacknon. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package ssh
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// host orders of command mode (Run.Order).
+const (
+	OrderName   = "name"
+	OrderRandom = "random"
+)
+
+// ValidateOrder checks the host order of command mode. Empty keeps the
+// selected order.
+func ValidateOrder(order string) error {
+	switch order {
+	case "", OrderName, OrderRandom:
+		return nil
+	}
+	return fmt.Errorf("unknown order %q. [name|random]", order)
+}
+
+// ParseBatchSize returns the number of hosts of a batch of total hosts. value
+// is a number of hosts (ex. `5`) or a percentage of total (ex. `25%`, rounded
+// up). Empty is one batch of all hosts.
+func ParseBatchSize(value string, total int) (int, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return total, nil
+	}
+
+	percent := strings.HasSuffix(value, "%")
+	n, err := strconv.Atoi(strings.TrimSuffix(value, "%"))
+	if err != nil || n <= 0 || (percent && n > 100) {
+		return 0, fmt.Errorf("invalid batch size %q. a number of hosts or a percentage (1%%-100%%)", value)
+	}
+	if percent {
+		n = (total*n + 99) / 100
+	}
+	return max(min(n, total), 1), nil
+}
+
+// orderServers returns a copy of servers in order.
+func orderServers(servers []string, order string) []string {
+	result := append([]string(nil), servers...)
+	switch order {
+	case OrderName:
+		sort.Strings(result)
+	case OrderRandom:
+		rand.Shuffle(len(result), func(i, j int) { result[i], result[j] = result[j], result[i] })
+	}
+	return result
+}
+
+// splitBatches splits servers into batches of size hosts.
+func splitBatches(servers []string, size int) [][]string {
+	if size <= 0 {
+		size = len(servers)
+	}
+	batches := [][]string{}
+	for len(servers) > 0 {
+		n := min(size, len(servers))
+		batches = append(batches, servers[:n])
+		servers = servers[n:]
+	}
+	return batches
+}
+
+// runHosts calls run for each of servers in order, with at most parallel
+// hosts at the same time. Once status is stopped, the remaining servers are
+// skipped without calling run.
+func runHosts(servers []string, parallel int, status *cmdStatus, run func(server string)) {
+	limit := make(chan struct{}, max(parallel, 1))
+	var wg sync.WaitGroup
+	for _, server := range servers {
+		limit <- struct{}{}
+		if status.stopped() {
+			<-limit
+			status.skip(server)
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-limit }()
+			run(server)
+		}()
+	}
+	wg.Wait()
+}
+
+// cmdInput is the stdin of command mode, shared by the batches.
+type cmdInput struct {
+	// data is the piped stdin, sent to each host once captured.
+	data     []byte
+	captured bool
+
+	// fanout sends the stdin to the running parallel hosts, from
+	// output.PushInput started by the first batch and stopped by exit.
+	fanout  stdinFanout
+	pushing bool
+	exit    chan bool
+}
+
+// stdinFanout writes to the writers of the running hosts, so that one
+// output.PushInput serves all hosts and batches.
+type stdinFanout struct {
+	mu      sync.Mutex
+	writers []io.WriteCloser
+}
+
+// add starts sending the stdin to w.
+func (f *stdinFanout) add(w io.WriteCloser) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.writers = append(f.writers, w)
+}
+
+// remove stops sending the stdin to w, and closes it.
+func (f *stdinFanout) remove(w io.WriteCloser) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i, writer := range f.writers {
+		if writer == w {
+			f.writers = append(f.writers[:i], f.writers[i+1:]...)
+			break
+		}
+	}
+	w.Close()
+}
+
+func (f *stdinFanout) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, w := range f.writers {
+		_, _ = w.Write(p)
+	}
+	return len(p), nil
+}
+
+func (f *stdinFanout) Close() error {
+	return nil
+}
@@ -0,0 +1,143 @@
+package ssh
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestParseBatchSize(t *testing.T) {
+	tds := []struct {
+		value string
+		total int
+		want  int
+	}{
+		{value: "", total: 7, want: 7},
+		{value: "3", total: 7, want: 3},
+		{value: "10", total: 7, want: 7},
+		{value: "25%", total: 7, want: 2},
+		{value: "50%", total: 10, want: 5},
+		{value: "1%", total: 10, want: 1},
+		{value: "100%", total: 10, want: 10},
+	}
+	for _, td := range tds {
+		got, err := ParseBatchSize(td.value, td.total)
+		if err != nil || got != td.want {
+			t.Fatalf("ParseBatchSize(%q, %d) = %d, %v, want %d", td.value, td.total, got, err, td.want)
+		}
+	}
+
+	for _, value := range []string{"0", "-1", "0%", "101%", "a", "5x"} {
+		if _, err := ParseBatchSize(value, 10); err == nil {
+			t.Fatalf("ParseBatchSize(%q) error = nil", value)
+		}
+	}
+}
+
+func TestSplitBatches(t *testing.T) {
+	got := splitBatches([]string{"a", "b", "c", "d", "e"}, 2)
+	want := [][]string{{"a", "b"}, {"c", "d"}, {"e"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("splitBatches() = %v, want %v", got, want)
+	}
+	if got := splitBatches([]string{"a", "b"}, 0); len(got) != 1 {
+		t.Fatalf("splitBatches(size 0) = %v, want one batch", got)
+	}
+}
+
+func TestOrderServers(t *testing.T) {
+	servers := []string{"web02", "db01", "web01"}
+	if got := orderServers(servers, ""); !reflect.DeepEqual(got, servers) {
+		t.Fatalf("orderServers(\"\") = %v", got)
+	}
+	if got := orderServers(servers, OrderName); !reflect.DeepEqual(got, []string{"db01", "web01", "web02"}) {
+		t.Fatalf("orderServers(name) = %v", got)
+	}
+	got := orderServers(servers, OrderRandom)
+	sort.Strings(got)
+	if !reflect.DeepEqual(got, []string{"db01", "web01", "web02"}) {
+		t.Fatalf("orderServers(random) = %v", got)
+	}
+	if servers[0] != "web02" {
+		t.Fatalf("orderServers() modified its argument: %v", servers)
+	}
+}
+
+type nopWriteCloser struct{ io.Writer }
+
+func (nopWriteCloser) Close() error { return nil }
+
+func TestStdinFanout(t *testing.T) {
+	var first, second bytes.Buffer
+	f := &stdinFanout{}
+	f.add(nopWriteCloser{&first})
+	_, _ = f.Write([]byte("a"))
+	f.add(nopWriteCloser{&second})
+	_, _ = f.Write([]byte("b"))
+	f.remove(nopWriteCloser{&first})
+	_, _ = f.Write([]byte("c"))
+	if first.String() != "ab" || second.String() != "bc" {
+		t.Fatalf("stdinFanout wrote %q and %q", first.String(), second.String())
+	}
+}
+
+func TestRunHostsFailFast(t *testing.T) {
+	servers := []string{"web01", "web02", "web03", "web04"}
+	status := newCmdStatus(servers, 1)
+
+	var mu sync.Mutex
+	var ran []string
+	runHosts(servers, 2, status, func(server string) {
+		mu.Lock()
+		ran = append(ran, server)
+		mu.Unlock()
+		if server == "web01" {
+			status.finish(server, 1, nil)
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+		status.finish(server, 0, nil)
+	})
+
+	// web02 may start while web01 is running, but no host after the
+	// failure is connected.
+	for _, server := range ran {
+		if server == "web03" || server == "web04" {
+			t.Fatalf("runHosts() ran %v after web01 failed", ran)
+		}
+	}
+	for _, result := range status.list() {
+		if (result.Host == "web03" || result.Host == "web04") && result.Status != ResultSkipped {
+			t.Fatalf("%s status = %s, want skipped", result.Host, result.Status)
+		}
+	}
+}
+
+func TestRunHostsMaxParallel(t *testing.T) {
+	servers := []string{"web01", "web02", "web03", "web04", "web05"}
+	status := newCmdStatus(servers, 0)
+
+	var mu sync.Mutex
+	running, peak, count := 0, 0, 0
+	runHosts(servers, 2, status, func(server string) {
+		mu.Lock()
+		running++
+		count++
+		peak = max(peak, running)
+		mu.Unlock()
+
+		time.Sleep(10 * time.Millisecond)
+
+		mu.Lock()
+		running--
+		mu.Unlock()
+	})
+
+	if count != len(servers) || peak != 2 {
+		t.Fatalf("runHosts() ran %d hosts with %d at the same time, want %d with 2", count, peak, len(servers))
+	}
+}
@@ -6,33 +6,28 @@ package ssh
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"os"
 	"strings"
-	"sync"
 	"time"
 
 	"github.com/blacknon/go-sshlib"
+	"github.com/blacknon/lssh/internal/audit"
+	conf "github.com/blacknon/lssh/internal/config"
 	"github.com/blacknon/lssh/internal/output"
 )
 
 var cmdOPROMPT = "${SERVER} :: "
 
+// structuredSettle is how long the structured output waits for the output of
+// a host after its command returned, as sshlib copies it in the background.
+const structuredSettle = 50 * time.Millisecond
+
 // cmd is run command.
 func (r *Run) cmd() (err error) {
-	// command
-	command := strings.Join(r.ExecCmd, " ")
-
-	// create connect map
-	connmap := map[string]*sshlib.Connect{}
-	connectorServers := map[string]bool{}
-
-	// make channel
-	finished := make(chan bool)
-	exitInput := make(chan bool)
-
 	// print header
 	r.PrintSelectServer()
 	r.printRunCommand()
@@ -43,346 +38,427 @@ func (r *Run) cmd() (err error) {
 	// set enable stdoutMutex
 	r.EnableStdoutMutex = true
 
-	var wg sync.WaitGroup
-	var mu sync.Mutex // Mutex to safely update connmap
+	servers := orderServers(r.ServerList, r.Order)
+	size, err := ParseBatchSize(r.Batch, len(servers))
+	if err != nil {
+		return err
+	}
+	batches := splitBatches(servers, size)
+	r.cmdStatus = newCmdStatus(servers, r.MaxFail)
+	if output.IsStructured(r.OutputFormat) {
+		r.structured = output.NewStructured(os.Stdout, r.OutputFormat, strings.Join(r.ExecCmd, " "), servers)
+		r.structured.Settle = structuredSettle
+		r.cmdStatus.onFinish = r.finishStructured
+	}
+	if r.GroupOutput || r.DiffFromMajority {
+		r.grouped = output.NewHostBuffers()
+	}
+	if audit.Enabled(r.Conf) {
+		r.auditOutput = map[string]*audit.Counter{}
+		for _, server := range servers {
+			r.auditOutput[server] = &audit.Counter{}
+		}
+		onFinish := r.cmdStatus.onFinish
+		r.cmdStatus.onFinish = func(result HostResult) {
+			r.auditCommandResult(result)
+			if onFinish != nil {
+				onFinish(result)
+			}
+		}
+	}
 
-	// Create sshlib.Connect to connmap
-	// TODO: go-sshlib側でstdout用にMutexを渡して、それで重複出力を排除する感じにすればいいかも？？？
-	//       → もしかしたら、これでlsshellの並列outputも処理できるかも？？？…と思ったが、parallelのときは握りっぱなしになるので、間でうまいこと処理するナニカ(writer)がいないとダメかも？
-	for _, server := range r.ServerList {
-		server := server // Capture range variable for goroutine
-		wg.Add(1)        // Increment WaitGroup counter
+	// In parallel mode, a piped stdin is read first when there are batches
+	// or hosts waiting for a slot, so that every host gets all of it.
+	input := &cmdInput{exit: make(chan bool)}
+	limited := r.MaxParallel > 0 && r.MaxParallel < size
+	if r.IsParallel && r.IsStdinPipe && (len(batches) > 1 || limited) {
+		input.data, _ = io.ReadAll(os.Stdin)
+		input.captured = true
+	}
 
-		go func() {
-			defer wg.Done() // Decrement counter when goroutine completes
+	for i, batch := range batches {
+		if r.cmdStatus.stopped() {
+			r.cmdStatus.skip(batch...)
+			continue
+		}
+		if i > 0 && r.BatchPause > 0 {
+			time.Sleep(r.BatchPause)
+		}
+		if len(batches) > 1 {
+			fmt.Fprintf(os.Stderr, "Batch         :%d/%d %s\n", i+1, len(batches), strings.Join(batch, ","))
+		}
+		r.cmdBatch(batch, input)
+	}
 
-			if r.usesConnector(server) {
-				mu.Lock()
-				connectorServers[server] = true
-				mu.Unlock()
-				return
-			}
+	close(input.exit)
 
-			// check count AuthMethod
-			if len(r.serverAuthMethodMap[server]) == 0 {
-				fmt.Fprintf(os.Stderr, "Error: %s is No AuthMethod.\n", server)
-				return
-			}
+	// sleep
+	time.Sleep(300 * time.Millisecond)
 
-			conn, err := r.CreateSshConnect(server)
-			if err != nil {
-				log.Printf("Error: %s:%s\n", server, err)
-				return
-			}
+	if r.structured != nil {
+		if err := r.structured.Close(); err != nil {
+			return err
+		}
+	}
+
+	results := r.Results()
+	if r.grouped != nil {
+		output.PrintGroups(os.Stdout, output.GroupOutputs(r.groupedOutputs(results)), r.DiffFromMajority)
+	}
+	skipped := []string{}
+	for _, result := range results {
+		if result.Status == ResultSkipped {
+			skipped = append(skipped, result.Host)
+		}
+	}
+	if len(skipped) > 0 {
+		fmt.Fprintf(os.Stderr, "Skipped       :%s (failed: %s)\n", strings.Join(skipped, ","), strings.Join(r.cmdStatus.failed, ","))
+	}
+	if r.Summary {
+		fmt.Fprintln(os.Stderr)
+		printSummary(os.Stderr, results)
+	}
+	if r.ResultFile != "" {
+		if err := r.writeResultFile(r.ResultFile); err != nil {
+			return fmt.Errorf("write result file: %w", err)
+		}
+	}
+
+	return
+}
 
-			// centralized informational output
-			r.PrintConnectInfo(server, conn, r.Conf.Server[server])
+// cmdBatch runs the command on servers. Each host is connected, run and
+// closed within one of at most Run.MaxParallel slots (one at a time if not
+// parallel), and hosts are skipped without connecting once the command is
+// stopped by Run.MaxFail.
+func (r *Run) cmdBatch(servers []string, input *cmdInput) {
+	// command
+	command := strings.Join(r.ExecCmd, " ")
 
-			mu.Lock()
-			connmap[server] = conn
-			mu.Unlock()
-		}()
+	parallel := 1
+	if r.IsParallel {
+		parallel = len(servers)
+		if r.MaxParallel > 0 && r.MaxParallel < parallel {
+			parallel = r.MaxParallel
+		}
 	}
 
-	// Wait for all goroutines to finish
-	wg.Wait()
+	// if parallel flag true, and select server is not single,
+	// send stdin to the running hosts.
+	if r.IsParallel && !input.captured && len(r.ServerList) > 1 && !input.pushing {
+		input.pushing = true
+		go output.PushInput(input.exit, []io.WriteCloser{&input.fanout}, os.Stdin)
+	}
 
-	// Run command and print loop
-	writers := []io.WriteCloser{}
-	for s, c := range connmap {
-		// Get server config
-		config := r.Conf.Server[s]
+	runHosts(servers, parallel, r.cmdStatus, func(server string) {
+		r.cmdHost(server, command, input)
+	})
+}
 
-		// Agent will be set by CreateSshConnect; ForwardAgent is configured there.
+// cmdHost connects to server, runs command on it and closes the connection.
+func (r *Run) cmdHost(server, command string, input *cmdInput) {
+	r.cmdStatus.begin(server)
 
-		// If this connection is NOT a control client, create a session now
-		if !c.IsControlClient() {
-			c.Session, _ = c.CreateSession()
-		}
+	if err := r.interpolateErrs[server]; err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		r.cmdStatus.finish(server, -1, err)
+		return
+	}
 
-		// create Output
-		o := &output.Output{
-			Templete:      cmdOPROMPT,
-			Count:         0,
-			ServerList:    r.ServerList,
-			Conf:          r.Conf.Server[s],
-			EnableHeader:  r.EnableHeader,
-			DisableHeader: r.DisableHeader,
-			AutoColor:     true,
+	if r.usesConnector(server) {
+		stdoutWriter, stderrWriter := connectorOutputWriters(r, server, len(r.ServerList) == 1)
+		if counter, ok := r.auditOutput[server]; ok {
+			stdoutWriter, stderrWriter = counter.Writer(stdoutWriter), counter.Writer(stderrWriter)
 		}
-		o.Create(s)
+		code, runErr := r.runConnectorCommand(server, stdoutWriter, stderrWriter)
+		if runErr != nil {
+			fmt.Fprintln(os.Stderr, connectorErrorString(server, runErr))
+		}
+		if runErr == nil {
+			r.cmdStatus.connected(server)
+		}
+		r.cmdStatus.finish(server, code, runErr)
+		return
+	}
 
-		// set output
-		c.Stdout = o.NewWriter()
-		c.Stderr = o.NewWriter()
+	// check count AuthMethod
+	if len(r.serverAuthMethodMap[server]) == 0 {
+		fmt.Fprintf(os.Stderr, "Error: %s is No AuthMethod.\n", server)
+		r.cmdStatus.finish(server, -1, fmt.Errorf("no AuthMethod"))
+		return
+	}
 
-		// if single server, setup port forwarding.
-		if len(r.ServerList) == 1 {
-			// set port forwarding
-			config = r.setPortForwards(s, config)
+	c, err := r.CreateSshConnect(server)
+	if err != nil {
+		log.Printf("Error: %s:%s\n", server, err)
+		r.cmdStatus.finish(server, -1, err)
+		return
+	}
+	defer c.Close()
+	r.cmdStatus.connected(server)
+
+	// centralized informational output
+	r.PrintConnectInfo(server, c, r.Conf.Server[server])
+
+	// If this connection is NOT a control client, create a session now.
+	// set_env/send_env the server refuses are dropped, or exported by the
+	// command with set_env_export.
+	env := r.Conf.Server[server].RemoteEnv()
+	if !c.IsControlClient() {
+		c.Session, _ = c.CreateSession()
+		env = RequestRemoteEnv(c.Session, env)
+	}
+	env, notice := ExportRefusedRemoteEnv(server, r.Conf.Server[server], env)
+	warnRemoteEnv(os.Stderr, notice)
+	command = RemoteEnvCommand(env, command)
 
-			// OverWrite dynamic port forwarding
-			if r.DynamicPortForward != "" {
-				config.DynamicPortForward = r.DynamicPortForward
-			}
+	// set output
+	o := r.createCommandOutput(server)
+	c.Stdout, c.Stderr = o.NewWriter(), o.NewWriter()
+	switch {
+	case r.structured != nil:
+		c.Stdout, c.Stderr = r.structured.Writer(server, "stdout"), r.structured.Writer(server, "stderr")
+	case r.grouped != nil:
+		c.Stdout, c.Stderr = r.grouped.Writer(server), r.grouped.Writer(server)
+	}
 
-			// OverWrite reverse dynamic port forwarding
-			if r.ReverseDynamicPortForward != "" {
-				config.ReverseDynamicPortForward = r.ReverseDynamicPortForward
-			}
+	// if single server, setup port forwarding.
+	var stdin io.WriteCloser
+	if len(r.ServerList) == 1 {
+		r.startCmdForwards(c, server)
 
-			// OverWrite http dynamic port forwarding
-			if r.HTTPDynamicPortForward != "" {
-				config.HTTPDynamicPortForward = r.HTTPDynamicPortForward
-			}
+		// if tty
+		if r.IsTerm && r.structured == nil && r.grouped == nil {
+			c.Stdin = os.Stdin
+			c.Stdout = os.Stdout
+			c.Stderr = os.Stderr
+		}
 
-			// OverWrite reverse http dynamic port forwarding
-			if r.HTTPReverseDynamicPortForward != "" {
-				config.HTTPReverseDynamicPortForward = r.HTTPReverseDynamicPortForward
-			}
+		// If started as daemonized child, notify parent that forwarding is ready
+		notifyParentReady()
+	} else if r.IsParallel {
+		// For parallel mode, prepare a writer to send stdin to the host.
+		// - For non-control clients: use session.StdinPipe()
+		// - For control clients: create an io.Pipe(), set read-side to c.Stdin
+		//   so Command() will read from it.
+		if c.Session != nil {
+			stdin, _ = c.Session.StdinPipe()
+		} else if c.IsControlClient() {
+			pr, pw := io.Pipe()
+			c.Stdin = pr
+			stdin = pw
+		}
+	}
 
-			// OverWrite nfs dynamic forwarding
-			if r.NFSDynamicForwardPort != "" {
-				config.NFSDynamicForwardPort = r.NFSDynamicForwardPort
-			}
+	if counter, ok := r.auditOutput[server]; ok {
+		c.Stdout, c.Stderr = counter.Writer(c.Stdout), counter.Writer(c.Stderr)
+	}
 
-			// OverWrite nfs dynamic path
-			if r.NFSDynamicForwardPath != "" {
-				config.NFSDynamicForwardPath = r.NFSDynamicForwardPath
-			}
-			if r.SMBDynamicForwardPort != "" {
-				config.SMBDynamicForwardPort = r.SMBDynamicForwardPort
-			}
-			if r.SMBDynamicForwardPath != "" {
-				config.SMBDynamicForwardPath = r.SMBDynamicForwardPath
+	// run command
+	if r.IsParallel {
+		if stdin != nil {
+			if input.captured {
+				go func() {
+					_, _ = stdin.Write(input.data)
+					stdin.Close()
+				}()
+			} else {
+				input.fanout.add(stdin)
+				defer input.fanout.remove(stdin)
 			}
+		}
 
-			// OverWrite nfs reverse dynamic forwarding
-			if r.NFSReverseDynamicForwardPort != "" {
-				config.NFSReverseDynamicForwardPort = r.NFSReverseDynamicForwardPort
-			}
+		// When control client, Command handles control path internally.
+		r.cmdStatus.finishCommand(server, c.Command(command))
+		return
+	}
 
-			// OverWrite nfs reverse dynamic path
-			if r.NFSReverseDynamicForwardPath != "" {
-				config.NFSReverseDynamicForwardPath = r.NFSReverseDynamicForwardPath
-			}
-			if r.SMBReverseDynamicForwardPort != "" {
-				config.SMBReverseDynamicForwardPort = r.SMBReverseDynamicForwardPort
-			}
-			if r.SMBReverseDynamicForwardPath != "" {
-				config.SMBReverseDynamicForwardPath = r.SMBReverseDynamicForwardPath
-			}
+	var cmdErr error
+	if !input.captured && r.IsStdinPipe && len(r.ServerList) > 1 {
+		input.captured = true
+		var stdinBuffer bytes.Buffer
+		reader := io.TeeReader(os.Stdin, &stdinBuffer)
 
-			// OverWrite local bashrc use
-			if r.IsBashrc {
-				config.LocalRcUse = "yes"
-			}
+		// If control client, set c.Stdin so runControlCommand reads it.
+		if c.IsControlClient() {
+			c.Stdin = reader
 
-			// OverWrite local bashrc not use
-			if r.IsNotBashrc {
-				config.LocalRcUse = "no"
-			}
+			// run command (synchronous)
+			cmdErr = c.Command(command)
+		} else {
+			// get stdin via session pipe for non-control client
+			w, _ := c.Session.StdinPipe()
 
-			// print header
-			for _, fw := range config.Forwards {
-				r.printPortForward(fw.Mode, fw.Local, fw.Remote)
-			}
+			// run command
+			done := make(chan error, 1)
+			go func() { done <- c.Command(command) }()
 
-			// Port Forwarding
-			for _, fw := range config.Forwards {
-				err = r.startPortForward(c, fw)
-				if err != nil {
-					fmt.Fprintln(os.Stderr, err)
-				}
-			}
+			// send stdin while caching it for subsequent hosts
+			io.Copy(w, reader)
+			w.Close()
+			cmdErr = <-done
+		}
 
-			// Dynamic Port Forwarding
-			if config.DynamicPortForward != "" {
-				r.printDynamicPortForward(config.DynamicPortForward)
-				go c.TCPDynamicForward("localhost", config.DynamicPortForward)
-			}
+		input.data = stdinBuffer.Bytes()
+	} else if len(input.data) > 0 {
+		// If control client, set c.Stdin so runControlCommand reads it.
+		if c.IsControlClient() {
+			c.Stdin = bytes.NewReader(input.data)
 
-			// Reverse Dynamic Port Forwarding
-			if config.ReverseDynamicPortForward != "" {
-				r.printReverseDynamicPortForward(config.ReverseDynamicPortForward)
-				go c.TCPReverseDynamicForward("localhost", config.ReverseDynamicPortForward)
-			}
+			// run command (synchronous)
+			cmdErr = c.Command(command)
+		} else {
+			// get stdin via session pipe for non-control client
+			rd := bytes.NewReader(input.data)
+			w, _ := c.Session.StdinPipe()
+
+			// run command
+			done := make(chan error, 1)
+			go func() { done <- c.Command(command) }()
+
+			// send stdin
+			io.Copy(w, rd)
+			w.Close()
+			cmdErr = <-done
+		}
+	} else {
+		// run command (both control and direct handled by Command)
+		cmdErr = c.Command(command)
+	}
+	r.cmdStatus.finishCommand(server, cmdErr)
+}
 
-			// HTTP Dynamic Port Forwarding
-			if config.HTTPDynamicPortForward != "" {
-				r.printHTTPDynamicPortForward(config.HTTPDynamicPortForward)
-				go c.HTTPDynamicForward("localhost", config.HTTPDynamicPortForward)
-			}
+// startCmdForwards starts the port forwardings of server on c, for command
+// mode with a single server.
+func (r *Run) startCmdForwards(c *sshlib.Connect, server string) {
+	// Get server config
+	config := r.Conf.Server[server]
 
-			// HTTP Reverse Dynamic Port Forwarding
-			if config.HTTPReverseDynamicPortForward != "" {
-				r.printHTTPReverseDynamicPortForward(config.HTTPReverseDynamicPortForward)
-				go c.HTTPReverseDynamicForward("localhost", config.HTTPReverseDynamicPortForward)
-			}
+	// set port forwarding
+	config = r.setPortForwards(server, config)
 
-			// NFS Dynamic Forward
-			if config.NFSDynamicForwardPort != "" && config.NFSDynamicForwardPath != "" {
-				r.printNFSDynamicForward(config.NFSDynamicForwardPort, config.NFSDynamicForwardPath)
-				go c.NFSForward("localhost", config.NFSDynamicForwardPort, config.NFSDynamicForwardPath)
-			}
+	// OverWrite dynamic port forwarding
+	if r.DynamicPortForward != "" {
+		config.DynamicPortForward = r.DynamicPortForward
+	}
 
-			// NFS Reverse Dynamic Forward
-			if config.NFSReverseDynamicForwardPort != "" && config.NFSReverseDynamicForwardPath != "" {
-				r.printNFSReverseDynamicForward(config.NFSReverseDynamicForwardPort, config.NFSReverseDynamicForwardPath)
-				go c.NFSReverseForward("localhost", config.NFSReverseDynamicForwardPort, config.NFSReverseDynamicForwardPath)
-			}
+	// OverWrite reverse dynamic port forwarding
+	if r.ReverseDynamicPortForward != "" {
+		config.ReverseDynamicPortForward = r.ReverseDynamicPortForward
+	}
 
-			if config.SMBDynamicForwardPort != "" && config.SMBDynamicForwardPath != "" {
-				r.printSMBDynamicForward(config.SMBDynamicForwardPort, config.SMBDynamicForwardPath)
-				go c.SMBForward("localhost", config.SMBDynamicForwardPort, "", config.SMBDynamicForwardPath)
-			}
+	// OverWrite http dynamic port forwarding
+	if r.HTTPDynamicPortForward != "" {
+		config.HTTPDynamicPortForward = r.HTTPDynamicPortForward
+	}
 
-			if config.SMBReverseDynamicForwardPort != "" && config.SMBReverseDynamicForwardPath != "" {
-				r.printSMBReverseDynamicForward(config.SMBReverseDynamicForwardPort, config.SMBReverseDynamicForwardPath)
-				go c.SMBReverseForward("localhost", config.SMBReverseDynamicForwardPort, "", config.SMBReverseDynamicForwardPath)
-			}
+	// OverWrite reverse http dynamic port forwarding
+	if r.HTTPReverseDynamicPortForward != "" {
+		config.HTTPReverseDynamicPortForward = r.HTTPReverseDynamicPortForward
+	}
 
-			// if tty
-			if r.IsTerm {
-				c.Stdin = os.Stdin
-				c.Stdout = os.Stdout
-				c.Stderr = os.Stderr
-			}
-		} else {
-			if r.IsParallel {
-				// For parallel mode, prepare writers to send stdin to each host.
-				// - For non-control clients: use session.StdinPipe()
-				// - For control clients: create an io.Pipe(), set read-side to c.Stdin
-				//   so Command() will read from it, and append write-side to writers.
-				if c.Session != nil {
-					w, _ := c.Session.StdinPipe()
-					writers = append(writers, w)
-				} else if c.IsControlClient() {
-					pr, pw := io.Pipe()
-					c.Stdin = pr
-					writers = append(writers, pw)
-				}
-			}
-		}
+	// OverWrite nfs dynamic forwarding
+	if r.NFSDynamicForwardPort != "" {
+		config.NFSDynamicForwardPort = r.NFSDynamicForwardPort
 	}
 
-	connectorFinished := 0
-	for _, server := range r.ServerList {
-		if !connectorServers[server] {
-			continue
-		}
-		connectorFinished++
+	// OverWrite nfs dynamic path
+	if r.NFSDynamicForwardPath != "" {
+		config.NFSDynamicForwardPath = r.NFSDynamicForwardPath
+	}
+	if r.SMBDynamicForwardPort != "" {
+		config.SMBDynamicForwardPort = r.SMBDynamicForwardPort
+	}
+	if r.SMBDynamicForwardPath != "" {
+		config.SMBDynamicForwardPath = r.SMBDynamicForwardPath
+	}
 
-		stdoutWriter, stderrWriter := connectorOutputWriters(r, server, len(r.ServerList) == 1)
-		if r.IsParallel {
-			go func(server string, stdoutWriter, stderrWriter io.Writer) {
-				if _, runErr := r.runConnectorCommand(server, stdoutWriter, stderrWriter); runErr != nil {
-					fmt.Fprintln(os.Stderr, connectorErrorString(server, runErr))
-				}
-				finished <- true
-			}(server, stdoutWriter, stderrWriter)
-			continue
-		}
+	// OverWrite nfs reverse dynamic forwarding
+	if r.NFSReverseDynamicForwardPort != "" {
+		config.NFSReverseDynamicForwardPort = r.NFSReverseDynamicForwardPort
+	}
 
-		if _, runErr := r.runConnectorCommand(server, stdoutWriter, stderrWriter); runErr != nil {
-			fmt.Fprintln(os.Stderr, connectorErrorString(server, runErr))
-		}
-		go func() { finished <- true }()
+	// OverWrite nfs reverse dynamic path
+	if r.NFSReverseDynamicForwardPath != "" {
+		config.NFSReverseDynamicForwardPath = r.NFSReverseDynamicForwardPath
+	}
+	if r.SMBReverseDynamicForwardPort != "" {
+		config.SMBReverseDynamicForwardPort = r.SMBReverseDynamicForwardPort
+	}
+	if r.SMBReverseDynamicForwardPath != "" {
+		config.SMBReverseDynamicForwardPath = r.SMBReverseDynamicForwardPath
 	}
 
-	// if parallel flag true, and select server is not single,
-	// set send stdin.
+	// OverWrite local bashrc use
+	if r.IsBashrc {
+		config.LocalRcUse = "yes"
+	}
 
-	// If started as daemonized child, notify parent that forwarding is ready
-	notifyParentReady()
+	// OverWrite local bashrc not use
+	if r.IsNotBashrc {
+		config.LocalRcUse = "no"
+	}
 
-	var stdinData []byte
-	var stdinBuffer bytes.Buffer
-	stdinCaptured := false
-	switch {
-	case r.IsParallel && len(r.ServerList) > 1:
-		go output.PushInput(exitInput, writers, os.Stdin)
+	// print header
+	for _, fw := range config.Forwards {
+		r.printPortForward(fw.Mode, fw.Local, fw.Remote)
 	}
 
-	// run command
-	for _, c := range connmap {
-		conn := c
-		if r.IsParallel {
-			go func() {
-				// When control client, Command handles control path internally.
-				conn.Command(command)
-				finished <- true
-			}()
-		} else {
-			if !stdinCaptured && r.IsStdinPipe && len(r.ServerList) > 1 {
-				stdinCaptured = true
-				reader := io.TeeReader(os.Stdin, &stdinBuffer)
-
-				// If control client, set conn.Stdin so runControlCommand reads it.
-				if conn.IsControlClient() {
-					conn.Stdin = reader
-
-					// run command (synchronous)
-					conn.Command(command)
-					finished <- true
-				} else {
-					// get stdin via session pipe for non-control client
-					w, _ := conn.Session.StdinPipe()
-
-					// run command
-					go func() {
-						conn.Command(command)
-						finished <- true
-					}()
-
-					// send stdin while caching it for subsequent hosts
-					io.Copy(w, reader)
-					w.Close()
-				}
-
-				stdinData = stdinBuffer.Bytes()
-			} else if len(stdinData) > 0 {
-				// If control client, set conn.Stdin so runControlCommand reads it.
-				if conn.IsControlClient() {
-					conn.Stdin = bytes.NewReader(stdinData)
-
-					// run command (synchronous)
-					conn.Command(command)
-					finished <- true
-				} else {
-					// get stdin via session pipe for non-control client
-					rd := bytes.NewReader(stdinData)
-					w, _ := conn.Session.StdinPipe()
-
-					// run command
-					go func() {
-						conn.Command(command)
-						finished <- true
-					}()
-
-					// send stdin
-					io.Copy(w, rd)
-					w.Close()
-				}
-			} else {
-				// run command (both control and direct handled by Command)
-				conn.Command(command)
-				go func() { finished <- true }()
-			}
+	// Port Forwarding
+	failedForwards := map[*conf.PortForward]error{}
+	for _, fw := range config.Forwards {
+		if err := r.startPortForward(c, fw); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			failedForwards[fw] = err
 		}
 	}
+	r.auditForwards(server, config, failedForwards)
 
-	// wait
-	for i := 0; i < len(connmap)+connectorFinished; i++ {
-		<-finished
+	// Dynamic Port Forwarding
+	if config.DynamicPortForward != "" {
+		r.printDynamicPortForward(config.DynamicPortForward)
+		go c.TCPDynamicForward("localhost", config.DynamicPortForward)
 	}
 
-	close(exitInput)
+	// Reverse Dynamic Port Forwarding
+	if config.ReverseDynamicPortForward != "" {
+		r.printReverseDynamicPortForward(config.ReverseDynamicPortForward)
+		go c.TCPReverseDynamicForward("localhost", config.ReverseDynamicPortForward)
+	}
 
-	// sleep
-	time.Sleep(300 * time.Millisecond)
+	// HTTP Dynamic Port Forwarding
+	if config.HTTPDynamicPortForward != "" {
+		r.printHTTPDynamicPortForward(config.HTTPDynamicPortForward)
+		go c.HTTPDynamicForward("localhost", config.HTTPDynamicPortForward)
+	}
 
-	return
+	// HTTP Reverse Dynamic Port Forwarding
+	if config.HTTPReverseDynamicPortForward != "" {
+		r.printHTTPReverseDynamicPortForward(config.HTTPReverseDynamicPortForward)
+		go c.HTTPReverseDynamicForward("localhost", config.HTTPReverseDynamicPortForward)
+	}
+
+	// NFS Dynamic Forward
+	if config.NFSDynamicForwardPort != "" && config.NFSDynamicForwardPath != "" {
+		r.printNFSDynamicForward(config.NFSDynamicForwardPort, config.NFSDynamicForwardPath)
+		go c.NFSForward("localhost", config.NFSDynamicForwardPort, config.NFSDynamicForwardPath)
+	}
+
+	// NFS Reverse Dynamic Forward
+	if config.NFSReverseDynamicForwardPort != "" && config.NFSReverseDynamicForwardPath != "" {
+		r.printNFSReverseDynamicForward(config.NFSReverseDynamicForwardPort, config.NFSReverseDynamicForwardPath)
+		go c.NFSReverseForward("localhost", config.NFSReverseDynamicForwardPort, config.NFSReverseDynamicForwardPath)
+	}
+
+	if config.SMBDynamicForwardPort != "" && config.SMBDynamicForwardPath != "" {
+		r.printSMBDynamicForward(config.SMBDynamicForwardPort, config.SMBDynamicForwardPath)
+		go c.SMBForward("localhost", config.SMBDynamicForwardPort, "", config.SMBDynamicForwardPath)
+	}
+
+	if config.SMBReverseDynamicForwardPort != "" && config.SMBReverseDynamicForwardPath != "" {
+		r.printSMBReverseDynamicForward(config.SMBReverseDynamicForwardPort, config.SMBReverseDynamicForwardPath)
+		go c.SMBReverseForward("localhost", config.SMBReverseDynamicForwardPort, "", config.SMBReverseDynamicForwardPath)
+	}
 }
 
 func (r *Run) createCommandOutput(server string) *output.Output {
@@ -398,3 +474,36 @@ func (r *Run) createCommandOutput(server string) *output.Output {
 	o.Create(server)
 	return o
 }
+
+// finishStructured writes the exit of a host to the structured output.
+func (r *Run) finishStructured(result HostResult) {
+	var err error
+	switch {
+	case result.Status == ResultSkipped:
+		err = errors.New(ResultSkipped)
+	case result.Error != "":
+		err = errors.New(result.Error)
+	}
+	r.structured.Finish(result.Host, result.ExitCode, result.Duration, err)
+}
+
+// groupedOutputs returns the buffered output of the hosts that were not
+// skipped, for GroupOutput. The output of a host with an error ends with the
+// error.
+func (r *Run) groupedOutputs(results []HostResult) []output.HostOutput {
+	outputs := []output.HostOutput{}
+	for _, result := range results {
+		if result.Status == ResultSkipped {
+			continue
+		}
+		o := output.HostOutput{Host: result.Host, Output: r.grouped.Output(result.Host), ExitCode: result.ExitCode}
+		if result.Error != "" {
+			if o.Output != "" && !strings.HasSuffix(o.Output, "\n") {
+				o.Output += "\n"
+			}
+			o.Output += "Error: " + result.Error + "\n"
+		}
+		outputs = append(outputs, o)
+	}
+	return outputs
+}
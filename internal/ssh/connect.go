@@ -2,9 +2,6 @@
 // Use of this source code is governed by an MIT license
 // that can be found in the LICENSE file.
 
-// TODO: CheckKnownHostsを使ってなかったので、HostKeyのチェックを追加し、さらにオプションで状況に応じた挙動の変化を実装する。
-// TODO: HostKey Checkの挙動をどうするかについて、Configで指定できるようにする
-
 package ssh
 
 import (
@@ -80,9 +77,6 @@ func (r *Run) createSshConnectWithControlPersist(server string, proxyRoute []*pr
 		ConnectTimeout:        s.ConnectTimeout,
 		SendKeepAliveMax:      s.ServerAliveCountMax,
 		SendKeepAliveInterval: s.ServerAliveCountInterval,
-		CheckKnownHosts:       s.CheckKnownHosts,
-		KnownHostsFiles:       s.KnownHostsFiles,
-		OverwriteKnownHosts:   true,
 		ControlPersistAuth:    &sshlib.ControlPersistAuth{AuthMethods: topMethods},
 	}
 
@@ -235,7 +229,9 @@ func (r *Run) effectiveServerConfig(server string, forceDirect bool) conf.Server
 		}
 	}
 
-	if forceDirect {
+	// the detached master can not check pinned host keys or the host keys of
+	// ssh proxy hops, so such servers are connected directly.
+	if forceDirect || (s.ControlMaster && controlMasterSkipsHostKeyCheck(server, r.Conf)) {
 		s.ControlMaster = false
 		s.ControlPersist = 0
 	}
@@ -246,6 +242,10 @@ func (r *Run) effectiveServerConfig(server string, forceDirect bool) conf.Server
 // createSshConnect is the main function for creating sshlib.Connect objects.
 // If forceDirect is true, it disables ControlMaster/ControlPersist for the connection (used for features like SFTP that require a concrete *ssh.Client).
 func (r *Run) createSshConnect(server string, forceDirect bool) (connect *sshlib.Connect, err error) {
+	if err = r.interpolateErrs[server]; err != nil {
+		return nil, err
+	}
+
 	if !r.Conf.ServerUsesBuiltInSSH(server) {
 		return nil, fmt.Errorf("server %q uses connector %q; direct ssh is not supported", server, r.Conf.ServerConnectorName(server))
 	}
@@ -293,7 +293,7 @@ func (r *Run) createSshConnect(server string, forceDirect bool) (connect *sshlib
 			pxy := &sshlib.Connect{
 				ProxyDialer: dialer,
 			}
-			err := pxy.CreateClient(c.Addr, c.Port, c.User, r.serverAuthMethodMap[p.Name])
+			err = r.createClient(pxy, p.Name, c.Addr, c.Port, c.User)
 			if err != nil {
 				return connect, err
 			}
@@ -316,7 +316,10 @@ func (r *Run) createSshConnect(server string, forceDirect bool) (connect *sshlib
 			connect.StdoutMutex = &r.stdoutMutex
 		}
 
-		err = connect.CreateClient(s.Addr, s.Port, s.User, r.serverAuthMethodMap[server])
+		err = r.setControlMasterHostKeyCheck(connect, server, s, dialer)
+		if err == nil {
+			err = connect.CreateClient(s.Addr, s.Port, s.User, r.serverAuthMethodMap[server])
+		}
 		if err != nil {
 			if client, ok := dialer.(*ssh.Client); ok {
 				client.Close()
@@ -365,9 +368,6 @@ func (r *Run) createSshConnect(server string, forceDirect bool) (connect *sshlib
 		ConnectTimeout:        s.ConnectTimeout,
 		SendKeepAliveMax:      s.ServerAliveCountMax,
 		SendKeepAliveInterval: s.ServerAliveCountInterval,
-		CheckKnownHosts:       s.CheckKnownHosts,
-		KnownHostsFiles:       s.KnownHostsFiles,
-		OverwriteKnownHosts:   true,
 	}
 
 	if s.ControlMaster {
@@ -388,7 +388,14 @@ func (r *Run) createSshConnect(server string, forceDirect bool) (connect *sshlib
 		connect.StdoutMutex = &r.stdoutMutex
 	}
 
-	err = connect.CreateClient(s.Addr, s.Port, s.User, r.serverAuthMethodMap[server])
+	if s.ControlMaster {
+		err = r.setControlMasterHostKeyCheck(connect, server, s, dialer)
+		if err == nil {
+			err = connect.CreateClient(s.Addr, s.Port, s.User, r.serverAuthMethodMap[server])
+		}
+	} else {
+		err = r.createClient(connect, server, s.Addr, s.Port, s.User)
+	}
 	if err != nil {
 		if client, ok := dialer.(*ssh.Client); ok {
 			client.Close()
@@ -24,3 +24,38 @@ func TestCreateSshConnectRejectsExternalConnector(t *testing.T) {
 		t.Fatalf("CreateSshConnect() error = %q, want connector-specific error", err)
 	}
 }
+
+func TestCreateSshConnectInterpolateError(t *testing.T) {
+	run := &Run{
+		ServerList: []string{"web01", "web02"},
+		Conf: conf.Config{
+			Server: map[string]conf.ServerConfig{
+				"bastion": {Addr: "${env:LSSH_TEST_UNSET_BASTION}", User: "demo", Pass: "secret"},
+				"web01":   {Addr: "${env:LSSH_TEST_UNSET_ADDR}", User: "demo", Pass: "secret"},
+				"web02":   {Addr: "192.0.2.10", User: "demo", Pass: "secret", Proxy: "bastion"},
+			},
+		},
+	}
+	run.CreateAuthMethodMap()
+
+	for server, want := range map[string]string{
+		"web01": "web01: addr: ${env:LSSH_TEST_UNSET_ADDR}: environment variable is not set",
+		"web02": "web02: proxy bastion: addr: ${env:LSSH_TEST_UNSET_BASTION}: environment variable is not set",
+	} {
+		_, err := run.CreateSshConnect(server)
+		if err == nil || err.Error() != want {
+			t.Fatalf("CreateSshConnect(%q) error = %v, want %q", server, err, want)
+		}
+	}
+
+	// the failed host is reported as an error result, without connecting.
+	run.cmdStatus = newCmdStatus([]string{"web01"}, 0)
+	run.cmdHost("web01", "hostname", &cmdInput{exit: make(chan bool)})
+	got := run.cmdStatus.list()
+	if len(got) != 1 || got[0].Status != ResultError || !strings.Contains(got[0].Error, "LSSH_TEST_UNSET_ADDR") {
+		t.Fatalf("cmdHost() results = %+v, want an interpolation error", got)
+	}
+	if hosts := run.cmdStatus.connectedHosts(); len(hosts) != 0 {
+		t.Fatalf("connectedHosts() = %v, want none", hosts)
+	}
+}
@@ -11,9 +11,11 @@ import (
 	"strings"
 	"sync"
 
+	"github.com/blacknon/lssh/internal/asciicast"
 	conf "github.com/blacknon/lssh/internal/config"
 	"github.com/blacknon/lssh/internal/connectorruntime"
 	"github.com/blacknon/lssh/internal/termenv"
+	"github.com/blacknon/lssh/providerapi"
 )
 
 func (r *Run) UsesConnector(server string) bool {
@@ -45,6 +47,7 @@ func (r *Run) runConnectorShellWithConfig(server string, config conf.ServerConfi
 		return fmt.Errorf("server %q uses connector %q; ssh tunnel devices are not supported", server, prepared.ConnectorName)
 	}
 	if connectorHasForwarding(config) {
+		r.auditForwards(server, config, nil)
 		if r.connectorForwardingSharesShell(config, prepared) {
 			return r.runConnectorShellWithSharedForwarding(server, config, prepared)
 		}
@@ -71,26 +74,21 @@ func (r *Run) runConnectorShellOnly(server string, config conf.ServerConfig, pre
 			startupMarker = InteractiveLocalRCStartupMarker()
 		}
 
+		rec, err := r.startRecording(server)
+		if err != nil {
+			return err
+		}
+		if rec != nil {
+			defer rec.Close()
+		}
+
 		switch {
 		case prepared.Command != nil:
-			return runCommandPlanShell(*prepared.Command)
+			return runCommandPlanShell(*prepared.Command, rec, r.Conf.Log.RecordInput)
 		case prepared.ManagedSSH != nil:
-			return r.runConnectorManagedSSHTransportShell(server, config)
+			return r.runConnectorManagedSSHTransportShell(server, config, rec)
 		case prepared.ProviderManagedPlan != nil:
-			return runNativeInteractiveSession(func() error {
-				return r.providerManagedExecutor().RunShell(context.Background(), connectorruntime.ShellRequest{
-					Server:         server,
-					Plan:           *prepared.ProviderManagedPlan,
-					LocalRCCommand: startupCommand,
-					StartupMarker:  startupMarker,
-					Dialer:         r.providerBridgeDialer(server),
-					Stream: connectorruntime.StreamConfig{
-						Stdin:  os.Stdin,
-						Stdout: os.Stdout,
-						Stderr: os.Stderr,
-					},
-				})
-			})
+			return r.runProviderManagedShell(server, *prepared.ProviderManagedPlan, startupCommand, startupMarker, rec)
 		default:
 			return fmt.Errorf("server %q connector %q returned unsupported plan kind %q", server, prepared.ConnectorName, prepared.PlanKind)
 		}
@@ -123,6 +121,14 @@ func (r *Run) runConnectorShellWithSharedForwarding(server string, config conf.S
 			startupMarker = InteractiveLocalRCStartupMarker()
 		}
 
+		rec, err := r.startRecording(server)
+		if err != nil {
+			return err
+		}
+		if rec != nil {
+			defer rec.Close()
+		}
+
 		forwardErrCh := make(chan error, 1)
 		go func() {
 			switch {
@@ -138,22 +144,9 @@ func (r *Run) runConnectorShellWithSharedForwarding(server string, config conf.S
 		var shellErr error
 		switch {
 		case prepared.ManagedSSH != nil:
-			shellErr = r.runConnectorManagedSSHTransportShell(server, config)
+			shellErr = r.runConnectorManagedSSHTransportShell(server, config, rec)
 		case prepared.ProviderManagedPlan != nil:
-			shellErr = runNativeInteractiveSession(func() error {
-				return r.providerManagedExecutor().RunShell(context.Background(), connectorruntime.ShellRequest{
-					Server:         server,
-					Plan:           *prepared.ProviderManagedPlan,
-					LocalRCCommand: startupCommand,
-					StartupMarker:  startupMarker,
-					Dialer:         r.providerBridgeDialer(server),
-					Stream: connectorruntime.StreamConfig{
-						Stdin:  os.Stdin,
-						Stdout: os.Stdout,
-						Stderr: os.Stderr,
-					},
-				})
-			})
+			shellErr = r.runProviderManagedShell(server, *prepared.ProviderManagedPlan, startupCommand, startupMarker, rec)
 		default:
 			cancel()
 			<-forwardErrCh
@@ -256,7 +249,39 @@ func (r *Run) connectorShellOperation() (conf.ConnectorOperation, error) {
 	return operation, nil
 }
 
-func runCommandPlanShell(plan conf.ConnectorCommandPlan) error {
+// runProviderManagedShell runs the shell of a provider managed connector on
+// the terminal, recorded to rec if it is not nil.
+func (r *Run) runProviderManagedShell(server string, plan providerapi.ConnectorPlan, startupCommand, startupMarker string, rec *asciicast.Recorder) error {
+	runShell := func(stream connectorruntime.StreamConfig) error {
+		return r.providerManagedExecutor().RunShell(context.Background(), connectorruntime.ShellRequest{
+			Server:         server,
+			Plan:           plan,
+			LocalRCCommand: startupCommand,
+			StartupMarker:  startupMarker,
+			Dialer:         r.providerBridgeDialer(server),
+			Stream:         stream,
+		})
+	}
+
+	if rec != nil {
+		return runRecordedTerminal(rec, r.Conf.Log.RecordInput, func(tty *os.File) error {
+			return runNativeInteractiveSessionOn(tty, func() error {
+				return runShell(connectorruntime.StreamConfig{Stdin: tty, Stdout: tty, Stderr: tty})
+			})
+		})
+	}
+	return runNativeInteractiveSession(func() error {
+		return runShell(connectorruntime.StreamConfig{
+			Stdin:  os.Stdin,
+			Stdout: os.Stdout,
+			Stderr: os.Stderr,
+		})
+	})
+}
+
+// runCommandPlanShell runs the shell of a command connector on the
+// terminal, recorded to rec if it is not nil.
+func runCommandPlanShell(plan conf.ConnectorCommandPlan, rec *asciicast.Recorder, recordInput bool) error {
 	if strings.TrimSpace(plan.Program) == "" {
 		return fmt.Errorf("connector command plan is missing program")
 	}
@@ -266,6 +291,12 @@ func runCommandPlanShell(plan conf.ConnectorCommandPlan) error {
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 	cmd.Env = mergedCommandPlanEnv(plan.Env)
+	if rec != nil {
+		return runRecordedTerminal(rec, recordInput, func(tty *os.File) error {
+			setCommandTTY(cmd, tty)
+			return cmd.Run()
+		})
+	}
 	return cmd.Run()
 }
 
@@ -342,7 +373,7 @@ func (r *Run) runConnectorLocalPortForward(server string, config conf.ServerConf
 	return runConnectorWithPrePost(config, func() error {
 		switch {
 		case prepared.Command != nil:
-			return runCommandPlanShell(*prepared.Command)
+			return runCommandPlanShell(*prepared.Command, nil, false)
 		case prepared.ManagedSSH != nil:
 			return r.runConnectorManagedSSHLocalPortForward(server, config)
 		default:
@@ -579,6 +610,12 @@ func connectorErrorString(server string, err error) string {
 }
 
 func connectorOutputWriters(r *Run, server string, single bool) (io.Writer, io.Writer) {
+	if r.structured != nil {
+		return r.structured.Writer(server, "stdout"), r.structured.Writer(server, "stderr")
+	}
+	if r.grouped != nil {
+		return r.grouped.Writer(server), r.grouped.Writer(server)
+	}
 	if single && r.IsTerm {
 		return os.Stdout, os.Stderr
 	}
@@ -608,6 +645,10 @@ func connectorOptionString(raw map[string]interface{}, key string) string {
 }
 
 func (r *Run) prepareConnectorOperation(server string, operation conf.ConnectorOperation) (conf.PreparedConnector, error) {
+	if err := r.interpolateErrs[server]; err != nil {
+		return conf.PreparedConnector{}, err
+	}
+
 	connectorName := r.Conf.ServerConnectorName(server)
 	if connectorName == "" || connectorName == "ssh" {
 		return conf.PreparedConnector{}, fmt.Errorf("server %q does not use an external connector", server)
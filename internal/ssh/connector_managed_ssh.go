@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/blacknon/go-sshlib"
+	"github.com/blacknon/lssh/internal/asciicast"
 	conf "github.com/blacknon/lssh/internal/config"
 	"github.com/pkg/sftp"
 	"golang.org/x/net/proxy"
@@ -79,9 +80,6 @@ func (r *Run) createConnectorManagedSSHConnect(server string, config conf.Server
 		ConnectTimeout:        config.ConnectTimeout,
 		SendKeepAliveMax:      config.ServerAliveCountMax,
 		SendKeepAliveInterval: config.ServerAliveCountInterval,
-		CheckKnownHosts:       config.CheckKnownHosts,
-		KnownHostsFiles:       config.KnownHostsFiles,
-		OverwriteKnownHosts:   true,
 		ControlMaster:         "no",
 	}
 	if r.EnableStdoutMutex {
@@ -93,7 +91,7 @@ func (r *Run) createConnectorManagedSSHConnect(server string, config conf.Server
 		port = "22"
 	}
 
-	if err := connect.CreateClient(config.Addr, port, config.User, r.serverAuthMethodMap[server]); err != nil {
+	if err := createHostKeyCheckedClient(connect, server, config, config.Addr, port, config.User, r.serverAuthMethodMap[server]); err != nil {
 		return nil, err
 	}
 
@@ -139,7 +137,7 @@ func (r *Run) CreateConnectorManagedSSHConnectDirect(server string) (*sshlib.Con
 	return r.createConnectorManagedSSHConnect(server, r.effectiveServerConfig(server, true))
 }
 
-func (r *Run) runConnectorManagedSSHTransportShell(server string, config conf.ServerConfig) error {
+func (r *Run) runConnectorManagedSSHTransportShell(server string, config conf.ServerConfig, rec *asciicast.Recorder) error {
 	connect, err := r.createConnectorManagedSSHConnect(server, config)
 	if err != nil {
 		return err
@@ -150,11 +148,12 @@ func (r *Run) runConnectorManagedSSHTransportShell(server string, config conf.Se
 		connect.Agent = r.agent
 	}
 
-	if connectorLocalRCEnabled(r, config) {
-		return localrcShell(connect, nil, config.LocalRcPath, config.LocalRcDecodeCmd, config.LocalRcCompress, config.LocalRcUncompressCmd)
+	if rec != nil {
+		return recordShell(connect, rec, r.Conf.Log.RecordInput, func() error {
+			return remoteShell(connect, nil, server, config, connectorLocalRCEnabled(r, config))
+		})
 	}
-
-	return connect.Shell(nil)
+	return remoteShell(connect, nil, server, config, connectorLocalRCEnabled(r, config))
 }
 
 func (r *Run) runConnectorManagedSSHTransportExec(server string, config conf.ServerConfig, command []string, commandLine string, stdin io.Reader, stdout, stderr io.Writer, tty bool) (int, error) {
@@ -176,7 +175,17 @@ func (r *Run) runConnectorManagedSSHTransportExec(server string, config conf.Ser
 	connect.Stderr = stderr
 	connect.TTY = tty
 
-	return 0, connect.Command(commandLine)
+	env := config.RemoteEnv()
+	if len(env) > 0 && !connect.IsControlClient() {
+		connect.Session, err = connect.CreateSession()
+		if err != nil {
+			return 0, err
+		}
+	}
+	env, notice := ExportRefusedRemoteEnv(server, config, RequestRemoteEnv(connect.Session, env))
+	warnRemoteEnv(os.Stderr, notice)
+
+	return 0, connect.Command(RemoteEnvCommand(env, commandLine))
 }
 
 func (r *Run) runConnectorManagedSSHLocalPortForward(server string, config conf.ServerConfig) error {
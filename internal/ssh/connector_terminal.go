@@ -7,7 +7,12 @@ import (
 )
 
 func runNativeInteractiveSession(run func() error) error {
-	fd := int(os.Stdin.Fd())
+	return runNativeInteractiveSessionOn(os.Stdin, run)
+}
+
+// runNativeInteractiveSessionOn runs run with tty in raw mode.
+func runNativeInteractiveSessionOn(tty *os.File, run func() error) error {
+	fd := int(tty.Fd())
 	if !terminal.IsTerminal(fd) {
 		return run()
 	}
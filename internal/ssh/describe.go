@@ -0,0 +1,74 @@
+// Copyright (c) 2026 Blacknon. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package ssh
+
+import (
+	"strings"
+
+	conf "github.com/blacknon/lssh/internal/config"
+)
+
+// DescribeProxyRoute returns the proxy hops to server, first hop first, as
+// they are connected (ex. `ssh bastion:22`, `socks5 corp:1080`,
+// `command ssh -W %h:%p jump`).
+func DescribeProxyRoute(server string, config conf.Config) ([]string, error) {
+	route, err := getProxyRoute(server, config)
+	if err != nil {
+		return nil, err
+	}
+
+	hops := make([]string, 0, len(route))
+	for _, proxy := range route {
+		hop := proxy.Type + " " + proxy.Name
+		if proxy.Type != "command" && proxy.Port != "" {
+			hop += ":" + proxy.Port
+		}
+		hops = append(hops, hop)
+	}
+	return hops, nil
+}
+
+// AuthMethodNames returns the auth methods CreateAuthMethodMap tries for
+// config, in the same order. Secrets are not included.
+func AuthMethodNames(config conf.ServerConfig) []string {
+	var methods []string
+
+	if config.Pass != "" || config.PassRef != "" {
+		methods = append(methods, "password")
+	}
+	for range config.Passes {
+		methods = append(methods, "password")
+	}
+	if config.Key != "" || config.KeyRef != "" {
+		methods = append(methods, "publickey "+authSourceName(config.Key, config.KeyRef))
+	}
+	for _, key := range config.Keys {
+		methods = append(methods, "publickey "+strings.SplitN(key, "::", 2)[0])
+	}
+	if config.KeyCommand != "" {
+		methods = append(methods, "publickey command")
+	}
+	if config.Cert != "" || config.CertRef != "" {
+		methods = append(methods, "certificate "+authSourceName(config.Cert, config.CertRef))
+	}
+	for _, cert := range config.Certs {
+		methods = append(methods, "certificate "+strings.SplitN(cert, "::", 2)[0])
+	}
+	if config.PKCS11Use {
+		methods = append(methods, "pkcs11 "+config.PKCS11Provider)
+	}
+	if config.SSHAgentUse {
+		methods = append(methods, "ssh-agent")
+	}
+
+	return methods
+}
+
+func authSourceName(path, ref string) string {
+	if ref != "" {
+		return ref
+	}
+	return path
+}
@@ -0,0 +1,574 @@
+// Copyright (c) 2026 Blacknon. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package ssh
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/blacknon/go-sshlib"
+	conf "github.com/blacknon/lssh/internal/config"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+	"golang.org/x/net/proxy"
+	"golang.org/x/term"
+)
+
+var (
+	// hostKeyOutput is where host key warnings are written.
+	hostKeyOutput io.Writer = os.Stderr
+
+	// hostKeyTerminal opens the terminal to ask whether to trust an unknown host key.
+	hostKeyTerminal = openHostKeyTerminal
+
+	// hostKeyMutex serializes the prompts and known_hosts updates of parallel connections.
+	hostKeyMutex sync.Mutex
+)
+
+// hostKeyChecker checks the host key of a server by its strict_host_key_checking
+// policy, host_key_fingerprints and known_hosts files.
+type hostKeyChecker struct {
+	server       string
+	policy       string
+	fingerprints []string
+	files        []string
+}
+
+// newHostKeyCallback returns the ssh.HostKeyCallback that checks the host key
+// of server. It returns nil if the host key is not checked.
+func newHostKeyCallback(server string, config conf.ServerConfig) (ssh.HostKeyCallback, error) {
+	checker, err := newHostKeyChecker(server, config)
+	if err != nil || checker == nil {
+		return nil, err
+	}
+	return checker.check, nil
+}
+
+// newHostKeyChecker returns the hostKeyChecker of server. It returns nil if
+// the host key is not checked.
+func newHostKeyChecker(server string, config conf.ServerConfig) (*hostKeyChecker, error) {
+	policy, err := config.HostKeyChecking()
+	if err != nil {
+		return nil, fmt.Errorf("server %q: %w", server, err)
+	}
+	if err := config.CheckHostKeyFingerprints(); err != nil {
+		return nil, fmt.Errorf("server %q: host_key_fingerprints: %w", server, err)
+	}
+	if policy == conf.HostKeyCheckingNo && len(config.HostKeyFingerprints) == 0 {
+		return nil, nil
+	}
+
+	files := config.KnownHostsFiles
+	if len(files) == 0 {
+		files = []string{"~/.ssh/known_hosts"}
+	}
+
+	checker := &hostKeyChecker{server: server, policy: policy}
+	for _, file := range files {
+		checker.files = append(checker.files, expandKnownHostsPath(file))
+	}
+	for _, fingerprint := range config.HostKeyFingerprints {
+		checker.fingerprints = append(checker.fingerprints, strings.TrimRight(strings.TrimSpace(fingerprint), "="))
+	}
+
+	return checker, nil
+}
+
+// createClient connects connect to host:port of server without ControlMaster.
+// See createHostKeyCheckedClient.
+func (r *Run) createClient(connect *sshlib.Connect, server, host, port, user string) error {
+	return createHostKeyCheckedClient(connect, server, r.Conf.Server[server], host, port, user, r.serverAuthMethodMap[server])
+}
+
+// createHostKeyCheckedClient connects connect to host:port like
+// connect.CreateClient does without ControlMaster, and checks the host key by
+// the policy of server. sshlib can not set the host key algorithms, so the
+// connection is made here when the host key is checked.
+func createHostKeyCheckedClient(connect *sshlib.Connect, server string, config conf.ServerConfig, host, port, user string, authMethods []ssh.AuthMethod) error {
+	checker, err := newHostKeyChecker(server, config)
+	if err != nil {
+		return err
+	}
+	if checker == nil {
+		return connect.CreateClient(host, port, user, authMethods)
+	}
+
+	dialer := connect.ProxyDialer
+	if dialer == nil {
+		dialer = proxy.Direct
+	}
+	if connect.ConnectTimeout == 0 {
+		connect.ConnectTimeout = 20
+	}
+	timeout := time.Duration(connect.ConnectTimeout) * time.Second
+
+	addr := net.JoinHostPort(host, port)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	_ = conn.SetDeadline(time.Now().Add(timeout))
+
+	sshConfig := checker.clientConfig(user, addr, conn.RemoteAddr())
+	sshConfig.Auth = authMethods
+	c, chans, reqs, err := ssh.NewClientConn(conn, addr, sshConfig)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	_ = conn.SetDeadline(time.Time{})
+
+	connect.Client = ssh.NewClient(c, chans, reqs)
+	return nil
+}
+
+// clientConfig returns the ssh.ClientConfig that checks the host key of addr.
+func (h *hostKeyChecker) clientConfig(user, addr string, remote net.Addr) *ssh.ClientConfig {
+	return &ssh.ClientConfig{
+		User:              user,
+		HostKeyCallback:   h.check,
+		HostKeyAlgorithms: h.hostKeyAlgorithms(addr, remote),
+	}
+}
+
+// hostKeyAlgorithms returns the host key algorithms to offer to hostname. Like
+// OpenSSH, the algorithms of the key types known for hostname in known_hosts
+// are offered first, so that the server sends a key that can be checked. It
+// returns nil (the default algorithms) if no key of hostname is known.
+func (h *hostKeyChecker) hostKeyAlgorithms(hostname string, remote net.Addr) []string {
+	if len(h.fingerprints) > 0 {
+		return nil
+	}
+
+	db, err := readKnownHostsMarkers(h.files)
+	if err != nil || len(db.files) == 0 {
+		return nil
+	}
+	callback, err := knownhosts.New(db.files...)
+	if err != nil {
+		return nil
+	}
+
+	// a key that is never known lists the known keys of hostname.
+	var keyErr *knownhosts.KeyError
+	if !errors.As(callback(hostname, remote, unknownHostKey{}), &keyErr) {
+		return nil
+	}
+
+	// the algorithms that golang.org/x/crypto/ssh offers by default.
+	supported := append(ssh.SupportedAlgorithms().HostKeys, ssh.InsecureAlgorithms().HostKeys...)
+	preferred := []string{}
+	for _, want := range keyErr.Want {
+		var algorithms []string
+		switch {
+		case db.isAuthority(want.Key):
+			for _, algorithm := range supported {
+				if strings.Contains(algorithm, "-cert-v01@") {
+					algorithms = append(algorithms, algorithm)
+				}
+			}
+		case want.Key.Type() == ssh.KeyAlgoRSA:
+			algorithms = []string{ssh.KeyAlgoRSASHA512, ssh.KeyAlgoRSASHA256, ssh.KeyAlgoRSA}
+		default:
+			algorithms = []string{want.Key.Type()}
+		}
+		for _, algorithm := range algorithms {
+			if slices.Contains(supported, algorithm) && !slices.Contains(preferred, algorithm) {
+				preferred = append(preferred, algorithm)
+			}
+		}
+	}
+	if len(preferred) == 0 {
+		return nil
+	}
+
+	for _, algorithm := range supported {
+		if !slices.Contains(preferred, algorithm) {
+			preferred = append(preferred, algorithm)
+		}
+	}
+	return preferred
+}
+
+// unknownHostKey is a host key that is not in any known_hosts file.
+type unknownHostKey struct{}
+
+func (unknownHostKey) Type() string                        { return "lssh-unknown" }
+func (unknownHostKey) Marshal() []byte                     { return []byte("lssh-unknown") }
+func (unknownHostKey) Verify([]byte, *ssh.Signature) error { return errors.New("unknown host key") }
+
+// controlMasterSkipsHostKeyCheck reports whether the detached ControlMaster
+// helper of server would skip a host key check that is configured. The helper
+// only checks known_hosts of server when it connects or reconnects, so
+// host_key_fingerprints of server and the host keys of ssh proxy hops are not
+// checked by it.
+func controlMasterSkipsHostKeyCheck(server string, config conf.Config) bool {
+	if len(config.Server[server].HostKeyFingerprints) > 0 {
+		return true
+	}
+
+	proxyRoute, _ := getProxyRoute(server, config)
+	for _, p := range proxyRoute {
+		if p.Type != "ssh" {
+			continue
+		}
+		hop := config.Server[p.Name]
+		policy, err := hop.HostKeyChecking()
+		if err != nil || policy != conf.HostKeyCheckingNo || len(hop.HostKeyFingerprints) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// setControlMasterHostKeyCheck sets the host key check of a connect that
+// starts a detached ControlMaster. The detached helper can only check
+// known_hosts, so the host key is checked here by the policy of server before
+// the helper is spawned, and the helper checks it again against known_hosts.
+// Servers that the helper can not check are connected without ControlMaster
+// (see controlMasterSkipsHostKeyCheck).
+func (r *Run) setControlMasterHostKeyCheck(connect *sshlib.Connect, server string, config conf.ServerConfig, dialer sshlib.ProxyDialer) error {
+	checker, err := newHostKeyChecker(server, config)
+	if err != nil || checker == nil {
+		return err
+	}
+
+	// the master is already running, and its host key was checked when it started.
+	if _, err := os.Stat(connect.ControlPath); err == nil {
+		return nil
+	}
+
+	port := config.Port
+	if port == "" {
+		port = "22"
+	}
+	if err := preflightHostKey(dialer, net.JoinHostPort(config.Addr, port), config.User, config.ConnectTimeout, checker); err != nil {
+		return err
+	}
+
+	connect.CheckKnownHosts = true
+	connect.KnownHostsFiles = append([]string(nil), config.KnownHostsFiles...)
+	return nil
+}
+
+// preflightHostKey connects to addr only to check its host key with checker.
+func preflightHostKey(dialer sshlib.ProxyDialer, addr, user string, timeout int, checker *hostKeyChecker) error {
+	conn, err := dialer.Dial("tcp", addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	var hostKeyErr error
+	checked := false
+	config := checker.clientConfig(user, addr, conn.RemoteAddr())
+	config.HostKeyCallback = func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		checked = true
+		hostKeyErr = checker.check(hostname, remote, key)
+		return hostKeyErr
+	}
+	if timeout > 0 {
+		_ = conn.SetDeadline(time.Now().Add(time.Duration(timeout) * time.Second))
+	}
+
+	// no auth method is offered, so the connection ends after the key exchange.
+	c, chans, reqs, err := ssh.NewClientConn(conn, addr, config)
+	if err == nil {
+		ssh.NewClient(c, chans, reqs).Close()
+	}
+	if hostKeyErr != nil {
+		return hostKeyErr
+	}
+	if !checked {
+		return err
+	}
+	return nil
+}
+
+func (h *hostKeyChecker) check(hostname string, remote net.Addr, key ssh.PublicKey) error {
+	plain := key
+	cert, isCert := key.(*ssh.Certificate)
+	if isCert {
+		plain = cert.Key
+	}
+	fingerprint := ssh.FingerprintSHA256(plain)
+
+	db, err := readKnownHostsMarkers(h.files)
+	if err != nil {
+		return fmt.Errorf("host key verification failed for %s: %w", h.server, err)
+	}
+	if db.isRevoked(key) {
+		return fmt.Errorf("host key verification failed for %s: the %s host key %s is marked as revoked", h.server, plain.Type(), fingerprint)
+	}
+
+	if len(h.fingerprints) > 0 {
+		for _, pinned := range h.fingerprints {
+			if pinned == fingerprint || pinned == ssh.FingerprintSHA256(key) {
+				return nil
+			}
+		}
+		return fmt.Errorf("host key verification failed for %s: the %s host key %s does not match host_key_fingerprints", h.server, plain.Type(), fingerprint)
+	}
+
+	err = h.checkKnownHosts(db, hostname, remote, key)
+	var keyErr *knownhosts.KeyError
+	switch {
+	case err == nil:
+		return nil
+	case !errors.As(err, &keyErr):
+		return fmt.Errorf("host key verification failed for %s: %w", h.server, err)
+	}
+
+	// a key of another type is not a changed key, since it depends on the
+	// negotiated algorithm. @cert-authority lines are also reported as wanted.
+	others := []knownhosts.KnownKey{}
+	for _, want := range keyErr.Want {
+		if db.isAuthority(want.Key) {
+			continue
+		}
+		if want.Key.Type() == plain.Type() {
+			h.writeChangedWarning(plain, want)
+			return fmt.Errorf("host key verification failed for %s: remote host identification has changed", h.server)
+		}
+		others = append(others, want)
+	}
+
+	return h.addUnknown(hostname, remote, key, others)
+}
+
+// checkKnownHosts checks key against the known_hosts files. A certificate
+// that is not signed by any @cert-authority is checked as a plain key.
+func (h *hostKeyChecker) checkKnownHosts(db knownHostsMarkers, hostname string, remote net.Addr, key ssh.PublicKey) error {
+	if len(db.files) == 0 {
+		return &knownhosts.KeyError{}
+	}
+
+	callback, err := knownhosts.New(db.files...)
+	if err != nil {
+		return err
+	}
+
+	if cert, ok := key.(*ssh.Certificate); ok && !db.isAuthority(cert.SignatureKey) {
+		key = cert.Key
+	}
+	return callback(hostname, remote, key)
+}
+
+// addUnknown trusts an unknown host key by the policy, and adds it to the
+// first known_hosts file.
+func (h *hostKeyChecker) addUnknown(hostname string, remote net.Addr, key ssh.PublicKey, others []knownhosts.KnownKey) error {
+	hostKeyMutex.Lock()
+	defer hostKeyMutex.Unlock()
+
+	// another connection may have added it while waiting for the lock.
+	if db, err := readKnownHostsMarkers(h.files); err == nil {
+		if h.checkKnownHosts(db, hostname, remote, key) == nil {
+			return nil
+		}
+	}
+
+	if cert, ok := key.(*ssh.Certificate); ok {
+		key = cert.Key
+	}
+	fingerprint := ssh.FingerprintSHA256(key)
+
+	switch h.policy {
+	case conf.HostKeyCheckingYes:
+		return fmt.Errorf("host key verification failed for %s: no %s host key is known for %s (%s) and strict_host_key_checking is yes", h.server, key.Type(), knownhosts.Normalize(hostname), fingerprint)
+	case conf.HostKeyCheckingAcceptNew:
+		// accept-new only adds the keys of new hosts.
+		if len(others) > 0 {
+			return fmt.Errorf("host key verification failed for %s: %s is known by other key types (%s), not by the %s host key %s, and strict_host_key_checking is accept-new", h.server, knownhosts.Normalize(hostname), knownKeyTypes(others), key.Type(), fingerprint)
+		}
+	case conf.HostKeyCheckingAsk:
+		ok, err := h.ask(hostname, remote, key, others)
+		if err != nil {
+			return fmt.Errorf("host key verification failed for %s: %w", h.server, err)
+		}
+		if !ok {
+			return fmt.Errorf("host key verification failed for %s: the host key was not accepted", h.server)
+		}
+	}
+
+	if err := appendKnownHost(h.files[0], hostname, key); err != nil {
+		return fmt.Errorf("host key verification failed for %s: %w", h.server, err)
+	}
+	fmt.Fprintf(hostKeyOutput, "Warning: Permanently added '%s' (%s) to the list of known hosts.\n", knownhosts.Normalize(hostname), key.Type())
+	return nil
+}
+
+// ask asks on the terminal whether to trust the unknown host key.
+func (h *hostKeyChecker) ask(hostname string, remote net.Addr, key ssh.PublicKey, others []knownhosts.KnownKey) (bool, error) {
+	tty, err := hostKeyTerminal()
+	if err != nil {
+		return false, err
+	}
+	defer tty.Close()
+
+	fingerprint := ssh.FingerprintSHA256(key)
+	fmt.Fprintf(tty, "The authenticity of host '%s' (%s) can't be established.\n", h.server, remote)
+	fmt.Fprintf(tty, "%s key fingerprint is %s.\n", key.Type(), fingerprint)
+	if len(others) > 0 {
+		fmt.Fprintf(tty, "This host key is known by other key types: %s.\n", knownKeyTypes(others))
+	}
+	fmt.Fprint(tty, "Are you sure you want to continue connecting (yes/no/[fingerprint])? ")
+
+	reader := bufio.NewReader(tty)
+	for {
+		line, err := reader.ReadString('\n')
+		answer := strings.TrimSpace(line)
+		switch {
+		case strings.EqualFold(answer, "yes"), answer == fingerprint:
+			return true, nil
+		case strings.EqualFold(answer, "no"):
+			return false, nil
+		case err != nil:
+			return false, err
+		}
+		fmt.Fprint(tty, "Please type 'yes', 'no' or the fingerprint: ")
+	}
+}
+
+func knownKeyTypes(keys []knownhosts.KnownKey) string {
+	types := make([]string, 0, len(keys))
+	for _, key := range keys {
+		types = append(types, key.Key.Type())
+	}
+	return strings.Join(types, ", ")
+}
+
+func (h *hostKeyChecker) writeChangedWarning(key ssh.PublicKey, want knownhosts.KnownKey) {
+	fmt.Fprintln(hostKeyOutput, "@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@")
+	fmt.Fprintln(hostKeyOutput, "@    WARNING: REMOTE HOST IDENTIFICATION HAS CHANGED!     @")
+	fmt.Fprintln(hostKeyOutput, "@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@")
+	fmt.Fprintln(hostKeyOutput, "IT IS POSSIBLE THAT SOMEONE IS DOING SOMETHING NASTY!")
+	fmt.Fprintln(hostKeyOutput, "Someone could be eavesdropping on you right now (man-in-the-middle attack)!")
+	fmt.Fprintln(hostKeyOutput, "It is also possible that a host key has just been changed.")
+	fmt.Fprintf(hostKeyOutput, "The fingerprint for the %s key sent by the remote host '%s' is\n", key.Type(), h.server)
+	fmt.Fprintf(hostKeyOutput, "%s.\n", ssh.FingerprintSHA256(key))
+	fmt.Fprintf(hostKeyOutput, "Offending %s key in %s:%d\n", want.Key.Type(), want.Filename, want.Line)
+	fmt.Fprintf(hostKeyOutput, "Host key for %s has changed and strict checking is enabled.\n", h.server)
+}
+
+// knownHostsMarkers is the @cert-authority and @revoked keys of the
+// known_hosts files that exist.
+type knownHostsMarkers struct {
+	files       []string
+	authorities [][]byte
+	revoked     [][]byte
+}
+
+func readKnownHostsMarkers(files []string) (db knownHostsMarkers, err error) {
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return db, err
+		}
+		db.files = append(db.files, file)
+
+		for len(data) > 0 {
+			var marker string
+			var key ssh.PublicKey
+			marker, _, key, _, data, err = ssh.ParseKnownHosts(data)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return db, fmt.Errorf("%s: %w", file, err)
+			}
+
+			switch marker {
+			case "cert-authority":
+				db.authorities = append(db.authorities, key.Marshal())
+			case "revoked":
+				db.revoked = append(db.revoked, key.Marshal())
+			}
+		}
+	}
+	return db, nil
+}
+
+func (db knownHostsMarkers) isAuthority(key ssh.PublicKey) bool {
+	return containsKey(db.authorities, key)
+}
+
+// isRevoked reports whether key, or the key or the CA of a certificate, is
+// marked as @revoked.
+func (db knownHostsMarkers) isRevoked(key ssh.PublicKey) bool {
+	if cert, ok := key.(*ssh.Certificate); ok {
+		return containsKey(db.revoked, cert) || containsKey(db.revoked, cert.Key) || containsKey(db.revoked, cert.SignatureKey)
+	}
+	return containsKey(db.revoked, key)
+}
+
+func containsKey(keys [][]byte, key ssh.PublicKey) bool {
+	data := key.Marshal()
+	for _, k := range keys {
+		if bytes.Equal(k, data) {
+			return true
+		}
+	}
+	return false
+}
+
+func appendKnownHost(file, hostname string, key ssh.PublicKey) error {
+	if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(file, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = fmt.Fprintln(f, knownhosts.Line([]string{knownhosts.Normalize(hostname)}, key))
+	return err
+}
+
+func expandKnownHostsPath(path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, strings.TrimPrefix(path, "~"))
+		}
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
+
+// stdioTerminal prompts on stderr and reads the answer from stdin.
+type stdioTerminal struct{}
+
+func (stdioTerminal) Read(p []byte) (int, error)  { return os.Stdin.Read(p) }
+func (stdioTerminal) Write(p []byte) (int, error) { return os.Stderr.Write(p) }
+func (stdioTerminal) Close() error                { return nil }
+
+func openHostKeyTerminal() (io.ReadWriteCloser, error) {
+	if tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0); err == nil {
+		return tty, nil
+	}
+	if term.IsTerminal(int(os.Stdin.Fd())) {
+		return stdioTerminal{}, nil
+	}
+	return nil, errors.New("no terminal to confirm the unknown host key; add it to known_hosts or set strict_host_key_checking")
+}
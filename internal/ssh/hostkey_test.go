@@ -0,0 +1,417 @@
+package ssh
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/blacknon/go-sshlib"
+	conf "github.com/blacknon/lssh/internal/config"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+var testRemote = &net.TCPAddr{IP: net.ParseIP("192.0.2.10"), Port: 22}
+
+type testTerminal struct {
+	io.Reader
+	bytes.Buffer
+}
+
+func (t *testTerminal) Read(p []byte) (int, error) { return t.Reader.Read(p) }
+func (t *testTerminal) Close() error               { return nil }
+
+func testHostKeyOutput(t *testing.T, answer string) (*bytes.Buffer, *testTerminal) {
+	t.Helper()
+
+	var output bytes.Buffer
+	tty := &testTerminal{Reader: strings.NewReader(answer)}
+	hostKeyOutput = &output
+	hostKeyTerminal = func() (io.ReadWriteCloser, error) { return tty, nil }
+	t.Cleanup(func() {
+		hostKeyOutput = os.Stderr
+		hostKeyTerminal = openHostKeyTerminal
+	})
+	return &output, tty
+}
+
+func testSigner(t *testing.T) ssh.Signer {
+	t.Helper()
+
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	signer, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatalf("new signer: %v", err)
+	}
+	return signer
+}
+
+func testHostKeyCallback(t *testing.T, config conf.ServerConfig) ssh.HostKeyCallback {
+	t.Helper()
+
+	callback, err := newHostKeyCallback("web", config)
+	if err != nil || callback == nil {
+		t.Fatalf("newHostKeyCallback() = %v, %v", callback, err)
+	}
+	return callback
+}
+
+func writeKnownHosts(t *testing.T, lines ...string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "known_hosts")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0600); err != nil {
+		t.Fatalf("write known_hosts: %v", err)
+	}
+	return path
+}
+
+func TestHostKeyCallbackNo(t *testing.T) {
+	callback, err := newHostKeyCallback("web", conf.ServerConfig{})
+	if err != nil || callback != nil {
+		t.Fatalf("newHostKeyCallback() = %v, %v, want no check", callback, err)
+	}
+
+	if _, err := newHostKeyCallback("web", conf.ServerConfig{StrictHostKeyChecking: "maybe"}); err == nil {
+		t.Fatal("newHostKeyCallback() accepts an unknown policy")
+	}
+}
+
+func TestHostKeyCallbackAskAddsKnownHost(t *testing.T) {
+	output, tty := testHostKeyOutput(t, "maybe\nyes\n")
+	path := filepath.Join(t.TempDir(), "ssh", "known_hosts")
+	key := testSigner(t).PublicKey()
+
+	callback := testHostKeyCallback(t, conf.ServerConfig{CheckKnownHosts: true, KnownHostsFiles: []string{path}})
+	if err := callback("web.example.com:22", testRemote, key); err != nil {
+		t.Fatalf("callback() error = %v", err)
+	}
+
+	prompt := tty.Buffer.String()
+	if !strings.Contains(prompt, ssh.FingerprintSHA256(key)) || !strings.Contains(prompt, "Please type 'yes', 'no' or the fingerprint") {
+		t.Fatalf("prompt = %q", prompt)
+	}
+	if !strings.Contains(output.String(), "Permanently added 'web.example.com'") {
+		t.Fatalf("output = %q", output.String())
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read known_hosts: %v", err)
+	}
+	if want := knownhosts.Line([]string{"web.example.com"}, key) + "\n"; string(data) != want {
+		t.Fatalf("known_hosts = %q, want %q", data, want)
+	}
+
+	// the added key is trusted without asking again
+	tty.Reader = strings.NewReader("")
+	tty.Buffer.Reset()
+	if err := callback("web.example.com:22", testRemote, key); err != nil || tty.Buffer.Len() != 0 {
+		t.Fatalf("callback() error = %v, prompt = %q", err, tty.Buffer.String())
+	}
+}
+
+func TestHostKeyCallbackAskRejected(t *testing.T) {
+	testHostKeyOutput(t, "no\n")
+	path := filepath.Join(t.TempDir(), "known_hosts")
+
+	callback := testHostKeyCallback(t, conf.ServerConfig{StrictHostKeyChecking: "ask", KnownHostsFiles: []string{path}})
+	if err := callback("web.example.com:22", testRemote, testSigner(t).PublicKey()); err == nil {
+		t.Fatal("callback() accepts a rejected host key")
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("known_hosts is written: %v", err)
+	}
+}
+
+func TestHostKeyCallbackYesRejectsUnknown(t *testing.T) {
+	_, tty := testHostKeyOutput(t, "yes\n")
+	path := filepath.Join(t.TempDir(), "known_hosts")
+
+	callback := testHostKeyCallback(t, conf.ServerConfig{StrictHostKeyChecking: "yes", KnownHostsFiles: []string{path}})
+	err := callback("web.example.com:2222", testRemote, testSigner(t).PublicKey())
+	if err == nil || !strings.Contains(err.Error(), "[web.example.com]:2222") {
+		t.Fatalf("callback() error = %v", err)
+	}
+	if tty.Buffer.Len() != 0 {
+		t.Fatalf("asked with strict_host_key_checking = yes: %q", tty.Buffer.String())
+	}
+}
+
+func TestHostKeyCallbackChangedKey(t *testing.T) {
+	output, _ := testHostKeyOutput(t, "yes\n")
+	known := testSigner(t).PublicKey()
+	path := writeKnownHosts(t, knownhosts.Line([]string{"web.example.com"}, known))
+	key := testSigner(t).PublicKey()
+
+	callback := testHostKeyCallback(t, conf.ServerConfig{StrictHostKeyChecking: "accept-new", KnownHostsFiles: []string{path}})
+	err := callback("web.example.com:22", testRemote, key)
+	if err == nil || !strings.Contains(err.Error(), "remote host identification has changed") {
+		t.Fatalf("callback() error = %v", err)
+	}
+	for _, want := range []string{"REMOTE HOST IDENTIFICATION HAS CHANGED", ssh.FingerprintSHA256(key), path + ":1"} {
+		if !strings.Contains(output.String(), want) {
+			t.Fatalf("warning does not contain %q: %q", want, output.String())
+		}
+	}
+}
+
+func TestHostKeyCallbackAcceptNewOtherKeyType(t *testing.T) {
+	testHostKeyOutput(t, "")
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	known, err := ssh.NewPublicKey(&ecdsaKey.PublicKey)
+	if err != nil {
+		t.Fatalf("new public key: %v", err)
+	}
+	path := writeKnownHosts(t, knownhosts.Line([]string{"web.example.com"}, known))
+
+	callback := testHostKeyCallback(t, conf.ServerConfig{StrictHostKeyChecking: "accept-new", KnownHostsFiles: []string{path}})
+	err = callback("web.example.com:22", testRemote, testSigner(t).PublicKey())
+	if err == nil || !strings.Contains(err.Error(), "known by other key types (ecdsa-sha2-nistp256)") {
+		t.Fatalf("callback() error = %v, want the key of another type rejected", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != knownhosts.Line([]string{"web.example.com"}, known)+"\n" {
+		t.Fatalf("known_hosts = %q, want it unchanged", data)
+	}
+}
+
+func TestHostKeyCallbackFingerprints(t *testing.T) {
+	testHostKeyOutput(t, "")
+	key := testSigner(t).PublicKey()
+
+	callback := testHostKeyCallback(t, conf.ServerConfig{
+		KnownHostsFiles:     []string{filepath.Join(t.TempDir(), "known_hosts")},
+		HostKeyFingerprints: []string{ssh.FingerprintSHA256(key)},
+	})
+	if err := callback("web.example.com:22", testRemote, key); err != nil {
+		t.Fatalf("callback() error = %v", err)
+	}
+	if err := callback("web.example.com:22", testRemote, testSigner(t).PublicKey()); err == nil || !strings.Contains(err.Error(), "host_key_fingerprints") {
+		t.Fatalf("callback() error = %v, want a fingerprint mismatch", err)
+	}
+}
+
+func TestHostKeyCallbackRevoked(t *testing.T) {
+	testHostKeyOutput(t, "")
+	key := testSigner(t).PublicKey()
+	path := writeKnownHosts(t,
+		knownhosts.Line([]string{"web.example.com"}, key),
+		"@revoked * "+strings.TrimSpace(string(ssh.MarshalAuthorizedKey(key))),
+	)
+
+	callback := testHostKeyCallback(t, conf.ServerConfig{
+		StrictHostKeyChecking: "yes",
+		KnownHostsFiles:       []string{path},
+		HostKeyFingerprints:   []string{ssh.FingerprintSHA256(key)},
+	})
+	if err := callback("web.example.com:22", testRemote, key); err == nil || !strings.Contains(err.Error(), "revoked") {
+		t.Fatalf("callback() error = %v, want revoked", err)
+	}
+}
+
+func TestHostKeyCallbackCertAuthority(t *testing.T) {
+	testHostKeyOutput(t, "")
+	ca := testSigner(t)
+	hostKey := testSigner(t).PublicKey()
+
+	cert := &ssh.Certificate{
+		Key:             hostKey,
+		CertType:        ssh.HostCert,
+		ValidPrincipals: []string{"web.example.com"},
+		ValidBefore:     ssh.CertTimeInfinity,
+	}
+	if err := cert.SignCert(rand.Reader, ca); err != nil {
+		t.Fatalf("sign cert: %v", err)
+	}
+
+	caLine := "@cert-authority *.example.com " + strings.TrimSpace(string(ssh.MarshalAuthorizedKey(ca.PublicKey())))
+	path := writeKnownHosts(t, caLine)
+	callback := testHostKeyCallback(t, conf.ServerConfig{StrictHostKeyChecking: "yes", KnownHostsFiles: []string{path}})
+	if err := callback("web.example.com:22", testRemote, cert); err != nil {
+		t.Fatalf("callback() error = %v", err)
+	}
+
+	// a plain key of the same type as the CA is not a changed key
+	if err := callback("web.example.com:22", testRemote, hostKey); err == nil || strings.Contains(err.Error(), "changed") {
+		t.Fatalf("callback() error = %v, want an unknown key", err)
+	}
+
+	revoked := writeKnownHosts(t, caLine, "@revoked * "+strings.TrimSpace(string(ssh.MarshalAuthorizedKey(ca.PublicKey()))))
+	callback = testHostKeyCallback(t, conf.ServerConfig{StrictHostKeyChecking: "yes", KnownHostsFiles: []string{revoked}})
+	if err := callback("web.example.com:22", testRemote, cert); err == nil || !strings.Contains(err.Error(), "revoked") {
+		t.Fatalf("callback() error = %v, want the revoked CA rejected", err)
+	}
+}
+
+// testSSHServer starts an ssh server with the host keys of signers, and
+// returns its address.
+func testSSHServer(t *testing.T, signers ...ssh.Signer) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	config := &ssh.ServerConfig{PasswordCallback: func(ssh.ConnMetadata, []byte) (*ssh.Permissions, error) {
+		return nil, nil
+	}}
+	for _, signer := range signers {
+		config.AddHostKey(signer)
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				sconn, chans, reqs, err := ssh.NewServerConn(conn, config)
+				if err != nil {
+					return
+				}
+				go ssh.DiscardRequests(reqs)
+				go func() {
+					for ch := range chans {
+						ch.Reject(ssh.Prohibited, "test server")
+					}
+				}()
+				_ = sconn.Wait()
+			}()
+		}
+	}()
+
+	return listener.Addr().String()
+}
+
+func testRSASigner(t *testing.T) ssh.Signer {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatalf("new signer: %v", err)
+	}
+	return signer
+}
+
+func testConnect(t *testing.T, addr string, config conf.ServerConfig) error {
+	t.Helper()
+
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		t.Fatalf("split %s: %v", addr, err)
+	}
+	connect := &sshlib.Connect{ConnectTimeout: 5}
+	err = createHostKeyCheckedClient(connect, "web", config, host, port, "user", []ssh.AuthMethod{ssh.Password("pass")})
+	if err == nil {
+		connect.Client.Close()
+	}
+	return err
+}
+
+func TestPreflightHostKey(t *testing.T) {
+	testHostKeyOutput(t, "")
+	signer := testSigner(t)
+	addr := testSSHServer(t, signer)
+
+	path := writeKnownHosts(t, knownhosts.Line([]string{addr}, signer.PublicKey()))
+	checker, _ := newHostKeyChecker("web", conf.ServerConfig{StrictHostKeyChecking: "yes", KnownHostsFiles: []string{path}})
+	if err := preflightHostKey(&net.Dialer{}, addr, "user", 5, checker); err != nil {
+		t.Fatalf("preflightHostKey() error = %v", err)
+	}
+
+	path = writeKnownHosts(t, knownhosts.Line([]string{addr}, testSigner(t).PublicKey()))
+	checker, _ = newHostKeyChecker("web", conf.ServerConfig{StrictHostKeyChecking: "yes", KnownHostsFiles: []string{path}})
+	if err := preflightHostKey(&net.Dialer{}, addr, "user", 5, checker); err == nil || !strings.Contains(err.Error(), "changed") {
+		t.Fatalf("preflightHostKey() error = %v, want a changed key", err)
+	}
+}
+
+func TestCreateHostKeyCheckedClientPrefersKnownKeyType(t *testing.T) {
+	testHostKeyOutput(t, "")
+	rsaSigner := testRSASigner(t)
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	ecdsaSigner, err := ssh.NewSignerFromKey(ecdsaKey)
+	if err != nil {
+		t.Fatalf("new signer: %v", err)
+	}
+
+	// the server has an ed25519 and an ecdsa key, which are preferred to RSA
+	// by default, but the host is known only by its RSA key.
+	addr := testSSHServer(t, testSigner(t), ecdsaSigner, rsaSigner)
+	path := writeKnownHosts(t, knownhosts.Line([]string{addr}, rsaSigner.PublicKey()))
+
+	if err := testConnect(t, addr, conf.ServerConfig{StrictHostKeyChecking: "yes", KnownHostsFiles: []string{path}}); err != nil {
+		t.Fatalf("strict_host_key_checking = yes: error = %v", err)
+	}
+
+	if err := testConnect(t, addr, conf.ServerConfig{StrictHostKeyChecking: "accept-new", KnownHostsFiles: []string{path}}); err != nil {
+		t.Fatalf("strict_host_key_checking = accept-new: error = %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read known_hosts: %v", err)
+	}
+	if want := knownhosts.Line([]string{addr}, rsaSigner.PublicKey()) + "\n"; string(data) != want {
+		t.Fatalf("known_hosts = %q, want only the RSA key", data)
+	}
+}
+
+func TestEffectiveServerConfigControlMasterHostKeyCheck(t *testing.T) {
+	pin := "SHA256:+DiY3wvvV6TuJJhbpZisF/zLDA0zPMSvHdkr4UvCOqU"
+	run := &Run{
+		Conf: conf.Config{
+			Server: map[string]conf.ServerConfig{
+				"known":   {Addr: "192.0.2.10", StrictHostKeyChecking: "yes", ControlMaster: true, ControlPersist: 600},
+				"pinned":  {Addr: "192.0.2.11", HostKeyFingerprints: []string{pin}, ControlMaster: true, ControlPersist: 600},
+				"bastion": {Addr: "192.0.2.12", StrictHostKeyChecking: "yes"},
+				"jumped":  {Addr: "192.0.2.13", Proxy: "bastion", ControlMaster: true, ControlPersist: 600},
+				"open":    {Addr: "192.0.2.14"},
+				"tunnel":  {Addr: "192.0.2.15", Proxy: "open", ControlMaster: true, ControlPersist: 600},
+			},
+		},
+	}
+
+	tests := []struct {
+		server string
+		master bool
+	}{
+		{server: "known", master: true},
+		{server: "pinned", master: false},
+		{server: "jumped", master: false},
+		{server: "tunnel", master: true},
+	}
+	for _, tt := range tests {
+		s := run.effectiveServerConfig(tt.server, false)
+		if s.ControlMaster != tt.master {
+			t.Fatalf("%s: ControlMaster = %v, want %v", tt.server, s.ControlMaster, tt.master)
+		}
+		if !tt.master && s.ControlPersist != 0 {
+			t.Fatalf("%s: ControlPersist = %d, want 0", tt.server, s.ControlPersist)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+// Copyright (c) 2026 Blacknon. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package ssh
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/blacknon/go-sshlib"
+	conf "github.com/blacknon/lssh/internal/config"
+)
+
+// Reachability of ProbeResult.
+const (
+	ProbeUp      = "up"
+	ProbeDown    = "down"
+	ProbeUnknown = "unknown"
+)
+
+// ProbeResult is the reachability of a server.
+type ProbeResult struct {
+	Status string
+	// RTT is the time to connect, or to answer `health.check`.
+	RTT time.Duration
+	// Reason tells why the server is down or unknown.
+	Reason string
+}
+
+// Probe checks whether server is reachable within timeout, without logging
+// in. A server is probed with a TCP connect to addr:port, through the proxy
+// if its only hop is an http or socks proxy. Connector servers are probed
+// with `health.check` of the connector provider.
+//
+// Servers behind an ssh or command proxy can not be probed without logging
+// in to the proxy, so they are unknown, or down if the first hop is.
+func Probe(server string, config conf.Config, timeout time.Duration) ProbeResult {
+	if _, ok := config.Server[server]; !ok {
+		return ProbeResult{Status: ProbeUnknown, Reason: "not configured"}
+	}
+
+	if config.ServerUsesConnector(server) {
+		start := time.Now()
+		result, err := config.CheckConnectorHealth(server)
+		rtt := time.Since(start)
+		switch {
+		case err != nil:
+			return ProbeResult{Status: ProbeUnknown, Reason: err.Error()}
+		case !result.OK:
+			return ProbeResult{Status: ProbeDown, RTT: rtt, Reason: result.Message}
+		}
+		return ProbeResult{Status: ProbeUp, RTT: rtt}
+	}
+
+	route, err := getProxyRoute(server, config)
+	if err != nil {
+		return ProbeResult{Status: ProbeUnknown, Reason: err.Error()}
+	}
+
+	target := probeAddr(config.Server[server].Addr, config.Server[server].Port)
+	if len(route) == 0 {
+		return probeDial(&net.Dialer{}, target, timeout)
+	}
+
+	first := route[0]
+	switch first.Type {
+	case "http", "https", "socks", "socks5":
+		c := config.Proxy[first.Name]
+		if len(route) == 1 {
+			pxy := &sshlib.Proxy{
+				Type:     first.Type,
+				Addr:     c.Addr,
+				Port:     c.Port,
+				User:     c.User,
+				Password: c.Pass,
+			}
+			dialer, err := pxy.CreateProxyDialer()
+			if err != nil {
+				return ProbeResult{Status: ProbeUnknown, Reason: err.Error()}
+			}
+			return probeDial(dialer, target, timeout)
+		}
+		return probeFirstHop(first.Name, probeAddr(c.Addr, c.Port), timeout)
+	case "command":
+		return ProbeResult{Status: ProbeUnknown, Reason: "behind ProxyCommand"}
+	default:
+		c := config.Server[first.Name]
+		return probeFirstHop(first.Name, probeAddr(c.Addr, c.Port), timeout)
+	}
+}
+
+// probeFirstHop probes the first proxy hop of a server that can not be
+// probed directly. The server is down if the hop is down, else unknown.
+func probeFirstHop(name, addr string, timeout time.Duration) ProbeResult {
+	result := probeDial(&net.Dialer{}, addr, timeout)
+	if result.Status == ProbeDown {
+		result.Reason = fmt.Sprintf("proxy %s: %s", name, result.Reason)
+		return result
+	}
+	return ProbeResult{Status: ProbeUnknown, Reason: "behind proxy " + name}
+}
+
+type probeDialer interface {
+	DialContext(ctx context.Context, network, addr string) (net.Conn, error)
+}
+
+func probeDial(dialer probeDialer, addr string, timeout time.Duration) ProbeResult {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	start := time.Now()
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return ProbeResult{Status: ProbeDown, Reason: err.Error()}
+	}
+	rtt := time.Since(start)
+	conn.Close()
+	return ProbeResult{Status: ProbeUp, RTT: rtt}
+}
+
+func probeAddr(addr, port string) string {
+	if port == "" {
+		port = "22"
+	}
+	return net.JoinHostPort(addr, port)
+}
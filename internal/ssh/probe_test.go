@@ -0,0 +1,60 @@
+package ssh
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	conf "github.com/blacknon/lssh/internal/config"
+)
+
+func TestProbe(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	defer listener.Close()
+	upHost, upPort, _ := net.SplitHostPort(listener.Addr().String())
+
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	downHost, downPort, _ := net.SplitHostPort(closed.Addr().String())
+	closed.Close()
+
+	config := conf.Config{
+		Server: map[string]conf.ServerConfig{
+			"up":          {Addr: upHost, Port: upPort},
+			"down":        {Addr: downHost, Port: downPort},
+			"bastion":     {Addr: upHost, Port: upPort},
+			"bastion-off": {Addr: downHost, Port: downPort},
+			"via-up":      {Addr: "192.0.2.10", Port: "22", Proxy: "bastion"},
+			"via-down":    {Addr: "192.0.2.10", Port: "22", Proxy: "bastion-off"},
+			"via-command": {Addr: "192.0.2.10", Port: "22", ProxyCommand: "ssh -W %h:%p bastion"},
+		},
+	}
+
+	tests := []struct {
+		server string
+		status string
+		reason string
+	}{
+		{server: "up", status: ProbeUp},
+		{server: "down", status: ProbeDown},
+		{server: "via-up", status: ProbeUnknown, reason: "behind proxy bastion"},
+		{server: "via-down", status: ProbeDown, reason: "proxy bastion-off:"},
+		{server: "via-command", status: ProbeUnknown, reason: "ProxyCommand"},
+		{server: "missing", status: ProbeUnknown},
+	}
+	for _, tt := range tests {
+		got := Probe(tt.server, config, time.Second)
+		if got.Status != tt.status {
+			t.Fatalf("Probe(%q) = %+v, want status %s", tt.server, got, tt.status)
+		}
+		if !strings.Contains(got.Reason, tt.reason) {
+			t.Fatalf("Probe(%q) reason = %q, want %q", tt.server, got.Reason, tt.reason)
+		}
+	}
+}
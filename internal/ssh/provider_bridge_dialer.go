@@ -17,6 +17,9 @@ func (r *Run) providerBridgeDialer(server string) connectorruntime.Dialer {
 }
 
 func (r *Run) dialServerViaProxyChain(ctx context.Context, server, network, address string) (net.Conn, error) {
+	if err := r.interpolateErrs[server]; err != nil {
+		return nil, err
+	}
 	if network == "" {
 		network = "tcp"
 	}
@@ -54,7 +57,8 @@ func (r *Run) dialServerViaProxyChain(ctx context.Context, server, network, addr
 			pxy := &sshlib.Connect{
 				ProxyDialer: dialer,
 			}
-			if err = pxy.CreateClient(c.Addr, c.Port, c.User, r.serverAuthMethodMap[p.Name]); err != nil {
+			if err = r.createClient(pxy, p.Name, c.Addr, c.Port, c.User); err != nil {
+				_ = multiCloser{closers: closers}.Close()
 				return nil, err
 			}
 			closers = append(closers, pxy)
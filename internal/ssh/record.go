@@ -0,0 +1,47 @@
+// Copyright (c) 2026 Blacknon. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package ssh
+
+import (
+	"os"
+
+	"github.com/blacknon/go-sshlib"
+	"github.com/blacknon/lssh/internal/asciicast"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// startRecording creates the asciicast recording of the shell of server, or
+// returns nil if the terminal log is not enabled in the asciicast format.
+func (r *Run) startRecording(server string) (*asciicast.Recorder, error) {
+	logConf := r.Conf.Log
+	if !logConf.Enable {
+		return nil, nil
+	}
+	if err := logConf.CheckFormat(); err != nil {
+		return nil, err
+	}
+	if !logConf.IsAsciicast() {
+		return nil, nil
+	}
+
+	width, height, err := terminal.GetSize(int(os.Stdout.Fd()))
+	if err != nil {
+		width, height = 80, 24
+	}
+	return asciicast.Create(r.getLogPath(server), asciicast.NewHeader(width, height))
+}
+
+// recordShell runs shell, a shell of connect, with its terminal recorded to
+// rec. The shell uses a local pty relayed to the terminal by
+// runRecordedTerminal.
+func recordShell(connect *sshlib.Connect, rec *asciicast.Recorder, recordInput bool, shell func() error) error {
+	return runRecordedTerminal(rec, recordInput, func(tty *os.File) error {
+		connect.PtyRelayTty = tty
+		defer func() {
+			connect.PtyRelayTty = nil
+		}()
+		return shell()
+	})
+}
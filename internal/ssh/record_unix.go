@@ -0,0 +1,149 @@
+//go:build !windows
+
+package ssh
+
+import (
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/blacknon/lssh/internal/asciicast"
+	"github.com/creack/pty"
+	"golang.org/x/crypto/ssh/terminal"
+	"golang.org/x/sys/unix"
+)
+
+// recordDrainTimeout is how long the output left in the local pty is read
+// after the shell has returned.
+const recordDrainTimeout = 500 * time.Millisecond
+
+// runRecordedTerminal runs run with the slave of a local pty, like
+// script(1). The pty has the size of the terminal, and is relayed to it in
+// raw mode with the output, the size changes and, with recordInput, the
+// input recorded to rec.
+func runRecordedTerminal(rec *asciicast.Recorder, recordInput bool, run func(tty *os.File) error) error {
+	ptmx, tty, err := pty.Open()
+	if err != nil {
+		return err
+	}
+	defer ptmx.Close()
+	defer tty.Close()
+
+	if size, sizeErr := pty.GetsizeFull(os.Stdout); sizeErr == nil {
+		_ = pty.Setsize(tty, size)
+	}
+
+	fd := int(os.Stdin.Fd())
+	if terminal.IsTerminal(fd) {
+		state, rawErr := terminal.MakeRaw(fd)
+		if rawErr != nil {
+			return rawErr
+		}
+		defer func() {
+			_ = terminal.Restore(fd, state)
+		}()
+	}
+
+	stopResize := watchRecordedResize(rec, tty)
+	defer stopResize()
+
+	stdin, closeStdin, err := openCancelableStdin()
+	if err != nil {
+		return err
+	}
+	defer closeStdin()
+
+	var input io.Writer = ptmx
+	if recordInput {
+		input = io.MultiWriter(ptmx, rec.Input())
+	}
+	inputDone := make(chan struct{})
+	go func() {
+		_, _ = io.Copy(input, stdin)
+		close(inputDone)
+	}()
+
+	outputDone := make(chan struct{})
+	go func() {
+		_, _ = io.Copy(io.MultiWriter(os.Stdout, rec.Output()), ptmx)
+		close(outputDone)
+	}()
+
+	err = run(tty)
+
+	// the shell has written all of its output to the pty, so read the rest
+	// until the pty is closed or idle.
+	_ = tty.Close()
+	_ = ptmx.SetReadDeadline(time.Now().Add(recordDrainTimeout))
+	select {
+	case <-outputDone:
+	case <-time.After(2 * recordDrainTimeout):
+	}
+
+	// stop reading stdin, so that the input after the session is not
+	// written to it.
+	_ = stdin.SetReadDeadline(time.Now())
+	_ = ptmx.SetWriteDeadline(time.Now())
+	<-inputDone
+	return err
+}
+
+// openCancelableStdin returns a copy of stdin whose Read can be stopped with
+// SetReadDeadline, and a function that closes it. The copy is non-blocking
+// so that it is read through the runtime poller, and the blocking mode of
+// stdin is restored when it is closed.
+func openCancelableStdin() (*os.File, func(), error) {
+	fd, err := unix.Dup(int(os.Stdin.Fd()))
+	if err != nil {
+		return nil, nil, err
+	}
+	flags, err := unix.FcntlInt(uintptr(fd), unix.F_GETFL, 0)
+	if err == nil {
+		err = unix.SetNonblock(fd, true)
+	}
+	if err != nil {
+		_ = unix.Close(fd)
+		return nil, nil, err
+	}
+
+	stdin := os.NewFile(uintptr(fd), os.Stdin.Name())
+	return stdin, func() {
+		_ = unix.SetNonblock(fd, flags&unix.O_NONBLOCK != 0)
+		_ = stdin.Close()
+	}, nil
+}
+
+// watchRecordedResize resizes tty to the terminal and records the size on
+// SIGWINCH, until the returned stop is called.
+func watchRecordedResize(rec *asciicast.Recorder, tty *os.File) (stop func()) {
+	resizeCh := make(chan os.Signal, 1)
+	signal.Notify(resizeCh, syscall.SIGWINCH)
+	go func() {
+		for range resizeCh {
+			size, err := pty.GetsizeFull(os.Stdout)
+			if err != nil {
+				continue
+			}
+			if tty != nil {
+				_ = pty.Setsize(tty, size)
+			}
+			rec.Resize(int(size.Cols), int(size.Rows))
+		}
+	}()
+
+	return func() {
+		signal.Stop(resizeCh)
+		close(resizeCh)
+	}
+}
+
+// setCommandTTY sets cmd to run on tty as its controlling terminal.
+func setCommandTTY(cmd *exec.Cmd, tty *os.File) {
+	cmd.Stdin = tty
+	cmd.Stdout = tty
+	cmd.Stderr = tty
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true, Setctty: true}
+}
@@ -0,0 +1,118 @@
+//go:build !windows
+
+package ssh
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/blacknon/lssh/internal/asciicast"
+	conf "github.com/blacknon/lssh/internal/config"
+)
+
+func TestRunCommandPlanShellRecordsOnTTY(t *testing.T) {
+	var buf bytes.Buffer
+	rec, err := asciicast.NewRecorder(&buf, asciicast.Header{Width: 80, Height: 24})
+	if err != nil {
+		t.Fatalf("NewRecorder() error = %v", err)
+	}
+
+	plan := conf.ConnectorCommandPlan{
+		Program: "/bin/sh",
+		Args:    []string{"-c", "test -t 1 && printf 'on tty\n'"},
+	}
+	if err := runCommandPlanShell(plan, rec, false); err != nil {
+		t.Fatalf("runCommandPlanShell() error = %v", err)
+	}
+	_ = rec.Close()
+
+	_, events, err := asciicast.Read(&buf)
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	output := ""
+	for _, event := range events {
+		if event.Type == asciicast.EventOutput {
+			output += event.Data
+		}
+	}
+	if !strings.Contains(output, "on tty") {
+		t.Fatalf("recorded output = %q, want the output of the command on a tty", output)
+	}
+}
+
+func TestRunRecordedTerminalStopsReadingStdin(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Pipe() error = %v", err)
+	}
+	defer r.Close()
+	defer w.Close()
+
+	stdin := os.Stdin
+	os.Stdin = r
+	t.Cleanup(func() { os.Stdin = stdin })
+
+	rec, err := asciicast.NewRecorder(io.Discard, asciicast.Header{Width: 80, Height: 24})
+	if err != nil {
+		t.Fatalf("NewRecorder() error = %v", err)
+	}
+	defer rec.Close()
+
+	if err := runRecordedTerminal(rec, false, func(*os.File) error { return nil }); err != nil {
+		t.Fatalf("runRecordedTerminal() error = %v", err)
+	}
+
+	// the input after the session is left to the next reader of stdin.
+	if _, err := w.Write([]byte("next\n")); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	read := make(chan string, 1)
+	go func() {
+		buf := make([]byte, 16)
+		n, _ := r.Read(buf)
+		read <- string(buf[:n])
+	}()
+	select {
+	case got := <-read:
+		if got != "next\n" {
+			t.Fatalf("stdin after the session = %q, want %q", got, "next\n")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the input after the session was read by the session")
+	}
+}
+
+func TestStartRecording(t *testing.T) {
+	dir := t.TempDir()
+	r := &Run{Conf: conf.Config{Log: conf.LogConfig{Enable: true, Dir: dir}}}
+	if rec, err := r.startRecording("web"); rec != nil || err != nil {
+		t.Fatalf("startRecording() of a text log = %v, %v, want nil", rec, err)
+	}
+
+	r.Conf.Log.Format = "ttyrec"
+	if _, err := r.startRecording("web"); err == nil {
+		t.Fatal("startRecording() with an unknown format error = nil")
+	}
+
+	r.Conf.Log.Format = conf.LogFormatAsciicast
+	rec, err := r.startRecording("web")
+	if err != nil || rec == nil {
+		t.Fatalf("startRecording() = %v, %v", rec, err)
+	}
+	_ = rec.Close()
+
+	paths, _ := filepath.Glob(filepath.Join(dir, "*_web.cast"))
+	if len(paths) != 1 {
+		t.Fatalf("recordings = %v, want a .cast file", paths)
+	}
+	header, _, err := asciicast.ReadFile(paths[0])
+	if err != nil || header.Width <= 0 || header.Height <= 0 {
+		t.Fatalf("recording header = %+v, %v", header, err)
+	}
+}
@@ -0,0 +1,21 @@
+//go:build windows
+
+package ssh
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+
+	"github.com/blacknon/lssh/internal/asciicast"
+)
+
+func runRecordedTerminal(rec *asciicast.Recorder, recordInput bool, run func(tty *os.File) error) error {
+	return fmt.Errorf("asciicast recording of shells is not supported on windows yet")
+}
+
+func setCommandTTY(cmd *exec.Cmd, tty *os.File) {
+	cmd.Stdin = tty
+	cmd.Stdout = tty
+	cmd.Stderr = tty
+}
@@ -0,0 +1,202 @@
+// Copyright (c) 2026 Blacknon. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package ssh
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/blacknon/go-sshlib"
+	conf "github.com/blacknon/lssh/internal/config"
+	shellquote "github.com/kballard/go-shellquote"
+	"golang.org/x/crypto/ssh"
+)
+
+// remoteLoginShellCommand starts the login shell of the remote user. It is
+// used instead of a shell request when env has to be exported first, with
+// set_env_export.
+const remoteLoginShellCommand = `exec "${SHELL:-/bin/sh}" -l`
+
+// RequestRemoteEnv sends env to session with "env" requests. It returns the
+// variables the server refused (ex. not allowed by sshd AcceptEnv), or all
+// of env if session is nil (ex. a ControlMaster client).
+func RequestRemoteEnv(session *ssh.Session, env map[string]string) map[string]string {
+	if session == nil {
+		return env
+	}
+
+	var refused map[string]string
+	for _, name := range sortedEnvNames(env) {
+		if err := session.Setenv(name, env[name]); err != nil {
+			if refused == nil {
+				refused = map[string]string{}
+			}
+			refused[name] = env[name]
+		}
+	}
+	return refused
+}
+
+// ExportRefusedRemoteEnv returns the variables of refused to export in the
+// command of server. They are exported only with set_env_export. Otherwise
+// they are dropped like OpenSSH does, and it returns a notice naming them.
+func ExportRefusedRemoteEnv(server string, config conf.ServerConfig, refused map[string]string) (map[string]string, string) {
+	if len(refused) == 0 {
+		return nil, ""
+	}
+	if config.SetEnvExport {
+		return refused, ""
+	}
+	return nil, fmt.Sprintf("%s: set_env/send_env %s not set, the server refused them (set_env_export = true exports them in the command)", server, strings.Join(sortedEnvNames(refused), ","))
+}
+
+// warnRemoteEnv writes notice of ExportRefusedRemoteEnv to w.
+func warnRemoteEnv(w io.Writer, notice string) {
+	if notice != "" {
+		fmt.Fprintf(w, "Warning: %s\n", notice)
+	}
+}
+
+// RemoteEnvCommand returns command with env exported before it.
+func RemoteEnvCommand(env map[string]string, command string) string {
+	if len(env) == 0 {
+		return command
+	}
+
+	exports := make([]string, 0, len(env))
+	for _, name := range sortedEnvNames(env) {
+		exports = append(exports, name+"="+shellquote.Join(env[name]))
+	}
+	return "export " + strings.Join(exports, " ") + "; " + command
+}
+
+// RemoteEnvShellCommand returns the command that exports env and starts the
+// login shell.
+func RemoteEnvShellCommand(env map[string]string) string {
+	return RemoteEnvCommand(env, remoteLoginShellCommand)
+}
+
+// remoteShell starts the interactive session of config: remote_command, the
+// local rc file or the login shell, with set_env/send_env applied.
+func remoteShell(connect *sshlib.Connect, session *ssh.Session, server string, config conf.ServerConfig, localrc bool) (err error) {
+	env := config.RemoteEnv()
+	if len(env) > 0 && session == nil && !connect.IsControlClient() {
+		session, err = connect.CreateSession()
+		if err != nil {
+			return err
+		}
+		defer session.Close()
+	}
+	env, notice := ExportRefusedRemoteEnv(server, config, RequestRemoteEnv(session, env))
+	warnRemoteEnv(os.Stderr, notice)
+
+	switch {
+	case config.RemoteCommand != "":
+		return connect.CmdShell(session, RemoteEnvCommand(env, config.RemoteCommand))
+	case localrc:
+		return connect.CmdShell(session, RemoteEnvCommand(env, BuildLocalRCShellCommand(config.LocalRcPath, config.LocalRcDecodeCmd, config.LocalRcCompress, config.LocalRcUncompressCmd)))
+	case len(env) > 0:
+		return connect.CmdShell(session, RemoteEnvShellCommand(env))
+	default:
+		return connect.Shell(session)
+	}
+}
+
+func sortedEnvNames(env map[string]string) []string {
+	names := make([]string, 0, len(env))
+	for name := range env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// OpenRemoteEnvTerminal is sshlib.Connect.OpenTerminal, with set_env/send_env
+// of server sent by "env" requests before the shell or the command starts.
+// It returns the notice of ExportRefusedRemoteEnv.
+func OpenRemoteEnvTerminal(connect *sshlib.Connect, opts sshlib.TerminalOptions, server string, config conf.ServerConfig) (*sshlib.Terminal, string, error) {
+	env := config.RemoteEnv()
+	if len(env) == 0 {
+		terminal, err := connect.OpenTerminal(opts)
+		return terminal, "", err
+	}
+
+	// A control client can not send "env" requests.
+	if connect.IsControlClient() {
+		export, notice := ExportRefusedRemoteEnv(server, config, env)
+		terminal, err := connect.OpenTerminal(exportTerminalEnv(opts, export))
+		return terminal, notice, err
+	}
+
+	session, err := connect.CreateSession()
+	if err != nil {
+		return nil, "", err
+	}
+	terminal, notice, err := startRemoteEnvTerminal(connect, session, opts, server, config, env)
+	if err != nil {
+		session.Close()
+		return nil, "", err
+	}
+	return terminal, notice, nil
+}
+
+// startRemoteEnvTerminal starts the terminal of opts on session, like
+// sshlib.Connect.OpenTerminal, sending env before the shell or the command.
+func startRemoteEnvTerminal(connect *sshlib.Connect, session *ssh.Session, opts sshlib.TerminalOptions, server string, config conf.ServerConfig, env map[string]string) (*sshlib.Terminal, string, error) {
+	stdin, err := session.StdinPipe()
+	if err != nil {
+		return nil, "", err
+	}
+	stdout, err := session.StdoutPipe()
+	if err != nil {
+		return nil, "", err
+	}
+	stderr, err := session.StderrPipe()
+	if err != nil {
+		return nil, "", err
+	}
+
+	if err := sshlib.RequestTtyWithSize(session, opts.Term, opts.Cols, opts.Rows, opts.Modes); err != nil {
+		return nil, "", err
+	}
+	if connect.ForwardX11 {
+		if err := connect.X11Forward(session); err != nil {
+			return nil, "", err
+		}
+	}
+	if connect.ForwardAgent {
+		connect.ForwardSshAgent(session)
+	}
+
+	export, notice := ExportRefusedRemoteEnv(server, config, RequestRemoteEnv(session, env))
+	opts = exportTerminalEnv(opts, export)
+	switch {
+	case opts.StartShell:
+		err = session.Shell()
+	case opts.Command != "":
+		err = session.Start(opts.Command)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	return &sshlib.Terminal{Session: session, Stdin: stdin, Stdout: stdout, Stderr: stderr}, notice, nil
+}
+
+// exportTerminalEnv returns opts with env exported by its command.
+func exportTerminalEnv(opts sshlib.TerminalOptions, env map[string]string) sshlib.TerminalOptions {
+	switch {
+	case len(env) == 0:
+	case opts.StartShell:
+		opts.StartShell = false
+		opts.Command = RemoteEnvShellCommand(env)
+	default:
+		opts.Command = RemoteEnvCommand(env, opts.Command)
+	}
+	return opts
+}
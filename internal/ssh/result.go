@@ -0,0 +1,325 @@
+// Copyright (c) 2026 Blacknon. All rights reserved.
+// Use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package ssh
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// statuses of a host in command mode (HostResult.Status).
+const (
+	// ResultOK is a host where the command exited 0.
+	ResultOK = "ok"
+
+	// ResultFailed is a host where the command exited non-zero.
+	ResultFailed = "failed"
+
+	// ResultError is a host that could not be connected, or where the
+	// command did not return an exit status.
+	ResultError = "error"
+
+	// ResultSkipped is a host the command was not run on, after Run.MaxFail
+	// hosts failed.
+	ResultSkipped = "skipped"
+)
+
+// exit policies of command mode (Run.ExitPolicy). The exit status is
+// non-zero if any host, all hosts or more than half of the hosts did not
+// succeed.
+const (
+	ExitPolicyAny      = "any"
+	ExitPolicyAll      = "all"
+	ExitPolicyMajority = "majority"
+)
+
+// ValidateExitPolicy checks the exit policy of command mode. Empty is any.
+func ValidateExitPolicy(policy string) error {
+	switch policy {
+	case "", ExitPolicyAny, ExitPolicyAll, ExitPolicyMajority:
+		return nil
+	}
+	return fmt.Errorf("unknown exit policy %q. [any|all|majority]", policy)
+}
+
+// HostResult is the result of the command on a host in command mode.
+type HostResult struct {
+	Host   string `json:"host"`
+	Status string `json:"status"`
+
+	// ExitCode is the exit status of the command, or -1 if there is none.
+	ExitCode int `json:"exit_code"`
+
+	// Duration is the time from connecting to the end of the command.
+	Duration time.Duration `json:"-"`
+
+	Error string `json:"error,omitempty"`
+}
+
+// MarshalJSON writes Duration in milliseconds as `duration_ms`.
+func (h HostResult) MarshalJSON() ([]byte, error) {
+	type hostResult HostResult
+	return json.Marshal(struct {
+		hostResult
+		DurationMS int64 `json:"duration_ms"`
+	}{hostResult(h), h.Duration.Milliseconds()})
+}
+
+// cmdStatus collects the host results of command mode. The command is not
+// started on more hosts after maxFail hosts did not succeed.
+type cmdStatus struct {
+	mu      sync.Mutex
+	maxFail int
+	order   []string
+	started map[string]time.Time
+	results map[string]*HostResult
+	failed  []string
+
+	// reached is the hosts that were connected.
+	reached map[string]bool
+
+	// onFinish is called with the result of each host that finished or was
+	// skipped.
+	onFinish func(HostResult)
+}
+
+func newCmdStatus(servers []string, maxFail int) *cmdStatus {
+	return &cmdStatus{
+		maxFail: maxFail,
+		order:   servers,
+		started: map[string]time.Time{},
+		results: map[string]*HostResult{},
+		reached: map[string]bool{},
+	}
+}
+
+// begin starts the duration of server.
+func (s *cmdStatus) begin(server string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.started[server] = time.Now()
+}
+
+// connected records that server was connected.
+func (s *cmdStatus) connected(server string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reached[server] = true
+}
+
+// finish records the result of server from the exit code of the command
+// and err. An exit code of -1 or an err is an error.
+func (s *cmdStatus) finish(server string, exitCode int, err error) {
+	result := &HostResult{Host: server, Status: ResultOK, ExitCode: exitCode}
+	switch {
+	case err != nil || exitCode < 0:
+		result.Status = ResultError
+		result.ExitCode = -1
+		if err != nil {
+			result.Error = err.Error()
+		}
+	case exitCode > 0:
+		result.Status = ResultFailed
+	}
+
+	s.mu.Lock()
+	if start, ok := s.started[server]; ok {
+		result.Duration = time.Since(start)
+	}
+	s.results[server] = result
+	if result.Status != ResultOK {
+		s.failed = append(s.failed, server)
+	}
+	onFinish := s.onFinish
+	s.mu.Unlock()
+
+	if onFinish != nil {
+		onFinish(*result)
+	}
+}
+
+// finishCommand records the result of server from the error of
+// sshlib.Connect.Command.
+func (s *cmdStatus) finishCommand(server string, err error) {
+	code, err := CommandExitCode(err)
+	s.finish(server, code, err)
+}
+
+func (s *cmdStatus) skip(servers ...string) {
+	s.mu.Lock()
+	skipped := []HostResult{}
+	for _, server := range servers {
+		result := &HostResult{Host: server, Status: ResultSkipped, ExitCode: -1}
+		s.results[server] = result
+		skipped = append(skipped, *result)
+	}
+	onFinish := s.onFinish
+	s.mu.Unlock()
+
+	if onFinish != nil {
+		for _, result := range skipped {
+			onFinish(result)
+		}
+	}
+}
+
+// stopped returns that maxFail hosts did not succeed.
+func (s *cmdStatus) stopped() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.maxFail > 0 && len(s.failed) >= s.maxFail
+}
+
+// connectedHosts returns the connected hosts in the run order.
+func (s *cmdStatus) connectedHosts() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	hosts := []string{}
+	for _, server := range s.order {
+		if s.reached[server] {
+			hosts = append(hosts, server)
+		}
+	}
+	return hosts
+}
+
+// list returns the results in the run order. The hosts without a result
+// are errors.
+func (s *cmdStatus) list() []HostResult {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	results := make([]HostResult, 0, len(s.order))
+	for _, server := range s.order {
+		if result, ok := s.results[server]; ok {
+			results = append(results, *result)
+			continue
+		}
+		results = append(results, HostResult{Host: server, Status: ResultError, ExitCode: -1, Error: "no result"})
+	}
+	return results
+}
+
+// CommandExitCode returns the exit code of the remote command from the error
+// of sshlib.Connect.Command, or -1 and the error if it did not exit.
+func CommandExitCode(err error) (int, error) {
+	if err == nil {
+		return 0, nil
+	}
+
+	var exitErr *ssh.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitStatus(), nil
+	}
+
+	// ControlMaster clients return an unexported error with the status.
+	const controlExit = "remote command exited with status "
+	if i := strings.LastIndex(err.Error(), controlExit); i >= 0 {
+		if code, convErr := strconv.Atoi(err.Error()[i+len(controlExit):]); convErr == nil {
+			return code, nil
+		}
+	}
+	return -1, err
+}
+
+// Results returns the host results of the last command mode run, in the
+// run order.
+func (r *Run) Results() []HostResult {
+	if r.cmdStatus == nil {
+		return nil
+	}
+	return r.cmdStatus.list()
+}
+
+// ExitStatus returns the exit status of the command mode run by
+// Run.ExitPolicy. It is the exit code of the command with a single host,
+// and 1 with more hosts.
+func (r *Run) ExitStatus() int {
+	results := r.Results()
+	bad := 0
+	for _, result := range results {
+		if result.Status != ResultOK {
+			bad++
+		}
+	}
+
+	var failed bool
+	switch r.ExitPolicy {
+	case ExitPolicyAll:
+		failed = bad > 0 && bad == len(results)
+	case ExitPolicyMajority:
+		failed = bad*2 > len(results)
+	default:
+		failed = bad > 0
+	}
+
+	switch {
+	case !failed:
+		return 0
+	case len(results) == 1 && results[0].ExitCode > 0:
+		return results[0].ExitCode
+	default:
+		return 1
+	}
+}
+
+// printSummary writes the results as a table, followed by the counts of
+// each status.
+func printSummary(w io.Writer, results []HostResult) {
+	counts := map[string]int{}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "HOST\tSTATUS\tEXIT\tDURATION\tERROR")
+	for _, result := range results {
+		counts[result.Status]++
+
+		exitCode, duration := "-", "-"
+		if result.ExitCode >= 0 {
+			exitCode = strconv.Itoa(result.ExitCode)
+		}
+		if result.Status != ResultSkipped {
+			duration = result.Duration.Round(time.Millisecond).String()
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", result.Host, result.Status, exitCode, duration, result.Error)
+	}
+	tw.Flush()
+
+	fmt.Fprintf(w, "%d ok, %d failed, %d error, %d skipped\n",
+		counts[ResultOK], counts[ResultFailed], counts[ResultError], counts[ResultSkipped])
+}
+
+// cmdResultFile is the `--result-file` JSON document.
+type cmdResultFile struct {
+	Command    string       `json:"command"`
+	ExitPolicy string       `json:"exit_policy"`
+	ExitStatus int          `json:"exit_status"`
+	Hosts      []HostResult `json:"hosts"`
+}
+
+// writeResultFile writes the results of the run to path as JSON.
+func (r *Run) writeResultFile(path string) error {
+	policy := r.ExitPolicy
+	if policy == "" {
+		policy = ExitPolicyAny
+	}
+	data, err := json.MarshalIndent(cmdResultFile{
+		Command:    strings.Join(r.ExecCmd, " "),
+		ExitPolicy: policy,
+		ExitStatus: r.ExitStatus(),
+		Hosts:      r.Results(),
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o600)
+}
@@ -0,0 +1,162 @@
+package ssh
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCmdStatusResults(t *testing.T) {
+	s := newCmdStatus([]string{"web01", "web02", "web03", "web04", "web05"}, 2)
+	s.begin("web01")
+	s.finishCommand("web01", nil)
+	s.finishCommand("web02", errors.New("sshlib: remote command exited with status 3"))
+	if s.stopped() {
+		t.Fatal("stopped() after 1 failure = true, want false")
+	}
+	s.finish("web03", -1, errors.New("dial tcp: connection refused"))
+	if !s.stopped() {
+		t.Fatal("stopped() after 2 failures = false, want true")
+	}
+	s.skip("web04")
+
+	got := s.list()
+	want := []HostResult{
+		{Host: "web01", Status: ResultOK, ExitCode: 0},
+		{Host: "web02", Status: ResultFailed, ExitCode: 3},
+		{Host: "web03", Status: ResultError, ExitCode: -1, Error: "dial tcp: connection refused"},
+		{Host: "web04", Status: ResultSkipped, ExitCode: -1},
+		{Host: "web05", Status: ResultError, ExitCode: -1, Error: "no result"},
+	}
+	for i := range want {
+		got[i].Duration = 0
+		if got[i] != want[i] {
+			t.Fatalf("list()[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestRunHistoryHosts(t *testing.T) {
+	r := &Run{ServerList: []string{"web01", "web02", "web03"}}
+	if got := r.historyHosts(); strings.Join(got, ",") != "web01,web02,web03" {
+		t.Fatalf("historyHosts() without command mode = %v", got)
+	}
+
+	// hosts that could not be connected are not recorded, and hosts where
+	// the command failed are.
+	r.cmdStatus = newCmdStatus(r.ServerList, 0)
+	r.cmdStatus.connected("web03")
+	r.cmdStatus.finish("web03", 1, nil)
+	r.cmdStatus.finish("web02", -1, errors.New("dial tcp: connection refused"))
+	r.cmdStatus.connected("web01")
+	r.cmdStatus.finish("web01", 0, nil)
+	if got := r.historyHosts(); strings.Join(got, ",") != "web01,web03" {
+		t.Fatalf("historyHosts() = %v, want [web01 web03]", got)
+	}
+}
+
+func TestCommandExitCode(t *testing.T) {
+	if code, err := CommandExitCode(nil); code != 0 || err != nil {
+		t.Fatalf("CommandExitCode(nil) = %d, %v", code, err)
+	}
+	if code, err := CommandExitCode(errors.New("sshlib: remote command exited with status 127")); code != 127 || err != nil {
+		t.Fatalf("CommandExitCode(control) = %d, %v", code, err)
+	}
+	if code, err := CommandExitCode(errors.New("EOF")); code != -1 || err == nil {
+		t.Fatalf("CommandExitCode(EOF) = %d, %v", code, err)
+	}
+}
+
+func TestRunExitStatus(t *testing.T) {
+	newRun := func(policy string, statuses ...string) *Run {
+		servers := []string{}
+		for i := range statuses {
+			servers = append(servers, string(rune('a'+i)))
+		}
+		r := &Run{ExitPolicy: policy, cmdStatus: newCmdStatus(servers, 0)}
+		for i, status := range statuses {
+			switch status {
+			case ResultOK:
+				r.cmdStatus.finish(servers[i], 0, nil)
+			case ResultFailed:
+				r.cmdStatus.finish(servers[i], 2, nil)
+			case ResultSkipped:
+				r.cmdStatus.skip(servers[i])
+			}
+		}
+		return r
+	}
+
+	tds := []struct {
+		policy   string
+		statuses []string
+		want     int
+	}{
+		{policy: "", statuses: []string{ResultOK, ResultOK}, want: 0},
+		{policy: "", statuses: []string{ResultOK, ResultFailed}, want: 1},
+		{policy: ExitPolicyAll, statuses: []string{ResultOK, ResultFailed}, want: 0},
+		{policy: ExitPolicyAll, statuses: []string{ResultFailed, ResultSkipped}, want: 1},
+		{policy: ExitPolicyMajority, statuses: []string{ResultOK, ResultFailed}, want: 0},
+		{policy: ExitPolicyMajority, statuses: []string{ResultOK, ResultFailed, ResultFailed}, want: 1},
+		{policy: ExitPolicyAny, statuses: []string{ResultFailed}, want: 2},
+	}
+	for _, td := range tds {
+		if got := newRun(td.policy, td.statuses...).ExitStatus(); got != td.want {
+			t.Fatalf("ExitStatus(%q, %v) = %d, want %d", td.policy, td.statuses, got, td.want)
+		}
+	}
+
+	if got := (&Run{}).ExitStatus(); got != 0 {
+		t.Fatalf("ExitStatus() without a run = %d", got)
+	}
+}
+
+func TestPrintSummary(t *testing.T) {
+	var buf bytes.Buffer
+	printSummary(&buf, []HostResult{
+		{Host: "web01", Status: ResultOK, Duration: 1500 * time.Millisecond},
+		{Host: "db01", Status: ResultError, ExitCode: -1, Error: "connection refused"},
+		{Host: "web02", Status: ResultSkipped, ExitCode: -1},
+	})
+	want := strings.Join([]string{
+		"HOST   STATUS   EXIT  DURATION  ERROR",
+		"web01  ok       0     1.5s      ",
+		"db01   error    -     0s        connection refused",
+		"web02  skipped  -     -         ",
+		"1 ok, 0 failed, 1 error, 1 skipped",
+		"",
+	}, "\n")
+	if buf.String() != want {
+		t.Fatalf("printSummary() =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestWriteResultFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "result.json")
+	r := &Run{ExecCmd: []string{"uptime"}, cmdStatus: newCmdStatus([]string{"web01"}, 0)}
+	r.cmdStatus.finish("web01", 1, nil)
+	if err := r.writeResultFile(path); err != nil {
+		t.Fatalf("writeResultFile() error = %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	var got map[string]interface{}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if got["command"] != "uptime" || got["exit_policy"] != "any" || got["exit_status"] != float64(1) {
+		t.Fatalf("result file = %s", data)
+	}
+	host := got["hosts"].([]interface{})[0].(map[string]interface{})
+	if host["host"] != "web01" || host["status"] != "failed" || host["exit_code"] != float64(1) || host["duration_ms"] != float64(0) {
+		t.Fatalf("result file host = %v", host)
+	}
+}
@@ -12,11 +12,14 @@ import (
 	"runtime"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/blacknon/go-sshlib"
-	"github.com/blacknon/lssh/internal/common"
+	"github.com/blacknon/lssh/internal/audit"
 	conf "github.com/blacknon/lssh/internal/config"
 	"github.com/blacknon/lssh/internal/connectorruntime"
+	"github.com/blacknon/lssh/internal/hoststate"
+	"github.com/blacknon/lssh/internal/output"
 	"golang.org/x/crypto/ssh"
 	"golang.org/x/crypto/ssh/terminal"
 )
@@ -39,12 +42,55 @@ type Run struct {
 	//     - cmd
 	Mode string
 
+	// HistoryCommand is the command name the hosts are recorded in the host
+	// history as, after a successful connection. Empty disables recording.
+	HistoryCommand string
+
 	// tty use (-t option)
 	IsTerm bool
 
 	// parallel connect (-p option)
 	IsParallel bool
 
+	// MaxParallel is the maximum number of hosts connecting or running the
+	// command at the same time in command mode. 0 is unlimited.
+	MaxParallel int
+
+	// Batch runs the command mode in batches of hosts, a number of hosts
+	// (ex. `5`) or a percentage (ex. `25%`), with BatchPause between the
+	// batches. Empty runs all hosts in one batch.
+	Batch      string
+	BatchPause time.Duration
+
+	// MaxFail stops starting the command on more hosts after it failed on
+	// MaxFail hosts (--fail-fast is 1). 0 is unlimited.
+	MaxFail int
+
+	// Order is the host order of command mode. [name|random]
+	// Empty keeps the order of ServerList.
+	Order string
+
+	// ExitPolicy is the hosts that must fail for a non-zero ExitStatus of
+	// command mode. [any|all|majority], empty is any.
+	ExitPolicy string
+
+	// Summary prints the result of each host at the end of command mode.
+	Summary bool
+
+	// ResultFile is the path the host results of command mode are written
+	// to as JSON.
+	ResultFile string
+
+	// OutputFormat is the output format of command mode and lsshell.
+	// [text|ndjson|json], empty is text.
+	OutputFormat string
+
+	// GroupOutput buffers the output of each host of command mode, and
+	// prints each distinct output once under the hosts that produced it.
+	// DiffFromMajority prints the groups as a diff from the largest group.
+	GroupOutput      bool
+	DiffFromMajority bool
+
 	// not run (-N option)
 	IsNone bool
 
@@ -162,6 +208,10 @@ type Run struct {
 	// Map of AuthMethod used by target server
 	serverAuthMethodMap map[string][]ssh.AuthMethod
 
+	// interpolateErrs is the map of servers whose `${...}` references could
+	// not be resolved. These servers are not connected.
+	interpolateErrs map[string]error
+
 	// donedPKCS11 is　the value of panic measures (v0.6.2-).
 	// If error occurs and pkcs11 processing occurs more than once, the library will keep the token and Panic will occur.
 	// this value is so for countermeasures.
@@ -172,6 +222,19 @@ type Run struct {
 
 	// ConnectorRuntime executes provider-managed connector plans.
 	ConnectorRuntime connectorruntime.Executor
+
+	// cmdStatus is the host results of command mode.
+	cmdStatus *cmdStatus
+
+	// structured writes the output of command mode in OutputFormat.
+	structured *output.Structured
+
+	// grouped buffers the output of command mode for GroupOutput.
+	grouped *output.HostBuffers
+
+	// auditOutput counts the output of each host of command mode for the
+	// audit log.
+	auditOutput map[string]*audit.Counter
 }
 
 // AuthKey Auth map key struct.
@@ -246,6 +309,11 @@ func (r *Run) Start() {
 
 	if err != nil {
 		fmt.Println(err)
+		return
+	}
+
+	if r.HistoryCommand != "" {
+		_ = hoststate.Record(r.HistoryCommand, r.historyHosts())
 	}
 }
 
@@ -413,38 +481,7 @@ func (r *Run) setPortForwards(server string, config conf.ServerConfig) (c conf.S
 
 	// append port forwards from c, to r.PortForward
 	for _, f := range c.PortForwards {
-		var err error
-
-		// create forward
-		fw := new(conf.PortForward)
-
-		// split config forward settings
-		farray := strings.SplitN(f, ":", 2)
-
-		// check array count
-		if len(farray) == 1 {
-			fmt.Fprintf(os.Stderr, "port forward format is incorrect: %s: \"%s\"", server, f)
-			continue
-		}
-
-		//
-		mode := strings.ToLower(farray[0])
-		switch mode {
-		// local/remote port forward
-		case "local", "l":
-			fw.Mode = "L"
-			fw.Local, fw.Remote, err = common.ParseForwardPort(farray[1])
-
-		case "remote", "r":
-			fw.Mode = "R"
-			fw.Local, fw.Remote, err = common.ParseForwardPort(farray[1])
-
-		// other
-		default:
-			fmt.Fprintf(os.Stderr, "port forward format is incorrect: %s: \"%s\"", server, f)
-			continue
-		}
-
+		fw, err := conf.ParsePortForward(f)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "port forward format is incorrect: %s: \"%s\"", server, f)
 			continue
@@ -633,3 +670,12 @@ func notifyParentReady() {
 
 	_, _ = f.Write([]byte("OK\n"))
 }
+
+// historyHosts returns the hosts to record to the host history after a
+// successful Start. In command mode, these are the hosts that were connected.
+func (r *Run) historyHosts() []string {
+	if r.cmdStatus != nil {
+		return r.cmdStatus.connectedHosts()
+	}
+	return r.ServerList
+}
@@ -17,7 +17,9 @@ import (
 	"time"
 
 	"github.com/blacknon/go-sshlib"
+	"github.com/blacknon/lssh/internal/asciicast"
 	"github.com/blacknon/lssh/internal/common"
+	conf "github.com/blacknon/lssh/internal/config"
 	"golang.org/x/crypto/ssh"
 )
 
@@ -27,6 +29,15 @@ func (r *Run) shell() (err error) {
 	server := r.ServerList[0]
 	config := r.resolveShellConfig(server)
 
+	if !r.IsNone {
+		auditEnd := AuditShell(r.Conf, server)
+		defer func() { auditEnd(err) }()
+	}
+
+	if err = r.interpolateErrs[server]; err != nil {
+		return
+	}
+
 	if r.usesConnector(server) {
 		return r.runConnectorShellWithConfig(server, config)
 	}
@@ -96,12 +107,15 @@ func (r *Run) shell() (err error) {
 	}
 
 	// Local/Remote Port Forwarding
+	failedForwards := map[*conf.PortForward]error{}
 	for _, fw := range config.Forwards {
 		err = r.startPortForward(connect, fw)
 		if err != nil {
 			fmt.Fprintln(os.Stderr, err)
+			failedForwards[fw] = err
 		}
 	}
+	r.auditForwards(server, config, failedForwards)
 
 	// Dynamic Port Forwarding
 	if config.DynamicPortForward != "" {
@@ -163,7 +177,16 @@ func (r *Run) shell() (err error) {
 
 		// if terminal log enable
 		logConf := r.Conf.Log
-		if logConf.Enable {
+		var rec *asciicast.Recorder
+		rec, err = r.startRecording(server)
+		if err != nil {
+			return
+		}
+		switch {
+		case rec != nil:
+			defer rec.Close()
+
+		case logConf.Enable:
 			logPath := r.getLogPath(server)
 
 			// Check logging with remove ANSI code flag.
@@ -177,11 +200,13 @@ func (r *Run) shell() (err error) {
 		// TODO(blacknon): local rc file add
 		// No special handling for ControlMaster: allow agent/X11 forwarding to proceed normally.
 
-		if config.LocalRcUse == "yes" {
-			err = localrcShell(connect, session, config.LocalRcPath, config.LocalRcDecodeCmd, config.LocalRcCompress, config.LocalRcUncompressCmd)
+		// Connect shell (remote_command, local rc or login shell)
+		if rec != nil {
+			err = recordShell(connect, rec, logConf.RecordInput, func() error {
+				return remoteShell(connect, session, server, config, config.LocalRcUse == "yes")
+			})
 		} else {
-			// Connect shell
-			err = connect.Shell(session)
+			err = remoteShell(connect, session, server, config, config.LocalRcUse == "yes")
 		}
 
 		// No special handling for ControlMaster: allow agent/X11 forwarding to proceed normally.
@@ -206,7 +231,7 @@ func (r *Run) getLogPath(server string) (logPath string) {
 		log.Println(err)
 	}
 
-	file := time.Now().Format("20060102_150405") + "_" + server + ".log"
+	file := time.Now().Format("20060102_150405") + "_" + server + r.Conf.Log.FileExt()
 	logPath = dir + "/" + file
 
 	return
@@ -254,11 +279,6 @@ loop:
 	return
 }
 
-// localRcShell connect to remote shell using local bashrc
-func localrcShell(connect *sshlib.Connect, session *ssh.Session, localrcPath []string, decoder string, compress bool, uncompress string) (err error) {
-	return connect.CmdShell(session, BuildLocalRCShellCommand(localrcPath, decoder, compress, uncompress))
-}
-
 func BuildLocalRCShellCommand(localrcPath []string, decoder string, compress bool, uncompress string) string {
 	return buildLocalRCShellCommand(localrcPath, decoder, compress, uncompress, false)
 }
@@ -4,7 +4,9 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/blacknon/go-sshlib"
 	"github.com/blacknon/lssh/internal/common"
+	conf "github.com/blacknon/lssh/internal/config"
 )
 
 func TestLocalrcArchiveMode(t *testing.T) {
@@ -39,3 +41,56 @@ func TestBuildInteractiveLocalRCShellCommandUsesInteractiveBash(t *testing.T) {
 		t.Fatalf("BuildInteractiveLocalRCShellCommand() = %q, want base64-backed marker print command", cmd)
 	}
 }
+
+func TestRemoteEnvCommand(t *testing.T) {
+	env := map[string]string{"LANG": "C.UTF-8", "GREETING": "it's me", "EMPTY": ""}
+
+	got := RemoteEnvCommand(env, "uptime")
+	want := `export EMPTY='' GREETING='it'\''s me' LANG=C.UTF-8; uptime`
+	if got != want {
+		t.Fatalf("RemoteEnvCommand() = %q, want %q", got, want)
+	}
+
+	if got := RemoteEnvCommand(nil, "uptime"); got != "uptime" {
+		t.Fatalf("RemoteEnvCommand(nil) = %q", got)
+	}
+	if got := RemoteEnvShellCommand(map[string]string{"A": "1"}); got != `export A=1; exec "${SHELL:-/bin/sh}" -l` {
+		t.Fatalf("RemoteEnvShellCommand() = %q", got)
+	}
+}
+
+func TestExportRefusedRemoteEnv(t *testing.T) {
+	refused := map[string]string{"LC_ALL": "C", "APP_ENV": "prod"}
+
+	// refused variables are dropped with a notice, like OpenSSH.
+	env, notice := ExportRefusedRemoteEnv("web01", conf.ServerConfig{}, refused)
+	if env != nil || !strings.Contains(notice, "web01: set_env/send_env APP_ENV,LC_ALL not set") {
+		t.Fatalf("ExportRefusedRemoteEnv() = %v, %q", env, notice)
+	}
+
+	env, notice = ExportRefusedRemoteEnv("web01", conf.ServerConfig{SetEnvExport: true}, refused)
+	if len(env) != 2 || notice != "" {
+		t.Fatalf("ExportRefusedRemoteEnv(set_env_export) = %v, %q", env, notice)
+	}
+
+	if env, notice := ExportRefusedRemoteEnv("web01", conf.ServerConfig{}, nil); env != nil || notice != "" {
+		t.Fatalf("ExportRefusedRemoteEnv(nil) = %v, %q", env, notice)
+	}
+}
+
+func TestExportTerminalEnv(t *testing.T) {
+	env := map[string]string{"A": "1"}
+
+	opts := exportTerminalEnv(sshlib.TerminalOptions{StartShell: true}, env)
+	if opts.StartShell || opts.Command != `export A=1; exec "${SHELL:-/bin/sh}" -l` {
+		t.Fatalf("exportTerminalEnv(shell) = %+v", opts)
+	}
+	opts = exportTerminalEnv(sshlib.TerminalOptions{Command: "top"}, env)
+	if opts.Command != "export A=1; top" {
+		t.Fatalf("exportTerminalEnv(command) = %+v", opts)
+	}
+	opts = exportTerminalEnv(sshlib.TerminalOptions{StartShell: true}, nil)
+	if !opts.StartShell || opts.Command != "" {
+		t.Fatalf("exportTerminalEnv(nil) = %+v", opts)
+	}
+}
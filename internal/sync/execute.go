@@ -11,6 +11,7 @@ import (
 	syncpkg "sync"
 	"time"
 
+	"github.com/blacknon/lssh/internal/audit"
 	"github.com/blacknon/lssh/internal/output"
 )
 
@@ -24,6 +25,10 @@ type ApplyOptions struct {
 	TargetLabel       string
 	SourcePathDisplay func(string) string
 	TargetPathDisplay func(string) string
+
+	// Transfer is the audit log transfer the sizes of the copied files are
+	// added to.
+	Transfer *audit.Transfer
 }
 
 func ApplyPlan(ctx context.Context, srcFS FileSystem, dstFS FileSystem, plan *Plan, options ApplyOptions) error {
@@ -251,6 +256,7 @@ func copySingleFile(srcFS FileSystem, dstFS FileSystem, file DesiredEntry, optio
 		_ = dstFS.Chmod(file.DestinationPath, file.Mode)
 	}
 	_ = dstFS.Chtimes(file.DestinationPath, file.ModTime, file.ModTime)
+	options.Transfer.Add(file.Size, nil)
 
 	return nil
 }
@@ -12,6 +12,7 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/blacknon/lssh/internal/audit"
 	conf "github.com/blacknon/lssh/internal/config"
 	"github.com/blacknon/lssh/internal/output"
 	sshl "github.com/blacknon/lssh/internal/ssh"
@@ -44,6 +45,10 @@ type Sync struct {
 
 	Progress   *mpb.Progress
 	ProgressWG *syncpkg.WaitGroup
+
+	// synced is the hosts of a sync that succeeded.
+	synced   map[string]bool
+	syncedMu syncpkg.Mutex
 }
 
 type SyncInfo struct {
@@ -112,7 +117,7 @@ func (s *Sync) localToRemote(ctx context.Context) {
 		server := server
 		go func() {
 			s.runLoop(ctx, func(loopCtx context.Context) error {
-				return s.localToRemoteOnce(loopCtx, localFS, server)
+				return s.done(s.localToRemoteOnce(loopCtx, localFS, server), server)
 			})
 			exit <- true
 		}()
@@ -137,7 +142,7 @@ func (s *Sync) remoteToLocal(ctx context.Context) {
 		server := server
 		go func() {
 			s.runLoop(ctx, func(loopCtx context.Context) error {
-				return s.remoteToLocalOnce(loopCtx, localFS, server)
+				return s.done(s.remoteToLocalOnce(loopCtx, localFS, server), server)
 			})
 			exit <- true
 		}()
@@ -162,7 +167,7 @@ func (s *Sync) remoteToRemote(ctx context.Context) {
 		server := server
 		go func() {
 			s.runLoop(ctx, func(loopCtx context.Context) error {
-				return s.remoteToRemoteOnce(loopCtx, sourceServer, server)
+				return s.done(s.remoteToRemoteOnce(loopCtx, sourceServer, server), sourceServer, server)
 			})
 			exit <- true
 		}()
@@ -205,7 +210,7 @@ func (s *Sync) bidirectionalLocalRemote(ctx context.Context) {
 	}
 
 	s.runBidirectionalLoop(ctx, s.To.Server, func(loopCtx context.Context, server string) error {
-		return s.bidirectionalLocalRemoteOnce(loopCtx, localFS, server)
+		return s.done(s.bidirectionalLocalRemoteOnce(loopCtx, localFS, server), server)
 	})
 
 	s.Progress.Wait()
@@ -219,7 +224,7 @@ func (s *Sync) bidirectionalRemoteLocal(ctx context.Context) {
 	}
 
 	s.runBidirectionalLoop(ctx, s.From.Server, func(loopCtx context.Context, server string) error {
-		return s.bidirectionalRemoteLocalOnce(loopCtx, localFS, server)
+		return s.done(s.bidirectionalRemoteLocalOnce(loopCtx, localFS, server), server)
 	})
 
 	s.Progress.Wait()
@@ -233,7 +238,7 @@ func (s *Sync) bidirectionalRemoteRemote(ctx context.Context) {
 	sourceServer := s.From.Server[0]
 
 	s.runBidirectionalLoop(ctx, s.To.Server, func(loopCtx context.Context, server string) error {
-		return s.bidirectionalRemoteRemoteOnce(loopCtx, sourceServer, server)
+		return s.done(s.bidirectionalRemoteRemoteOnce(loopCtx, sourceServer, server), sourceServer, server)
 	})
 
 	s.Progress.Wait()
@@ -273,7 +278,10 @@ func (s *Sync) runBidirectionalLoop(ctx context.Context, servers []string, fn fu
 	_ = runCycle(ctx)
 }
 
-func (s *Sync) localToRemoteOnce(ctx context.Context, localFS FileSystem, server string) error {
+func (s *Sync) localToRemoteOnce(ctx context.Context, localFS FileSystem, server string) (err error) {
+	transfer := s.startTransfer(server, s.From.Path, server+":"+s.To.Path[0])
+	defer func() { transfer.Done(err) }()
+
 	conn := s.createSyncConnect(server, s.To.Server)
 	if conn == nil {
 		return fmt.Errorf("%s connect error", server)
@@ -292,13 +300,17 @@ func (s *Sync) localToRemoteOnce(ctx context.Context, localFS FileSystem, server
 		Permission:        s.Permission,
 		ParallelNum:       s.ParallelNum,
 		Output:            conn.Output,
+		Transfer:          transfer,
 		SourceLabel:       "local",
 		TargetLabel:       server,
 		SourcePathDisplay: newDisplayPathResolver(s.From.Path, s.From.DisplayPath),
 	})
 }
 
-func (s *Sync) remoteToLocalOnce(ctx context.Context, localFS FileSystem, server string) error {
+func (s *Sync) remoteToLocalOnce(ctx context.Context, localFS FileSystem, server string) (err error) {
+	transfer := s.startTransfer(server, remotePaths(server, s.From.Path), s.To.Path[0])
+	defer func() { transfer.Done(err) }()
+
 	conn := s.createSyncConnect(server, s.From.Server)
 	if conn == nil {
 		return fmt.Errorf("%s connect error", server)
@@ -322,13 +334,17 @@ func (s *Sync) remoteToLocalOnce(ctx context.Context, localFS FileSystem, server
 		Permission:        s.Permission,
 		ParallelNum:       s.ParallelNum,
 		Output:            conn.Output,
+		Transfer:          transfer,
 		SourceLabel:       server,
 		TargetLabel:       "local",
 		TargetPathDisplay: destinationDisplayResolver(s.To.Path[0], s.displayPathAt(s.To.DisplayPath, 0, s.To.Path[0]), len(s.From.Server) > 1, server),
 	})
 }
 
-func (s *Sync) remoteToRemoteOnce(ctx context.Context, sourceServer string, targetServer string) error {
+func (s *Sync) remoteToRemoteOnce(ctx context.Context, sourceServer string, targetServer string) (err error) {
+	transfer := s.startTransfer(targetServer, remotePaths(sourceServer, s.From.Path), targetServer+":"+s.To.Path[0])
+	defer func() { transfer.Done(err) }()
+
 	srcConn := s.createSyncConnect(sourceServer, []string{sourceServer})
 	dstConn := s.createSyncConnect(targetServer, s.To.Server)
 	if srcConn == nil || dstConn == nil {
@@ -350,12 +366,16 @@ func (s *Sync) remoteToRemoteOnce(ctx context.Context, sourceServer string, targ
 		Permission:  s.Permission,
 		ParallelNum: s.ParallelNum,
 		Output:      dstConn.Output,
+		Transfer:    transfer,
 		SourceLabel: sourceServer,
 		TargetLabel: targetServer,
 	})
 }
 
-func (s *Sync) bidirectionalLocalRemoteOnce(ctx context.Context, localFS FileSystem, server string) error {
+func (s *Sync) bidirectionalLocalRemoteOnce(ctx context.Context, localFS FileSystem, server string) (err error) {
+	transfer := s.startTransfer(server, s.From.Path, server+":"+s.To.Path[0])
+	defer func() { transfer.Done(err) }()
+
 	conn := s.createSyncConnect(server, s.To.Server)
 	if conn == nil {
 		return fmt.Errorf("%s connect error", server)
@@ -373,6 +393,7 @@ func (s *Sync) bidirectionalLocalRemoteOnce(ctx context.Context, localFS FileSys
 		Permission:        s.Permission,
 		ParallelNum:       s.ParallelNum,
 		Output:            conn.Output,
+		Transfer:          transfer,
 		SourceLabel:       "local",
 		TargetLabel:       server,
 		SourcePathDisplay: newDisplayPathResolver(s.From.Path, s.From.DisplayPath),
@@ -385,13 +406,17 @@ func (s *Sync) bidirectionalLocalRemoteOnce(ctx context.Context, localFS FileSys
 		Permission:        s.Permission,
 		ParallelNum:       s.ParallelNum,
 		Output:            conn.Output,
+		Transfer:          transfer,
 		SourceLabel:       server,
 		TargetLabel:       "local",
 		TargetPathDisplay: newDisplayPathResolver(s.From.Path, s.From.DisplayPath),
 	})
 }
 
-func (s *Sync) bidirectionalRemoteLocalOnce(ctx context.Context, localFS FileSystem, server string) error {
+func (s *Sync) bidirectionalRemoteLocalOnce(ctx context.Context, localFS FileSystem, server string) (err error) {
+	transfer := s.startTransfer(server, remotePaths(server, s.From.Path), s.To.Path[0])
+	defer func() { transfer.Done(err) }()
+
 	conn := s.createSyncConnect(server, s.From.Server)
 	if conn == nil {
 		return fmt.Errorf("%s connect error", server)
@@ -414,6 +439,7 @@ func (s *Sync) bidirectionalRemoteLocalOnce(ctx context.Context, localFS FileSys
 		Permission:        s.Permission,
 		ParallelNum:       s.ParallelNum,
 		Output:            conn.Output,
+		Transfer:          transfer,
 		SourceLabel:       server,
 		TargetLabel:       "local",
 		TargetPathDisplay: destinationDisplayResolver(s.To.Path[0], s.displayPathAt(s.To.DisplayPath, 0, s.To.Path[0]), len(s.From.Server) > 1, server),
@@ -426,13 +452,17 @@ func (s *Sync) bidirectionalRemoteLocalOnce(ctx context.Context, localFS FileSys
 		Permission:        s.Permission,
 		ParallelNum:       s.ParallelNum,
 		Output:            conn.Output,
+		Transfer:          transfer,
 		SourceLabel:       "local",
 		TargetLabel:       server,
 		SourcePathDisplay: destinationDisplayResolver(s.To.Path[0], s.displayPathAt(s.To.DisplayPath, 0, s.To.Path[0]), len(s.From.Server) > 1, server),
 	})
 }
 
-func (s *Sync) bidirectionalRemoteRemoteOnce(ctx context.Context, sourceServer string, targetServer string) error {
+func (s *Sync) bidirectionalRemoteRemoteOnce(ctx context.Context, sourceServer string, targetServer string) (err error) {
+	transfer := s.startTransfer(targetServer, remotePaths(sourceServer, s.From.Path), targetServer+":"+s.To.Path[0])
+	defer func() { transfer.Done(err) }()
+
 	srcConn := s.createSyncConnect(sourceServer, []string{sourceServer})
 	dstConn := s.createSyncConnect(targetServer, s.To.Server)
 	if srcConn == nil || dstConn == nil {
@@ -453,6 +483,7 @@ func (s *Sync) bidirectionalRemoteRemoteOnce(ctx context.Context, sourceServer s
 		Permission:  s.Permission,
 		ParallelNum: s.ParallelNum,
 		Output:      dstConn.Output,
+		Transfer:    transfer,
 		SourceLabel: sourceServer,
 		TargetLabel: targetServer,
 	}); err != nil {
@@ -464,11 +495,61 @@ func (s *Sync) bidirectionalRemoteRemoteOnce(ctx context.Context, sourceServer s
 		Permission:  s.Permission,
 		ParallelNum: s.ParallelNum,
 		Output:      srcConn.Output,
+		Transfer:    transfer,
 		SourceLabel: targetServer,
 		TargetLabel: sourceServer,
 	})
 }
 
+// done marks servers as synced if err is nil, and returns err.
+func (s *Sync) done(err error, servers ...string) error {
+	if err != nil {
+		return err
+	}
+
+	s.syncedMu.Lock()
+	defer s.syncedMu.Unlock()
+	if s.synced == nil {
+		s.synced = map[string]bool{}
+	}
+	for _, server := range servers {
+		s.synced[server] = true
+	}
+	return nil
+}
+
+// Synced returns the hosts where a sync succeeded, after Start.
+func (s *Sync) Synced() []string {
+	s.syncedMu.Lock()
+	defer s.syncedMu.Unlock()
+
+	result := []string{}
+	for _, server := range append(append([]string(nil), s.From.Server...), s.To.Server...) {
+		if s.synced[server] {
+			result = append(result, server)
+		}
+	}
+	return result
+}
+
+// startTransfer returns the audit log transfer of a sync with server, or nil
+// on a dry run.
+func (s *Sync) startTransfer(server string, source []string, destination string) *audit.Transfer {
+	if s.DryRun {
+		return nil
+	}
+	return sshl.StartAuditTransfer(s.Config, server, source, destination)
+}
+
+// remotePaths returns paths as `server:path`.
+func remotePaths(server string, paths []string) []string {
+	result := make([]string, 0, len(paths))
+	for _, path := range paths {
+		result = append(result, server+":"+path)
+	}
+	return result
+}
+
 func (s *Sync) createSyncConnect(server string, serverList []string) *SyncConnect {
 	client, closer, err := s.Run.CreateSFTPClient(server)
 	if err != nil {
@@ -2,6 +2,8 @@ package sync
 
 import (
 	"context"
+	"errors"
+	"strings"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -38,3 +40,16 @@ func TestRunBidirectionalLoopRepeatsServerCyclesInDaemonMode(t *testing.T) {
 		t.Fatalf("expected at least two daemon cycles across both servers, got %d calls", got)
 	}
 }
+
+func TestSyncedHosts(t *testing.T) {
+	t.Parallel()
+
+	s := &Sync{From: SyncInfo{Server: []string{"src"}}, To: SyncInfo{Server: []string{"web01", "web02", "web03"}}}
+	_ = s.done(nil, "src", "web01")
+	_ = s.done(errors.New("web02 connect error"), "src", "web02")
+	_ = s.done(nil, "src", "web03")
+
+	if got := s.Synced(); strings.Join(got, ",") != "src,web01,web03" {
+		t.Fatalf("Synced() = %v, want [src web01 web03]", got)
+	}
+}